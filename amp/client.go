@@ -2,11 +2,14 @@ package amp
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 
 	"amp-sentinel/logger"
 )
@@ -64,6 +67,43 @@ func NewClient(binary, apiKey string, log logger.Logger) *Client {
 // Execute runs a prompt through Amp CLI with --stream-json and returns the result.
 // The onMessage callback is invoked for each streaming message (may be nil).
 func (c *Client) Execute(ctx context.Context, prompt string, opt ExecuteOption, onMessage MessageHandler) (*ExecuteResult, error) {
+	// Log command without the full prompt to avoid huge log entries
+	c.log.Debug("amp.execute",
+		logger.String("binary", c.binary),
+		logger.String("mode", opt.Mode),
+		logger.String("workdir", opt.WorkDir),
+		logger.Int("prompt_len", len(prompt)),
+	)
+	return c.run(ctx, opt, nil, func(settingsPath string) []string {
+		return c.buildArgs(prompt, opt, settingsPath)
+	}, onMessage)
+}
+
+// Continue sends a follow-up message to an existing thread (the SessionID
+// of an earlier execution) and returns the agent's reply. The message is
+// passed on stdin with --stream-json-input; the thread keeps its history.
+func (c *Client) Continue(ctx context.Context, threadID, message string, opt ExecuteOption, onMessage MessageHandler) (*ExecuteResult, error) {
+	if threadID == "" {
+		return nil, NonRetryable(errors.New("continue: thread id is required"))
+	}
+	input, err := json.Marshal(NewUserInputMessage(message))
+	if err != nil {
+		return nil, fmt.Errorf("marshal user input: %w", err)
+	}
+	c.log.Debug("amp.continue",
+		logger.String("binary", c.binary),
+		logger.String("thread_id", threadID),
+		logger.String("workdir", opt.WorkDir),
+		logger.Int("message_len", len(message)),
+	)
+	return c.run(ctx, opt, append(input, '\n'), func(settingsPath string) []string {
+		return c.buildContinueArgs(threadID, opt, settingsPath)
+	}, onMessage)
+}
+
+// run starts the Amp CLI with the arguments from buildArgs, feeds it input
+// on stdin (if any) and collects the streamed result.
+func (c *Client) run(ctx context.Context, opt ExecuteOption, input []byte, buildArgs func(settingsPath string) []string, onMessage MessageHandler) (*ExecuteResult, error) {
 	// Write settings file (permissions + MCP servers) so Amp enforces them
 	var settingsPath string
 	if len(opt.Permissions) > 0 || len(opt.MCPServers) > 0 {
@@ -75,20 +115,17 @@ func (c *Client) Execute(ctx context.Context, prompt string, opt ExecuteOption,
 		defer os.Remove(settingsPath)
 	}
 
-	args := c.buildArgs(prompt, opt, settingsPath)
-	// Log command without the full prompt to avoid huge log entries
-	c.log.Debug("amp.execute",
-		logger.String("binary", c.binary),
-		logger.String("mode", opt.Mode),
-		logger.String("workdir", opt.WorkDir),
-		logger.Int("prompt_len", len(prompt)),
-	)
-
-	cmd := exec.CommandContext(ctx, c.binary, args...)
+	cmd := exec.CommandContext(ctx, c.binary, buildArgs(settingsPath)...)
 	if opt.WorkDir != "" {
 		cmd.Dir = opt.WorkDir
 	}
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
 	cmd.Env = append(cmd.Environ(), "AMP_API_KEY="+c.apiKey)
+	// Keep the end of stderr to classify failures (rate limit, auth).
+	stderr := &tailBuffer{max: 4096}
+	cmd.Stderr = stderr
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -96,7 +133,10 @@ func (c *Client) Execute(ctx context.Context, prompt string, opt ExecuteOption,
 	}
 
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("start amp: %w", err)
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, NonRetryable(fmt.Errorf("start amp: %w", err))
+		}
+		return nil, Retryable(fmt.Errorf("start amp: %w", err))
 	}
 
 	var cmdDone bool
@@ -166,7 +206,7 @@ func (c *Client) Execute(ctx context.Context, prompt string, opt ExecuteOption,
 	if err := scanner.Err(); err != nil {
 		c.log.Warn("amp.scanner_error", logger.Err(err))
 		if result.Result == "" && result.Error == "" {
-			return result, fmt.Errorf("amp scanner: %w", err)
+			return result, Retryable(fmt.Errorf("amp scanner: %w", err))
 		}
 	}
 
@@ -179,15 +219,35 @@ func (c *Client) Execute(ctx context.Context, prompt string, opt ExecuteOption,
 		if ctx.Err() != nil {
 			return result, fmt.Errorf("amp execution cancelled: %w", ctx.Err())
 		}
-		return result, fmt.Errorf("amp exited with error: %w", err)
+		output := strings.TrimSpace(stderr.String())
+		err = fmt.Errorf("amp exited with error: %w", err)
+		if output != "" {
+			err = fmt.Errorf("%w: %s", err, truncate(output, 300))
+		}
+		return result, classifyOutput(err, result.Error+"\n"+output)
+	}
+
+	// A rate-limited run may still exit cleanly with an error result;
+	// surface it so the scheduler backs off instead of reporting it.
+	if result.IsError && rateLimitPattern.MatchString(result.Error) {
+		return result, RateLimited(fmt.Errorf("amp rate limited: %s", truncate(result.Error, 300)), parseRetryHint(result.Error))
 	}
 
 	return result, nil
 }
 
 func (c *Client) buildArgs(prompt string, opt ExecuteOption, settingsPath string) []string {
-	args := []string{"--execute", prompt, "--stream-json"}
+	return c.optionArgs([]string{"--execute", prompt, "--stream-json"}, opt, settingsPath)
+}
+
+// buildContinueArgs continues threadID, reading the next user message from
+// stdin.
+func (c *Client) buildContinueArgs(threadID string, opt ExecuteOption, settingsPath string) []string {
+	return c.optionArgs([]string{"threads", "continue", threadID, "--execute", "--stream-json", "--stream-json-input"}, opt, settingsPath)
+}
 
+// optionArgs appends the flags for opt to args.
+func (c *Client) optionArgs(args []string, opt ExecuteOption, settingsPath string) []string {
 	// NOTE: --dangerously-allow-all is intentionally NOT added.
 	// Callers must always provide explicit permissions.
 	// If no permissions are provided, Amp will use its default behavior
@@ -244,6 +304,22 @@ func writeSettingsFile(permissions []string, mcpServers map[string]MCPServerConf
 	return f.Name(), nil
 }
 
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	buf []byte
+	max int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		b.buf = b.buf[len(b.buf)-b.max:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string { return string(b.buf) }
+
 func truncate(s string, maxRunes int) string {
 	runes := []rune(s)
 	if len(runes) <= maxRunes {
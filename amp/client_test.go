@@ -0,0 +1,64 @@
+package amp
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"amp-sentinel/logger"
+)
+
+// fakeAmp writes a stand-in amp binary that records its arguments and stdin
+// in dir and streams a fixed thread.
+func fakeAmp(t *testing.T, dir string) string {
+	t.Helper()
+	script := `#!/bin/sh
+printf '%s\n' "$@" > "` + dir + `/args"
+cat > "` + dir + `/stdin"
+echo '{"type":"system","subtype":"init","session_id":"T-123"}'
+echo '{"type":"result","subtype":"success","result":"the retry path is fine","duration_ms":42,"num_turns":2}'
+`
+	path := filepath.Join(dir, "amp")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("write fake amp: %v", err)
+	}
+	return path
+}
+
+func TestClient_Continue(t *testing.T) {
+	dir := t.TempDir()
+	c := NewClient(fakeAmp(t, dir), "key", logger.Nop())
+
+	res, err := c.Continue(context.Background(), "T-123", "what about the retry path?", ExecuteOption{
+		Permissions: ReadOnlyPermissions(),
+	}, nil)
+	if err != nil {
+		t.Fatalf("Continue: %v", err)
+	}
+	if res.SessionID != "T-123" || res.Result != "the retry path is fine" || res.NumTurns != 2 {
+		t.Errorf("result = %+v", res)
+	}
+
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	got := strings.Fields(string(args))
+	want := []string{"threads", "continue", "T-123", "--execute", "--stream-json", "--stream-json-input", "--settings-file"}
+	if len(got) < len(want) || strings.Join(got[:len(want)], " ") != strings.Join(want, " ") {
+		t.Errorf("args = %v, want prefix %v", got, want)
+	}
+
+	stdin, _ := os.ReadFile(filepath.Join(dir, "stdin"))
+	var msg UserInputMessage
+	if err := json.Unmarshal(stdin, &msg); err != nil {
+		t.Fatalf("stdin is not a user input message: %q", stdin)
+	}
+	if msg.Type != "user" || len(msg.Message.Content) != 1 || msg.Message.Content[0].Text != "what about the retry path?" {
+		t.Errorf("stdin message = %+v", msg)
+	}
+
+	if _, err := c.Continue(context.Background(), "", "question", ExecuteOption{}, nil); err == nil {
+		t.Error("Continue without a thread id succeeded")
+	}
+}
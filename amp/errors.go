@@ -0,0 +1,102 @@
+package amp
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FailureClass tells the scheduler whether a failed diagnosis is worth retrying.
+type FailureClass string
+
+const (
+	// ClassRetryable is a transient failure (network, git, Amp crash).
+	ClassRetryable FailureClass = "retryable"
+	// ClassNonRetryable can never succeed on retry (unknown project, bad API key).
+	ClassNonRetryable FailureClass = "non_retryable"
+	// ClassRateLimited is an upstream rate limit; retry after the hint.
+	ClassRateLimited FailureClass = "rate_limited"
+)
+
+// Error is a classified diagnosis failure.
+type Error struct {
+	Class FailureClass
+	// Hint is how long the upstream asked us to wait (rate limits only, may be 0).
+	Hint time.Duration
+	Err  error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+func (e *Error) Unwrap() error { return e.Err }
+
+// FailureClass returns the class name; the scheduler reads it through an interface.
+func (e *Error) FailureClass() string { return string(e.Class) }
+
+// Retryable reports whether the failure may succeed on retry.
+func (e *Error) Retryable() bool { return e.Class != ClassNonRetryable }
+
+// RetryAfter returns the rate-limit hint.
+func (e *Error) RetryAfter() time.Duration { return e.Hint }
+
+// Retryable marks err as transient.
+func Retryable(err error) error { return classify(err, ClassRetryable, 0) }
+
+// NonRetryable marks err as permanent.
+func NonRetryable(err error) error { return classify(err, ClassNonRetryable, 0) }
+
+// RateLimited marks err as an upstream rate limit with an optional wait hint.
+func RateLimited(err error, hint time.Duration) error { return classify(err, ClassRateLimited, hint) }
+
+// classify wraps err unless it is nil or already classified, in which case
+// the innermost classification wins.
+func classify(err error, class FailureClass, hint time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	var classified *Error
+	if errors.As(err, &classified) {
+		return err
+	}
+	return &Error{Class: class, Hint: hint, Err: err}
+}
+
+var (
+	rateLimitPattern = regexp.MustCompile(`(?i)rate[ _-]?limit|too many requests|\b429\b|quota exceeded|overloaded`)
+	authPattern      = regexp.MustCompile(`(?i)unauthori[sz]ed|invalid api key|\b401\b|\b403\b|forbidden|not logged in`)
+	retryHintPattern = regexp.MustCompile(`(?i)(?:retry[- ]after|try again in|retry in)[:=\s]*(\d+(?:\.\d+)?)\s*(ms|milliseconds?|s|secs?|seconds?|m|mins?|minutes?)?`)
+)
+
+// classifyOutput classifies a failed Amp run from its error text and stderr.
+func classifyOutput(err error, output string) error {
+	switch {
+	case rateLimitPattern.MatchString(output):
+		return RateLimited(err, parseRetryHint(output))
+	case authPattern.MatchString(output):
+		return NonRetryable(err)
+	default:
+		return Retryable(err)
+	}
+}
+
+// parseRetryHint extracts a wait such as "retry after 30s" or "try again in
+// 2 minutes" from an error message. Bare numbers are seconds.
+func parseRetryHint(s string) time.Duration {
+	m := retryHintPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0
+	}
+	unit := time.Second
+	switch u := strings.ToLower(m[2]); {
+	case strings.HasPrefix(u, "ms"), strings.HasPrefix(u, "milli"):
+		unit = time.Millisecond
+	case strings.HasPrefix(u, "m"):
+		unit = time.Minute
+	}
+	return time.Duration(n * float64(unit))
+}
@@ -0,0 +1,49 @@
+package amp
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseRetryHint(t *testing.T) {
+	cases := map[string]time.Duration{
+		"429 Too Many Requests, retry after 30s":      30 * time.Second,
+		"Rate limit exceeded. Try again in 2 minutes": 2 * time.Minute,
+		"retry-after: 1500ms":                         1500 * time.Millisecond,
+		"Retry-After: 12":                             12 * time.Second,
+		"rate limited":                                0,
+	}
+	for in, want := range cases {
+		if got := parseRetryHint(in); got != want {
+			t.Errorf("parseRetryHint(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestClassifyOutput(t *testing.T) {
+	base := errors.New("amp exited with error: exit status 1")
+	cases := []struct {
+		output string
+		class  FailureClass
+		hint   time.Duration
+	}{
+		{"Error: rate limit reached, retry after 20s", ClassRateLimited, 20 * time.Second},
+		{"Error: invalid API key", ClassNonRetryable, 0},
+		{"panic: runtime error", ClassRetryable, 0},
+	}
+	for _, tc := range cases {
+		var e *Error
+		if !errors.As(classifyOutput(base, tc.output), &e) || e.Class != tc.class || e.Hint != tc.hint {
+			t.Errorf("%q: got %+v, want %s/%s", tc.output, e, tc.class, tc.hint)
+		}
+	}
+
+	// The innermost classification wins when errors are re-wrapped.
+	err := Retryable(fmt.Errorf("amp execution: %w", NonRetryable(base)))
+	var e *Error
+	if !errors.As(err, &e) || e.Retryable() || !errors.Is(err, base) {
+		t.Errorf("re-wrapped error lost its class: %v", err)
+	}
+}
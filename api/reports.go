@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"amp-sentinel/diagnosis"
+	"amp-sentinel/logger"
+	"amp-sentinel/store"
+
+	"github.com/google/uuid"
+)
+
+// Asker answers a follow-up question by continuing the Amp thread
+// (sessionID) of a finished diagnosis of the project.
+type Asker func(ctx context.Context, projectKey, sessionID, revision, question string) (*diagnosis.FollowUp, error)
+
+// askTimeout bounds a follow-up run, which answers within the request.
+const askTimeout = 10 * time.Minute
+
+// maxQuestionLen bounds follow-up questions, in bytes.
+const maxQuestionLen = 8 << 10
+
+// reportWithFollowUps is a report with its follow-up Q&A history.
+type reportWithFollowUps struct {
+	*store.DiagnosisReport
+	FollowUps []*store.ReportFollowUp `json:"follow_ups"`
+}
+
+// askRequest is the body of POST /admin/v1/reports/{task_id}/ask.
+type askRequest struct {
+	Question string `json:"question"`
+	By       string `json:"by"`
+}
+
+// handleReportAsk handles POST /admin/v1/reports/{task_id}/ask: it asks a
+// follow-up question in the diagnosis' Amp thread, records the Q&A turn in
+// the report history and returns it.
+func (s *Server) handleReportAsk(w http.ResponseWriter, r *http.Request, taskID string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.ask == nil {
+		writeError(w, http.StatusNotImplemented, "follow-up questions are not available")
+		return
+	}
+	var req askRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json: "+err.Error())
+		return
+	}
+	req.Question = strings.TrimSpace(req.Question)
+	if req.Question == "" {
+		writeError(w, http.StatusBadRequest, "question is required")
+		return
+	}
+	if len(req.Question) > maxQuestionLen {
+		writeError(w, http.StatusBadRequest, "question is too long")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	report, err := s.store.GetReport(ctx, taskID)
+	if err != nil {
+		s.log.Error("admin.get_report_failed", logger.String("task_id", taskID), logger.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to get report")
+		return
+	}
+	if report == nil {
+		writeError(w, http.StatusNotFound, "report not found")
+		return
+	}
+	// A reused report continues the thread of the diagnosis it came from.
+	sessionTaskID := report.TaskID
+	if report.ReusedFromID != "" {
+		sessionTaskID = report.ReusedFromID
+	}
+	task, err := s.store.GetTask(ctx, sessionTaskID)
+	if err != nil {
+		s.log.Error("admin.get_task_failed", logger.String("id", sessionTaskID), logger.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to get task")
+		return
+	}
+	if task == nil || task.SessionID == "" {
+		writeError(w, http.StatusConflict, "diagnosis has no amp session to continue")
+		return
+	}
+
+	// The answer comes back within this request: lift the server's write
+	// deadline for the run.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(askTimeout + 30*time.Second))
+	askCtx, askCancel := context.WithTimeout(r.Context(), askTimeout)
+	defer askCancel()
+	askedAt := time.Now()
+	answer, err := s.ask(askCtx, report.ProjectKey, task.SessionID, report.CommitHash, req.Question)
+	if err != nil {
+		s.log.Error("admin.ask_failed", logger.String("task_id", taskID), logger.Err(err))
+		writeError(w, http.StatusBadGateway, "follow-up failed: "+err.Error())
+		return
+	}
+
+	followUp := &store.ReportFollowUp{
+		ID:         "fu-" + uuid.New().String()[:8],
+		ReportID:   report.ID,
+		TaskID:     report.TaskID,
+		ProjectKey: report.ProjectKey,
+		Question:   req.Question,
+		Answer:     answer.Answer,
+		AskedBy:    req.By,
+		SessionID:  answer.SessionID,
+		CommitHash: answer.CommitHash,
+		Tainted:    answer.Tainted,
+		DurationMs: answer.DurationMs,
+		AskedAt:    askedAt,
+	}
+	if answer.Usage != nil {
+		followUp.InputTokens = answer.Usage.InputTokens
+		followUp.OutputTokens = answer.Usage.OutputTokens
+	}
+	saveCtx, saveCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer saveCancel()
+	if err := s.store.AddReportFollowUp(saveCtx, followUp); err != nil {
+		s.log.Error("admin.save_follow_up_failed", logger.String("task_id", taskID), logger.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to save follow-up")
+		return
+	}
+
+	s.log.Info("admin.report_follow_up",
+		logger.String("task_id", taskID),
+		logger.String("by", req.By),
+		logger.Bool("tainted", answer.Tainted),
+	)
+	writeJSON(w, http.StatusOK, followUp)
+}
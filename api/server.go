@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"amp-sentinel/budget"
 	"amp-sentinel/intake"
 	"amp-sentinel/logger"
 	"amp-sentinel/project"
@@ -24,6 +25,15 @@ type Server struct {
 	log       logger.Logger
 	resubmit  func(event *intake.RawEvent) (string, error)
 	authToken string
+
+	// reloadSilences refreshes the intake silence rules after a change (may be nil).
+	reloadSilences func() error
+	// rateLimitStats reports intake token bucket utilization (may be nil).
+	rateLimitStats func() []intake.RateLimitStat
+	// budgetUsage reports token budget usage (nil when budgets are disabled).
+	budgetUsage func() []budget.Status
+	// ask answers follow-up questions on reports (may be nil).
+	ask Asker
 }
 
 // NewServer creates a new Admin API server.
@@ -34,14 +44,22 @@ func NewServer(
 	log logger.Logger,
 	resubmit func(event *intake.RawEvent) (string, error),
 	authToken string,
+	reloadSilences func() error,
+	rateLimitStats func() []intake.RateLimitStat,
+	budgetUsage func() []budget.Status,
+	ask Asker,
 ) *Server {
 	return &Server{
-		store:     st,
-		registry:  reg,
-		sched:     sched,
-		log:       log,
-		resubmit:  resubmit,
-		authToken: authToken,
+		store:          st,
+		registry:       reg,
+		sched:          sched,
+		log:            log,
+		resubmit:       resubmit,
+		authToken:      authToken,
+		reloadSilences: reloadSilences,
+		rateLimitStats: rateLimitStats,
+		budgetUsage:    budgetUsage,
+		ask:            ask,
 	}
 }
 
@@ -60,11 +78,17 @@ func (s *Server) Handler() http.Handler {
 	mux.HandleFunc("/admin/v1/health", s.handleHealth)
 	mux.HandleFunc("/admin/v1/stats", s.handleStats)
 	mux.HandleFunc("/admin/v1/projects", s.handleProjects)
-	mux.HandleFunc("/admin/v1/incidents", s.handleEventsList)
-	mux.HandleFunc("/admin/v1/incidents/", s.handleEventsDetail)
+	mux.HandleFunc("/admin/v1/projects/", s.handleProjectsDetail)
+	mux.HandleFunc("/admin/v1/incidents", s.handleIncidentsList)
+	mux.HandleFunc("/admin/v1/incidents/", s.handleIncidentsDetail)
+	mux.HandleFunc("/admin/v1/events", s.handleEventsList)
+	mux.HandleFunc("/admin/v1/events/", s.handleEventsDetail)
 	mux.HandleFunc("/admin/v1/tasks", s.handleTasksList)
 	mux.HandleFunc("/admin/v1/tasks/", s.handleTasksDetail)
 	mux.HandleFunc("/admin/v1/reports/", s.handleReports)
+	mux.HandleFunc("/admin/v1/silences", s.handleSilencesList)
+	mux.HandleFunc("/admin/v1/silences/", s.handleSilencesDetail)
+	mux.HandleFunc("/admin/v1/budgets", s.handleBudgets)
 
 	if s.authToken == "" {
 		return mux
@@ -116,10 +140,30 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 
 	schedStats := s.sched.Stats()
 
-	writeJSON(w, http.StatusOK, map[string]any{
+	resp := map[string]any{
 		"usage":     usage,
 		"scheduler": schedStats,
-	})
+	}
+	if s.rateLimitStats != nil {
+		resp["rate_limits"] = s.rateLimitStats()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleBudgets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.budgetUsage == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"enabled": false, "budgets": []budget.Status{}})
+		return
+	}
+	usage := s.budgetUsage()
+	if usage == nil {
+		usage = []budget.Status{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"enabled": true, "budgets": usage})
 }
 
 func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
@@ -143,6 +187,81 @@ func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, sanitized)
 }
 
+func (s *Server) handleIncidentsList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	q := r.URL.Query()
+	filter := store.IncidentFilter{
+		ProjectKey: q.Get("project_key"),
+		Status:     q.Get("status"),
+		Severity:   q.Get("severity"),
+		Limit:      parseIntParam(q.Get("limit"), 50),
+		Offset:     parseIntParam(q.Get("offset"), 0),
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	incidents, err := s.store.ListIncidents(ctx, filter)
+	if err != nil {
+		s.log.Error("admin.list_incidents_failed", logger.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to list incidents")
+		return
+	}
+	writeJSON(w, http.StatusOK, incidents)
+}
+
+func (s *Server) handleIncidentsDetail(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/admin/v1/incidents/")
+	if path == "" {
+		writeError(w, http.StatusBadRequest, "incident id required")
+		return
+	}
+
+	// POST /admin/v1/incidents/{id}/retry
+	if strings.HasSuffix(path, "/retry") {
+		s.handleRetry(w, r, strings.TrimSuffix(path, "/retry"))
+		return
+	}
+
+	// GET /admin/v1/incidents/{id}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	inc, err := s.store.GetIncident(ctx, path)
+	if err != nil {
+		s.log.Error("admin.get_incident_failed", logger.String("id", path), logger.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to get incident")
+		return
+	}
+	if inc == nil {
+		writeError(w, http.StatusNotFound, "incident not found")
+		return
+	}
+
+	events, err := s.store.ListEvents(ctx, store.EventFilter{
+		IncidentID: inc.ID,
+		Limit:      parseIntParam(r.URL.Query().Get("event_limit"), 50),
+	})
+	if err != nil {
+		s.log.Error("admin.list_incident_events_failed", logger.String("id", path), logger.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to list incident events")
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		*store.Incident
+		Events []*store.Event `json:"events"`
+	}{inc, events})
+}
+
 func (s *Server) handleEventsList(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -154,6 +273,7 @@ func (s *Server) handleEventsList(w http.ResponseWriter, r *http.Request) {
 		ProjectKey: q.Get("project_key"),
 		Status:     q.Get("status"),
 		Severity:   q.Get("severity"),
+		IncidentID: q.Get("incident_id"),
 		Limit:      parseIntParam(q.Get("limit"), 50),
 		Offset:     parseIntParam(q.Get("offset"), 0),
 	}
@@ -171,19 +291,19 @@ func (s *Server) handleEventsList(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleEventsDetail(w http.ResponseWriter, r *http.Request) {
-	path := strings.TrimPrefix(r.URL.Path, "/admin/v1/incidents/")
-	if path == "" {
+	id := strings.TrimPrefix(r.URL.Path, "/admin/v1/events/")
+	if id == "" {
 		writeError(w, http.StatusBadRequest, "event id required")
 		return
 	}
 
-	// POST /admin/v1/incidents/{id}/retry
-	if strings.HasSuffix(path, "/retry") {
-		s.handleRetry(w, r, strings.TrimSuffix(path, "/retry"))
+	// POST /admin/v1/events/{id}/retry
+	if strings.HasSuffix(id, "/retry") {
+		s.handleEventRetry(w, r, strings.TrimSuffix(id, "/retry"))
 		return
 	}
 
-	// GET /admin/v1/incidents/{id}
+	// GET /admin/v1/events/{id}
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
@@ -192,9 +312,9 @@ func (s *Server) handleEventsDetail(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
-	evt, err := s.store.GetEvent(ctx, path)
+	evt, err := s.store.GetEvent(ctx, id)
 	if err != nil {
-		s.log.Error("admin.get_event_failed", logger.String("id", path), logger.Err(err))
+		s.log.Error("admin.get_event_failed", logger.String("id", id), logger.Err(err))
 		writeError(w, http.StatusInternalServerError, "failed to get event")
 		return
 	}
@@ -205,6 +325,7 @@ func (s *Server) handleEventsDetail(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, evt)
 }
 
+// handleRetry re-diagnoses an incident by resubmitting its first event.
 func (s *Server) handleRetry(w http.ResponseWriter, r *http.Request, id string) {
 	if r.Method != http.MethodPost {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -214,46 +335,49 @@ func (s *Server) handleRetry(w http.ResponseWriter, r *http.Request, id string)
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
-	storeEvt, err := s.store.GetEvent(ctx, id)
+	inc, err := s.store.GetIncident(ctx, id)
 	if err != nil {
 		s.log.Error("admin.retry_get_failed", logger.String("id", id), logger.Err(err))
-		writeError(w, http.StatusInternalServerError, "failed to get event")
+		writeError(w, http.StatusInternalServerError, "failed to get incident")
 		return
 	}
-	if storeEvt == nil {
-		writeError(w, http.StatusNotFound, "event not found")
+	if inc == nil {
+		writeError(w, http.StatusNotFound, "incident not found")
 		return
 	}
 
-	// Check for active tasks (pending/queued/running)
-	tasks, err := s.store.ListTasks(ctx, store.TaskFilter{EventID: id, Limit: 100})
+	storeEvt, err := s.store.GetEvent(ctx, inc.FirstEventID)
 	if err != nil {
-		s.log.Error("admin.retry_list_tasks_failed", logger.String("id", id), logger.Err(err))
-		writeError(w, http.StatusInternalServerError, "failed to check active tasks")
+		s.log.Error("admin.retry_get_failed", logger.String("id", id), logger.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to get event")
 		return
 	}
-	for _, t := range tasks {
-		if t.Status == store.StatusPending || t.Status == store.StatusQueued || t.Status == store.StatusRunning {
-			writeError(w, http.StatusConflict, "incident has an active diagnosis task: "+t.ID)
-			return
-		}
+	if storeEvt == nil {
+		writeError(w, http.StatusNotFound, "incident has no stored event")
+		return
 	}
 
 	event := &intake.RawEvent{
-		ID:         storeEvt.ID,
-		ProjectKey: storeEvt.ProjectKey,
-		Payload:    storeEvt.Payload,
-		Source:     storeEvt.Source,
-		Severity:   storeEvt.Severity,
-		Title:      storeEvt.Title,
-		ReceivedAt: storeEvt.ReceivedAt,
+		ID:          storeEvt.ID,
+		ProjectKey:  storeEvt.ProjectKey,
+		Payload:     storeEvt.Payload,
+		Source:      storeEvt.Source,
+		Severity:    inc.Severity,
+		Title:       storeEvt.Title,
+		ReceivedAt:  storeEvt.ReceivedAt,
+		Fingerprint: storeEvt.Fingerprint,
+		Credential:  storeEvt.Credential,
+		GroupKey:    inc.Fingerprint,
+	}
+
+	taskID, ok := s.retryEvent(ctx, w, event)
+	if !ok {
+		return
 	}
 
-	taskID, err := s.resubmit(event)
-	if err != nil {
-		s.log.Error("admin.retry_submit_failed", logger.String("id", id), logger.Err(err))
-		writeError(w, http.StatusInternalServerError, "failed to resubmit incident")
-		return
+	inc.TaskID = taskID
+	if err := s.store.UpdateIncident(ctx, inc); err != nil {
+		s.log.Warn("admin.retry_update_incident_failed", logger.String("id", id), logger.Err(err))
 	}
 
 	s.log.Info("admin.retry_submitted",
@@ -267,6 +391,81 @@ func (s *Server) handleRetry(w http.ResponseWriter, r *http.Request, id string)
 	})
 }
 
+// handleEventRetry re-diagnoses a single stored event. It covers events that
+// belong to no incident: those stored before grouping existed, cron events,
+// and events whose grouping failed.
+func (s *Server) handleEventRetry(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	storeEvt, err := s.store.GetEvent(ctx, id)
+	if err != nil {
+		s.log.Error("admin.retry_get_failed", logger.String("id", id), logger.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to get event")
+		return
+	}
+	if storeEvt == nil {
+		writeError(w, http.StatusNotFound, "event not found")
+		return
+	}
+
+	taskID, ok := s.retryEvent(ctx, w, &intake.RawEvent{
+		ID:          storeEvt.ID,
+		ProjectKey:  storeEvt.ProjectKey,
+		Payload:     storeEvt.Payload,
+		Source:      storeEvt.Source,
+		Severity:    storeEvt.Severity,
+		Title:       storeEvt.Title,
+		ReceivedAt:  storeEvt.ReceivedAt,
+		Fingerprint: storeEvt.Fingerprint,
+		Credential:  storeEvt.Credential,
+	})
+	if !ok {
+		return
+	}
+
+	s.log.Info("admin.retry_submitted",
+		logger.String("event_id", id),
+		logger.String("task_id", taskID),
+	)
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"event_id": id,
+		"task_id":  taskID,
+	})
+}
+
+// retryEvent resubmits event unless it already has an active task. On failure
+// it writes the error response and returns false.
+func (s *Server) retryEvent(ctx context.Context, w http.ResponseWriter, event *intake.RawEvent) (string, bool) {
+	// Check for active tasks (pending/queued/running)
+	tasks, err := s.store.ListTasks(ctx, store.TaskFilter{EventID: event.ID, Limit: 100})
+	if err != nil {
+		s.log.Error("admin.retry_list_tasks_failed", logger.String("event_id", event.ID), logger.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to check active tasks")
+		return "", false
+	}
+	for _, t := range tasks {
+		if t.Status == store.StatusPending || t.Status == store.StatusQueued || t.Status == store.StatusRunning {
+			writeError(w, http.StatusConflict, "event has an active diagnosis task: "+t.ID)
+			return "", false
+		}
+	}
+
+	taskID, err := s.resubmit(event)
+	if err != nil {
+		s.log.Error("admin.retry_submit_failed", logger.String("event_id", event.ID), logger.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to resubmit event")
+		return "", false
+	}
+	return taskID, true
+}
+
 func (s *Server) handleTasksList(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -295,17 +494,29 @@ func (s *Server) handleTasksList(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleTasksDetail(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-		return
-	}
-
 	id := strings.TrimPrefix(r.URL.Path, "/admin/v1/tasks/")
 	if id == "" {
 		writeError(w, http.StatusBadRequest, "task id required")
 		return
 	}
 
+	// POST /admin/v1/tasks/{id}/cancel
+	if strings.HasSuffix(id, "/cancel") {
+		s.handleTaskCancel(w, r, strings.TrimSuffix(id, "/cancel"))
+		return
+	}
+	// POST /admin/v1/tasks/{id}/priority
+	if strings.HasSuffix(id, "/priority") {
+		s.handleTaskPriority(w, r, strings.TrimSuffix(id, "/priority"))
+		return
+	}
+
+	// GET /admin/v1/tasks/{id}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
@@ -323,12 +534,20 @@ func (s *Server) handleTasksDetail(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleReports(w http.ResponseWriter, r *http.Request) {
+	taskID, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/admin/v1/reports/"), "/")
+	if action == "ask" {
+		s.handleReportAsk(w, r, taskID)
+		return
+	}
+	if action != "" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	taskID := strings.TrimPrefix(r.URL.Path, "/admin/v1/reports/")
 	if taskID == "" {
 		writeError(w, http.StatusBadRequest, "task_id required")
 		return
@@ -347,7 +566,13 @@ func (s *Server) handleReports(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusNotFound, "report not found")
 		return
 	}
-	writeJSON(w, http.StatusOK, report)
+	followUps, err := s.store.ListReportFollowUps(ctx, report.ID)
+	if err != nil {
+		s.log.Error("admin.list_follow_ups_failed", logger.String("task_id", taskID), logger.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to get report")
+		return
+	}
+	writeJSON(w, http.StatusOK, reportWithFollowUps{DiagnosisReport: report, FollowUps: followUps})
 }
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"amp-sentinel/intake"
+	"amp-sentinel/logger"
+	"amp-sentinel/store"
+
+	"github.com/google/uuid"
+)
+
+// handleSilencesList handles GET (list) and POST (create) on /admin/v1/silences.
+func (s *Server) handleSilencesList(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listSilences(w, r)
+	case http.MethodPost:
+		s.createSilence(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) listSilences(w http.ResponseWriter, r *http.Request) {
+	// Expired rules are kept for auditing; ?all=true includes them.
+	activeAt := time.Now()
+	if r.URL.Query().Get("all") == "true" {
+		activeAt = time.Time{}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	rules, err := s.store.ListSilenceRules(ctx, activeAt)
+	if err != nil {
+		s.log.Error("admin.list_silences_failed", logger.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to list silence rules")
+		return
+	}
+	writeJSON(w, http.StatusOK, rules)
+}
+
+func (s *Server) createSilence(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ProjectKey string                 `json:"project_key"`
+		Matchers   []store.SilenceMatcher `json:"matchers"`
+		Action     string                 `json:"action"`
+		Severity   string                 `json:"severity"`
+		Reason     string                 `json:"reason"`
+		CreatedBy  string                 `json:"created_by"`
+		ExpiresAt  time.Time              `json:"expires_at"`
+		Duration   string                 `json:"duration"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json: "+err.Error())
+		return
+	}
+
+	now := time.Now()
+	if req.Action == "" {
+		req.Action = intake.SilenceDrop
+	}
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid duration: "+req.Duration)
+			return
+		}
+		req.ExpiresAt = now.Add(d)
+	}
+	switch {
+	case strings.TrimSpace(req.Reason) == "":
+		writeError(w, http.StatusBadRequest, "reason is required")
+		return
+	case strings.TrimSpace(req.CreatedBy) == "":
+		writeError(w, http.StatusBadRequest, "created_by is required")
+		return
+	case req.ExpiresAt.IsZero():
+		writeError(w, http.StatusBadRequest, "expires_at or duration is required")
+		return
+	case !req.ExpiresAt.After(now):
+		writeError(w, http.StatusBadRequest, "expires_at must be in the future")
+		return
+	}
+	if req.ProjectKey != "" && !s.registry.Exists(req.ProjectKey) {
+		writeError(w, http.StatusBadRequest, "unknown project: "+req.ProjectKey)
+		return
+	}
+
+	rule := &store.SilenceRule{
+		ID:         "sil-" + uuid.New().String()[:8],
+		ProjectKey: req.ProjectKey,
+		Matchers:   req.Matchers,
+		Action:     req.Action,
+		Severity:   req.Severity,
+		Reason:     req.Reason,
+		CreatedBy:  req.CreatedBy,
+		CreatedAt:  now,
+		ExpiresAt:  req.ExpiresAt,
+	}
+	if err := intake.ValidateSilenceRule(toIntakeSilence(rule)); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := s.store.CreateSilenceRule(ctx, rule); err != nil {
+		s.log.Error("admin.create_silence_failed", logger.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to create silence rule")
+		return
+	}
+	s.silencesChanged()
+
+	s.log.Info("admin.silence_created",
+		logger.String("rule_id", rule.ID),
+		logger.String("project", rule.ProjectKey),
+		logger.String("created_by", rule.CreatedBy),
+	)
+	writeJSON(w, http.StatusCreated, rule)
+}
+
+// handleSilencesDetail handles GET and DELETE on /admin/v1/silences/{id}.
+// DELETE expires the rule immediately; the record is kept for auditing.
+func (s *Server) handleSilencesDetail(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/admin/v1/silences/")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "silence rule id required")
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	rule, err := s.store.GetSilenceRule(ctx, id)
+	if err != nil {
+		s.log.Error("admin.get_silence_failed", logger.String("id", id), logger.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to get silence rule")
+		return
+	}
+	if rule == nil {
+		writeError(w, http.StatusNotFound, "silence rule not found")
+		return
+	}
+	if r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, rule)
+		return
+	}
+
+	if now := time.Now(); rule.ExpiresAt.After(now) {
+		rule.ExpiresAt = now
+		if err := s.store.UpdateSilenceRule(ctx, rule); err != nil {
+			s.log.Error("admin.expire_silence_failed", logger.String("id", id), logger.Err(err))
+			writeError(w, http.StatusInternalServerError, "failed to expire silence rule")
+			return
+		}
+		s.silencesChanged()
+		s.log.Info("admin.silence_expired", logger.String("rule_id", id))
+	}
+	writeJSON(w, http.StatusOK, rule)
+}
+
+func (s *Server) silencesChanged() {
+	if s.reloadSilences == nil {
+		return
+	}
+	if err := s.reloadSilences(); err != nil {
+		s.log.Warn("admin.reload_silences_failed", logger.Err(err))
+	}
+}
+
+func toIntakeSilence(rule *store.SilenceRule) intake.SilenceRule {
+	matchers := make([]intake.SilenceMatcher, len(rule.Matchers))
+	for i, m := range rule.Matchers {
+		matchers[i] = intake.SilenceMatcher{Field: m.Field, Pattern: m.Pattern}
+	}
+	return intake.SilenceRule{
+		ID:         rule.ID,
+		ProjectKey: rule.ProjectKey,
+		Matchers:   matchers,
+		Action:     rule.Action,
+		Severity:   rule.Severity,
+		ExpiresAt:  rule.ExpiresAt,
+	}
+}
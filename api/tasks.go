@@ -0,0 +1,166 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"amp-sentinel/intake"
+	"amp-sentinel/logger"
+	"amp-sentinel/scheduler"
+)
+
+// actionRequest is the optional body of admin task and project actions.
+type actionRequest struct {
+	By     string `json:"by"`
+	Reason string `json:"reason"`
+	// Priority and Severity set the new priority of .../priority; Severity
+	// is a shorthand for the priority of that severity.
+	Priority int    `json:"priority"`
+	Severity string `json:"severity"`
+}
+
+// decodeAction reads an optional action body; an empty body is allowed.
+func decodeAction(w http.ResponseWriter, r *http.Request) (actionRequest, bool) {
+	var req actionRequest
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid json: "+err.Error())
+		return req, false
+	}
+	return req, true
+}
+
+// handleTaskCancel cancels a queued or running task.
+func (s *Server) handleTaskCancel(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	req, ok := decodeAction(w, r)
+	if !ok || !s.taskExists(w, r, id) {
+		return
+	}
+
+	status, err := s.sched.Cancel(id, req.By, req.Reason)
+	if errors.Is(err, scheduler.ErrTaskNotActive) || errors.Is(err, scheduler.ErrTaskLeased) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		s.log.Error("admin.cancel_task_failed", logger.String("id", id), logger.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to cancel task")
+		return
+	}
+
+	s.log.Info("admin.task_cancelled", logger.String("task_id", id), logger.String("by", req.By))
+	// A running task reports "running" until its worker stops it.
+	writeJSON(w, http.StatusOK, map[string]string{
+		"task_id": id,
+		"status":  string(status),
+	})
+}
+
+// handleTaskPriority changes the priority of a queued task.
+func (s *Server) handleTaskPriority(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	req, ok := decodeAction(w, r)
+	if !ok {
+		return
+	}
+	priority := req.Priority
+	if req.Severity != "" {
+		if !intake.ValidSeverities[req.Severity] {
+			writeError(w, http.StatusBadRequest, "invalid severity: "+req.Severity)
+			return
+		}
+		priority = intake.SeverityPriority(req.Severity)
+	}
+	if priority <= 0 {
+		writeError(w, http.StatusBadRequest, "priority or severity is required")
+		return
+	}
+	if !s.taskExists(w, r, id) {
+		return
+	}
+
+	err := s.sched.SetPriority(id, priority, req.By, req.Reason)
+	if errors.Is(err, scheduler.ErrTaskNotActive) || errors.Is(err, scheduler.ErrTaskNotQueued) || errors.Is(err, scheduler.ErrTaskLeased) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		s.log.Error("admin.set_priority_failed", logger.String("id", id), logger.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to change priority")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"task_id":  id,
+		"priority": priority,
+	})
+}
+
+// taskExists writes 404 and returns false if the task is not in the store.
+func (s *Server) taskExists(w http.ResponseWriter, r *http.Request, id string) bool {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	task, err := s.store.GetTask(ctx, id)
+	if err != nil {
+		s.log.Error("admin.get_task_failed", logger.String("id", id), logger.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to get task")
+		return false
+	}
+	if task == nil {
+		writeError(w, http.StatusNotFound, "task not found")
+		return false
+	}
+	return true
+}
+
+// handleProjectsDetail handles POST /admin/v1/projects/{key}/pause and
+// /resume. A paused project keeps queuing tasks but none are dispatched.
+func (s *Server) handleProjectsDetail(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/admin/v1/projects/")
+	key, action, found := strings.Cut(path, "/")
+	if !found || (action != "pause" && action != "resume") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.registry.Exists(key) {
+		writeError(w, http.StatusNotFound, "project not found")
+		return
+	}
+	req, ok := decodeAction(w, r)
+	if !ok {
+		return
+	}
+
+	var changed bool
+	if action == "pause" {
+		changed = s.sched.PauseProject(key, req.By, req.Reason)
+	} else {
+		changed = s.sched.ResumeProject(key, req.By, req.Reason)
+	}
+	s.log.Info("admin.project_"+action,
+		logger.String("project", key),
+		logger.String("by", req.By),
+		logger.Bool("changed", changed),
+	)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"project_key": key,
+		"paused":      action == "pause",
+		"changed":     changed,
+	})
+}
@@ -169,13 +169,13 @@ async function loadDashboard() {
 function renderStatusChart(bs) {
     const ctx = document.getElementById('chart-status');
     if (!ctx) return;
-    const data = [bs.completed||0, bs.failed||0, bs.running||0, bs.pending||0, bs.timeout||0];
+    const data = [bs.completed||0, bs.failed||0, bs.running||0, (bs.pending||0)+(bs.queued||0), bs.timeout||0, bs.expired||0];
     if (statusChart) { statusChart.data.datasets[0].data = data; statusChart.update(); return; }
     statusChart = new Chart(ctx, {
         type: 'doughnut',
         data: {
-            labels: ['已完成','失败','运行中','等待中','超时'],
-            datasets: [{ data, backgroundColor: ['#10b981','#ef4444','#f59e0b','#3b82f6','#f97316'], borderWidth: 0 }]
+            labels: ['已完成','失败','运行中','等待中','超时','已过期'],
+            datasets: [{ data, backgroundColor: ['#10b981','#ef4444','#f59e0b','#3b82f6','#f97316','#94a3b8'], borderWidth: 0 }]
         },
         options: {
             responsive: true, maintainAspectRatio: false, cutout: '60%',
@@ -205,6 +205,11 @@ function renderTokensChart(input, output) {
     });
 }
 
+function countBadge(n) {
+    if (!n || n <= 1) return '';
+    return `<span class="inline-block px-1.5 py-0.5 rounded bg-slate-100 text-slate-500 text-xs font-medium">×${n}</span>`;
+}
+
 function renderRecentIncidents(list) {
     const el = document.getElementById('recent-incidents');
     if (!list.length) { el.innerHTML = '<div class="text-slate-400 text-sm py-4 text-center">暂无故障事件</div>'; return; }
@@ -212,11 +217,12 @@ function renderRecentIncidents(list) {
         <div class="flex items-center justify-between py-2 px-3 rounded-lg hover:bg-blue-50 cursor-pointer transition-colors" onclick="showIncidentDetail('${i.id}')">
             <div class="flex items-center gap-3 min-w-0">
                 ${sevBadge(i.severity)}
-                <span class="text-sm truncate text-slate-700">${esc(i.title||i.error_msg||i.id)}</span>
+                <span class="text-sm truncate text-slate-700">${esc(i.title||i.id)}</span>
+                ${countBadge(i.event_count)}
             </div>
             <div class="flex items-center gap-3 text-xs text-slate-400 flex-shrink-0 ml-3">
                 <span>${esc(i.project_key)}</span>
-                <span>${fmtTime(i.occurred_at)}</span>
+                <span>${fmtTime(i.last_seen)}</span>
             </div>
         </div>`).join('');
 }
@@ -240,11 +246,11 @@ async function loadIncidents(offset) {
             tb.innerHTML = list.map(i => `
                 <tr class="hover:bg-blue-50/50 transition-colors">
                     <td class="px-4 py-3 text-xs font-mono text-slate-400">${esc(i.id)}</td>
-                    <td class="px-4 py-3 text-sm max-w-xs truncate text-slate-700">${esc(i.title||i.error_msg||'-')}</td>
+                    <td class="px-4 py-3 text-sm max-w-xs truncate text-slate-700">${esc(i.title||'-')} ${countBadge(i.event_count)}</td>
                     <td class="px-4 py-3 text-sm text-slate-600">${esc(i.project_key)}</td>
                     <td class="px-4 py-3">${sevBadge(i.severity)}</td>
                     <td class="px-4 py-3 text-sm text-slate-400">${esc(i.source||'-')}</td>
-                    <td class="px-4 py-3 text-sm text-slate-400">${fmtTime(i.occurred_at)}</td>
+                    <td class="px-4 py-3 text-sm text-slate-400">${fmtTime(i.last_seen)}</td>
                     <td class="px-4 py-3">
                         <div class="flex gap-1">
                             <button onclick="showIncidentDetail('${i.id}')" class="text-blue-600 hover:text-blue-700 text-xs px-2 py-1 rounded hover:bg-blue-50">详情</button>
@@ -307,6 +313,9 @@ async function loadProjectsPage() {
     const el = document.getElementById('projects-grid');
     try {
         const data = projects.length ? projects : (await api('/projects') || []);
+        const budgets = (await api('/budgets').catch(() => null))?.budgets || [];
+        const budgetRows = key => budgets.filter(b => b.project_key === key).map(b => `
+                    <div class="flex justify-between"><span class="text-slate-400">${b.period === 'daily' ? '今日预算' : '本月预算'}</span><span class="${b.over ? 'text-red-500 font-medium' : 'text-slate-600'}">${b.percent}% · ${b.used.toLocaleString()} / ${b.limit.toLocaleString()}</span></div>`).join('');
         if (!data.length) { el.innerHTML = '<div class="text-slate-400 col-span-full text-center py-8">暂未注册项目</div>'; return; }
         el.innerHTML = data.map(p => `
             <div class="bg-white rounded-xl p-5 border border-slate-200 hover:border-blue-300 hover:shadow-md transition-all shadow-sm">
@@ -318,6 +327,7 @@ async function loadProjectsPage() {
                     <div class="flex justify-between"><span class="text-slate-400">Key</span><span class="font-mono text-slate-600">${esc(p.key)}</span></div>
                     <div class="flex justify-between"><span class="text-slate-400">语言</span><span class="text-slate-600">${esc(p.language||'-')}</span></div>
                     <div class="flex justify-between"><span class="text-slate-400">分支</span><span class="font-mono text-slate-600">${esc(p.branch||'main')}</span></div>
+                    ${budgetRows(p.key)}
                     ${p.owners?.length ? `<div class="flex justify-between"><span class="text-slate-400">负责人</span><span class="text-slate-600">${p.owners.map(o=>esc(o)).join(', ')}</span></div>` : ''}
                     ${p.skills?.length ? `<div class="pt-2 border-t border-slate-100"><span class="text-slate-400 text-xs">Skills:</span><div class="flex flex-wrap gap-1 mt-1">${p.skills.map(s=>`<span class="bg-blue-50 text-blue-600 text-xs px-2 py-0.5 rounded-full">${esc(s)}</span>`).join('')}</div></div>` : ''}
                 </div>
@@ -359,21 +369,29 @@ window.showIncidentDetail = async function(id) {
     content.innerHTML = '<div class="text-slate-400">加载中...</div>';
     try {
         const i = await api('/incidents/' + id);
+        const events = i.events || [];
         content.innerHTML = `
             <div class="space-y-4">
                 <div class="grid grid-cols-2 gap-4">
                     <div><span class="text-slate-400 text-sm">ID</span><div class="font-mono text-sm text-slate-700">${esc(i.id)}</div></div>
                     <div><span class="text-slate-400 text-sm">项目</span><div class="text-slate-700">${esc(i.project_key)}</div></div>
                     <div><span class="text-slate-400 text-sm">严重度</span><div>${sevBadge(i.severity)}</div></div>
-                    <div><span class="text-slate-400 text-sm">环境</span><div class="text-slate-700">${esc(i.environment||'-')}</div></div>
+                    <div><span class="text-slate-400 text-sm">状态</span><div class="text-slate-700">${esc(i.status||'-')}</div></div>
                     <div><span class="text-slate-400 text-sm">来源</span><div class="text-slate-700">${esc(i.source||'-')}</div></div>
-                    <div><span class="text-slate-400 text-sm">发生时间</span><div class="text-slate-700">${fmtTime(i.occurred_at)}</div></div>
+                    <div><span class="text-slate-400 text-sm">事件数</span><div class="text-slate-700">${i.event_count||0}</div></div>
+                    <div><span class="text-slate-400 text-sm">首次发生</span><div class="text-slate-700">${fmtTime(i.first_seen)}</div></div>
+                    <div><span class="text-slate-400 text-sm">最近发生</span><div class="text-slate-700">${fmtTime(i.last_seen)}</div></div>
                 </div>
                 <div><span class="text-slate-400 text-sm">标题</span><div class="mt-1 text-slate-800 font-medium">${esc(i.title||'-')}</div></div>
-                ${i.error_msg ? `<div><span class="text-slate-400 text-sm">错误信息</span><pre class="mt-1 bg-red-50 border border-red-100 rounded-lg p-3 text-sm text-red-600 overflow-x-auto whitespace-pre-wrap">${esc(i.error_msg)}</pre></div>` : ''}
-                ${i.stacktrace ? `<div><span class="text-slate-400 text-sm">堆栈跟踪</span><pre class="mt-1 bg-slate-50 border border-slate-200 rounded-lg p-3 text-xs text-slate-600 overflow-x-auto max-h-64 overflow-y-auto">${esc(i.stacktrace)}</pre></div>` : ''}
-                ${i.url ? `<div><span class="text-slate-400 text-sm">URL</span><div class="mt-1 font-mono text-sm text-blue-600 break-all">${esc(i.url)}</div></div>` : ''}
-                ${i.metadata && Object.keys(i.metadata).length ? `<div><span class="text-slate-400 text-sm">元数据</span><pre class="mt-1 bg-slate-50 border border-slate-200 rounded-lg p-3 text-sm text-slate-600">${esc(JSON.stringify(i.metadata,null,2))}</pre></div>` : ''}
+                ${i.task_id ? `<div><span class="text-slate-400 text-sm">诊断任务</span><div class="mt-1"><button onclick="showTaskDetail('${i.task_id}')" class="font-mono text-sm text-blue-600 hover:underline">${esc(i.task_id)}</button></div></div>` : ''}
+                ${events.length ? `<div><span class="text-slate-400 text-sm">关联事件</span>
+                    <div class="mt-1 border border-slate-200 rounded-lg divide-y divide-slate-100 max-h-64 overflow-y-auto">
+                        ${events.map(e => `<div class="flex items-center justify-between px-3 py-2 text-sm">
+                            <span class="font-mono text-xs text-slate-400">${esc(e.id)}</span>
+                            <span class="text-slate-500">${esc(e.status)}</span>
+                            <span class="text-slate-400 text-xs">${fmtTime(e.received_at)}</span>
+                        </div>`).join('')}
+                    </div></div>` : ''}
                 <div class="pt-4 border-t border-slate-200">
                     <button onclick="retryIncident('${i.id}')" class="bg-orange-500 hover:bg-orange-600 text-white px-4 py-2 rounded-lg text-sm transition-colors shadow-sm">🔄 重新诊断</button>
                 </div>
@@ -427,9 +445,13 @@ window.showTaskDetail = async function(id) {
                         <div><span class="text-slate-400 text-sm">对话轮次</span><div class="text-slate-700">${t.num_turns||'-'}</div></div>
                         <div><span class="text-slate-400 text-sm">Token</span><div class="text-slate-700">${t.input_tokens ? fmtNum(t.input_tokens)+' 输入 / '+fmtNum(t.output_tokens)+' 输出' : '-'}</div></div>
                         <div><span class="text-slate-400 text-sm">重试次数</span><div class="text-slate-700">${t.retry_count||0}</div></div>
+                        ${t.failure_class ? `<div><span class="text-slate-400 text-sm">失败类型</span><div class="font-mono text-sm text-slate-700">${esc(t.failure_class)}</div></div>` : ''}
                         ${t.session_id ? `<div><span class="text-slate-400 text-sm">Amp 会话</span><div class="font-mono text-xs text-blue-600">${esc(t.session_id)}</div></div>` : ''}
                         <div><span class="text-slate-400 text-sm">创建时间</span><div class="text-slate-700">${fmtTime(t.created_at)}</div></div>
                     </div>
+                    ${(t.attempts||[]).length ? `<div><span class="text-slate-400 text-sm">失败尝试</span><ul class="mt-1 space-y-1 text-sm text-slate-600">${t.attempts.map(a => `<li>#${a.number+1} · ${fmtTime(a.finished_at)} · <span class="font-mono">${esc(a.class)}</span> · ${esc(a.error)}${a.backoff_ms ? ' · '+(a.backoff_ms/1000).toFixed(1)+' 秒后重试' : ''}</li>`).join('')}</ul></div>` : ''}
+                    ${(t.actions||[]).length ? `<div><span class="text-slate-400 text-sm">管理操作</span><ul class="mt-1 space-y-1 text-sm text-slate-600">${t.actions.map(a => `<li>${fmtTime(a.at)} · ${esc(a.detail||a.action)}${a.by ? ' · '+esc(a.by) : ''}${a.reason ? ' · '+esc(a.reason) : ''}</li>`).join('')}</ul></div>` : ''}
+                    ${isActive ? `<div><button onclick="cancelTask('${t.id}')" class="bg-red-500 hover:bg-red-600 text-white px-4 py-2 rounded-lg text-sm transition-colors shadow-sm">⏹ 取消任务</button></div>` : ''}
                     ${t.error ? `<div><span class="text-slate-400 text-sm">错误信息</span><pre class="mt-1 bg-red-50 border border-red-100 rounded-lg p-3 text-sm text-red-600 whitespace-pre-wrap">${esc(t.error)}</pre></div>` : ''}
                     ${rptHtml}
                 </div>`;
@@ -495,6 +517,16 @@ window.retryIncident = async function(id) {
     } catch (e) { showToast('重试失败: ' + e.message, 'error'); }
 };
 
+window.cancelTask = async function(id) {
+    const ok = await showConfirm('确定要取消该诊断任务吗？运行中的任务会被中止。');
+    if (!ok) return;
+    try {
+        const r = await api('/tasks/' + id + '/cancel', { method: 'POST' });
+        showToast(r.status === 'cancelled' ? '任务已取消' : '已请求取消，正在中止运行中的任务', 'success');
+        if (currentPage === 'tasks') loadTasks(taskOffset);
+    } catch (e) { showToast('取消失败: ' + e.message, 'error'); }
+};
+
 // ── Pagination ──
 
 function renderPagination(cid, count, offset, type) {
@@ -569,7 +601,7 @@ function sevBadge(s) {
 }
 
 function statusBadge(s) {
-    const m = { completed:'bg-emerald-50 text-emerald-600 border border-emerald-200', running:'bg-amber-50 text-amber-600 border border-amber-200', pending:'bg-blue-50 text-blue-600 border border-blue-200', queued:'bg-blue-50 text-blue-600 border border-blue-200', failed:'bg-red-50 text-red-600 border border-red-200', timeout:'bg-orange-50 text-orange-600 border border-orange-200' };
+    const m = { completed:'bg-emerald-50 text-emerald-600 border border-emerald-200', running:'bg-amber-50 text-amber-600 border border-amber-200', pending:'bg-blue-50 text-blue-600 border border-blue-200', queued:'bg-blue-50 text-blue-600 border border-blue-200', failed:'bg-red-50 text-red-600 border border-red-200', timeout:'bg-orange-50 text-orange-600 border border-orange-200', expired:'bg-slate-100 text-slate-500 border border-slate-200', cancelled:'bg-slate-100 text-slate-500 border border-slate-200' };
     return `<span class="${m[s]||'bg-slate-100 text-slate-500 border border-slate-200'} px-2 py-0.5 rounded-full text-xs font-medium">${esc(s||'unknown')}</span>`;
 }
 
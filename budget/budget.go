@@ -0,0 +1,389 @@
+// Package budget tracks token usage against daily and monthly budgets,
+// globally and per project, and decides what happens to tasks of a project
+// that is over budget.
+package budget
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"amp-sentinel/logger"
+)
+
+// Actions for tasks of an over-budget project.
+const (
+	ActionAllow   = "allow"   // run normally
+	ActionDegrade = "degrade" // run in the cheaper degraded mode
+	ActionDefer   = "defer"   // keep queued until the budget frees up
+)
+
+// Periods a budget applies to.
+const (
+	PeriodDaily   = "daily"
+	PeriodMonthly = "monthly"
+)
+
+// Limits caps token usage (input + output) per period. Zero is unlimited.
+type Limits struct {
+	Daily   int64
+	Monthly int64
+}
+
+func (l Limits) of(period string) int64 {
+	if period == PeriodDaily {
+		return l.Daily
+	}
+	return l.Monthly
+}
+
+// UsageSource reports tokens consumed since a time. An empty projectKey
+// sums every project. store.Store implements it.
+type UsageSource interface {
+	SumTokens(ctx context.Context, projectKey string, since time.Time) (int64, error)
+}
+
+// Warning is raised once per scope, period and threshold when usage crosses
+// a warn threshold.
+type Warning struct {
+	// ProjectKey is empty for the global budget.
+	ProjectKey string
+	Period     string
+	Used       int64
+	Limit      int64
+	Threshold  int
+}
+
+// Config holds budget settings.
+type Config struct {
+	Global   Limits
+	Projects map[string]Limits
+	// OverBudget maps severity to the action for an over-budget project's
+	// tasks. Missing severities default to degrade for critical and warning
+	// and defer for info.
+	OverBudget map[string]string
+	// WarnThresholds are usage percentages that raise a warning (default 80, 100).
+	WarnThresholds []int
+	// RefreshInterval re-reads usage from the source (default 5m), picking up
+	// tokens spent by other replicas.
+	RefreshInterval time.Duration
+	Source          UsageSource
+	// Warn is called for threshold crossings (may be nil). It runs in its
+	// own goroutine.
+	Warn func(Warning)
+}
+
+// Status is the usage of one budget in the current period.
+type Status struct {
+	ProjectKey string  `json:"project_key,omitempty"`
+	Period     string  `json:"period"`
+	Used       int64   `json:"used"`
+	Limit      int64   `json:"limit"`
+	Percent    float64 `json:"percent"`
+	Over       bool    `json:"over"`
+	ResetsAt   string  `json:"resets_at"`
+}
+
+// usage is the token count of one scope in the current day and month.
+type usage struct {
+	day, month int64
+}
+
+// Manager enforces budgets. Decide is cheap and safe to call from the
+// scheduler's dispatch path; usage is kept in memory, incremented by Record
+// and periodically reconciled with the source.
+type Manager struct {
+	cfg   Config
+	log   logger.Logger
+	clock func() time.Time
+
+	mu sync.Mutex
+	// usage by scope; the global scope is "".
+	usage map[string]*usage
+	// dayStart and monthStart begin the periods usage is counted in.
+	dayStart, monthStart time.Time
+	// warned holds the thresholds already warned in the current periods.
+	warned map[string]bool
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewManager creates a budget manager. Call Start to load usage.
+func NewManager(cfg Config, log logger.Logger) *Manager {
+	if len(cfg.WarnThresholds) == 0 {
+		cfg.WarnThresholds = []int{80, 100}
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = 5 * time.Minute
+	}
+	return &Manager{
+		cfg:    cfg,
+		log:    log,
+		clock:  time.Now,
+		usage:  make(map[string]*usage),
+		warned: make(map[string]bool),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start loads current usage and starts the refresh loop.
+func (m *Manager) Start() {
+	m.refresh()
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.cfg.RefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				m.refresh()
+			}
+		}
+	}()
+}
+
+// Stop ends the refresh loop.
+func (m *Manager) Stop() {
+	close(m.stop)
+	m.wg.Wait()
+}
+
+// refresh replaces in-memory usage with the source's totals.
+func (m *Manager) refresh() {
+	if m.cfg.Source == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	now := m.clock()
+	dayStart, monthStart := periodStarts(now)
+	fresh := make(map[string]*usage)
+	for _, scope := range m.scopes() {
+		day, err := m.cfg.Source.SumTokens(ctx, scope, dayStart)
+		if err != nil {
+			m.log.Warn("budget.refresh_failed", logger.String("project", scope), logger.Err(err))
+			return
+		}
+		month, err := m.cfg.Source.SumTokens(ctx, scope, monthStart)
+		if err != nil {
+			m.log.Warn("budget.refresh_failed", logger.String("project", scope), logger.Err(err))
+			return
+		}
+		fresh[scope] = &usage{day: day, month: month}
+	}
+
+	m.mu.Lock()
+	m.rollover(now)
+	m.usage = fresh
+	warnings := m.crossed()
+	m.mu.Unlock()
+	m.fire(warnings)
+}
+
+// scopes lists the global scope and every project with a budget.
+func (m *Manager) scopes() []string {
+	scopes := []string{""}
+	for key, l := range m.cfg.Projects {
+		if l.Daily > 0 || l.Monthly > 0 {
+			scopes = append(scopes, key)
+		}
+	}
+	sort.Strings(scopes[1:])
+	return scopes
+}
+
+// Record adds the tokens of a finished diagnosis.
+func (m *Manager) Record(projectKey string, tokens int64) {
+	if tokens <= 0 {
+		return
+	}
+	m.mu.Lock()
+	m.rollover(m.clock())
+	for _, scope := range []string{"", projectKey} {
+		u := m.usage[scope]
+		if u == nil {
+			u = &usage{}
+			m.usage[scope] = u
+		}
+		u.day += tokens
+		u.month += tokens
+	}
+	warnings := m.crossed()
+	m.mu.Unlock()
+	m.fire(warnings)
+}
+
+// Decide returns the action for a task of the project and severity, and
+// why it is not ActionAllow.
+func (m *Manager) Decide(projectKey, severity string) (action, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rollover(m.clock())
+
+	reason = m.overReason("", m.cfg.Global)
+	if reason == "" {
+		reason = m.overReason(projectKey, m.cfg.Projects[projectKey])
+	}
+	if reason == "" {
+		return ActionAllow, ""
+	}
+	if a, ok := m.cfg.OverBudget[severity]; ok {
+		return a, reason
+	}
+	if severity == "info" {
+		return ActionDefer, reason
+	}
+	return ActionDegrade, reason
+}
+
+// overReason describes the first exceeded limit of a scope, or returns "".
+// Callers hold mu.
+func (m *Manager) overReason(scope string, l Limits) string {
+	u := m.usage[scope]
+	if u == nil {
+		return ""
+	}
+	name := "global"
+	if scope != "" {
+		name = "project " + scope
+	}
+	switch {
+	case l.Daily > 0 && u.day >= l.Daily:
+		return fmt.Sprintf("%s over daily token budget (%d/%d)", name, u.day, l.Daily)
+	case l.Monthly > 0 && u.month >= l.Monthly:
+		return fmt.Sprintf("%s over monthly token budget (%d/%d)", name, u.month, l.Monthly)
+	}
+	return ""
+}
+
+// Usage returns the state of every configured budget.
+func (m *Manager) Usage() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rollover(m.clock())
+
+	var out []Status
+	for _, scope := range m.scopes() {
+		limits := m.limits(scope)
+		u := m.usage[scope]
+		if u == nil {
+			u = &usage{}
+		}
+		for _, period := range []string{PeriodDaily, PeriodMonthly} {
+			limit := limits.of(period)
+			if limit <= 0 {
+				continue
+			}
+			used := u.day
+			resets := m.dayStart.AddDate(0, 0, 1)
+			if period == PeriodMonthly {
+				used = u.month
+				resets = m.monthStart.AddDate(0, 1, 0)
+			}
+			out = append(out, Status{
+				ProjectKey: scope,
+				Period:     period,
+				Used:       used,
+				Limit:      limit,
+				Percent:    float64(int64(float64(used)/float64(limit)*1000)) / 10,
+				Over:       used >= limit,
+				ResetsAt:   resets.Format(time.RFC3339),
+			})
+		}
+	}
+	return out
+}
+
+func (m *Manager) limits(scope string) Limits {
+	if scope == "" {
+		return m.cfg.Global
+	}
+	return m.cfg.Projects[scope]
+}
+
+// rollover resets counters and warnings when a new day or month begins.
+// Callers hold mu.
+func (m *Manager) rollover(now time.Time) {
+	dayStart, monthStart := periodStarts(now)
+	if dayStart.Equal(m.dayStart) {
+		return
+	}
+	newMonth := !monthStart.Equal(m.monthStart)
+	for _, u := range m.usage {
+		u.day = 0
+		if newMonth {
+			u.month = 0
+		}
+	}
+	m.warned = make(map[string]bool)
+	m.dayStart, m.monthStart = dayStart, monthStart
+}
+
+// crossed returns threshold crossings not warned yet in this period.
+// Callers hold mu.
+func (m *Manager) crossed() []Warning {
+	var out []Warning
+	for _, scope := range m.scopes() {
+		u := m.usage[scope]
+		if u == nil {
+			continue
+		}
+		limits := m.limits(scope)
+		for _, period := range []string{PeriodDaily, PeriodMonthly} {
+			limit := limits.of(period)
+			if limit <= 0 {
+				continue
+			}
+			used := u.day
+			if period == PeriodMonthly {
+				used = u.month
+			}
+			// Only the highest crossed threshold is reported.
+			top := 0
+			for _, t := range m.cfg.WarnThresholds {
+				if used*100 >= limit*int64(t) && t > top {
+					top = t
+				}
+			}
+			key := fmt.Sprintf("%s|%s|%d", scope, period, top)
+			if top == 0 || m.warned[key] {
+				continue
+			}
+			for _, t := range m.cfg.WarnThresholds {
+				if t <= top {
+					m.warned[fmt.Sprintf("%s|%s|%d", scope, period, t)] = true
+				}
+			}
+			out = append(out, Warning{ProjectKey: scope, Period: period, Used: used, Limit: limit, Threshold: top})
+		}
+	}
+	return out
+}
+
+func (m *Manager) fire(warnings []Warning) {
+	for _, w := range warnings {
+		m.log.Warn("budget.threshold_crossed",
+			logger.String("project", w.ProjectKey),
+			logger.String("period", w.Period),
+			logger.Int64("used", w.Used),
+			logger.Int64("limit", w.Limit),
+			logger.Int("threshold", w.Threshold),
+		)
+		if m.cfg.Warn != nil {
+			go m.cfg.Warn(w)
+		}
+	}
+}
+
+// periodStarts returns the start of the local day and month containing t.
+func periodStarts(t time.Time) (day, month time.Time) {
+	y, mo, d := t.Date()
+	return time.Date(y, mo, d, 0, 0, 0, 0, t.Location()), time.Date(y, mo, 1, 0, 0, 0, 0, t.Location())
+}
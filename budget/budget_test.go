@@ -0,0 +1,181 @@
+package budget
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"amp-sentinel/logger"
+)
+
+// fakeSource reports fixed usage per project since any time.
+type fakeSource struct {
+	tokens map[string]int64
+}
+
+func (f *fakeSource) SumTokens(_ context.Context, projectKey string, _ time.Time) (int64, error) {
+	if projectKey == "" {
+		var total int64
+		for _, n := range f.tokens {
+			total += n
+		}
+		return total, nil
+	}
+	return f.tokens[projectKey], nil
+}
+
+func newTestManager(cfg Config, now *time.Time) *Manager {
+	m := NewManager(cfg, logger.Nop())
+	m.clock = func() time.Time { return *now }
+	return m
+}
+
+func TestManager_Decide(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	m := newTestManager(Config{
+		Global:   Limits{Daily: 10000},
+		Projects: map[string]Limits{"proj-a": {Daily: 100}},
+	}, &now)
+
+	if a, _ := m.Decide("proj-a", "info"); a != ActionAllow {
+		t.Fatalf("under budget: action = %s", a)
+	}
+	m.Record("proj-a", 150)
+
+	tests := []struct {
+		project, severity, want string
+	}{
+		{"proj-a", "critical", ActionDegrade},
+		{"proj-a", "warning", ActionDegrade},
+		{"proj-a", "info", ActionDefer},
+		{"proj-b", "info", ActionAllow},
+	}
+	for _, tt := range tests {
+		got, reason := m.Decide(tt.project, tt.severity)
+		if got != tt.want {
+			t.Errorf("Decide(%s, %s) = %s, want %s", tt.project, tt.severity, got, tt.want)
+		}
+		if got != ActionAllow && reason == "" {
+			t.Errorf("Decide(%s, %s): missing reason", tt.project, tt.severity)
+		}
+	}
+
+	// The global budget applies to every project.
+	m.Record("proj-b", 10000)
+	if a, _ := m.Decide("proj-b", "info"); a != ActionDefer {
+		t.Errorf("over global budget: action = %s, want defer", a)
+	}
+
+	// A new day resets daily usage.
+	now = now.Add(24 * time.Hour)
+	if a, _ := m.Decide("proj-a", "info"); a != ActionAllow {
+		t.Errorf("after rollover: action = %s, want allow", a)
+	}
+}
+
+func TestManager_OverBudgetOverride(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	m := newTestManager(Config{
+		Global:     Limits{Monthly: 100},
+		OverBudget: map[string]string{"critical": ActionAllow, "warning": ActionDefer},
+	}, &now)
+	m.Record("proj-a", 100)
+
+	if a, _ := m.Decide("proj-a", "critical"); a != ActionAllow {
+		t.Errorf("critical = %s, want allow", a)
+	}
+	if a, _ := m.Decide("proj-a", "warning"); a != ActionDefer {
+		t.Errorf("warning = %s, want defer", a)
+	}
+	// Monthly usage survives a new day but not a new month.
+	now = time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	if a, _ := m.Decide("proj-a", "warning"); a != ActionDefer {
+		t.Errorf("same month: warning = %s, want defer", a)
+	}
+	now = time.Date(2026, 4, 1, 0, 0, 1, 0, time.UTC)
+	if a, _ := m.Decide("proj-a", "warning"); a != ActionAllow {
+		t.Errorf("new month: warning = %s, want allow", a)
+	}
+}
+
+func TestManager_Warnings(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	var got []Warning
+	done := make(chan struct{}, 10)
+	m := newTestManager(Config{
+		Projects: map[string]Limits{"proj-a": {Daily: 100}},
+		Warn: func(w Warning) {
+			mu.Lock()
+			got = append(got, w)
+			mu.Unlock()
+			done <- struct{}{}
+		},
+	}, &now)
+
+	wait := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("warning not sent")
+			}
+		}
+	}
+
+	m.Record("proj-a", 50)
+	m.Record("proj-a", 35) // crosses 80%
+	wait(1)
+	m.Record("proj-a", 5)  // still between 80% and 100%: no new warning
+	m.Record("proj-a", 20) // crosses 100%
+	wait(1)
+	m.Record("proj-a", 50)
+
+	now = now.Add(24 * time.Hour)
+	m.Record("proj-a", 120) // new day: straight past 100%, reported once
+	wait(1)
+	time.Sleep(10 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []int{80, 100, 100}
+	if len(got) != len(want) {
+		t.Fatalf("warnings = %+v, want thresholds %v", got, want)
+	}
+	for i, w := range got {
+		if w.Threshold != want[i] || w.ProjectKey != "proj-a" || w.Period != PeriodDaily {
+			t.Errorf("warning %d = %+v, want threshold %d", i, w, want[i])
+		}
+	}
+}
+
+func TestManager_RefreshAndUsage(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	src := &fakeSource{tokens: map[string]int64{"proj-a": 60, "proj-b": 500}}
+	m := newTestManager(Config{
+		Global:   Limits{Monthly: 1000},
+		Projects: map[string]Limits{"proj-a": {Daily: 50, Monthly: 200}},
+		Source:   src,
+	}, &now)
+	m.refresh()
+
+	if a, _ := m.Decide("proj-a", "info"); a != ActionDefer {
+		t.Errorf("proj-a over daily budget after refresh: action = %s", a)
+	}
+
+	usage := m.Usage()
+	if len(usage) != 3 {
+		t.Fatalf("usage = %+v, want global monthly and proj-a daily and monthly", usage)
+	}
+	if u := usage[0]; u.ProjectKey != "" || u.Period != PeriodMonthly || u.Used != 560 || u.Over {
+		t.Errorf("global = %+v", u)
+	}
+	if u := usage[1]; u.ProjectKey != "proj-a" || u.Period != PeriodDaily || u.Used != 60 || !u.Over || u.Percent != 120 {
+		t.Errorf("proj-a daily = %+v", u)
+	}
+	if u := usage[1]; u.ResetsAt != "2026-03-11T00:00:00Z" {
+		t.Errorf("proj-a daily resets at %s", u.ResetsAt)
+	}
+}
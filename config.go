@@ -6,7 +6,11 @@ import (
 	"strings"
 	"time"
 
+	"amp-sentinel/budget"
+	"amp-sentinel/cron"
+	"amp-sentinel/intake"
 	"amp-sentinel/project"
+	"amp-sentinel/scheduler"
 
 	"gopkg.in/yaml.v3"
 )
@@ -15,6 +19,7 @@ import (
 type Config struct {
 	Amp       AmpConfig              `yaml:"amp"`
 	Scheduler SchedulerConfig        `yaml:"scheduler"`
+	Budget    BudgetCfg              `yaml:"budget"`
 	Intake    IntakeConfig           `yaml:"intake"`
 	Diagnosis DiagnosisCfg           `yaml:"diagnosis"`
 	Projects  []project.Project      `yaml:"projects"`
@@ -36,6 +41,40 @@ type DiagnosisCfg struct {
 	FingerprintReuseEnabled  bool   `yaml:"fingerprint_reuse_enabled"`
 	FingerprintReuseWindow   string `yaml:"fingerprint_reuse_window"`
 	FingerprintReuseMinScore int    `yaml:"fingerprint_reuse_min_score"`
+
+	// Review is the optional reviewer stage that challenges the first
+	// diagnosis (needs structured_output).
+	Review ReviewCfg `yaml:"review"`
+
+	// RecentChanges lists the commits made before the event in the prompt.
+	RecentChanges RecentChangesCfg `yaml:"recent_changes"`
+
+	// Version locates the deployed version in event payloads; projects
+	// override the fields and map build IDs (project.ProjectVersionConfig).
+	Version VersionCfg `yaml:"version"`
+}
+
+// VersionCfg configures diagnosing the deployed version of the code.
+type VersionCfg struct {
+	// Fields are payload paths of the version (commit SHA, tag or build
+	// ID), tried in order. Empty = always diagnose the branch tip.
+	Fields []string `yaml:"fields"`
+}
+
+// RecentChangesCfg configures deployment correlation.
+type RecentChangesCfg struct {
+	Enabled    bool   `yaml:"enabled"`
+	Window     string `yaml:"window"`      // look-back from the event time (default 24h)
+	MaxCommits int    `yaml:"max_commits"` // default 20
+}
+
+// ReviewCfg configures the reviewer stage; projects may override Enabled
+// and Severities (project.ProjectReviewConfig).
+type ReviewCfg struct {
+	Enabled    bool     `yaml:"enabled"`
+	Mode       string   `yaml:"mode"`       // Amp mode of the reviewer (default rush)
+	Timeout    string   `yaml:"timeout"`    // default 5m
+	Severities []string `yaml:"severities"` // empty = all severities
 }
 
 type AmpConfig struct {
@@ -50,26 +89,257 @@ type SchedulerConfig struct {
 	DefaultTimeout string `yaml:"default_timeout"`
 	RetryCount     int    `yaml:"retry_count"`
 	RetryDelay     string `yaml:"retry_delay"`
+	// RetryMaxDelay caps the exponential backoff between retries (default 5m).
+	RetryMaxDelay string `yaml:"retry_max_delay"`
+	// ProjectConcurrency caps running diagnoses per project (default 1);
+	// projects may override it with scheduler.max_concurrency.
+	ProjectConcurrency int `yaml:"project_concurrency"`
+	// AgingInterval raises a queued task one severity level per interval
+	// waited (empty disables aging).
+	AgingInterval string `yaml:"aging_interval"`
+	// MaxQueueWait bounds the queue wait per severity (critical/warning/info).
+	MaxQueueWait map[string]QueueWaitCfg `yaml:"max_queue_wait"`
+	// Lease runs several replicas on one shared queue in the store.
+	Lease LeaseCfg `yaml:"lease"`
+}
+
+// LeaseCfg configures distributed mode: tasks are claimed through leases
+// in the store, renewed by heartbeat and taken over once expired.
+type LeaseCfg struct {
+	Enabled bool `yaml:"enabled"`
+	// ReplicaID names this replica (default host, pid and a random suffix).
+	ReplicaID string `yaml:"replica_id"`
+	// Duration is how long a lease survives without a heartbeat (default 30s).
+	Duration string `yaml:"duration"`
+	// PollInterval is how often idle replicas look for tasks (default 2s).
+	PollInterval string `yaml:"poll_interval"`
+}
+
+// QueueWaitCfg is the max queue wait of one severity and what happens to
+// tasks that exceed it: "promote" (to critical, default) or "expire".
+type QueueWaitCfg struct {
+	Max    string `yaml:"max"`
+	Action string `yaml:"action"`
+}
+
+// queueWaitLimits converts max_queue_wait for the scheduler.
+func (s SchedulerConfig) queueWaitLimits() map[string]scheduler.QueueWaitLimit {
+	if len(s.MaxQueueWait) == 0 {
+		return nil
+	}
+	out := make(map[string]scheduler.QueueWaitLimit, len(s.MaxQueueWait))
+	for severity, w := range s.MaxQueueWait {
+		out[severity] = scheduler.QueueWaitLimit{Max: ParseDuration(w.Max, 0), Action: w.Action}
+	}
+	return out
+}
+
+// BudgetCfg configures token budgets. The global limits apply to all
+// projects together; projects set their own under projects[].budget.
+type BudgetCfg struct {
+	Enabled       bool  `yaml:"enabled"`
+	DailyTokens   int64 `yaml:"daily_tokens"`
+	MonthlyTokens int64 `yaml:"monthly_tokens"`
+	// DegradeMode is the Amp mode of degraded tasks (default rush).
+	DegradeMode string `yaml:"degrade_mode"`
+	// OverBudget maps severity to allow, degrade or defer (default
+	// critical and warning degrade, info defers).
+	OverBudget map[string]string `yaml:"over_budget"`
+	// WarnThresholds are usage percentages that send a Feishu warning
+	// (default [80, 100]).
+	WarnThresholds []int `yaml:"warn_thresholds"`
+	// RefreshInterval re-reads usage from the store (default 5m).
+	RefreshInterval string `yaml:"refresh_interval"`
+}
+
+// budgetConfig converts the budget section and per-project limits.
+func (c *Config) budgetConfig() budget.Config {
+	projects := make(map[string]budget.Limits)
+	for _, p := range c.Projects {
+		if p.Budget.DailyTokens > 0 || p.Budget.MonthlyTokens > 0 {
+			projects[p.Key] = budget.Limits{Daily: p.Budget.DailyTokens, Monthly: p.Budget.MonthlyTokens}
+		}
+	}
+	return budget.Config{
+		Global:          budget.Limits{Daily: c.Budget.DailyTokens, Monthly: c.Budget.MonthlyTokens},
+		Projects:        projects,
+		OverBudget:      c.Budget.OverBudget,
+		WarnThresholds:  c.Budget.WarnThresholds,
+		RefreshInterval: ParseDuration(c.Budget.RefreshInterval, 5*time.Minute),
+	}
 }
 
 type IntakeConfig struct {
-	Listen         string      `yaml:"listen"`
-	Dedup          DedupConfig `yaml:"dedup"`
-	RateLimit      int         `yaml:"rate_limit_per_hour"`
-	MinSeverity    string      `yaml:"min_severity"`
-	AuthToken      string      `yaml:"auth_token"`
-	MaxPayloadSize int         `yaml:"max_payload_size"`
+	Listen         string          `yaml:"listen"`
+	Dedup          DedupConfig     `yaml:"dedup"`
+	RateLimit      int             `yaml:"rate_limit_per_hour"`
+	MinSeverity    string          `yaml:"min_severity"`
+	AuthToken      string          `yaml:"auth_token"`
+	MaxPayloadSize int             `yaml:"max_payload_size"`
+	Alertmanager   AlertmanagerCfg `yaml:"alertmanager"`
+	OTLP           OTLPCfg         `yaml:"otlp"`
+	CloudEvents    CloudEventsCfg  `yaml:"cloudevents"`
+	// Credentials are named per-reporter secrets (see intake.Credential).
+	Credentials     []CredentialCfg `yaml:"credentials"`
+	SignatureWindow string          `yaml:"signature_window"`
+	// GroupWindow is the maximum gap between events grouped into one incident.
+	GroupWindow string `yaml:"group_window"`
+	// Redaction masks PII and secrets in payloads at intake time.
+	Redaction RedactionCfg `yaml:"redaction"`
+	// Profiles map per-source payload fields and severities (see intake.MappingProfile).
+	Profiles []MappingProfileCfg `yaml:"profiles"`
+	// RateLimits configures token-bucket quotas; rate_limit_per_hour remains
+	// the per-project default when rate_limits.project is unset.
+	RateLimits RateLimitsCfg `yaml:"rate_limits"`
+	// IdempotencyWindow is how long an Idempotency-Key is remembered.
+	IdempotencyWindow string `yaml:"idempotency_window"`
+}
+
+// RateLimitCfg is one token bucket: refill rate and burst capacity.
+type RateLimitCfg struct {
+	PerHour float64 `yaml:"per_hour"`
+	Burst   int     `yaml:"burst"`
+}
+
+// RateLimitsCfg configures the intake token buckets (see intake.RateLimitConfig).
+type RateLimitsCfg struct {
+	Global      RateLimitCfg            `yaml:"global"`
+	Project     RateLimitCfg            `yaml:"project"`
+	Projects    map[string]RateLimitCfg `yaml:"projects"`
+	Credentials map[string]RateLimitCfg `yaml:"credentials"`
+	Sources     map[string]RateLimitCfg `yaml:"sources"`
+}
+
+func (r RateLimitsCfg) rateLimitConfig() intake.RateLimitConfig {
+	convert := func(m map[string]RateLimitCfg) map[string]intake.RateLimit {
+		out := make(map[string]intake.RateLimit, len(m))
+		for k, v := range m {
+			out[k] = intake.RateLimit(v)
+		}
+		return out
+	}
+	return intake.RateLimitConfig{
+		Global:      intake.RateLimit(r.Global),
+		Project:     intake.RateLimit(r.Project),
+		Projects:    convert(r.Projects),
+		Credentials: convert(r.Credentials),
+		Sources:     convert(r.Sources),
+	}
+}
+
+// each calls fn for every bucket with its config path.
+func (r RateLimitsCfg) each(fn func(path string, l RateLimitCfg) error) error {
+	if err := fn("global", r.Global); err != nil {
+		return err
+	}
+	if err := fn("project", r.Project); err != nil {
+		return err
+	}
+	for group, m := range map[string]map[string]RateLimitCfg{"projects": r.Projects, "credentials": r.Credentials, "sources": r.Sources} {
+		for k, l := range m {
+			if err := fn(group+"."+k, l); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// MappingProfileCfg declares field paths and a severity map for one source.
+type MappingProfileCfg struct {
+	Source      string            `yaml:"source"`
+	Title       string            `yaml:"title"`
+	Message     string            `yaml:"message"`
+	Stack       string            `yaml:"stack"`
+	Environment string            `yaml:"environment"`
+	Severity    string            `yaml:"severity"`
+	SeverityMap map[string]string `yaml:"severity_map"`
+}
+
+func (p MappingProfileCfg) mappingProfile() intake.MappingProfile {
+	return intake.MappingProfile{
+		Source:      p.Source,
+		Title:       p.Title,
+		Message:     p.Message,
+		Stack:       p.Stack,
+		Environment: p.Environment,
+		Severity:    p.Severity,
+		SeverityMap: p.SeverityMap,
+	}
+}
+
+// RedactionCfg configures payload redaction (see intake.RedactConfig).
+// Detectors defaults to all built-in detectors when omitted.
+type RedactionCfg struct {
+	Enabled    bool               `yaml:"enabled"`
+	Detectors  []string           `yaml:"detectors"`
+	Rules      []RedactionRuleCfg `yaml:"rules"`
+	DenyFields []string           `yaml:"deny_fields"`
+}
+
+// RedactionRuleCfg is a custom regex redaction rule.
+type RedactionRuleCfg struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+	Replace string `yaml:"replace"`
+}
+
+func (r RedactionCfg) redactConfig() intake.RedactConfig {
+	rules := make([]intake.RedactRule, 0, len(r.Rules))
+	for _, rule := range r.Rules {
+		rules = append(rules, intake.RedactRule{Name: rule.Name, Pattern: rule.Pattern, Replace: rule.Replace})
+	}
+	return intake.RedactConfig{Detectors: r.Detectors, Rules: rules, DenyFields: r.DenyFields}
+}
+
+// CredentialCfg is a named intake credential. Empty secrets (e.g. an unset
+// ${VAR} for the next rotation secret) are ignored.
+type CredentialCfg struct {
+	Name             string   `yaml:"name"`
+	Secrets          []string `yaml:"secrets"`
+	Projects         []string `yaml:"projects"`
+	RequireSignature bool     `yaml:"require_signature"`
+}
+
+// AlertmanagerCfg maps Alertmanager alert labels onto Sentinel events.
+type AlertmanagerCfg struct {
+	ProjectLabel   string            `yaml:"project_label"`
+	SeverityLabel  string            `yaml:"severity_label"`
+	DefaultProject string            `yaml:"default_project"`
+	SeverityMap    map[string]string `yaml:"severity_map"`
+}
+
+// OTLPCfg maps OTLP log records onto Sentinel events.
+type OTLPCfg struct {
+	MinSeverityNumber int               `yaml:"min_severity_number"`
+	ServiceProjects   map[string]string `yaml:"service_projects"`
+	DefaultProject    string            `yaml:"default_project"`
+}
+
+// CloudEventsCfg maps CloudEvents attributes onto Sentinel events.
+type CloudEventsCfg struct {
+	ProjectExtension  string            `yaml:"project_extension"`
+	SeverityExtension string            `yaml:"severity_extension"`
+	DefaultProject    string            `yaml:"default_project"`
+	SeverityMap       map[string]string `yaml:"severity_map"`
+	TypeSeverity      map[string]string `yaml:"type_severity"`
 }
 
 type DedupConfig struct {
 	DefaultWindow string   `yaml:"default_window"`
 	DefaultFields []string `yaml:"default_fields"`
+	// Backend selects where dedup state lives: "memory" (default, per-process)
+	// or "store" (persisted in the configured store, shared across replicas).
+	Backend string `yaml:"backend"`
 }
 
 type SourceConfig struct {
 	BaseDir          string `yaml:"base_dir"`
 	GitSSHKey        string `yaml:"git_ssh_key"`
 	MaxCacheProjects int    `yaml:"max_cache_projects"`
+	// HistoryWindow is the commit history kept in the clones (default:
+	// tip only, or 7 days with diagnosis.recent_changes).
+	HistoryWindow string `yaml:"history_window"`
 }
 
 type SkillConfig struct {
@@ -163,9 +433,212 @@ func LoadConfig(path string) (*Config, error) {
 	}
 
 	cfg.applyDefaults()
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
 
+// cronJobs builds the scheduled proactive diagnoses of every project.
+func (c *Config) cronJobs() ([]*cron.Job, error) {
+	var jobs []*cron.Job
+	for _, p := range c.Projects {
+		names := make(map[string]bool, len(p.Schedules))
+		for i, sc := range p.Schedules {
+			if names[sc.Name] {
+				return nil, fmt.Errorf("project %q: duplicate schedule name %q", p.Key, sc.Name)
+			}
+			names[sc.Name] = true
+			job, err := cron.NewJob(p.Key, sc.Name, sc.Cron, sc.Severity, sc.Prompt)
+			if err != nil {
+				return nil, fmt.Errorf("project %q: schedules[%d]: %w", p.Key, i, err)
+			}
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// validateReview checks the reviewer settings. The reviewer works on the
+// structured diagnosis, so enabling it anywhere needs structured_output.
+func (d *DiagnosisCfg) validateReview(projects []project.Project) error {
+	r := d.Review
+	if r.Timeout != "" {
+		if t, err := time.ParseDuration(r.Timeout); err != nil || t <= 0 {
+			return fmt.Errorf("diagnosis.review.timeout: invalid duration %q", r.Timeout)
+		}
+	}
+	if err := validSeverityList("diagnosis.review.severities", r.Severities); err != nil {
+		return err
+	}
+	enabled := r.Enabled
+	for _, p := range projects {
+		if err := validSeverityList(fmt.Sprintf("project %q: review.severities", p.Key), p.Review.Severities); err != nil {
+			return err
+		}
+		if p.Review.Enabled != nil && *p.Review.Enabled {
+			enabled = true
+		}
+	}
+	if enabled && !d.StructuredOutput {
+		return fmt.Errorf("diagnosis.review: the reviewer needs diagnosis.structured_output")
+	}
+	return nil
+}
+
+func validSeverityList(path string, severities []string) error {
+	for _, s := range severities {
+		if !intake.ValidSeverities[s] {
+			return fmt.Errorf("%s: invalid severity %q (must be critical, warning, or info)", path, s)
+		}
+	}
+	return nil
+}
+
+// validate rejects configurations that are invalid or would silently
+// misbehave: weak intake credentials, an unknown dedup backend, bad profiles,
+// rate limits and redaction rules, unparsable durations, queue-wait and lease
+// settings, project budgets and cron schedules.
+func (c *Config) validate() error {
+	seen := make(map[string]bool, len(c.Intake.Credentials))
+	for i := range c.Intake.Credentials {
+		cred := &c.Intake.Credentials[i]
+		if cred.Name == "" {
+			return fmt.Errorf("intake.credentials[%d]: name is required", i)
+		}
+		if seen[cred.Name] {
+			return fmt.Errorf("intake.credentials: duplicate name %q", cred.Name)
+		}
+		seen[cred.Name] = true
+
+		secrets := cred.Secrets[:0]
+		for _, s := range cred.Secrets {
+			if s = strings.TrimSpace(s); s != "" {
+				secrets = append(secrets, s)
+			}
+		}
+		cred.Secrets = secrets
+		if len(cred.Secrets) == 0 {
+			return fmt.Errorf("intake.credentials %q: at least one secret is required", cred.Name)
+		}
+		if len(cred.Secrets) > 2 {
+			return fmt.Errorf("intake.credentials %q: at most two active secrets are allowed", cred.Name)
+		}
+	}
+	switch c.Intake.Dedup.Backend {
+	case "memory", "store":
+	default:
+		return fmt.Errorf("intake.dedup.backend: unknown backend %q (want memory or store)", c.Intake.Dedup.Backend)
+	}
+	sources := make(map[string]bool, len(c.Intake.Profiles))
+	for i, p := range c.Intake.Profiles {
+		if err := intake.ValidateMappingProfile(p.mappingProfile()); err != nil {
+			return fmt.Errorf("intake.profiles[%d]: %w", i, err)
+		}
+		if sources[p.Source] {
+			return fmt.Errorf("intake.profiles[%d]: duplicate source %q", i, p.Source)
+		}
+		sources[p.Source] = true
+	}
+	if err := c.Intake.RateLimits.each(func(path string, l RateLimitCfg) error {
+		if l.PerHour < 0 || l.Burst < 0 {
+			return fmt.Errorf("intake.rate_limits.%s: per_hour and burst must not be negative", path)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if c.Intake.Redaction.Enabled {
+		if _, err := intake.NewRedactor(c.Intake.Redaction.redactConfig()); err != nil {
+			return fmt.Errorf("intake.redaction: %w", err)
+		}
+	}
+	if w := c.Source.HistoryWindow; w != "" {
+		if d, err := time.ParseDuration(w); err != nil || d < 0 {
+			return fmt.Errorf("source.history_window: invalid duration %q", w)
+		}
+	}
+	if w := c.Diagnosis.RecentChanges.Window; w != "" {
+		if d, err := time.ParseDuration(w); err != nil || d <= 0 {
+			return fmt.Errorf("diagnosis.recent_changes.window: invalid duration %q", w)
+		}
+	}
+	if err := c.Diagnosis.validateReview(c.Projects); err != nil {
+		return err
+	}
+	if d, err := time.ParseDuration(c.Scheduler.RetryMaxDelay); err != nil || d <= 0 {
+		return fmt.Errorf("scheduler.retry_max_delay: invalid duration %q", c.Scheduler.RetryMaxDelay)
+	}
+	if c.Scheduler.AgingInterval != "" {
+		if d, err := time.ParseDuration(c.Scheduler.AgingInterval); err != nil || d <= 0 {
+			return fmt.Errorf("scheduler.aging_interval: invalid duration %q", c.Scheduler.AgingInterval)
+		}
+	}
+	for severity, w := range c.Scheduler.MaxQueueWait {
+		if !intake.ValidSeverities[severity] {
+			return fmt.Errorf("scheduler.max_queue_wait: invalid severity %q (must be critical, warning, or info)", severity)
+		}
+		if d, err := time.ParseDuration(w.Max); err != nil || d <= 0 {
+			return fmt.Errorf("scheduler.max_queue_wait.%s: invalid max %q", severity, w.Max)
+		}
+		switch w.Action {
+		case "", scheduler.WaitActionPromote, scheduler.WaitActionExpire:
+		default:
+			return fmt.Errorf("scheduler.max_queue_wait.%s: unknown action %q (want promote or expire)", severity, w.Action)
+		}
+	}
+	if c.Scheduler.Lease.Enabled {
+		if c.Store.Type == "json" {
+			return fmt.Errorf("scheduler.lease: needs a shared store (sqlite or mysql), not json")
+		}
+		if d, err := time.ParseDuration(c.Scheduler.Lease.Duration); err != nil || d < 3*time.Second {
+			return fmt.Errorf("scheduler.lease.duration: invalid duration %q (minimum 3s)", c.Scheduler.Lease.Duration)
+		}
+		if d, err := time.ParseDuration(c.Scheduler.Lease.PollInterval); err != nil || d <= 0 {
+			return fmt.Errorf("scheduler.lease.poll_interval: invalid duration %q", c.Scheduler.Lease.PollInterval)
+		}
+	}
+	if c.Budget.Enabled {
+		if err := c.Budget.validate(); err != nil {
+			return err
+		}
+	}
+	for _, p := range c.Projects {
+		if p.Budget.DailyTokens < 0 || p.Budget.MonthlyTokens < 0 {
+			return fmt.Errorf("project %q: budget tokens must not be negative", p.Key)
+		}
+	}
+	if _, err := c.cronJobs(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (b BudgetCfg) validate() error {
+	if b.DailyTokens < 0 || b.MonthlyTokens < 0 {
+		return fmt.Errorf("budget: daily_tokens and monthly_tokens must not be negative")
+	}
+	for severity, action := range b.OverBudget {
+		if !intake.ValidSeverities[severity] {
+			return fmt.Errorf("budget.over_budget: invalid severity %q (must be critical, warning, or info)", severity)
+		}
+		switch action {
+		case budget.ActionAllow, budget.ActionDegrade, budget.ActionDefer:
+		default:
+			return fmt.Errorf("budget.over_budget.%s: unknown action %q (want allow, degrade or defer)", severity, action)
+		}
+	}
+	for _, t := range b.WarnThresholds {
+		if t <= 0 {
+			return fmt.Errorf("budget.warn_thresholds: %d must be a positive percentage", t)
+		}
+	}
+	if d, err := time.ParseDuration(b.RefreshInterval); err != nil || d <= 0 {
+		return fmt.Errorf("budget.refresh_interval: invalid duration %q", b.RefreshInterval)
+	}
+	return nil
+}
+
 func (c *Config) applyDefaults() {
 	if c.Amp.Binary == "" {
 		c.Amp.Binary = "amp"
@@ -185,6 +658,21 @@ func (c *Config) applyDefaults() {
 	if c.Scheduler.RetryDelay == "" {
 		c.Scheduler.RetryDelay = "10s"
 	}
+	if c.Scheduler.RetryMaxDelay == "" {
+		c.Scheduler.RetryMaxDelay = "5m"
+	}
+	if c.Scheduler.Lease.Duration == "" {
+		c.Scheduler.Lease.Duration = "30s"
+	}
+	if c.Scheduler.Lease.PollInterval == "" {
+		c.Scheduler.Lease.PollInterval = "2s"
+	}
+	if c.Budget.DegradeMode == "" {
+		c.Budget.DegradeMode = "rush"
+	}
+	if c.Budget.RefreshInterval == "" {
+		c.Budget.RefreshInterval = "5m"
+	}
 	if c.Intake.Listen == "" {
 		c.Intake.Listen = ":8080"
 	}
@@ -194,12 +682,33 @@ func (c *Config) applyDefaults() {
 	if len(c.Intake.Dedup.DefaultFields) == 0 {
 		c.Intake.Dedup.DefaultFields = []string{"error_msg", "error", "message", "msg"}
 	}
+	if c.Intake.GroupWindow == "" {
+		c.Intake.GroupWindow = "30m"
+	}
+	if c.Intake.IdempotencyWindow == "" {
+		c.Intake.IdempotencyWindow = "24h"
+	}
+	if c.Intake.Dedup.Backend == "" {
+		c.Intake.Dedup.Backend = "memory"
+	}
 	if c.Intake.MaxPayloadSize == 0 {
 		c.Intake.MaxPayloadSize = 65536
 	}
 	if c.Intake.MinSeverity == "" {
 		c.Intake.MinSeverity = "warning"
 	}
+	if c.Intake.Alertmanager.ProjectLabel == "" {
+		c.Intake.Alertmanager.ProjectLabel = "project"
+	}
+	if c.Intake.Alertmanager.SeverityLabel == "" {
+		c.Intake.Alertmanager.SeverityLabel = "severity"
+	}
+	if c.Intake.OTLP.MinSeverityNumber == 0 {
+		c.Intake.OTLP.MinSeverityNumber = 17 // ERROR
+	}
+	if c.Source.HistoryWindow == "" && c.Diagnosis.RecentChanges.Enabled {
+		c.Source.HistoryWindow = "168h"
+	}
 	if c.Source.BaseDir == "" {
 		c.Source.BaseDir = "./data/repos"
 	}
@@ -0,0 +1,179 @@
+package cron
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"amp-sentinel/intake"
+	"amp-sentinel/logger"
+)
+
+// Job is a recurring proactive diagnosis of one project.
+type Job struct {
+	ProjectKey string
+	Name       string
+	Schedule   *Schedule
+	// Severity of the synthetic events (default info).
+	Severity string
+	// Prompt is the text/template of the instructions, see PromptData.
+	Prompt *template.Template
+}
+
+// NewJob parses a job's schedule and prompt template.
+func NewJob(projectKey, name, expr, severity, prompt string) (*Job, error) {
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if strings.TrimSpace(prompt) == "" {
+		return nil, fmt.Errorf("job %q: prompt is required", name)
+	}
+	if severity == "" {
+		severity = "info"
+	}
+	if !intake.ValidSeverities[severity] {
+		return nil, fmt.Errorf("job %q: invalid severity %q (must be critical, warning, or info)", name, severity)
+	}
+	sched, err := Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("job %q: %w", name, err)
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(prompt)
+	if err != nil {
+		return nil, fmt.Errorf("job %q: prompt: %w", name, err)
+	}
+	return &Job{ProjectKey: projectKey, Name: name, Schedule: sched, Severity: severity, Prompt: tmpl}, nil
+}
+
+// PromptData is available to prompt templates, e.g.
+// "review commits merged since {{.Since.Format \"2006-01-02 15:04\"}}".
+type PromptData struct {
+	ProjectKey string
+	Job        string
+	// ScheduledAt is the time the run was due.
+	ScheduledAt time.Time
+	// Since is the previous scheduled run, the start of the period this
+	// run covers.
+	Since time.Time
+	// Date is ScheduledAt as 2006-01-02.
+	Date string
+}
+
+// Run is one firing of a job.
+type Run struct {
+	Job         *Job
+	ScheduledAt time.Time
+	Since       time.Time
+}
+
+// Payload is the payload of the synthetic event of a run.
+type Payload struct {
+	Job         string    `json:"job"`
+	Cron        string    `json:"cron"`
+	Prompt      string    `json:"prompt"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	Since       time.Time `json:"since"`
+}
+
+// Event renders the run's prompt into a synthetic event with source
+// intake.SourceSchedule. Its ID is derived from the job and the scheduled
+// time, so replicas firing the same run produce the same event.
+func (r Run) Event() (*intake.RawEvent, error) {
+	var buf bytes.Buffer
+	err := r.Job.Prompt.Execute(&buf, PromptData{
+		ProjectKey:  r.Job.ProjectKey,
+		Job:         r.Job.Name,
+		ScheduledAt: r.ScheduledAt,
+		Since:       r.Since,
+		Date:        r.ScheduledAt.Format("2006-01-02"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("render prompt of job %q: %w", r.Job.Name, err)
+	}
+	payload, err := json.Marshal(Payload{
+		Job:         r.Job.Name,
+		Cron:        r.Job.Schedule.String(),
+		Prompt:      buf.String(),
+		ScheduledAt: r.ScheduledAt,
+		Since:       r.Since,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &intake.RawEvent{
+		ID:         fmt.Sprintf("sched-%s-%s-%s", r.Job.ProjectKey, r.Job.Name, r.ScheduledAt.UTC().Format("200601021504")),
+		ProjectKey: r.Job.ProjectKey,
+		Payload:    payload,
+		Source:     intake.SourceSchedule,
+		Severity:   r.Job.Severity,
+		Title:      "定时巡检: " + r.Job.Name,
+		ReceivedAt: time.Now(),
+	}, nil
+}
+
+// Runner fires jobs on their schedules. Runs missed while the process was
+// down are not caught up.
+type Runner struct {
+	jobs  []*Job
+	fire  func(Run)
+	log   logger.Logger
+	clock func() time.Time
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewRunner creates a runner that calls fire for every due run.
+func NewRunner(jobs []*Job, fire func(Run), log logger.Logger) *Runner {
+	return &Runner{
+		jobs:  jobs,
+		fire:  fire,
+		log:   log,
+		clock: time.Now,
+		stop:  make(chan struct{}),
+	}
+}
+
+// Start launches one timer loop per job.
+func (r *Runner) Start() {
+	for _, job := range r.jobs {
+		r.wg.Add(1)
+		go r.loop(job)
+	}
+	if len(r.jobs) > 0 {
+		r.log.Info("cron.started", logger.Int("jobs", len(r.jobs)))
+	}
+}
+
+// Stop ends the timer loops. A run already firing completes.
+func (r *Runner) Stop() {
+	close(r.stop)
+	r.wg.Wait()
+}
+
+func (r *Runner) loop(job *Job) {
+	defer r.wg.Done()
+	log := r.log.WithFields(logger.String("project", job.ProjectKey), logger.String("job", job.Name))
+
+	next := job.Schedule.Next(r.clock())
+	for !next.IsZero() {
+		log.Debug("cron.scheduled", logger.String("next", next.Format(time.RFC3339)))
+		timer := time.NewTimer(next.Sub(r.clock()))
+		select {
+		case <-r.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if now := r.clock(); now.Before(next) {
+			continue // woke early, e.g. after a clock change
+		}
+		r.fire(Run{Job: job, ScheduledAt: next, Since: job.Schedule.Prev(next)})
+		next = job.Schedule.Next(r.clock())
+	}
+	log.Warn("cron.never_due", logger.String("cron", job.Schedule.String()))
+}
@@ -0,0 +1,90 @@
+package cron
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"amp-sentinel/intake"
+	"amp-sentinel/logger"
+)
+
+func TestNewJob_Invalid(t *testing.T) {
+	tests := []struct {
+		name, expr, severity, prompt string
+	}{
+		{"", "@daily", "", "x"},
+		{"j", "@daily", "", " "},
+		{"j", "bad", "", "x"},
+		{"j", "@daily", "urgent", "x"},
+		{"j", "@daily", "", "{{.Nope"},
+	}
+	for _, tt := range tests {
+		if _, err := NewJob("proj-a", tt.name, tt.expr, tt.severity, tt.prompt); err == nil {
+			t.Errorf("NewJob(%q, %q, %q, %q): expected error", tt.name, tt.expr, tt.severity, tt.prompt)
+		}
+	}
+}
+
+func TestRun_Event(t *testing.T) {
+	job, err := NewJob("proj-a", "commit-review", "0 9 * * *", "",
+		`Review commits since {{.Since.Format "2006-01-02 15:04"}} ({{.Date}}, {{.ProjectKey}})`)
+	if err != nil {
+		t.Fatalf("NewJob: %v", err)
+	}
+	at := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	run := Run{Job: job, ScheduledAt: at, Since: job.Schedule.Prev(at)}
+	evt, err := run.Event()
+	if err != nil {
+		t.Fatalf("Event: %v", err)
+	}
+	if evt.Source != intake.SourceSchedule || evt.Severity != "info" || evt.ProjectKey != "proj-a" {
+		t.Errorf("event = %+v", evt)
+	}
+	if evt.ID != "sched-proj-a-commit-review-202603100900" {
+		t.Errorf("ID = %q, want one derived from job and due time", evt.ID)
+	}
+	var p Payload
+	if err := json.Unmarshal(evt.Payload, &p); err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	if want := "Review commits since 2026-03-09 09:00 (2026-03-10, proj-a)"; p.Prompt != want {
+		t.Errorf("prompt = %q, want %q", p.Prompt, want)
+	}
+	if p.Job != "commit-review" || p.Cron != "0 9 * * *" || !p.ScheduledAt.Equal(at) {
+		t.Errorf("payload = %+v", p)
+	}
+
+	again, _ := run.Event()
+	if again.ID != evt.ID {
+		t.Error("the same run must produce the same event ID")
+	}
+}
+
+func TestRunner_Fires(t *testing.T) {
+	job, err := NewJob("proj-a", "sweep", "* * * * *", "warning", "check")
+	if err != nil {
+		t.Fatalf("NewJob: %v", err)
+	}
+	fired := make(chan Run, 1)
+	r := NewRunner([]*Job{job}, func(run Run) { fired <- run }, logger.Nop())
+	// Start just before a minute boundary.
+	start := time.Now()
+	base := time.Date(2026, 3, 10, 8, 59, 59, 950_000_000, time.UTC)
+	r.clock = func() time.Time { return base.Add(time.Since(start)) }
+	r.Start()
+	defer r.Stop()
+
+	select {
+	case run := <-fired:
+		want := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+		if !run.ScheduledAt.Equal(want) || !run.Since.Equal(want.Add(-time.Minute)) {
+			t.Errorf("run at %v since %v, want %v", run.ScheduledAt, run.Since, want)
+		}
+		if run.Job != job {
+			t.Errorf("job = %s", run.Job.Name)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("job did not fire")
+	}
+}
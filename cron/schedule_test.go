@@ -0,0 +1,78 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"@often",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q): expected error", expr)
+		}
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	base := time.Date(2026, 3, 10, 8, 30, 15, 0, time.UTC) // a Tuesday
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 3, 10, 8, 31, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)},
+		{"0 8 * * *", time.Date(2026, 3, 11, 8, 0, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2026, 3, 10, 8, 40, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * sat,sun", time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 29 feb *", time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches (the 15th or a Monday).
+		{"0 0 15 * 1", time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.expr, err)
+		}
+		if got := s.Next(base); !got.Equal(tt.want) {
+			t.Errorf("%q: Next = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	never, _ := Parse("0 0 30 2 *")
+	if got := never.Next(base); !got.IsZero() {
+		t.Errorf("Feb 30: Next = %v, want zero", got)
+	}
+}
+
+func TestSchedule_Prev(t *testing.T) {
+	s, err := Parse("0 9 * * 1-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The previous weekday run before Monday 09:00 is Friday 09:00.
+	monday := time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC)
+	if got, want := s.Prev(monday), time.Date(2026, 3, 13, 9, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Prev = %v, want %v", got, want)
+	}
+	if got := s.Next(s.Prev(monday)); !got.Equal(monday) {
+		t.Errorf("Next(Prev) = %v, want %v", got, monday)
+	}
+	// Between runs, Prev is the last run.
+	if got, want := s.Prev(monday.Add(90*time.Second)), monday; !got.Equal(want) {
+		t.Errorf("Prev = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package diagnosis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"amp-sentinel/intake"
+	"amp-sentinel/logger"
+	"amp-sentinel/project"
+)
+
+// maxChangedFiles caps the files listed per commit in the prompt.
+const maxChangedFiles = 10
+
+// RecentChangesConfig configures deployment correlation: the commits made
+// shortly before an event are listed in the diagnosis prompt.
+type RecentChangesConfig struct {
+	Enabled    bool
+	Window     time.Duration // look-back from event.ReceivedAt (default 24h)
+	MaxCommits int           // default 20
+}
+
+// RecentChanges is the commit history of a project in a window before an
+// event.
+type RecentChanges struct {
+	Since   time.Time
+	Until   time.Time
+	Commits []project.Commit // newest first
+}
+
+// recentChanges lists the commits in the window before the event. Scheduled
+// sweeps have no event time to correlate with. Callers hold the project
+// lock and have prepared the source. Returns nil if disabled or on error.
+func (e *Engine) recentChanges(ctx context.Context, proj *project.Project, event *intake.RawEvent, log logger.Logger) *RecentChanges {
+	if !e.changes.Enabled || event.Source == intake.SourceSchedule {
+		return nil
+	}
+	until := event.ReceivedAt
+	if until.IsZero() {
+		until = time.Now()
+	}
+	since := until.Add(-e.changes.Window)
+	commits, err := e.sources.RecentChanges(ctx, proj, since, until, e.changes.MaxCommits)
+	if err != nil {
+		log.Warn("diagnosis.recent_changes_failed", logger.Err(err))
+		return nil
+	}
+	log.Info("diagnosis.recent_changes", logger.Int("commits", len(commits)))
+	return &RecentChanges{Since: since, Until: until, Commits: commits}
+}
+
+// buildRecentChangesSection renders the commits compactly: one line per
+// commit and its diff stat. The agent reads full diffs with git show.
+func buildRecentChangesSection(changes *RecentChanges) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n## 近期代码变更\n\n事件接收前 %s（%s ~ %s）分支上的提交，最新在前：\n\n",
+		formatWindow(changes.Until.Sub(changes.Since)),
+		changes.Since.Format(time.RFC3339), changes.Until.Format(time.RFC3339)))
+	if len(changes.Commits) == 0 {
+		sb.WriteString("（窗口内没有提交，故障不太可能由近期发布引入）\n")
+		return sb.String()
+	}
+	for _, c := range changes.Commits {
+		sb.WriteString(fmt.Sprintf("- `%s` %s %s: %s\n",
+			c.Hash, c.Time.Format(time.RFC3339), c.Author, truncateText(c.Subject, 200)))
+		if c.Boundary {
+			sb.WriteString("  - （历史边界，文件列表不可用）\n")
+			continue
+		}
+		for i, f := range c.Files {
+			if i == maxChangedFiles {
+				sb.WriteString(fmt.Sprintf("  - ……共 %d 个文件\n", len(c.Files)))
+				break
+			}
+			if f.Added < 0 {
+				sb.WriteString(fmt.Sprintf("  - %s (binary)\n", f.Path))
+			} else {
+				sb.WriteString(fmt.Sprintf("  - %s (+%d -%d)\n", f.Path, f.Added, f.Deleted))
+			}
+		}
+	}
+	sb.WriteString("\n如故障现象与某次提交改动的代码相关，请用 `git show <hash>` 查看完整 diff 核实，并在 suspected_commit 中注明。\n")
+	return sb.String()
+}
+
+// formatWindow renders a look-back window in hours or days.
+func formatWindow(d time.Duration) string {
+	if d >= 48*time.Hour && d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%d 天", int(d/(24*time.Hour)))
+	}
+	return fmt.Sprintf("%g 小时", d.Hours())
+}
+
+// suspectedCommitKnown reports whether the suspected commit exists in the
+// checkout. Callers hold the project lock.
+func (e *Engine) suspectedCommitKnown(ctx context.Context, proj *project.Project, diag *DiagnosisJSON) bool {
+	_, err := e.sources.ResolveCommit(ctx, proj.Key, diag.SuspectedCommit.Hash)
+	return err == nil
+}
@@ -31,10 +31,15 @@ type Engine struct {
 	structuredOutput bool
 	jsonFixerEnabled bool
 	promptVersion    string
+	scrub            func(string) string
 
 	// P1: Fingerprint reuse
 	fingerprintLookup FingerprintLookup
 	fpConfig          FingerprintConfig
+
+	review        ReviewConfig
+	changes       RecentChangesConfig
+	versionFields []string
 }
 
 // EngineConfig holds configuration for the diagnosis engine.
@@ -45,10 +50,19 @@ type EngineConfig struct {
 	StructuredOutput bool   // P0: enable structured JSON output + quality scoring
 	JSONFixerEnabled bool   // P0: enable LLM JSON fixer as last-resort fallback
 	PromptVersion    string // version tag for A/B testing
+	// Scrub masks secrets the agent echoes back in reports and session logs (may be nil)
+	Scrub func(string) string
 
 	// P1: Fingerprint reuse
 	FingerprintLookup FingerprintLookup
 	FingerprintConfig FingerprintConfig
+
+	// Review configures the optional reviewer stage (needs StructuredOutput)
+	Review ReviewConfig
+	// RecentChanges lists the commits before the event in the prompt
+	RecentChanges RecentChangesConfig
+	// VersionFields are the default payload paths of the deployed version
+	VersionFields []string
 }
 
 // NewEngine creates a diagnosis engine.
@@ -76,6 +90,18 @@ func NewEngine(
 	if len(fpCfg.DefaultDedupFields) == 0 {
 		fpCfg.DefaultDedupFields = []string{"error_msg", "error", "message", "msg"}
 	}
+	if cfg.Review.Mode == "" {
+		cfg.Review.Mode = "rush"
+	}
+	if cfg.Review.Timeout <= 0 {
+		cfg.Review.Timeout = 5 * time.Minute
+	}
+	if cfg.RecentChanges.Window <= 0 {
+		cfg.RecentChanges.Window = 24 * time.Hour
+	}
+	if cfg.RecentChanges.MaxCommits <= 0 {
+		cfg.RecentChanges.MaxCommits = 20
+	}
 
 	return &Engine{
 		ampClient:         ampClient,
@@ -89,11 +115,32 @@ func NewEngine(
 		structuredOutput:  cfg.StructuredOutput,
 		jsonFixerEnabled:  cfg.JSONFixerEnabled,
 		promptVersion:     cfg.PromptVersion,
+		scrub:             cfg.Scrub,
 		fingerprintLookup: cfg.FingerprintLookup,
 		fpConfig:          fpCfg,
+		review:            cfg.Review,
+		changes:           cfg.RecentChanges,
+		versionFields:     cfg.VersionFields,
 	}
 }
 
+type modeKey struct{}
+
+// WithMode overrides the Amp agent mode for diagnoses run under ctx, e.g. to
+// degrade an over-budget project to the cheaper rush mode.
+func WithMode(ctx context.Context, mode string) context.Context {
+	return context.WithValue(ctx, modeKey{}, mode)
+}
+
+// modeFor returns the Amp mode for a diagnosis: the WithMode override if
+// set, otherwise the engine default.
+func (e *Engine) modeFor(ctx context.Context) string {
+	if mode, _ := ctx.Value(modeKey{}).(string); mode != "" {
+		return mode
+	}
+	return e.mode
+}
+
 // Diagnose runs a full diagnosis for the given incident.
 func (e *Engine) Diagnose(ctx context.Context, event *intake.RawEvent) (*Report, error) {
 	log := e.log.WithFields(
@@ -104,15 +151,16 @@ func (e *Engine) Diagnose(ctx context.Context, event *intake.RawEvent) (*Report,
 	// 1. Lookup project
 	proj, err := e.registry.Lookup(event.ProjectKey)
 	if err != nil {
-		return nil, fmt.Errorf("project lookup: %w", err)
+		return nil, amp.NonRetryable(fmt.Errorf("project lookup: %w", err))
 	}
 	log.Info("diagnosis.started", logger.String("project_name", proj.Name))
 
 	// 2. P1: Fingerprint reuse check — runs BEFORE acquiring the project lock
 	//    to avoid holding the lock during store I/O. Only needs the lock briefly
 	//    to get the current commit hash for reuse validation.
+	//    Scheduled sweeps look at the current state every time: never reused.
 	fingerprint := ""
-	if e.fpConfig.Enabled && e.fingerprintLookup != nil {
+	if e.fpConfig.Enabled && e.fingerprintLookup != nil && event.Source != intake.SourceSchedule {
 		fingerprint = ComputeDiagnosisFingerprint(
 			proj.Key, event.Payload, proj.Dedup.Fields, e.fpConfig.DefaultDedupFields,
 		)
@@ -124,11 +172,7 @@ func (e *Engine) Diagnose(ctx context.Context, event *intake.RawEvent) (*Report,
 		} else if cached != nil {
 			// Need commit hash to validate reuse — acquire lock briefly
 			reuseUnlock := e.sources.Lock(proj.Key)
-			_, prepErr := e.sources.Prepare(ctx, proj)
-			var commitHash string
-			if prepErr == nil {
-				commitHash, _ = e.sources.CommitHash(ctx, proj.Key)
-			}
+			_, commitHash, versionFlags, prepErr := e.prepareSource(ctx, proj, event, log)
 			reuseUnlock()
 
 			if prepErr != nil {
@@ -141,7 +185,7 @@ func (e *Engine) Diagnose(ctx context.Context, event *intake.RawEvent) (*Report,
 						logger.String("fingerprint", fingerprint),
 						logger.Bool("commit_match", commitHash == cached.CommitHash),
 					)
-					return buildReusedReport(cached, event, proj.Name, commitHash, fingerprint, extraFlags), nil
+					return buildReusedReport(cached, event, proj.Name, commitHash, fingerprint, append(extraFlags, versionFlags...)), nil
 				}
 			}
 		}
@@ -158,14 +202,13 @@ func (e *Engine) Diagnose(ctx context.Context, event *intake.RawEvent) (*Report,
 		}
 	}()
 
-	// 4. Prepare source code
+	// 4. Prepare source code at the deployed version (or the branch tip)
 	log.Info("diagnosis.preparing_source")
-	srcDir, err := e.sources.Prepare(ctx, proj)
+	srcDir, commitHash, versionFlags, err := e.prepareSource(ctx, proj, event, log)
 	if err != nil {
-		return nil, fmt.Errorf("source prepare: %w", err)
+		return nil, amp.Retryable(fmt.Errorf("source prepare: %w", err))
 	}
 
-	commitHash, _ := e.sources.CommitHash(ctx, proj.Key)
 	log.Info("diagnosis.source_ready",
 		logger.String("src_dir", srcDir),
 		logger.String("commit", commitHash),
@@ -173,8 +216,9 @@ func (e *Engine) Diagnose(ctx context.Context, event *intake.RawEvent) (*Report,
 
 	// 5. Build prompt — inject constraints directly instead of writing AGENTS.md
 	//    to the source directory (writing files would trigger false tainted detection).
+	changes := e.recentChanges(ctx, proj, event, log)
 	agentsMD := BuildAgentsMD(proj, event)
-	prompt := agentsMD + "\n---\n\n" + BuildPrompt(proj, event)
+	prompt := agentsMD + "\n---\n\n" + BuildPrompt(proj, event, changes)
 
 	var sessionFile *os.File
 	if e.sessionDir != "" {
@@ -199,33 +243,19 @@ func (e *Engine) Diagnose(ctx context.Context, event *intake.RawEvent) (*Report,
 	}()
 
 	// Resolve skills and MCP configs for this project
-	var mcpServers map[string]amp.MCPServerConfig
-	if e.skillMgr != nil && len(proj.Skills) > 0 {
-		resolved := e.skillMgr.Resolve(proj.Skills)
-		if len(resolved) > 0 {
-			skillMCP := e.skillMgr.MCPConfig(resolved)
-			if len(skillMCP) > 0 {
-				mcpServers = make(map[string]amp.MCPServerConfig, len(skillMCP))
-				for name, srv := range skillMCP {
-					mcpServers[name] = amp.MCPServerConfig{
-						Command: srv.Command,
-						Args:    srv.Args,
-						Env:     srv.Env,
-						URL:     srv.URL,
-						Headers: srv.Headers,
-					}
-				}
-			}
-			log.Info("diagnosis.skills_resolved", logger.Int("count", len(resolved)), logger.Int("mcp_servers", len(mcpServers)))
-		}
-	}
+	mcpServers := e.mcpServers(proj, log)
 
 	skillsUsed := map[string]struct{}{}
 
+	mode := e.modeFor(ctx)
+	if mode != e.mode {
+		log.Info("diagnosis.mode_override", logger.String("mode", mode))
+	}
+
 	startTime := time.Now()
 	result, err := e.ampClient.Execute(ctx, prompt, amp.ExecuteOption{
 		WorkDir:     srcDir,
-		Mode:        e.mode,
+		Mode:        mode,
 		Permissions: amp.ReadOnlyPermissions(),
 		MCPServers:  mcpServers,
 		Labels:      []string{"sentinel", proj.Key, event.Severity},
@@ -233,6 +263,9 @@ func (e *Engine) Diagnose(ctx context.Context, event *intake.RawEvent) (*Report,
 		// Save raw session log
 		if sessionFile != nil {
 			line, _ := marshalJSON(msg)
+			if e.scrub != nil {
+				line = []byte(e.scrub(string(line)))
+			}
 			sessionFile.Write(append(line, '\n'))
 		}
 
@@ -257,27 +290,7 @@ func (e *Engine) Diagnose(ctx context.Context, event *intake.RawEvent) (*Report,
 	}
 
 	// 6. Safety verification — check no source files were modified.
-	//    Use an independent context because the diagnosis ctx may be
-	//    cancelled due to timeout, but the safety check MUST still run.
-	//    Fail-closed: if the check itself fails, treat as tainted.
-	safetyCtx, safetyCancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer safetyCancel()
-
-	tainted := false
-	hasChanges, checkErr := e.sources.HasChanges(safetyCtx, proj.Key)
-	if checkErr != nil {
-		tainted = true
-		log.Error("diagnosis.safety_check_failed_marking_tainted", logger.Err(checkErr))
-	} else if hasChanges {
-		tainted = true
-		log.Error("security.tainted",
-			logger.String("project_key", proj.Key),
-			logger.String("event_id", event.ID),
-		)
-		if resetErr := e.sources.ResetChanges(safetyCtx, proj.Key); resetErr != nil {
-			log.Error("security.reset_failed", logger.Err(resetErr))
-		}
-	}
+	tainted := e.sourcesTainted(proj, log)
 
 	// 7. Structured JSON parsing + code verification (inside lock)
 	var structuredDiag *DiagnosisJSON
@@ -310,6 +323,31 @@ func (e *Engine) Diagnose(ctx context.Context, event *intake.RawEvent) (*Report,
 			if codeVerifyScore == -1 && HasCodeEvidence(structuredDiag) {
 				codeVerifyScore = 0
 			}
+			if structuredDiag.SuspectedCommit != nil && !e.suspectedCommitKnown(ctx, proj, structuredDiag) {
+				codeVerifyFlags = append(codeVerifyFlags, FlagHallucinatedCommit)
+			}
+		}
+	}
+
+	// 7b. Optional reviewer: a second, cheaper run that checks each root
+	//     cause against the code (inside lock — reads srcDir). Like the
+	//     fixer it must not lose the diagnosis ctx's remaining deadline,
+	//     so it gets its own timeout, but cancelling the task still stops
+	//     it. A failed review leaves the report unreviewed.
+	var review *Review
+	var reviewUsage *amp.Usage
+	reviewFailed := false
+	if structuredDiag != nil && e.reviewEnabled(proj, event.Severity) {
+		var reviewErr error
+		reviewCtx, stopReview := withoutDeadline(ctx)
+		review, reviewUsage, reviewErr = e.runReview(reviewCtx, srcDir, proj, event, structuredDiag)
+		stopReview()
+		if reviewErr != nil {
+			reviewFailed = true
+			log.Warn("diagnosis.review_failed", logger.Err(reviewErr))
+		}
+		if e.sourcesTainted(proj, log) {
+			tainted = true
 		}
 	}
 
@@ -324,6 +362,14 @@ func (e *Engine) Diagnose(ctx context.Context, event *intake.RawEvent) (*Report,
 		qualityScore.CodeVerify = codeVerifyScore
 		qualityScore.Flags = append(qualityScore.Flags, codeVerifyFlags...)
 		qualityScore.Normalized = NormalizeScore(qualityScore)
+		if reviewFailed {
+			qualityScore.Flags = appendFlag(qualityScore.Flags, FlagReviewFailed)
+		}
+	}
+	var reviewedConf float64
+	var reviewedLabel string
+	if review != nil {
+		reviewedConf, reviewedLabel = ApplyReview(structuredDiag, qualityScore, review)
 	}
 
 	// 10. Build report
@@ -368,6 +414,14 @@ func (e *Engine) Diagnose(ctx context.Context, event *intake.RawEvent) (*Report,
 		if qualityScore != nil {
 			report.QualityScore = *qualityScore
 		}
+		if review != nil {
+			// The reviewed confidence is the one reported and notified;
+			// OriginalConfidence keeps the first diagnosis' own.
+			report.Review = review
+			report.Confidence = reviewedLabel
+			report.FinalConfidence = reviewedConf
+			report.FinalConfLabel = reviewedLabel
+		}
 	} else {
 		// Fallback to heuristic detection (old path)
 		report.Summary = extractSummary(result.Result)
@@ -392,11 +446,24 @@ func (e *Engine) Diagnose(ctx context.Context, event *intake.RawEvent) (*Report,
 		}
 	}
 
-	if result.Usage != nil {
-		report.Usage = &UsageInfo{
-			InputTokens:  result.Usage.InputTokens,
-			OutputTokens: result.Usage.OutputTokens,
+	for _, f := range versionFlags {
+		report.QualityScore.Flags = appendFlag(report.QualityScore.Flags, f)
+	}
+
+	// Post-scrub: the agent may quote secrets it read from source or config
+	if e.scrub != nil {
+		report.Scrub(e.scrub)
+	}
+
+	for _, u := range []*amp.Usage{result.Usage, reviewUsage} {
+		if u == nil {
+			continue
+		}
+		if report.Usage == nil {
+			report.Usage = &UsageInfo{}
 		}
+		report.Usage.InputTokens += u.InputTokens
+		report.Usage.OutputTokens += u.OutputTokens
 	}
 
 	elapsed := time.Since(startTime)
@@ -408,6 +475,7 @@ func (e *Engine) Diagnose(ctx context.Context, event *intake.RawEvent) (*Report,
 		logger.Int("turns", result.NumTurns),
 		logger.Bool("tainted", tainted),
 		logger.Bool("structured", structuredDiag != nil),
+		logger.Bool("reviewed", review != nil),
 	)
 
 	return report, nil
@@ -482,6 +550,56 @@ func marshalJSON(v any) ([]byte, error) {
 	return json.Marshal(v)
 }
 
+// sourcesTainted checks that the agent left the project's checkout
+// unmodified and resets it if not. It runs on an independent context
+// because the caller's ctx may already be cancelled, and fails closed: if
+// the check itself fails, the checkout is treated as tainted.
+func (e *Engine) sourcesTainted(proj *project.Project, log logger.Logger) bool {
+	safetyCtx, safetyCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer safetyCancel()
+
+	hasChanges, checkErr := e.sources.HasChanges(safetyCtx, proj.Key)
+	if checkErr != nil {
+		log.Error("diagnosis.safety_check_failed_marking_tainted", logger.Err(checkErr))
+		return true
+	}
+	if !hasChanges {
+		return false
+	}
+	log.Error("security.tainted", logger.String("project_key", proj.Key))
+	if resetErr := e.sources.ResetChanges(safetyCtx, proj.Key); resetErr != nil {
+		log.Error("security.reset_failed", logger.Err(resetErr))
+	}
+	return true
+}
+
+// mcpServers resolves the MCP server configs of a project's skills.
+func (e *Engine) mcpServers(proj *project.Project, log logger.Logger) map[string]amp.MCPServerConfig {
+	if e.skillMgr == nil || len(proj.Skills) == 0 {
+		return nil
+	}
+	resolved := e.skillMgr.Resolve(proj.Skills)
+	if len(resolved) == 0 {
+		return nil
+	}
+	var mcpServers map[string]amp.MCPServerConfig
+	skillMCP := e.skillMgr.MCPConfig(resolved)
+	if len(skillMCP) > 0 {
+		mcpServers = make(map[string]amp.MCPServerConfig, len(skillMCP))
+		for name, srv := range skillMCP {
+			mcpServers[name] = amp.MCPServerConfig{
+				Command: srv.Command,
+				Args:    srv.Args,
+				Env:     srv.Env,
+				URL:     srv.URL,
+				Headers: srv.Headers,
+			}
+		}
+	}
+	log.Info("diagnosis.skills_resolved", logger.Int("count", len(resolved)), logger.Int("mcp_servers", len(mcpServers)))
+	return mcpServers
+}
+
 // sanitizeFilename replaces any character not in [A-Za-z0-9._-] with underscore
 // to prevent path traversal attacks in session log filenames.
 func sanitizeFilename(s string) string {
@@ -28,7 +28,8 @@ const FlagReusedStaleCommit = "REUSED_STALE_COMMIT"
 
 // ComputeDiagnosisFingerprint computes a fingerprint for diagnosis reuse.
 // It extends the intake dedup fingerprint with environment context and
-// value normalization to match semantically identical errors.
+// value normalization to match semantically identical errors. Payloads
+// carrying a normalized stacktrace block are keyed on their top in-app frames.
 func ComputeDiagnosisFingerprint(projectKey string, payload json.RawMessage, dedupFields []string, defaultDedupFields []string) string {
 	// Normalize the payload values before fingerprinting to collapse
 	// dynamic content (timestamps, UUIDs, etc.) into placeholders.
@@ -73,7 +74,7 @@ func canReuse(cached *Report, currentCommitHash, severity string, minScore int)
 
 	// Check for hallucination flags
 	for _, f := range cached.QualityScore.Flags {
-		if f == FlagHallucinatedFile || f == FlagHallucinatedLine {
+		if f == FlagHallucinatedFile || f == FlagHallucinatedLine || f == FlagHallucinatedCommit {
 			return false, nil
 		}
 	}
@@ -114,6 +115,7 @@ func buildReusedReport(cached *Report, event *intake.RawEvent, projName, current
 		OriginalConfidence: cached.OriginalConfidence,
 		FinalConfidence:    cached.FinalConfidence,
 		FinalConfLabel:     cached.FinalConfLabel,
+		Review:             cached.Review,
 		CommitHash:         currentCommitHash,
 		Fingerprint:        fingerprint,
 		ReusedFromID:       cached.TaskID,
@@ -363,3 +363,15 @@ func TestNormalizePayload(t *testing.T) {
 		t.Fatal("normalization should have replaced dynamic content")
 	}
 }
+
+func TestComputeDiagnosisFingerprint_StackFrames(t *testing.T) {
+	defaultFields := []string{"error_msg", "error", "message", "msg"}
+	a := json.RawMessage(`{"error_msg":"order 20260301001 failed","environment":"production",
+		"stacktrace":{"exception_type":"ValueError","frames":[{"module":"orders.pricing","function":"unit_price","line":17,"in_app":true}]}}`)
+	b := json.RawMessage(`{"error_msg":"order 20260301999 failed at 0x7ffdc0a1","environment":"production",
+		"stacktrace":{"exception_type":"ValueError","frames":[{"module":"orders.pricing","function":"unit_price","line":23,"in_app":true}]}}`)
+
+	if ComputeDiagnosisFingerprint("proj-a", a, nil, defaultFields) != ComputeDiagnosisFingerprint("proj-a", b, nil, defaultFields) {
+		t.Fatal("payloads with the same top in-app frames should share a fingerprint")
+	}
+}
@@ -0,0 +1,95 @@
+package diagnosis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"amp-sentinel/amp"
+	"amp-sentinel/logger"
+)
+
+// FollowUp is the answer to a follow-up question on a finished diagnosis.
+type FollowUp struct {
+	Answer     string
+	SessionID  string
+	CommitHash string
+	Tainted    bool
+	DurationMs int64
+	NumTurns   int
+	Usage      *amp.Usage
+}
+
+// Ask continues the Amp thread of a finished diagnosis (its SessionID) with
+// a follow-up question. It runs in the project's source checkout at
+// revision (the diagnosis' CommitHash; "" or unknown = branch tip) under
+// the project lock, with the same read-only permissions and skills as the
+// diagnosis, and checks the checkout for changes afterwards like Diagnose.
+func (e *Engine) Ask(ctx context.Context, projectKey, sessionID, revision, question string) (*FollowUp, error) {
+	if sessionID == "" {
+		return nil, amp.NonRetryable(fmt.Errorf("diagnosis has no amp session to continue"))
+	}
+	proj, err := e.registry.Lookup(projectKey)
+	if err != nil {
+		return nil, amp.NonRetryable(fmt.Errorf("project lookup: %w", err))
+	}
+	log := e.log.WithFields(
+		logger.String("project_key", proj.Key),
+		logger.String("session_id", sessionID),
+	)
+
+	unlock := e.sources.Lock(proj.Key)
+	defer unlock()
+
+	srcDir, err := e.sources.Prepare(ctx, proj)
+	if err != nil {
+		return nil, amp.Retryable(fmt.Errorf("source prepare: %w", err))
+	}
+	if revision != "" {
+		if _, err := e.sources.Checkout(ctx, proj, revision); err != nil {
+			log.Warn("diagnosis.follow_up_revision_unresolved", logger.String("revision", revision), logger.Err(err))
+		}
+	}
+	commitHash, _ := e.sources.CommitHash(ctx, proj.Key)
+
+	log.Info("diagnosis.follow_up_started", logger.String("commit", commitHash))
+	start := time.Now()
+	result, err := e.ampClient.Continue(ctx, sessionID, BuildFollowUpPrompt(question), amp.ExecuteOption{
+		WorkDir:     srcDir,
+		Mode:        e.modeFor(ctx),
+		Permissions: amp.ReadOnlyPermissions(),
+		MCPServers:  e.mcpServers(proj, log),
+	}, nil)
+	if err != nil {
+		log.Error("diagnosis.follow_up_failed", logger.Err(err))
+		return nil, fmt.Errorf("amp continue: %w", err)
+	}
+	if result.IsError {
+		return nil, fmt.Errorf("amp continue: %s", result.Error)
+	}
+
+	// Same fail-closed safety check as Diagnose.
+	tainted := e.sourcesTainted(proj, log)
+
+	answer := result.Result
+	if e.scrub != nil {
+		answer = e.scrub(answer)
+	}
+	sid := result.SessionID
+	if sid == "" {
+		sid = sessionID
+	}
+	log.Info("diagnosis.follow_up_completed",
+		logger.Int64("duration_ms", time.Since(start).Milliseconds()),
+		logger.Bool("tainted", tainted),
+	)
+	return &FollowUp{
+		Answer:     answer,
+		SessionID:  sid,
+		CommitHash: commitHash,
+		Tainted:    tainted,
+		DurationMs: result.DurationMs,
+		NumTurns:   result.NumTurns,
+		Usage:      result.Usage,
+	}, nil
+}
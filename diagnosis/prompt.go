@@ -13,8 +13,12 @@ import (
 
 const maxPayloadSize = 64 * 1024 // 64KB
 
-// BuildPrompt constructs the main diagnosis prompt sent to Amp.
-func BuildPrompt(p *project.Project, event *intake.RawEvent) string {
+// BuildPrompt constructs the main diagnosis prompt sent to Amp. changes
+// (may be nil) lists the commits made shortly before the event.
+func BuildPrompt(p *project.Project, event *intake.RawEvent, changes *RecentChanges) string {
+	if event.Source == intake.SourceSchedule {
+		return buildSchedulePrompt(p, event)
+	}
 	var sb strings.Builder
 
 	sb.WriteString(fmt.Sprintf(`你是一个线上故障诊断专家。请分析项目「%s」(%s) 的线上事件并给出诊断报告。
@@ -36,6 +40,9 @@ func BuildPrompt(p *project.Project, event *intake.RawEvent) string {
 	sb.WriteString("事件原始数据 (JSON):\n```json\n")
 	sb.WriteString(payloadStr)
 	sb.WriteString("\n```\n")
+	if changes != nil {
+		sb.WriteString(buildRecentChangesSection(changes))
+	}
 
 	sb.WriteString(`
 请先理解上述事件数据的结构和含义，然后阅读项目源码进行分析。你可以：
@@ -52,10 +59,68 @@ func BuildPrompt(p *project.Project, event *intake.RawEvent) string {
 	return sb.String()
 }
 
+// buildSchedulePrompt constructs the prompt of a scheduled proactive
+// diagnosis. Its instructions come from the operator's config, not from an
+// external reporter, so they are given as the task rather than as data.
+func buildSchedulePrompt(p *project.Project, event *intake.RawEvent) string {
+	var job struct {
+		Job         string    `json:"job"`
+		Prompt      string    `json:"prompt"`
+		ScheduledAt time.Time `json:"scheduled_at"`
+		Since       time.Time `json:"since"`
+	}
+	_ = json.Unmarshal(event.Payload, &job)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(`你是一个线上系统巡检专家。请对项目「%s」(%s) 执行定时巡检任务「%s」，主动发现潜在问题并给出诊断报告。
+
+`, p.Name, p.Key, job.Job))
+	sb.WriteString(fmt.Sprintf("计划执行时间: %s\n", job.ScheduledAt.Format(time.RFC3339)))
+	if !job.Since.IsZero() {
+		sb.WriteString(fmt.Sprintf("上次计划执行时间: %s\n", job.Since.Format(time.RFC3339)))
+	}
+	sb.WriteString("\n巡检要求:\n")
+	sb.WriteString(truncateText(job.Prompt, maxPayloadSize))
+	sb.WriteString(`
+
+你可以：
+1. 使用 Read / Grep / finder 等工具阅读和搜索代码
+2. 使用 git log / git blame / git diff 查看代码变更历史
+3. 使用可用的 Skill 工具查询订单、用户、日志等业务数据
+
+未发现需要处理的问题时，has_issue 设为 false，并在 conclusion 中简述巡检范围和结果。
+
+**输出格式要求**：请严格按以下 JSON Schema 输出诊断结论，不要输出 Markdown 或其他格式。
+允许用 ` + "```json```" + ` 代码块包裹。
+
+` + DiagnosisOutputSchemaDoc + `
+`)
+	return sb.String()
+}
+
+// BuildFollowUpPrompt wraps an engineer's follow-up question on a finished
+// diagnosis. The thread already holds the event and the diagnosis, so only
+// the question and the answering rules are sent.
+func BuildFollowUpPrompt(question string) string {
+	return `值班工程师对上面的诊断结论有一个追问，请结合之前的分析和代码回答：
+
+` + truncateText(question, maxPayloadSize) + `
+
+要求：
+1. 仍然只读：只阅读、搜索代码和查询数据，不要修改任何文件
+2. 直接回答问题，用中文 Markdown 输出，不需要重复完整的 JSON 诊断结论
+3. 给出结论时注明依据（文件路径与行号、日志或数据查询结果）；无法确定时说明还缺少哪些信息
+`
+}
+
 // truncatePayload truncates the payload to maxSize bytes,
 // ensuring valid UTF-8 and not breaking mid-character.
 func truncatePayload(payload json.RawMessage, maxSize int) string {
-	s := string(payload)
+	return truncateText(string(payload), maxSize)
+}
+
+// truncateText truncates s to maxSize bytes on a UTF-8 boundary.
+func truncateText(s string, maxSize int) string {
 	if len(s) <= maxSize {
 		return s
 	}
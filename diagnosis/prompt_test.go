@@ -116,7 +116,7 @@ func TestBuildPrompt_Basic(t *testing.T) {
 	p := newTestProject()
 	event := newTestEvent()
 
-	result := BuildPrompt(p, event)
+	result := BuildPrompt(p, event, nil)
 
 	checks := []string{
 		p.Name,
@@ -137,13 +137,51 @@ func TestBuildPrompt_ContainsSchemaDoc(t *testing.T) {
 	p := newTestProject()
 	event := newTestEvent()
 
-	result := BuildPrompt(p, event)
+	result := BuildPrompt(p, event, nil)
 
 	if !strings.Contains(result, "schema_version") {
 		t.Error("BuildPrompt output should contain schema doc")
 	}
 }
 
+func TestBuildPrompt_RecentChanges(t *testing.T) {
+	event := newTestEvent()
+	files := make([]project.FileChange, 12)
+	for i := range files {
+		files[i] = project.FileChange{Path: "pkg/file" + string(rune('a'+i)) + ".go", Added: 3, Deleted: 1}
+	}
+	files[0] = project.FileChange{Path: "assets/logo.png", Added: -1, Deleted: -1}
+	changes := &RecentChanges{
+		Since: event.ReceivedAt.Add(-24 * time.Hour),
+		Until: event.ReceivedAt,
+		Commits: []project.Commit{
+			{Hash: "a1b2c3d", Author: "dev", Time: event.ReceivedAt.Add(-time.Hour), Subject: "change price rounding", Files: files},
+			{Hash: "e4f5a6b", Author: "dev", Time: event.ReceivedAt.Add(-20 * time.Hour), Subject: "initial", Boundary: true},
+		},
+	}
+
+	result := BuildPrompt(newTestProject(), event, changes)
+	for _, want := range []string{
+		"近期代码变更", "24 小时", "`a1b2c3d`", "change price rounding",
+		"pkg/fileb.go (+3 -1)", "assets/logo.png (binary)", "共 12 个文件", "历史边界", "git show", "suspected_commit",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("BuildPrompt output missing %q", want)
+		}
+	}
+	if strings.Contains(result, "pkg/filel.go") {
+		t.Error("BuildPrompt should list at most 10 files per commit")
+	}
+	if strings.Index(result, "近期代码变更") > strings.Index(result, "schema_version") {
+		t.Error("recent changes should precede the output schema")
+	}
+
+	result = BuildPrompt(newTestProject(), event, &RecentChanges{Since: changes.Since, Until: changes.Until})
+	if !strings.Contains(result, "窗口内没有提交") {
+		t.Error("an empty window should be stated in the prompt")
+	}
+}
+
 func TestBuildAgentsMD_Basic(t *testing.T) {
 	p := newTestProject()
 	event := newTestEvent()
@@ -204,3 +242,33 @@ func TestBuildAgentsMD_WithoutSkills(t *testing.T) {
 		t.Error("BuildAgentsMD output should not contain skills section when no skills configured")
 	}
 }
+
+func TestBuildPrompt_Schedule(t *testing.T) {
+	p := &project.Project{Key: "svc", Name: "Service"}
+	payload, _ := json.Marshal(map[string]any{
+		"job":          "commit-review",
+		"prompt":       "审查昨日合入的提交",
+		"scheduled_at": time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC),
+		"since":        time.Date(2026, 3, 9, 9, 0, 0, 0, time.UTC),
+	})
+	prompt := BuildPrompt(p, &intake.RawEvent{Source: intake.SourceSchedule, Payload: payload}, nil)
+
+	for _, want := range []string{"定时巡检任务「commit-review」", "审查昨日合入的提交", "2026-03-09T09:00:00Z", "has_issue"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q", want)
+		}
+	}
+	if strings.Contains(prompt, "不可信输入") {
+		t.Error("scheduled prompt should not treat the configured instructions as untrusted event data")
+	}
+}
+
+func TestBuildFollowUpPrompt(t *testing.T) {
+	prompt := BuildFollowUpPrompt("what about the retry path?")
+	if !strings.Contains(prompt, "what about the retry path?") {
+		t.Error("prompt should contain the question")
+	}
+	if !strings.Contains(prompt, "只读") {
+		t.Error("prompt should restate the read-only constraint")
+	}
+}
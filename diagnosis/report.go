@@ -33,6 +33,7 @@ type Report struct {
 	OriginalConfidence float64 `json:"original_confidence"`
 	FinalConfidence    float64 `json:"final_confidence"`
 	FinalConfLabel     string  `json:"final_confidence_label"`
+	Review             *Review `json:"review,omitempty"`
 
 	// P1: Historical fingerprint reuse
 	Fingerprint  string `json:"fingerprint,omitempty"`
@@ -43,6 +44,48 @@ type Report struct {
 	PromptVersion string `json:"prompt_version,omitempty"`
 }
 
+// Scrub applies fn to every free-text field of the report, including the
+// structured result, so redacted content never reaches storage or notifications.
+func (r *Report) Scrub(fn func(string) string) {
+	r.Summary = fn(r.Summary)
+	r.RawResult = fn(r.RawResult)
+	if r.Review != nil {
+		r.Review.Comment = fn(r.Review.Comment)
+		for i := range r.Review.Verdicts {
+			r.Review.Verdicts[i].Reason = fn(r.Review.Verdicts[i].Reason)
+		}
+	}
+	d := r.StructuredResult
+	if d == nil {
+		return
+	}
+	d.Summary = fn(d.Summary)
+	for i := range d.RootCauses {
+		rc := &d.RootCauses[i]
+		rc.Hypothesis = fn(rc.Hypothesis)
+		for j := range rc.Evidence {
+			rc.Evidence[j].Detail = fn(rc.Evidence[j].Detail)
+		}
+		scrubStrings(rc.CounterEvidence, fn)
+		scrubStrings(rc.VerificationSteps, fn)
+	}
+	for i := range d.CodeLocations {
+		d.CodeLocations[i].Reason = fn(d.CodeLocations[i].Reason)
+	}
+	if d.SuspectedCommit != nil {
+		d.SuspectedCommit.Reason = fn(d.SuspectedCommit.Reason)
+	}
+	scrubStrings(d.Remediations, fn)
+	scrubStrings(d.NextActions, fn)
+	scrubStrings(d.NonCodeFactors, fn)
+}
+
+func scrubStrings(list []string, fn func(string) string) {
+	for i := range list {
+		list[i] = fn(list[i])
+	}
+}
+
 // UsageInfo tracks token consumption.
 type UsageInfo struct {
 	InputTokens  int `json:"input_tokens"`
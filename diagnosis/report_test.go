@@ -0,0 +1,40 @@
+package diagnosis
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReport_Scrub(t *testing.T) {
+	secret := "sk-live-secret"
+	r := &Report{
+		Summary:   "found " + secret,
+		RawResult: `{"summary":"found sk-live-secret"}`,
+		StructuredResult: &DiagnosisJSON{
+			Summary: secret,
+			RootCauses: []RootCause{{
+				Hypothesis:        secret,
+				Evidence:          []Evidence{{Type: "code", Detail: "key = " + secret}},
+				CounterEvidence:   []string{secret},
+				VerificationSteps: []string{secret},
+			}},
+			CodeLocations:  []CodeLocation{{File: "a.go", Reason: secret}},
+			Remediations:   []string{secret},
+			NextActions:    []string{secret},
+			NonCodeFactors: []string{secret},
+		},
+	}
+	r.Scrub(func(s string) string { return strings.ReplaceAll(s, secret, "[TOKEN]") })
+
+	d := r.StructuredResult
+	fields := []string{
+		r.Summary, r.RawResult, d.Summary, d.RootCauses[0].Hypothesis, d.RootCauses[0].Evidence[0].Detail,
+		d.RootCauses[0].CounterEvidence[0], d.RootCauses[0].VerificationSteps[0], d.CodeLocations[0].Reason,
+		d.Remediations[0], d.NextActions[0], d.NonCodeFactors[0],
+	}
+	for i, f := range fields {
+		if strings.Contains(f, secret) {
+			t.Errorf("field %d not scrubbed: %q", i, f)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package diagnosis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"amp-sentinel/amp"
+	"amp-sentinel/intake"
+	"amp-sentinel/project"
+)
+
+// Reviewer verdicts on a root cause hypothesis.
+const (
+	VerdictSupported    = "supported"
+	VerdictPartial      = "partial"
+	VerdictRefuted      = "refuted"
+	VerdictUnverifiable = "unverifiable"
+)
+
+// Quality flags set by the reviewer stage.
+const (
+	FlagReviewRefuted = "REVIEW_REFUTED" // the top-ranked hypothesis was refuted
+	FlagReviewFailed  = "REVIEW_FAILED"  // the reviewer ran but its output was unusable
+)
+
+var validVerdicts = map[string]bool{
+	VerdictSupported: true, VerdictPartial: true, VerdictRefuted: true, VerdictUnverifiable: true,
+}
+
+// ReviewConfig configures the reviewer stage: a second, cheaper Amp run
+// that checks the evidence of each root cause against the code.
+type ReviewConfig struct {
+	Enabled bool
+	Mode    string        // Amp mode of the reviewer (default rush)
+	Timeout time.Duration // default 5m
+	// Severities limits the review to events of these severities (empty = all).
+	Severities []string
+}
+
+// Review is the reviewer's assessment of a structured diagnosis.
+type Review struct {
+	Verdicts           []HypothesisVerdict `json:"verdicts"`
+	AdjustedConfidence float64             `json:"adjusted_confidence"`
+	Comment            string              `json:"comment,omitempty"`
+}
+
+// HypothesisVerdict is the reviewer's verdict on one root cause, matched
+// by rank.
+type HypothesisVerdict struct {
+	Rank    int    `json:"rank"`
+	Verdict string `json:"verdict"`
+	Reason  string `json:"reason"`
+}
+
+// reviewEnabled reports whether a diagnosis of the project at the given
+// severity is reviewed. Project settings override the global ones.
+func (e *Engine) reviewEnabled(proj *project.Project, severity string) bool {
+	enabled := e.review.Enabled
+	if proj.Review.Enabled != nil {
+		enabled = *proj.Review.Enabled
+	}
+	if !enabled {
+		return false
+	}
+	severities := e.review.Severities
+	if len(proj.Review.Severities) > 0 {
+		severities = proj.Review.Severities
+	}
+	if len(severities) == 0 {
+		return true
+	}
+	for _, s := range severities {
+		if s == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// runReview asks the reviewer to verify diag in srcDir. Callers hold the
+// project lock.
+func (e *Engine) runReview(ctx context.Context, srcDir string, proj *project.Project, event *intake.RawEvent, diag *DiagnosisJSON) (*Review, *amp.Usage, error) {
+	reviewCtx, cancel := context.WithTimeout(ctx, e.review.Timeout)
+	defer cancel()
+
+	prompt, err := BuildReviewPrompt(proj, event, diag)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := e.ampClient.Execute(reviewCtx, prompt, amp.ExecuteOption{
+		WorkDir:     srcDir,
+		Mode:        e.review.Mode,
+		Permissions: amp.ReadOnlyPermissions(),
+		Labels:      []string{"sentinel", "review", proj.Key},
+	}, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reviewer execution: %w", err)
+	}
+	if result.IsError {
+		return nil, result.Usage, fmt.Errorf("reviewer error: %s", result.Error)
+	}
+	review, err := ParseReview(result.Result)
+	return review, result.Usage, err
+}
+
+// withoutDeadline returns a context that outlives ctx's deadline but is
+// still cancelled when ctx is cancelled outright, e.g. when an admin cancels
+// the task. Call stop to release it.
+func withoutDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	unregister := context.AfterFunc(ctx, func() {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			cancel()
+		}
+	})
+	return detached, func() {
+		unregister()
+		cancel()
+	}
+}
+
+// ParseReview parses the reviewer's JSON output.
+func ParseReview(raw string) (*Review, error) {
+	jsonStr := extractJSONBlock(raw)
+	var review Review
+	if err := json.Unmarshal([]byte(jsonStr), &review); err != nil {
+		obj := extractJSONObject(raw)
+		if obj == "" {
+			return nil, fmt.Errorf("review: no JSON object in output")
+		}
+		if err := json.Unmarshal([]byte(fixTrailingComma(obj)), &review); err != nil {
+			return nil, fmt.Errorf("review: %w", err)
+		}
+	}
+	if review.AdjustedConfidence < 0 || review.AdjustedConfidence > 1 {
+		return nil, fmt.Errorf("review: adjusted_confidence must be between 0 and 1, got %f", review.AdjustedConfidence)
+	}
+	if len(review.Verdicts) == 0 {
+		return nil, fmt.Errorf("review: verdicts are required")
+	}
+	for i, v := range review.Verdicts {
+		if !validVerdicts[v.Verdict] {
+			review.Verdicts[i].Verdict = VerdictUnverifiable
+		}
+	}
+	return &review, nil
+}
+
+// ApplyReview recomputes the final confidence and the quality score of a
+// diagnosis from its review. Evidence of refuted hypotheses no longer
+// counts, and coherence is judged against the adjusted confidence. Returns
+// the final confidence and its label.
+func ApplyReview(diag *DiagnosisJSON, score *QualityScore, review *Review) (float64, string) {
+	final := review.AdjustedConfidence
+	label := confidenceLabelFromValue(final)
+
+	refuted := make(map[int]bool)
+	for _, v := range review.Verdicts {
+		if v.Verdict == VerdictRefuted {
+			refuted[v.Rank] = true
+		}
+	}
+	reviewed := *diag
+	reviewed.Conclusion.Confidence = final
+	reviewed.Conclusion.ConfidenceLabel = label
+	reviewed.RootCauses = make([]RootCause, len(diag.RootCauses))
+	for i, rc := range diag.RootCauses {
+		if refuted[rc.Rank] {
+			rc.Evidence = nil
+		}
+		reviewed.RootCauses[i] = rc
+	}
+
+	if score != nil {
+		score.Evidence = scoreEvidence(&reviewed)
+		score.Coherence = scoreCoherence(&reviewed)
+		for _, f := range collectFlags(&reviewed, score) {
+			score.Flags = appendFlag(score.Flags, f)
+		}
+		if len(diag.RootCauses) > 0 && refuted[topRank(diag.RootCauses)] {
+			score.Flags = appendFlag(score.Flags, FlagReviewRefuted)
+		}
+		score.Normalized = NormalizeScore(score)
+	}
+	return final, label
+}
+
+// topRank returns the best (lowest) rank among root causes.
+func topRank(causes []RootCause) int {
+	top := causes[0].Rank
+	for _, rc := range causes[1:] {
+		if rc.Rank < top {
+			top = rc.Rank
+		}
+	}
+	return top
+}
+
+func appendFlag(flags []string, flag string) []string {
+	for _, f := range flags {
+		if f == flag {
+			return flags
+		}
+	}
+	return append(flags, flag)
+}
+
+// BuildReviewPrompt constructs the reviewer prompt: the event and the first
+// diagnosis as data, and the instruction to verify each hypothesis.
+func BuildReviewPrompt(p *project.Project, event *intake.RawEvent, diag *DiagnosisJSON) (string, error) {
+	diagJSON, err := json.MarshalIndent(diag, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal diagnosis: %w", err)
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(`你是一个严谨的故障诊断复核专家。另一位工程师已经对项目「%s」(%s) 的一次故障给出了诊断结论，请逐条复核其根因假设。
+
+严格只读：只阅读、搜索代码，不要修改任何文件。
+
+## 原始事件
+
+来源: %s    严重级别: %s
+
+`+"```json\n", p.Name, p.Key, event.Source, event.Severity))
+	sb.WriteString(truncatePayload(event.Payload, maxPayloadSize))
+	sb.WriteString("\n```\n\n## 待复核的诊断结论\n\n```json\n")
+	sb.WriteString(string(diagJSON))
+	sb.WriteString("\n```\n\n")
+	sb.WriteString(`## 复核要求
+
+1. 对 root_causes 中的每条假设，打开其证据引用的文件和行号，确认代码确实如证据所述
+2. 判断证据是否足以支持该假设，并寻找反证
+3. 按以下规则给出 verdict：
+   - supported: 证据真实且足以支持假设
+   - partial: 证据部分成立，或只能解释部分现象
+   - refuted: 证据与代码不符，或存在明确反证
+   - unverifiable: 无法通过代码验证（如依赖运行时数据）
+4. 综合复核结果给出调整后的置信度 adjusted_confidence（0.0~1.0）；不要因为措辞自信而提高置信度
+
+**输出格式要求**：只输出如下 JSON，允许用 ` + "```json```" + ` 代码块包裹：
+
+{
+  "verdicts": [
+    {"rank": 1, "verdict": "supported|partial|refuted|unverifiable", "reason": "复核依据（文件路径与行号）"}
+  ],
+  "adjusted_confidence": 0.6,
+  "comment": "一句话复核结论"
+}
+`)
+	return sb.String(), nil
+}
@@ -0,0 +1,203 @@
+package diagnosis
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"amp-sentinel/project"
+)
+
+func reviewedDiagnosis() *DiagnosisJSON {
+	return &DiagnosisJSON{
+		Summary: "NPE in OrderService.createOrder",
+		Conclusion: Conclusion{
+			HasIssue:        true,
+			Confidence:      0.9,
+			ConfidenceLabel: "high",
+		},
+		RootCauses: []RootCause{
+			{
+				Rank:       1,
+				Hypothesis: "getPrice() returns null when product is discontinued",
+				Evidence: []Evidence{
+					{Type: "code", Detail: "OrderService.java:42 calls getPrice() without null check", File: "OrderService.java", LineStart: 42},
+					{Type: "log", Detail: "NullPointerException at OrderService.createOrder(OrderService.java:42)"},
+				},
+			},
+			{
+				Rank:       2,
+				Hypothesis: "cache returns a stale product",
+				Evidence: []Evidence{
+					{Type: "code", Detail: "ProductCache.java:10 never expires entries", File: "ProductCache.java", LineStart: 10},
+				},
+			},
+		},
+		Remediations: []string{"Add null check before getPrice() call and handle discontinued products gracefully"},
+	}
+}
+
+func TestParseReview(t *testing.T) {
+	raw := "复核完成。\n```json\n" + `{
+  "verdicts": [
+    {"rank": 1, "verdict": "refuted", "reason": "OrderService.java:42 already checks for null"},
+    {"rank": 2, "verdict": "maybe", "reason": "needs runtime data"},
+  ],
+  "adjusted_confidence": 0.4,
+  "comment": "首要假设与代码不符"
+}` + "\n```"
+	review, err := ParseReview(raw)
+	if err != nil {
+		t.Fatalf("ParseReview: %v", err)
+	}
+	if len(review.Verdicts) != 2 || review.AdjustedConfidence != 0.4 || review.Comment != "首要假设与代码不符" {
+		t.Fatalf("review = %+v", review)
+	}
+	if review.Verdicts[0].Verdict != VerdictRefuted {
+		t.Errorf("verdict[0] = %q, want refuted", review.Verdicts[0].Verdict)
+	}
+	if review.Verdicts[1].Verdict != VerdictUnverifiable {
+		t.Errorf("unknown verdict should map to unverifiable, got %q", review.Verdicts[1].Verdict)
+	}
+}
+
+func TestParseReview_Invalid(t *testing.T) {
+	for name, raw := range map[string]string{
+		"no json":          "looks fine to me",
+		"no verdicts":      `{"verdicts": [], "adjusted_confidence": 0.5}`,
+		"confidence range": `{"verdicts": [{"rank": 1, "verdict": "supported"}], "adjusted_confidence": 1.5}`,
+	} {
+		if _, err := ParseReview(raw); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestApplyReview_RefutedTopHypothesis(t *testing.T) {
+	diag := reviewedDiagnosis()
+	score := ScoreQuality(diag)
+	score.Normalized = NormalizeScore(score)
+	before := *score
+
+	final, label := ApplyReview(diag, score, &Review{
+		Verdicts: []HypothesisVerdict{
+			{Rank: 1, Verdict: VerdictRefuted},
+			{Rank: 2, Verdict: VerdictRefuted},
+		},
+		AdjustedConfidence: 0.35,
+	})
+	if final != 0.35 || label != "low" {
+		t.Errorf("final = %v %q, want 0.35 low", final, label)
+	}
+	if score.Evidence >= before.Evidence {
+		t.Errorf("Evidence = %d, want below %d once all evidence is refuted", score.Evidence, before.Evidence)
+	}
+	if score.Normalized >= before.Normalized {
+		t.Errorf("Normalized = %d, want below %d", score.Normalized, before.Normalized)
+	}
+	for _, flag := range []string{FlagReviewRefuted, FlagNoEvidence} {
+		if !containsFlag(score.Flags, flag) {
+			t.Errorf("Flags = %v, want %s", score.Flags, flag)
+		}
+	}
+	// The diagnosis itself is left as the agent wrote it.
+	if len(diag.RootCauses[0].Evidence) != 2 || diag.Conclusion.Confidence != 0.9 {
+		t.Error("ApplyReview must not modify the diagnosis")
+	}
+}
+
+func TestApplyReview_Supported(t *testing.T) {
+	diag := reviewedDiagnosis()
+	score := ScoreQuality(diag)
+	score.Normalized = NormalizeScore(score)
+	before := *score
+
+	final, label := ApplyReview(diag, score, &Review{
+		Verdicts:           []HypothesisVerdict{{Rank: 1, Verdict: VerdictSupported}, {Rank: 2, Verdict: VerdictRefuted}},
+		AdjustedConfidence: 0.85,
+	})
+	if final != 0.85 || label != "high" {
+		t.Errorf("final = %v %q, want 0.85 high", final, label)
+	}
+	if containsFlag(score.Flags, FlagReviewRefuted) {
+		t.Errorf("Flags = %v: only a refuted top hypothesis is flagged", score.Flags)
+	}
+	if score.Coherence != before.Coherence {
+		t.Errorf("Coherence = %d, want %d", score.Coherence, before.Coherence)
+	}
+}
+
+func TestReviewEnabled(t *testing.T) {
+	off, on := false, true
+	e := &Engine{review: ReviewConfig{Enabled: true, Severities: []string{"critical"}}}
+	cases := []struct {
+		name     string
+		review   project.ProjectReviewConfig
+		severity string
+		want     bool
+	}{
+		{"global severity", project.ProjectReviewConfig{}, "critical", true},
+		{"other severity", project.ProjectReviewConfig{}, "warning", false},
+		{"project off", project.ProjectReviewConfig{Enabled: &off}, "critical", false},
+		{"project severities", project.ProjectReviewConfig{Severities: []string{"warning"}}, "warning", true},
+	}
+	for _, c := range cases {
+		if got := e.reviewEnabled(&project.Project{Review: c.review}, c.severity); got != c.want {
+			t.Errorf("%s: reviewEnabled = %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	e.review.Enabled = false
+	if e.reviewEnabled(&project.Project{}, "critical") {
+		t.Error("globally disabled review should be off")
+	}
+	if !e.reviewEnabled(&project.Project{Review: project.ProjectReviewConfig{Enabled: &on}}, "critical") {
+		t.Error("project should be able to enable the review")
+	}
+}
+
+func TestBuildReviewPrompt(t *testing.T) {
+	prompt, err := BuildReviewPrompt(newTestProject(), newTestEvent(), reviewedDiagnosis())
+	if err != nil {
+		t.Fatalf("BuildReviewPrompt: %v", err)
+	}
+	for _, want := range []string{"getPrice() returns null", "adjusted_confidence", "refuted", "只读"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q", want)
+		}
+	}
+}
+
+func containsFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWithoutDeadline(t *testing.T) {
+	// The parent's deadline does not reach the review context.
+	parent, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	ctx, stop := withoutDeadline(parent)
+	defer stop()
+	<-parent.Done()
+	time.Sleep(10 * time.Millisecond)
+	if ctx.Err() != nil {
+		t.Fatalf("review context ended with the parent's deadline: %v", ctx.Err())
+	}
+
+	// Cancelling the parent (an admin cancelling the task) does.
+	parent, cancel = context.WithCancel(context.Background())
+	ctx, stop = withoutDeadline(parent)
+	defer stop()
+	cancel()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("review context not cancelled with the parent")
+	}
+}
@@ -9,14 +9,15 @@ import (
 
 // Quality flag constants identify specific quality issues in a diagnosis.
 const (
-	FlagSchemaInvalid     = "SCHEMA_INVALID"
-	FlagNoEvidence        = "NO_EVIDENCE"
-	FlagHallucinatedFile  = "HALLUCINATED_FILE"
-	FlagHallucinatedLine  = "HALLUCINATED_LINE"
-	FlagHighConfNoSupport = "HIGH_CONF_NO_SUPPORT"
-	FlagNoConclusion      = "NO_CONCLUSION"
-	FlagEmptyRemediation  = "EMPTY_REMEDIATION"
-	FlagAutoFixedEvType   = "AUTO_FIXED_EVIDENCE_TYPE"
+	FlagSchemaInvalid      = "SCHEMA_INVALID"
+	FlagNoEvidence         = "NO_EVIDENCE"
+	FlagHallucinatedFile   = "HALLUCINATED_FILE"
+	FlagHallucinatedLine   = "HALLUCINATED_LINE"
+	FlagHighConfNoSupport  = "HIGH_CONF_NO_SUPPORT"
+	FlagNoConclusion       = "NO_CONCLUSION"
+	FlagEmptyRemediation   = "EMPTY_REMEDIATION"
+	FlagAutoFixedEvType    = "AUTO_FIXED_EVIDENCE_TYPE"
+	FlagHallucinatedCommit = "HALLUCINATED_COMMIT"
 )
 
 var validEvidenceTypes = map[string]bool{
@@ -29,14 +30,17 @@ var validConfidenceLabels = map[string]bool{
 
 // DiagnosisJSON represents the structured output from AI diagnosis.
 type DiagnosisJSON struct {
-	SchemaVersion  string         `json:"schema_version"`
-	Summary        string         `json:"summary"`
-	Conclusion     Conclusion     `json:"conclusion"`
-	RootCauses     []RootCause    `json:"root_causes"`
-	CodeLocations  []CodeLocation `json:"code_locations"`
-	Remediations   []string       `json:"remediations"`
-	NextActions    []string       `json:"next_actions"`
-	NonCodeFactors []string       `json:"non_code_factors"`
+	SchemaVersion string         `json:"schema_version"`
+	Summary       string         `json:"summary"`
+	Conclusion    Conclusion     `json:"conclusion"`
+	RootCauses    []RootCause    `json:"root_causes"`
+	CodeLocations []CodeLocation `json:"code_locations"`
+	// SuspectedCommit names the recent commit that likely introduced the
+	// fault (nil if none is suspected).
+	SuspectedCommit *SuspectedCommit `json:"suspected_commit,omitempty"`
+	Remediations    []string         `json:"remediations"`
+	NextActions     []string         `json:"next_actions"`
+	NonCodeFactors  []string         `json:"non_code_factors"`
 
 	// AutoFixedEvidenceTypes records original invalid evidence types
 	// that were auto-corrected during validation. Not serialized.
@@ -76,6 +80,12 @@ type CodeLocation struct {
 	Reason    string `json:"reason"`
 }
 
+// SuspectedCommit is a commit the diagnosis blames for the fault.
+type SuspectedCommit struct {
+	Hash   string `json:"hash"`
+	Reason string `json:"reason"`
+}
+
 // IsInsufficientInformation returns true if any root cause indicates
 // insufficient information to make a diagnosis.
 func (d *DiagnosisJSON) IsInsufficientInformation() bool {
@@ -122,6 +132,10 @@ const DiagnosisOutputSchemaDoc = `{
       "reason": "此处未做 null 检查导致 NPE"
     }
   ],
+  "suspected_commit": {
+    "hash": "a1b2c3d",
+    "reason": "该提交移除了价格的 null 检查，提交时间与故障开始时间吻合"
+  },
   "remediations": ["具体的修复建议"],
   "next_actions": ["进一步排查建议"],
   "non_code_factors": ["可能的非代码因素（基础设施/配置/外部依赖等）"]
@@ -134,6 +148,7 @@ const DiagnosisOutputSchemaDoc = `{
 - root_causes: 必填，≥1项。允许 hypothesis="insufficient_information" 表示信息不足
 - evidence.type: 枚举 code|log|stack|config
 - code_locations: 可为空数组，但 has_issue=true 时应尽量提供
+- suspected_commit: 可选，故障疑似由近期提交引入时填写（hash 须来自近期代码变更或 git log），无法确定时省略
 - non_code_factors: 当 has_issue=false 时必填
 - 不要编造根因或证据来满足格式要求`
 
@@ -373,6 +388,10 @@ func validateDiagnosisJSON(d *DiagnosisJSON) error {
 		return fmt.Errorf("root_causes must have at least 1 entry")
 	}
 
+	if d.SuspectedCommit != nil && strings.TrimSpace(d.SuspectedCommit.Hash) == "" {
+		d.SuspectedCommit = nil
+	}
+
 	for i, rc := range d.RootCauses {
 		for j, ev := range rc.Evidence {
 			if ev.Type != "" && !validEvidenceTypes[ev.Type] {
@@ -1,6 +1,7 @@
 package diagnosis
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -150,6 +151,31 @@ func TestParseDiagnosisJSON_AutoFixConfidenceLabel(t *testing.T) {
 	}
 }
 
+func TestParseDiagnosisJSON_SuspectedCommit(t *testing.T) {
+	input := `{
+		"summary": "Test",
+		"conclusion": {"has_issue": true, "confidence": 0.8, "confidence_label": "high"},
+		"root_causes": [{"rank": 1, "hypothesis": "Test"}],
+		"suspected_commit": {"hash": "a1b2c3d", "reason": "removed the null check"}
+	}`
+	diag, err := ParseDiagnosisJSON(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diag.SuspectedCommit == nil || diag.SuspectedCommit.Hash != "a1b2c3d" {
+		t.Errorf("SuspectedCommit = %+v, want a1b2c3d", diag.SuspectedCommit)
+	}
+
+	// An empty hash names no commit.
+	diag, err = ParseDiagnosisJSON(strings.Replace(input, "a1b2c3d", " ", 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diag.SuspectedCommit != nil {
+		t.Errorf("SuspectedCommit = %+v, want nil", diag.SuspectedCommit)
+	}
+}
+
 func TestParseDiagnosisJSON_UnterminatedString(t *testing.T) {
 	// Simulates truncated AI output
 	input := `{
@@ -0,0 +1,65 @@
+package diagnosis
+
+import (
+	"context"
+
+	"amp-sentinel/intake"
+	"amp-sentinel/logger"
+	"amp-sentinel/project"
+)
+
+// FlagVersionUnresolved marks a diagnosis of the branch tip because the
+// deployed version reported by the event could not be checked out.
+const FlagVersionUnresolved = "VERSION_UNRESOLVED"
+
+// deployedRevision returns the version the event reports at the project's
+// (or the global) version fields, and the revision to check out for it:
+// the commit of a known build ID, otherwise the version itself. Both are ""
+// when the event reports no version.
+func (e *Engine) deployedRevision(proj *project.Project, event *intake.RawEvent) (version, rev string) {
+	if event.Source == intake.SourceSchedule {
+		return "", ""
+	}
+	fields := proj.Version.Fields
+	if len(fields) == 0 {
+		fields = e.versionFields
+	}
+	version = intake.ExtractField(event.Payload, fields)
+	if version == "" {
+		return "", ""
+	}
+	if commit, ok := proj.Version.Builds[version]; ok {
+		return version, commit
+	}
+	return version, version
+}
+
+// prepareSource prepares the checkout the event is diagnosed against: the
+// deployed revision if the event reports one, otherwise the branch tip.
+// When the revision can't be checked out it falls back to the branch tip
+// and returns FlagVersionUnresolved. Callers hold the project lock.
+func (e *Engine) prepareSource(ctx context.Context, proj *project.Project, event *intake.RawEvent, log logger.Logger) (srcDir, commitHash string, flags []string, err error) {
+	srcDir, err = e.sources.Prepare(ctx, proj)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if version, rev := e.deployedRevision(proj, event); rev != "" {
+		commitHash, err = e.sources.Checkout(ctx, proj, rev)
+		if err == nil {
+			log.Info("diagnosis.deployed_version",
+				logger.String("version", version),
+				logger.String("commit", commitHash),
+			)
+			return srcDir, commitHash, nil, nil
+		}
+		log.Warn("diagnosis.version_unresolved",
+			logger.String("version", version),
+			logger.String("revision", rev),
+			logger.Err(err),
+		)
+		flags = []string{FlagVersionUnresolved}
+		// A failed fetch or checkout leaves the branch tip in place.
+	}
+	commitHash, _ = e.sources.CommitHash(ctx, proj.Key)
+	return srcDir, commitHash, flags, nil
+}
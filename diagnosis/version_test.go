@@ -0,0 +1,42 @@
+package diagnosis
+
+import (
+	"encoding/json"
+	"testing"
+
+	"amp-sentinel/intake"
+	"amp-sentinel/project"
+)
+
+func TestDeployedRevision(t *testing.T) {
+	e := &Engine{versionFields: []string{"release"}}
+	event := &intake.RawEvent{
+		Source:  "sentry",
+		Payload: json.RawMessage(`{"release":"v1.4.2","build_id":"b-1042"}`),
+	}
+
+	if v, rev := e.deployedRevision(&project.Project{}, event); v != "v1.4.2" || rev != "v1.4.2" {
+		t.Errorf("global fields: got %q %q, want the release tag", v, rev)
+	}
+
+	proj := &project.Project{Version: project.ProjectVersionConfig{
+		Fields: []string{"build_id"},
+		Builds: map[string]string{"b-1042": "a1b2c3d"},
+	}}
+	if v, rev := e.deployedRevision(proj, event); v != "b-1042" || rev != "a1b2c3d" {
+		t.Errorf("project build map: got %q %q, want b-1042 → a1b2c3d", v, rev)
+	}
+
+	proj.Version.Builds = nil
+	if _, rev := e.deployedRevision(proj, event); rev != "b-1042" {
+		t.Errorf("unmapped build: rev = %q, want the build ID itself", rev)
+	}
+
+	if _, rev := e.deployedRevision(&project.Project{}, &intake.RawEvent{Source: "sentry", Payload: json.RawMessage(`{}`)}); rev != "" {
+		t.Errorf("no version: rev = %q, want branch tip", rev)
+	}
+	event.Source = intake.SourceSchedule
+	if _, rev := e.deployedRevision(&project.Project{}, event); rev != "" {
+		t.Errorf("scheduled sweep: rev = %q, want branch tip", rev)
+	}
+}
@@ -0,0 +1,179 @@
+package incident
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"amp-sentinel/intake"
+	"amp-sentinel/store"
+
+	"github.com/google/uuid"
+)
+
+// Grouper correlates events into incidents. Events of the same project with
+// the same group key are attached to an open incident as long as they arrive
+// within the window of its last event; otherwise a new incident is opened.
+type Grouper struct {
+	store  store.Store
+	window time.Duration
+	// mu serializes find-then-create so concurrent first events of one
+	// outage cannot open two incidents in this process.
+	mu sync.Mutex
+	// opening holds incidents opened by Group whose task has not been
+	// recorded yet, so they are not mistaken for abandoned ones.
+	opening map[string]bool
+}
+
+// NewGrouper creates a Grouper. window is the maximum gap between events of
+// one incident (default 30m).
+func NewGrouper(st store.Store, window time.Duration) *Grouper {
+	if window <= 0 {
+		window = 30 * time.Minute
+	}
+	return &Grouper{store: st, window: window, opening: make(map[string]bool)}
+}
+
+// Group stores evt and attaches it to an incident. isNew reports whether the
+// event opened the incident, in which case the caller should diagnose it and
+// record the task with SetTask, or Abandon the incident if it could not be
+// submitted. Attached events are stored as grouped. An open incident whose
+// diagnosis never started or failed is resolved and replaced by a new one,
+// so later events of the outage are still diagnosed.
+func (g *Grouper) Group(ctx context.Context, evt *store.Event, key string) (inc *store.Incident, isNew bool, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	inc, err = g.store.FindOpenIncident(ctx, evt.ProjectKey, key, evt.ReceivedAt.Add(-g.window))
+	if err != nil {
+		return nil, false, fmt.Errorf("find open incident: %w", err)
+	}
+
+	if inc != nil && g.stale(ctx, inc) {
+		inc.Status = store.IncidentStatusResolved
+		if err := g.store.UpdateIncident(ctx, inc); err != nil {
+			return nil, false, fmt.Errorf("update incident: %w", err)
+		}
+		inc = nil
+	}
+
+	if inc != nil {
+		evt.IncidentID = inc.ID
+		evt.Status = store.EventStatusGrouped
+		if err := g.store.CreateEvent(ctx, evt); err != nil {
+			return nil, false, fmt.Errorf("create event: %w", err)
+		}
+		g.touch(inc, evt.Severity, evt.ReceivedAt)
+		if err := g.store.UpdateIncident(ctx, inc); err != nil {
+			return nil, false, fmt.Errorf("update incident: %w", err)
+		}
+		return inc, false, nil
+	}
+
+	inc = &store.Incident{
+		ID:           "inc-" + uuid.New().String()[:8],
+		ProjectKey:   evt.ProjectKey,
+		Fingerprint:  key,
+		Title:        evt.Title,
+		Severity:     evt.Severity,
+		Source:       evt.Source,
+		Status:       store.IncidentStatusOpen,
+		FirstEventID: evt.ID,
+		EventCount:   1,
+		FirstSeen:    evt.ReceivedAt,
+		LastSeen:     evt.ReceivedAt,
+	}
+	evt.IncidentID = inc.ID
+	if err := g.store.CreateEvent(ctx, evt); err != nil {
+		return nil, false, fmt.Errorf("create event: %w", err)
+	}
+	if err := g.store.CreateIncident(ctx, inc); err != nil {
+		return nil, false, fmt.Errorf("create incident: %w", err)
+	}
+	g.opening[inc.ID] = true
+	return inc, true, nil
+}
+
+// stale reports whether an open incident can no longer produce a diagnosis:
+// it has no task and is not being opened right now, or its task failed.
+// A cancelled task is left alone; cancelling was a deliberate choice.
+func (g *Grouper) stale(ctx context.Context, inc *store.Incident) bool {
+	if inc.TaskID == "" {
+		return !g.opening[inc.ID]
+	}
+	task, err := g.store.GetTask(ctx, inc.TaskID)
+	if err != nil || task == nil {
+		return false
+	}
+	switch task.Status {
+	case store.StatusFailed, store.StatusTimeout, store.StatusExpired:
+		return true
+	}
+	return false
+}
+
+// Touch counts an occurrence that was not stored as an event (for example a
+// deduplicated one) against the open incident for key, if there is one.
+func (g *Grouper) Touch(ctx context.Context, projectKey, key, severity string, at time.Time) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	inc, err := g.store.FindOpenIncident(ctx, projectKey, key, at.Add(-g.window))
+	if err != nil {
+		return fmt.Errorf("find open incident: %w", err)
+	}
+	if inc == nil {
+		return nil
+	}
+	g.touch(inc, severity, at)
+	return g.store.UpdateIncident(ctx, inc)
+}
+
+// SetTask records the diagnosis task for an incident.
+func (g *Grouper) SetTask(ctx context.Context, incidentID, taskID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	inc, err := g.store.GetIncident(ctx, incidentID)
+	if err != nil {
+		return fmt.Errorf("get incident: %w", err)
+	}
+	if inc == nil {
+		return fmt.Errorf("incident %s not found", incidentID)
+	}
+	delete(g.opening, incidentID)
+	inc.TaskID = taskID
+	return g.store.UpdateIncident(ctx, inc)
+}
+
+// Abandon resolves an incident opened by Group whose diagnosis could not be
+// submitted, so the next event of the group opens a fresh incident instead
+// of attaching to one that will never be diagnosed.
+func (g *Grouper) Abandon(ctx context.Context, incidentID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.opening, incidentID)
+	inc, err := g.store.GetIncident(ctx, incidentID)
+	if err != nil {
+		return fmt.Errorf("get incident: %w", err)
+	}
+	if inc == nil {
+		return fmt.Errorf("incident %s not found", incidentID)
+	}
+	inc.Status = store.IncidentStatusResolved
+	return g.store.UpdateIncident(ctx, inc)
+}
+
+// touch bumps the occurrence count and last-seen time, and escalates the
+// incident severity if the new occurrence is more severe.
+func (g *Grouper) touch(inc *store.Incident, severity string, at time.Time) {
+	inc.EventCount++
+	if at.After(inc.LastSeen) {
+		inc.LastSeen = at
+	}
+	if intake.SeverityPriority(severity) > intake.SeverityPriority(inc.Severity) {
+		inc.Severity = severity
+	}
+}
@@ -0,0 +1,142 @@
+package incident
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"amp-sentinel/logger"
+	"amp-sentinel/store"
+)
+
+func newTestGrouper(t *testing.T, window time.Duration) (*Grouper, store.Store) {
+	t.Helper()
+	st, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"), logger.Nop())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return NewGrouper(st, window), st
+}
+
+func makeEvent(id, severity string, at time.Time) *store.Event {
+	return &store.Event{
+		ID:         id,
+		ProjectKey: "proj-a",
+		Payload:    json.RawMessage(`{"error":"boom"}`),
+		Source:     "custom",
+		Severity:   severity,
+		Title:      "boom",
+		Status:     "pending",
+		ReceivedAt: at,
+	}
+}
+
+func TestGrouper_GroupsWithinWindow(t *testing.T) {
+	g, st := newTestGrouper(t, 10*time.Minute)
+	ctx := context.Background()
+	base := time.Now().UTC().Truncate(time.Second)
+
+	first, isNew, err := g.Group(ctx, makeEvent("evt-1", "warning", base), "key-1")
+	if err != nil || !isNew {
+		t.Fatalf("first event: isNew=%v err=%v", isNew, err)
+	}
+	if err := g.SetTask(ctx, first.ID, "task-1"); err != nil {
+		t.Fatalf("SetTask: %v", err)
+	}
+
+	// Within the window of the last event: attached, severity escalates.
+	inc, isNew, err := g.Group(ctx, makeEvent("evt-2", "critical", base.Add(8*time.Minute)), "key-1")
+	if err != nil || isNew || inc.ID != first.ID {
+		t.Fatalf("second event: inc=%v isNew=%v err=%v", inc, isNew, err)
+	}
+	// The window slides with the last event.
+	if _, isNew, _ = g.Group(ctx, makeEvent("evt-3", "warning", base.Add(16*time.Minute)), "key-1"); isNew {
+		t.Fatal("third event should attach to the sliding window")
+	}
+	if err := g.Touch(ctx, "proj-a", "key-1", "info", base.Add(17*time.Minute)); err != nil {
+		t.Fatalf("Touch: %v", err)
+	}
+
+	got, err := st.GetIncident(ctx, first.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetIncident: %v %v", got, err)
+	}
+	if got.EventCount != 4 || got.Severity != "critical" || got.TaskID != "task-1" {
+		t.Errorf("unexpected incident: %+v", got)
+	}
+	if !got.FirstSeen.Equal(base) || !got.LastSeen.Equal(base.Add(17*time.Minute)) {
+		t.Errorf("first/last seen = %v/%v", got.FirstSeen, got.LastSeen)
+	}
+
+	evt, _ := st.GetEvent(ctx, "evt-2")
+	if evt == nil || evt.IncidentID != first.ID || evt.Status != store.EventStatusGrouped {
+		t.Errorf("attached event not recorded as grouped: %+v", evt)
+	}
+}
+
+func TestGrouper_NewIncident(t *testing.T) {
+	g, st := newTestGrouper(t, 10*time.Minute)
+	ctx := context.Background()
+	base := time.Now().UTC().Truncate(time.Second)
+
+	first, _, _ := g.Group(ctx, makeEvent("evt-1", "warning", base), "key-1")
+
+	// A different key, or a gap longer than the window, opens a new incident.
+	if inc, isNew, _ := g.Group(ctx, makeEvent("evt-2", "warning", base.Add(time.Minute)), "key-2"); !isNew || inc.ID == first.ID {
+		t.Error("different key should open a new incident")
+	}
+	if inc, isNew, _ := g.Group(ctx, makeEvent("evt-3", "warning", base.Add(30*time.Minute)), "key-1"); !isNew || inc.ID == first.ID {
+		t.Error("event after the window should open a new incident")
+	}
+
+	// Resolved incidents are not reopened.
+	first.Status = store.IncidentStatusResolved
+	st.UpdateIncident(ctx, first)
+	if err := g.Touch(ctx, "proj-a", "key-1", "warning", base.Add(time.Minute)); err != nil {
+		t.Fatalf("Touch: %v", err)
+	}
+	got, _ := st.GetIncident(ctx, first.ID)
+	if got.EventCount != 1 {
+		t.Errorf("resolved incident should not be touched, count=%d", got.EventCount)
+	}
+}
+
+func TestGrouper_ReplacesUndiagnosedIncident(t *testing.T) {
+	g, st := newTestGrouper(t, 10*time.Minute)
+	ctx := context.Background()
+	base := time.Now().UTC().Truncate(time.Second)
+
+	// An incident whose task is still being submitted takes new events.
+	first, _, _ := g.Group(ctx, makeEvent("evt-1", "warning", base), "key-1")
+	if _, isNew, _ := g.Group(ctx, makeEvent("evt-2", "warning", base.Add(time.Minute)), "key-1"); isNew {
+		t.Fatal("event should attach while the first task is being submitted")
+	}
+
+	// Once abandoned, the next event opens a fresh incident.
+	if err := g.Abandon(ctx, first.ID); err != nil {
+		t.Fatalf("Abandon: %v", err)
+	}
+	second, isNew, _ := g.Group(ctx, makeEvent("evt-3", "warning", base.Add(2*time.Minute)), "key-1")
+	if !isNew || second.ID == first.ID {
+		t.Fatal("abandoned incident should be replaced")
+	}
+	if got, _ := st.GetIncident(ctx, first.ID); got.Status != store.IncidentStatusResolved {
+		t.Errorf("abandoned incident status = %s", got.Status)
+	}
+
+	// An incident whose task failed is resolved and replaced as well.
+	if err := st.CreateTask(ctx, &store.DiagnosisTask{ID: "task-1", EventID: "evt-3", ProjectKey: "proj-a", Status: store.StatusFailed, CreatedAt: base}); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	g.SetTask(ctx, second.ID, "task-1")
+	third, isNew, _ := g.Group(ctx, makeEvent("evt-4", "warning", base.Add(3*time.Minute)), "key-1")
+	if !isNew || third.ID == second.ID {
+		t.Fatal("incident with a failed task should be replaced")
+	}
+	if got, _ := st.GetIncident(ctx, second.ID); got.Status != store.IncidentStatusResolved {
+		t.Errorf("failed incident status = %s", got.Status)
+	}
+}
@@ -0,0 +1,282 @@
+package intake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"amp-sentinel/logger"
+)
+
+// AlertmanagerConfig controls how Alertmanager webhook alerts are mapped
+// onto RawEvent envelopes.
+type AlertmanagerConfig struct {
+	ProjectLabel   string            // alert label holding the project key (default "project")
+	SeverityLabel  string            // alert label holding the severity (default "severity")
+	DefaultProject string            // project key used when the label is absent
+	SeverityMap    map[string]string // label value (case-insensitive) -> critical/warning/info (e.g. "page" -> "critical")
+}
+
+// ResolveFunc marks stored events matching the given project and reporter
+// fingerprint as resolved. Returns the number of events updated.
+type ResolveFunc func(projectKey, fingerprint string) (int, error)
+
+// alertmanagerWebhook is the Alertmanager webhook body (version "4").
+type alertmanagerWebhook struct {
+	Version           string              `json:"version"`
+	GroupKey          string              `json:"groupKey"`
+	Status            string              `json:"status"`
+	Receiver          string              `json:"receiver"`
+	GroupLabels       map[string]string   `json:"groupLabels"`
+	CommonLabels      map[string]string   `json:"commonLabels"`
+	CommonAnnotations map[string]string   `json:"commonAnnotations"`
+	ExternalURL       string              `json:"externalURL"`
+	Alerts            []alertmanagerAlert `json:"alerts"`
+}
+
+type alertmanagerAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// alertmanagerPayload is the per-alert payload handed to diagnosis.
+// Field names follow Alertmanager so display-field extraction
+// (startsAt, labels.env) works unchanged.
+type alertmanagerPayload struct {
+	Alertname    string            `json:"alertname,omitempty"`
+	Summary      string            `json:"summary,omitempty"`
+	Description  string            `json:"description,omitempty"`
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+	Fingerprint  string            `json:"fingerprint"`
+	Receiver     string            `json:"receiver,omitempty"`
+	ExternalURL  string            `json:"externalURL,omitempty"`
+}
+
+// ServeAlertmanager handles POST /api/v1/alertmanager (Alertmanager webhook v4).
+// Each firing alert in the group becomes its own event; resolved alerts mark
+// the matching stored events as resolved and never start a diagnosis.
+func (h *Handler) ServeAlertmanager(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cred, ok := h.checkAuth(w, r)
+	if !ok {
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, 1<<20)
+	rawBody, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var msg alertmanagerWebhook
+	if err := json.Unmarshal(rawBody, &msg); err != nil {
+		http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if msg.Version != "" && msg.Version != "4" {
+		http.Error(w, fmt.Sprintf("unsupported alertmanager webhook version: %s", msg.Version), http.StatusBadRequest)
+		return
+	}
+
+	var results []map[string]any
+	accepted := 0
+	var maxRetryAfter time.Duration
+	resolved := 0
+	for i, alert := range msg.Alerts {
+		projectKey := alertLabel(alert, msg.CommonLabels, h.alertmanager.ProjectLabel)
+		if projectKey == "" {
+			projectKey = h.alertmanager.DefaultProject
+		}
+
+		if alert.Fingerprint == "" {
+			results = append(results, map[string]any{
+				"index":  i,
+				"status": "error",
+				"error":  "alert fingerprint is required",
+			})
+			continue
+		}
+
+		if alert.Status == "resolved" {
+			if !h.credentialAllows(cred, projectKey) {
+				results = append(results, map[string]any{
+					"index":       i,
+					"fingerprint": alert.Fingerprint,
+					"status":      "error",
+					"error":       fmt.Sprintf("forbidden: credential %s may not resolve alerts for project %s", cred, projectKey),
+				})
+				continue
+			}
+			n, resolveErr := h.resolveAlert(projectKey, alert.Fingerprint)
+			if resolveErr != nil {
+				results = append(results, map[string]any{
+					"index":       i,
+					"fingerprint": alert.Fingerprint,
+					"status":      "error",
+					"error":       resolveErr.Error(),
+				})
+				continue
+			}
+			resolved++
+			results = append(results, map[string]any{
+				"index":       i,
+				"fingerprint": alert.Fingerprint,
+				"status":      "resolved",
+				"events":      n,
+			})
+			continue
+		}
+
+		payload, _ := json.Marshal(alertmanagerPayload{
+			Alertname:    alert.Labels["alertname"],
+			Summary:      alert.Annotations["summary"],
+			Description:  alert.Annotations["description"],
+			Status:       alert.Status,
+			Labels:       alert.Labels,
+			Annotations:  alert.Annotations,
+			StartsAt:     alert.StartsAt,
+			GeneratorURL: alert.GeneratorURL,
+			Fingerprint:  alert.Fingerprint,
+			Receiver:     msg.Receiver,
+			ExternalURL:  msg.ExternalURL,
+		})
+
+		event := &RawEvent{
+			ProjectKey:  projectKey,
+			Severity:    h.alertSeverity(alertLabel(alert, msg.CommonLabels, h.alertmanager.SeverityLabel)),
+			Payload:     json.RawMessage(payload),
+			Source:      "alertmanager",
+			Fingerprint: alert.Fingerprint,
+			Credential:  cred,
+		}
+		h.fillDefaults(event)
+
+		if event.ProjectKey == "" {
+			results = append(results, map[string]any{
+				"index":       i,
+				"event_id":    event.ID,
+				"fingerprint": alert.Fingerprint,
+				"status":      "error",
+				"error":       fmt.Sprintf("missing %q label and no default project configured", h.alertmanager.ProjectLabel),
+			})
+			continue
+		}
+
+		if !h.meetsMinSeverity(event.Severity) {
+			results = append(results, map[string]any{
+				"index":       i,
+				"event_id":    event.ID,
+				"fingerprint": alert.Fingerprint,
+				"status":      "filtered",
+				"message":     fmt.Sprintf("severity %s is below minimum %s", event.Severity, h.minSeverity),
+			})
+			continue
+		}
+
+		if h.validateProject != nil && !h.validateProject(event.ProjectKey) {
+			results = append(results, map[string]any{
+				"index":       i,
+				"event_id":    event.ID,
+				"fingerprint": alert.Fingerprint,
+				"status":      "error",
+				"error":       fmt.Sprintf("unknown project: %s", event.ProjectKey),
+			})
+			continue
+		}
+
+		event.Title = alertTitle(alert)
+		if event.Title == "" {
+			event.Title = ExtractTitle(event.Payload)
+		}
+
+		result, wait := h.submitItem(event, map[string]any{
+			"index":       i,
+			"fingerprint": alert.Fingerprint,
+		})
+		if result["status"] == "queued" {
+			accepted++
+		}
+		maxRetryAfter = max(maxRetryAfter, wait)
+		results = append(results, result)
+	}
+
+	if maxRetryAfter > 0 {
+		setRetryAfter(w, maxRetryAfter)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"total":    len(msg.Alerts),
+		"accepted": accepted,
+		"resolved": resolved,
+		"results":  results,
+	})
+}
+
+// resolveAlert marks stored events for the alert as resolved.
+func (h *Handler) resolveAlert(projectKey, fingerprint string) (int, error) {
+	if h.resolveEvent == nil || projectKey == "" {
+		return 0, nil
+	}
+	n, err := h.resolveEvent(projectKey, fingerprint)
+	if err != nil {
+		h.log.Error("alertmanager.resolve_failed",
+			logger.String("project", projectKey),
+			logger.String("fingerprint", fingerprint),
+			logger.Err(err),
+		)
+		return 0, err
+	}
+	h.log.Info("alertmanager.resolved",
+		logger.String("project", projectKey),
+		logger.String("fingerprint", fingerprint),
+		logger.Int("events", n),
+	)
+	return n, nil
+}
+
+// alertLabel returns the alert's own label, falling back to the group's common labels.
+func alertLabel(alert alertmanagerAlert, common map[string]string, name string) string {
+	if v := alert.Labels[name]; v != "" {
+		return v
+	}
+	return common[name]
+}
+
+// alertSeverity maps an Alertmanager severity label onto a Sentinel severity.
+// Unknown or empty values fall back to "" so fillDefaults applies the default.
+func (h *Handler) alertSeverity(label string) string {
+	v := strings.ToLower(strings.TrimSpace(label))
+	if mapped, ok := h.alertmanager.SeverityMap[v]; ok && ValidSeverities[mapped] {
+		return mapped
+	}
+	if ValidSeverities[v] {
+		return v
+	}
+	return ""
+}
+
+// alertTitle prefers the summary annotation, then the alertname label.
+func alertTitle(alert alertmanagerAlert) string {
+	for _, s := range []string{alert.Annotations["summary"], alert.Labels["alertname"]} {
+		if s = SanitizeDisplayText(s); s != "" {
+			return TruncateRunes(s, 100)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,172 @@
+package intake
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const alertmanagerBody = `{
+  "version": "4",
+  "groupKey": "{}:{alertname=\"HighErrorRate\"}",
+  "status": "firing",
+  "receiver": "sentinel",
+  "commonLabels": {"project": "proj-a"},
+  "externalURL": "http://alertmanager:9093",
+  "alerts": [
+    {
+      "status": "firing",
+      "labels": {"alertname": "HighErrorRate", "severity": "PAGE", "env": "prod"},
+      "annotations": {"summary": "error rate above 5%"},
+      "startsAt": "2025-01-01T10:00:00Z",
+      "fingerprint": "fp-1"
+    },
+    {
+      "status": "firing",
+      "labels": {"alertname": "SlowQueries", "severity": "warning"},
+      "annotations": {},
+      "startsAt": "2025-01-01T10:01:00Z",
+      "fingerprint": "fp-2"
+    },
+    {
+      "status": "resolved",
+      "labels": {"alertname": "DiskFull", "severity": "critical"},
+      "startsAt": "2025-01-01T09:00:00Z",
+      "fingerprint": "fp-3"
+    }
+  ]
+}`
+
+func TestServeAlertmanager_SplitsAlerts(t *testing.T) {
+	var submitted []*RawEvent
+	var resolved []string
+	h := newSubmitTestHandler(HandlerConfig{
+		RateLimit: 100,
+		Alertmanager: AlertmanagerConfig{
+			SeverityMap: map[string]string{"Page": "critical"}, // keys match case-insensitively
+		},
+		ResolveEvent: func(projectKey, fingerprint string) (int, error) {
+			resolved = append(resolved, projectKey+"/"+fingerprint)
+			return 1, nil
+		},
+	}, func(e *RawEvent) (string, error) {
+		submitted = append(submitted, e)
+		return "task-" + e.Fingerprint, nil
+	})
+	defer h.StopCleanup()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/alertmanager", strings.NewReader(alertmanagerBody))
+	rec := httptest.NewRecorder()
+	h.ServeAlertmanager(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+	if resp["accepted"] != float64(2) {
+		t.Errorf("expected accepted=2, got %v", resp["accepted"])
+	}
+	if resp["resolved"] != float64(1) {
+		t.Errorf("expected resolved=1, got %v", resp["resolved"])
+	}
+
+	if len(submitted) != 2 {
+		t.Fatalf("expected 2 submitted events, got %d", len(submitted))
+	}
+	first := submitted[0]
+	if first.ProjectKey != "proj-a" {
+		t.Errorf("ProjectKey = %q, want proj-a (from commonLabels)", first.ProjectKey)
+	}
+	if first.Severity != "critical" {
+		t.Errorf("Severity = %q, want critical (mapped from PAGE via Page)", first.Severity)
+	}
+	if first.Source != "alertmanager" {
+		t.Errorf("Source = %q, want alertmanager", first.Source)
+	}
+	if first.Fingerprint != "fp-1" {
+		t.Errorf("Fingerprint = %q, want fp-1", first.Fingerprint)
+	}
+	if first.Title != "error rate above 5%" {
+		t.Errorf("Title = %q, want summary annotation", first.Title)
+	}
+	if submitted[1].Title != "SlowQueries" {
+		t.Errorf("Title = %q, want alertname fallback", submitted[1].Title)
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(first.Payload, &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	labels, _ := payload["labels"].(map[string]any)
+	if labels["env"] != "prod" {
+		t.Errorf("expected labels to be passed through, got %v", payload["labels"])
+	}
+	df := ExtractDisplayFields(payload)
+	if df.Environment != "prod" || df.OccurredAt == "" {
+		t.Errorf("expected display fields from labels/startsAt, got %+v", df)
+	}
+
+	if len(resolved) != 1 || resolved[0] != "proj-a/fp-3" {
+		t.Errorf("expected resolve of proj-a/fp-3, got %v", resolved)
+	}
+}
+
+func TestServeAlertmanager_DedupByFingerprint(t *testing.T) {
+	calls := 0
+	h := newSubmitTestHandler(HandlerConfig{RateLimit: 100}, func(e *RawEvent) (string, error) { calls++; return "task-1", nil })
+	defer h.StopCleanup()
+
+	// Same fingerprint, different annotations: still a duplicate.
+	bodies := []string{
+		`{"version":"4","alerts":[{"status":"firing","labels":{"project":"proj-a","severity":"critical"},"annotations":{"summary":"a"},"fingerprint":"same"}]}`,
+		`{"version":"4","alerts":[{"status":"firing","labels":{"project":"proj-a","severity":"critical"},"annotations":{"summary":"b"},"fingerprint":"same"}]}`,
+	}
+	for _, body := range bodies {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/alertmanager", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.ServeAlertmanager(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 diagnosis for repeated fingerprint, got %d", calls)
+	}
+}
+
+func TestServeAlertmanager_ResolvedDoesNotDiagnose(t *testing.T) {
+	calls := 0
+	h := newSubmitTestHandler(HandlerConfig{RateLimit: 100}, func(e *RawEvent) (string, error) { calls++; return "task-1", nil })
+	defer h.StopCleanup()
+
+	body := `{"version":"4","status":"resolved","alerts":[{"status":"resolved","labels":{"project":"proj-a"},"fingerprint":"fp"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/alertmanager", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeAlertmanager(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if calls != 0 {
+		t.Errorf("resolved alert must not start a diagnosis, got %d submissions", calls)
+	}
+}
+
+func TestServeAlertmanager_UnsupportedVersion(t *testing.T) {
+	h := newTestHandler(HandlerConfig{RateLimit: 100})
+	defer h.StopCleanup()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/alertmanager", strings.NewReader(`{"version":"3","alerts":[]}`))
+	rec := httptest.NewRecorder()
+	h.ServeAlertmanager(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,393 @@
+package intake
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CloudEvents content types (CloudEvents 1.0 JSON event format).
+const (
+	cloudEventsContentType      = "application/cloudevents+json"
+	cloudEventsBatchContentType = "application/cloudevents-batch+json"
+)
+
+// CloudEventsConfig controls how CloudEvents are mapped onto RawEvent envelopes.
+type CloudEventsConfig struct {
+	ProjectExtension  string            // extension attribute holding the project key (default "project")
+	SeverityExtension string            // extension attribute holding the severity (default "severity")
+	DefaultProject    string            // project key used when the extension is absent
+	SeverityMap       map[string]string // extension value (case-insensitive) -> critical/warning/info (e.g. "error" -> "warning")
+	TypeSeverity      map[string]string // ce-type -> severity, used when the extension is absent
+}
+
+// cloudEvent holds the context attributes and data of one CloudEvent,
+// decoded from either content mode.
+type cloudEvent struct {
+	SpecVersion     string
+	ID              string
+	Source          string
+	Type            string
+	Subject         string
+	DataContentType string
+	// Data is the raw data: a JSON value for JSON content types, otherwise
+	// the literal bytes.
+	Data       []byte
+	Extensions map[string]string
+}
+
+// cloudEventContextAttrs are the attributes that are not extensions.
+var cloudEventContextAttrs = map[string]bool{
+	"specversion": true, "id": true, "source": true, "type": true, "subject": true,
+	"datacontenttype": true, "dataschema": true, "time": true, "data": true, "data_base64": true,
+}
+
+// isCloudEventsRequest reports whether a request to the generic events
+// endpoint carries CloudEvents, in either content mode.
+func isCloudEventsRequest(r *http.Request) bool {
+	if r.Header.Get("Ce-Specversion") != "" {
+		return true
+	}
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mt == cloudEventsContentType || mt == cloudEventsBatchContentType
+}
+
+// ServeCloudEvents handles POST /api/v1/cloudevents. It accepts structured
+// mode (application/cloudevents+json), batched structured mode
+// (application/cloudevents-batch+json) and binary mode (ce-* headers, body
+// is the data). ?project= and ?severity= override the mapped values.
+func (h *Handler) ServeCloudEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cred, ok := h.checkAuth(w, r)
+	if !ok {
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, 10<<20) // 10MB, same as batch
+	rawBody, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case mt == cloudEventsBatchContentType:
+		var items []json.RawMessage
+		if err := json.Unmarshal(rawBody, &items); err != nil {
+			http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.serveCloudEventsBatch(w, r, cred, items)
+		return
+	case mt == cloudEventsContentType:
+		ce, err := parseStructuredCloudEvent(rawBody)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.processEvent(w, h.cloudEventToRaw(r, cred, ce))
+	case r.Header.Get("Ce-Specversion") != "":
+		ce, err := parseBinaryCloudEvent(r.Header, rawBody)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.processEvent(w, h.cloudEventToRaw(r, cred, ce))
+	default:
+		http.Error(w, "unsupported content type: expected CloudEvents structured, batch or binary mode", http.StatusUnsupportedMediaType)
+	}
+}
+
+// serveCloudEventsBatch submits each event of a batch independently and
+// reports per-event results, like the NDJSON batch endpoint.
+func (h *Handler) serveCloudEventsBatch(w http.ResponseWriter, r *http.Request, cred string, items []json.RawMessage) {
+	results := make([]map[string]any, 0, len(items))
+	accepted := 0
+	var maxRetryAfter time.Duration
+	for i, item := range items {
+		ce, err := parseStructuredCloudEvent(item)
+		if err != nil {
+			results = append(results, map[string]any{
+				"index":  i,
+				"status": "error",
+				"error":  err.Error(),
+			})
+			continue
+		}
+		event := h.cloudEventToRaw(r, cred, ce)
+		h.fillDefaults(event)
+
+		result := map[string]any{
+			"index":    i,
+			"id":       ce.ID,
+			"event_id": event.ID,
+		}
+		results = append(results, result)
+		if errMsg := h.checkCloudEvent(event); errMsg != "" {
+			result["status"] = "error"
+			result["error"] = errMsg
+			continue
+		}
+		if event.Title == "" {
+			event.Title = ExtractTitle(event.Payload)
+		}
+		if !h.meetsMinSeverity(event.Severity) {
+			result["status"] = "filtered"
+			result["message"] = fmt.Sprintf("severity %s is below minimum %s", event.Severity, h.minSeverity)
+			continue
+		}
+
+		_, wait := h.submitItem(event, result)
+		if result["status"] == "queued" {
+			accepted++
+		}
+		maxRetryAfter = max(maxRetryAfter, wait)
+	}
+
+	if maxRetryAfter > 0 {
+		setRetryAfter(w, maxRetryAfter)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"total":    len(items),
+		"accepted": accepted,
+		"results":  results,
+	})
+}
+
+// checkCloudEvent applies the per-event validation of processEvent for batch
+// items, returning an error message or "".
+func (h *Handler) checkCloudEvent(event *RawEvent) string {
+	switch {
+	case event.ProjectKey == "":
+		return "no project: set the project extension or cloudevents.default_project"
+	case !ValidSeverities[event.Severity]:
+		return fmt.Sprintf("invalid severity: %s (must be critical, warning, or info)", event.Severity)
+	case h.maxPayloadSize > 0 && len(event.Payload) > h.maxPayloadSize:
+		return fmt.Sprintf("payload too large: %d bytes (max %d)", len(event.Payload), h.maxPayloadSize)
+	case h.validateProject != nil && !h.validateProject(event.ProjectKey):
+		return fmt.Sprintf("unknown project: %s", event.ProjectKey)
+	}
+	return ""
+}
+
+// cloudEventToRaw maps a CloudEvent onto a RawEvent. ce-source becomes the
+// event source (so mapping profiles and source quotas apply), data the
+// payload, and source+id a stable event ID and idempotency key, so a bus
+// redelivering the event gets the original result back.
+func (h *Handler) cloudEventToRaw(r *http.Request, cred string, ce *cloudEvent) *RawEvent {
+	q := r.URL.Query()
+	cfg := h.cloudEvents
+
+	projectKey := q.Get("project")
+	if projectKey == "" {
+		projectKey = ce.Extensions[cfg.ProjectExtension]
+	}
+	if projectKey == "" {
+		projectKey = cfg.DefaultProject
+	}
+
+	severity := strings.ToLower(q.Get("severity"))
+	if severity == "" {
+		severity = h.cloudEventSeverity(ce)
+	}
+
+	payload := cloudEventPayload(ce)
+	event := &RawEvent{
+		ID:             "evt-" + hashString(ce.Source+"\x00"+ce.ID),
+		ProjectKey:     projectKey,
+		Severity:       severity,
+		Payload:        payload,
+		Source:         ce.Source,
+		Credential:     cred,
+		IdempotencyKey: "ce:" + hashString(ce.Source+"\x00"+ce.ID),
+	}
+	// Fall back to the event type when neither the data nor a mapping
+	// profile provides a title.
+	if ExtractTitle(payload) == "" && h.profiles[ce.Source] == nil {
+		title := ce.Type
+		if ce.Subject != "" {
+			title += ": " + ce.Subject
+		}
+		event.Title = TruncateRunes(SanitizeDisplayText(title), 100)
+	}
+	return event
+}
+
+// cloudEventSeverity maps the severity extension, falling back to the
+// ce-type mapping. Unknown extension values are kept when a mapping profile
+// exists for the source, which may translate them.
+func (h *Handler) cloudEventSeverity(ce *cloudEvent) string {
+	cfg := h.cloudEvents
+	if v := strings.ToLower(strings.TrimSpace(ce.Extensions[cfg.SeverityExtension])); v != "" {
+		if mapped, ok := cfg.SeverityMap[v]; ok && ValidSeverities[mapped] {
+			return mapped
+		}
+		if ValidSeverities[v] || h.profiles[ce.Source] != nil {
+			return v
+		}
+	}
+	if mapped, ok := cfg.TypeSeverity[ce.Type]; ok && ValidSeverities[mapped] {
+		return mapped
+	}
+	return ""
+}
+
+// cloudEventPayload turns the event data into a payload. JSON objects are
+// used as-is; other JSON values and non-JSON data are wrapped as {"data": ...}.
+func cloudEventPayload(ce *cloudEvent) json.RawMessage {
+	data := bytes.TrimSpace(ce.Data)
+	if len(data) == 0 {
+		return json.RawMessage(`{}`)
+	}
+	var wrapped any
+	if isJSONContentType(ce.DataContentType) && json.Valid(data) {
+		if data[0] == '{' {
+			return json.RawMessage(data)
+		}
+		wrapped = json.RawMessage(data)
+	} else if utf8.Valid(ce.Data) {
+		wrapped = string(ce.Data)
+	} else {
+		wrapped = base64.StdEncoding.EncodeToString(ce.Data)
+	}
+	payload, _ := json.Marshal(map[string]any{"data": wrapped})
+	return payload
+}
+
+// isJSONContentType reports whether datacontenttype denotes JSON. An absent
+// datacontenttype means JSON in the JSON event format.
+func isJSONContentType(ct string) bool {
+	if ct == "" {
+		return true
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	return mt == "application/json" || mt == "text/json" || strings.HasSuffix(mt, "+json")
+}
+
+// parseStructuredCloudEvent decodes one event in the JSON event format.
+func parseStructuredCloudEvent(raw []byte) (*cloudEvent, error) {
+	var attrs map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &attrs); err != nil {
+		return nil, fmt.Errorf("invalid cloudevent: %v", err)
+	}
+	if attrs == nil {
+		return nil, errors.New("invalid cloudevent: expected a JSON object")
+	}
+
+	ce := &cloudEvent{Extensions: make(map[string]string)}
+	str := func(name string) string {
+		var s string
+		if v, ok := attrs[name]; ok {
+			_ = json.Unmarshal(v, &s)
+		}
+		return s
+	}
+	ce.SpecVersion = str("specversion")
+	ce.ID = str("id")
+	ce.Source = str("source")
+	ce.Type = str("type")
+	ce.Subject = str("subject")
+	ce.DataContentType = str("datacontenttype")
+
+	if b64, ok := attrs["data_base64"]; ok {
+		var s string
+		if err := json.Unmarshal(b64, &s); err != nil {
+			return nil, errors.New("invalid cloudevent: data_base64 must be a string")
+		}
+		data, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cloudevent: data_base64: %v", err)
+		}
+		ce.Data = data
+	} else if data, ok := attrs["data"]; ok {
+		ce.Data = data
+		// A JSON string holding non-JSON data is the data itself.
+		var s string
+		if !isJSONContentType(ce.DataContentType) && json.Unmarshal(data, &s) == nil {
+			ce.Data = []byte(s)
+		}
+	}
+
+	for name, v := range attrs {
+		if cloudEventContextAttrs[name] {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(v))
+		dec.UseNumber()
+		var val any
+		if dec.Decode(&val) == nil {
+			if s := scalarString(val); s != "" {
+				ce.Extensions[name] = s
+			}
+		}
+	}
+	return ce, ce.validate()
+}
+
+// parseBinaryCloudEvent decodes a binary-mode event: attributes come from
+// ce-* headers and the body is the data.
+func parseBinaryCloudEvent(header http.Header, body []byte) (*cloudEvent, error) {
+	ce := &cloudEvent{
+		DataContentType: header.Get("Content-Type"),
+		Data:            body,
+		Extensions:      make(map[string]string),
+	}
+	for key, values := range header {
+		name, ok := strings.CutPrefix(strings.ToLower(key), "ce-")
+		if !ok || len(values) == 0 {
+			continue
+		}
+		v := values[0]
+		switch name {
+		case "specversion":
+			ce.SpecVersion = v
+		case "id":
+			ce.ID = v
+		case "source":
+			ce.Source = v
+		case "type":
+			ce.Type = v
+		case "subject":
+			ce.Subject = v
+		default:
+			if !cloudEventContextAttrs[name] {
+				ce.Extensions[name] = v
+			}
+		}
+	}
+	if isJSONContentType(ce.DataContentType) && len(bytes.TrimSpace(body)) > 0 && !json.Valid(body) {
+		return nil, errors.New("invalid cloudevent: data is not valid JSON")
+	}
+	return ce, ce.validate()
+}
+
+// validate checks the required context attributes.
+func (ce *cloudEvent) validate() error {
+	if ce.SpecVersion != "1.0" {
+		return fmt.Errorf("invalid cloudevent: unsupported specversion %q (want 1.0)", ce.SpecVersion)
+	}
+	for _, attr := range []struct{ name, value string }{
+		{"id", ce.ID}, {"source", ce.Source}, {"type", ce.Type},
+	} {
+		if attr.value == "" {
+			return fmt.Errorf("invalid cloudevent: %s is required", attr.name)
+		}
+	}
+	return nil
+}
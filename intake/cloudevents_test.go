@@ -0,0 +1,150 @@
+package intake
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func newCloudEventsTestHandler(submitted *[]*RawEvent) *Handler {
+	return newCapturingTestHandler(HandlerConfig{
+		RateLimit:   100,
+		MinSeverity: "info",
+		CloudEvents: CloudEventsConfig{
+			DefaultProject: "proj-a",
+			SeverityMap:    map[string]string{"error": "warning", "Fatal": "critical"},
+			TypeSeverity:   map[string]string{"com.example.order.failed": "critical"},
+		},
+	}, submitted)
+}
+
+func TestCloudEvents_Structured(t *testing.T) {
+	var submitted []*RawEvent
+	h := newCloudEventsTestHandler(&submitted)
+	defer h.StopCleanup()
+
+	body := `{"specversion":"1.0","id":"a-1","source":"/order-service","type":"com.example.order.failed",` +
+		`"project":"proj-b","data":{"error":"payment timeout","order_id":42}}`
+	rec := postEvent(http.HandlerFunc(h.ServeCloudEvents), body, map[string]string{"Content-Type": cloudEventsContentType})
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(submitted) != 1 {
+		t.Fatalf("expected 1 submission, got %d", len(submitted))
+	}
+	e := submitted[0]
+	if e.Source != "/order-service" || e.ProjectKey != "proj-b" || e.Severity != "critical" {
+		t.Errorf("source=%q project=%q severity=%q", e.Source, e.ProjectKey, e.Severity)
+	}
+	if e.ID != "evt-"+hashString("/order-service\x00a-1") {
+		t.Errorf("ID = %q, want one derived from source and id", e.ID)
+	}
+	if string(e.Payload) != `{"error":"payment timeout","order_id":42}` {
+		t.Errorf("payload = %s", e.Payload)
+	}
+	if e.Title != "payment timeout" {
+		t.Errorf("title = %q", e.Title)
+	}
+}
+
+func TestCloudEvents_BinaryViaEventsEndpoint(t *testing.T) {
+	var submitted []*RawEvent
+	h := newCloudEventsTestHandler(&submitted)
+	defer h.StopCleanup()
+
+	rec := postEvent(h, "disk almost full", map[string]string{
+		"Content-Type":   "text/plain",
+		"Ce-Specversion": "1.0",
+		"Ce-Id":          "b-7",
+		"Ce-Source":      "//node-exporter",
+		"Ce-Type":        "com.example.disk",
+		"Ce-Subject":     "node-3",
+		"Ce-Severity":    "ERROR",
+	})
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	e := submitted[0]
+	if e.Source != "//node-exporter" || e.ProjectKey != "proj-a" || e.Severity != "warning" {
+		t.Errorf("source=%q project=%q severity=%q", e.Source, e.ProjectKey, e.Severity)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(e.Payload, &m); err != nil || m["data"] != "disk almost full" {
+		t.Errorf("payload = %s (%v)", e.Payload, err)
+	}
+	if e.Title != "com.example.disk: node-3" {
+		t.Errorf("title = %q", e.Title)
+	}
+}
+
+func TestCloudEvents_Invalid(t *testing.T) {
+	var submitted []*RawEvent
+	h := newCloudEventsTestHandler(&submitted)
+	defer h.StopCleanup()
+
+	cases := []struct {
+		name    string
+		body    string
+		headers map[string]string
+		want    int
+	}{
+		{"missing id", `{"specversion":"1.0","source":"/s","type":"t","data":{}}`,
+			map[string]string{"Content-Type": cloudEventsContentType}, http.StatusBadRequest},
+		{"bad specversion", `{"specversion":"0.3","id":"1","source":"/s","type":"t"}`,
+			map[string]string{"Content-Type": cloudEventsContentType}, http.StatusBadRequest},
+		{"binary bad json", `{oops`,
+			map[string]string{"Content-Type": "application/json", "Ce-Specversion": "1.0", "Ce-Id": "1", "Ce-Source": "/s", "Ce-Type": "t"},
+			http.StatusBadRequest},
+		{"not cloudevents", `{}`, map[string]string{"Content-Type": "application/json"}, http.StatusUnsupportedMediaType},
+	}
+	for _, tc := range cases {
+		rec := postEvent(http.HandlerFunc(h.ServeCloudEvents), tc.body, tc.headers)
+		if rec.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d: %s", tc.name, tc.want, rec.Code, rec.Body.String())
+		}
+	}
+	if len(submitted) != 0 {
+		t.Errorf("expected no submissions, got %d", len(submitted))
+	}
+}
+
+func TestCloudEvents_Batch(t *testing.T) {
+	var submitted []*RawEvent
+	h := newCloudEventsTestHandler(&submitted)
+	defer h.StopCleanup()
+
+	body := `[
+		{"specversion":"1.0","id":"1","source":"/s","type":"t","severity":"critical","data":{"error":"one"}},
+		{"specversion":"1.0","id":"2","source":"/s","type":"t","severity":"FATAL","datacontenttype":"text/plain","data_base64":"dHdv"},
+		{"specversion":"1.0","id":"3","source":"/s","type":"t","project":"nope","data":{"error":"three"}},
+		{"specversion":"1.0","source":"/s","type":"t"}
+	]`
+	rec := postEvent(h, body, map[string]string{"Content-Type": cloudEventsBatchContentType})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Accepted int              `json:"accepted"`
+		Results  []map[string]any `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+	if resp.Accepted != 2 || len(resp.Results) != 4 {
+		t.Fatalf("accepted=%d results=%v", resp.Accepted, resp.Results)
+	}
+	for i, want := range []string{"queued", "queued", "error", "error"} {
+		if resp.Results[i]["status"] != want {
+			t.Errorf("result %d: status %v, want %s", i, resp.Results[i]["status"], want)
+		}
+	}
+	if string(submitted[1].Payload) != `{"data":"two"}` {
+		t.Errorf("base64 payload = %s", submitted[1].Payload)
+	}
+	if submitted[0].Severity != "critical" {
+		t.Errorf("severity = %q", submitted[0].Severity)
+	}
+	if submitted[1].Severity != "critical" {
+		t.Errorf("severity_map keys should match case-insensitively, got %q", submitted[1].Severity)
+	}
+}
@@ -0,0 +1,179 @@
+package intake
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request signing headers. The signature is hex(HMAC-SHA256(secret,
+// timestamp + "." + body)), optionally prefixed with "sha256=".
+const (
+	HeaderCredential = "X-Sentinel-Credential"
+	HeaderTimestamp  = "X-Sentinel-Timestamp"
+	HeaderSignature  = "X-Sentinel-Signature"
+)
+
+// sharedCredentialName is recorded on events authenticated with the
+// global intake.auth_token.
+const sharedCredentialName = "default"
+
+// maxSignedBodySize bounds how much of a signed request is buffered for
+// verification (matches the batch/OTLP body limit).
+const maxSignedBodySize = 10 << 20
+
+// Credential is a named intake secret. Each reporter gets its own, so one
+// can be revoked without touching the others.
+type Credential struct {
+	Name string
+	// Secrets holds the active secrets; two are allowed during rotation.
+	Secrets []string
+	// Projects restricts which project keys may be submitted (empty = all).
+	Projects []string
+	// RequireSignature rejects plain Bearer auth for this credential.
+	RequireSignature bool
+}
+
+func (c *Credential) allowsProject(projectKey string) bool {
+	if len(c.Projects) == 0 {
+		return true
+	}
+	for _, p := range c.Projects {
+		if p == projectKey {
+			return true
+		}
+	}
+	return false
+}
+
+// credentialAllows reports whether the named credential may act on the
+// project. The shared token and unauthenticated requests are unrestricted.
+func (h *Handler) credentialAllows(name, projectKey string) bool {
+	cred, ok := h.credentials[name]
+	return !ok || cred.allowsProject(projectKey)
+}
+
+// authEnabled reports whether any form of intake auth is configured.
+func (h *Handler) authEnabled() bool {
+	return h.authToken != "" || len(h.credentials) > 0
+}
+
+// checkAuth authenticates the request and returns the credential name to
+// record on submitted events ("" when auth is disabled). Signed requests
+// have their body buffered and restored so handlers can read it as usual.
+func (h *Handler) checkAuth(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if !h.authEnabled() {
+		return "", true
+	}
+
+	if r.Header.Get(HeaderSignature) != "" {
+		name, err := h.verifySignature(w, r)
+		if err != "" {
+			http.Error(w, "unauthorized: "+err, http.StatusUnauthorized)
+			return "", false
+		}
+		return name, true
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if ok && token != "" {
+		if h.authToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.authToken)) == 1 {
+			return sharedCredentialName, true
+		}
+		for _, cred := range h.credentials {
+			if cred.RequireSignature {
+				continue
+			}
+			if matchSecret(cred.Secrets, token) {
+				return cred.Name, true
+			}
+		}
+	}
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+	return "", false
+}
+
+// verifySignature validates the HMAC headers. It returns the credential name,
+// or a short reason when verification fails.
+func (h *Handler) verifySignature(w http.ResponseWriter, r *http.Request) (string, string) {
+	cred, ok := h.credentials[r.Header.Get(HeaderCredential)]
+	if !ok {
+		return "", "unknown credential"
+	}
+
+	ts := r.Header.Get(HeaderTimestamp)
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return "", "invalid timestamp"
+	}
+	skew := time.Since(time.Unix(sec, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > h.signatureWindow {
+		return "", "timestamp outside replay window"
+	}
+
+	sig := strings.TrimPrefix(r.Header.Get(HeaderSignature), "sha256=")
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return "", "malformed signature"
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSignedBodySize))
+	if err != nil {
+		return "", "request body too large"
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	valid := false
+	for _, secret := range cred.Secrets {
+		if hmac.Equal(got, signBody(secret, ts, body)) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return "", "signature mismatch"
+	}
+
+	// Replay protection: a signature may be used once within the window.
+	// Key on the decoded MAC so re-encodings of the header (hex case, with
+	// or without the sha256= prefix) count as the same signature.
+	if _, seen := h.seenSignatures.LoadOrStore(cred.Name+":"+hex.EncodeToString(got), time.Now()); seen {
+		return "", "replayed request"
+	}
+	return cred.Name, ""
+}
+
+// SignRequest computes the signature header value for body at timestamp ts
+// (unix seconds). Exposed for reporters and tests written in Go.
+func SignRequest(secret string, ts int64, body []byte) string {
+	return "sha256=" + hex.EncodeToString(signBody(secret, strconv.FormatInt(ts, 10), body))
+}
+
+func signBody(secret, ts string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+func matchSecret(secrets []string, token string) bool {
+	matched := false
+	for _, s := range secrets {
+		// Compare against every secret to keep timing independent of position.
+		if s != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s)) == 1 {
+			matched = true
+		}
+	}
+	return matched
+}
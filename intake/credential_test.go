@@ -0,0 +1,152 @@
+package intake
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newCredentialTestHandler(submitted *[]*RawEvent) *Handler {
+	return newCapturingTestHandler(HandlerConfig{
+		RateLimit: 100,
+		AuthToken: "shared-token",
+		Credentials: []Credential{
+			{Name: "orders", Secrets: []string{"old-secret", "new-secret"}, Projects: []string{"proj-a"}},
+			{Name: "signed", Secrets: []string{"hmac-secret"}, RequireSignature: true},
+		},
+	}, submitted)
+}
+
+func TestCheckAuth_NamedCredentialBearer(t *testing.T) {
+	var submitted []*RawEvent
+	h := newCredentialTestHandler(&submitted)
+	defer h.StopCleanup()
+
+	// Both rotation secrets are accepted and recorded under the same name.
+	for i, secret := range []string{"old-secret", "new-secret"} {
+		body := `{"project_key":"proj-a","payload":{"error":"e` + strconv.Itoa(i) + `"}}`
+		rec := postEvent(h, body, map[string]string{"Authorization": "Bearer " + secret})
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("secret %s: expected 202, got %d: %s", secret, rec.Code, rec.Body.String())
+		}
+	}
+	if len(submitted) != 2 || submitted[0].Credential != "orders" || submitted[1].Credential != "orders" {
+		t.Fatalf("expected two events from credential orders, got %+v", submitted)
+	}
+
+	rec := postEvent(h, `{"project_key":"proj-a","payload":{"error":"shared"}}`,
+		map[string]string{"Authorization": "Bearer shared-token"})
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("shared token: expected 202, got %d", rec.Code)
+	}
+	if got := submitted[len(submitted)-1].Credential; got != sharedCredentialName {
+		t.Errorf("shared token credential = %q, want %q", got, sharedCredentialName)
+	}
+}
+
+func TestCheckAuth_ProjectScope(t *testing.T) {
+	var submitted []*RawEvent
+	h := newCredentialTestHandler(&submitted)
+	defer h.StopCleanup()
+
+	rec := postEvent(h, `{"project_key":"proj-b","payload":{"error":"x"}}`,
+		map[string]string{"Authorization": "Bearer new-secret"})
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(submitted) != 0 {
+		t.Fatalf("expected no submission, got %d", len(submitted))
+	}
+}
+
+func TestCheckAuth_Signature(t *testing.T) {
+	var submitted []*RawEvent
+	h := newCredentialTestHandler(&submitted)
+	defer h.StopCleanup()
+
+	body := `{"project_key":"proj-b","payload":{"error":"signed"}}`
+	ts := time.Now().Unix()
+	headers := map[string]string{
+		HeaderCredential: "signed",
+		HeaderTimestamp:  strconv.FormatInt(ts, 10),
+		HeaderSignature:  SignRequest("hmac-secret", ts, []byte(body)),
+	}
+
+	rec := postEvent(h, body, headers)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(submitted) != 1 || submitted[0].Credential != "signed" {
+		t.Fatalf("expected one event from credential signed, got %+v", submitted)
+	}
+
+	// Replaying the exact request is rejected.
+	if rec := postEvent(h, body, headers); rec.Code != http.StatusUnauthorized {
+		t.Errorf("replay: expected 401, got %d", rec.Code)
+	}
+
+	// Re-encoding the same MAC does not get around replay protection.
+	sig := headers[HeaderSignature]
+	for _, variant := range []string{strings.ToUpper(strings.TrimPrefix(sig, "sha256=")), strings.TrimPrefix(sig, "sha256="), "sha256=" + strings.ToUpper(strings.TrimPrefix(sig, "sha256="))} {
+		replay := map[string]string{HeaderCredential: "signed", HeaderTimestamp: headers[HeaderTimestamp], HeaderSignature: variant}
+		if rec := postEvent(h, body, replay); rec.Code != http.StatusUnauthorized {
+			t.Errorf("replay as %q: expected 401, got %d", variant, rec.Code)
+		}
+	}
+	if len(submitted) != 1 {
+		t.Errorf("replays were submitted: %d events", len(submitted))
+	}
+
+	// Bearer auth is not allowed for a signature-only credential.
+	if rec := postEvent(h, body, map[string]string{"Authorization": "Bearer hmac-secret"}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("bearer on signed credential: expected 401, got %d", rec.Code)
+	}
+}
+
+func TestCheckAuth_SignatureRejected(t *testing.T) {
+	var submitted []*RawEvent
+	h := newCredentialTestHandler(&submitted)
+	defer h.StopCleanup()
+
+	body := `{"project_key":"proj-a","payload":{"error":"x"}}`
+	stale := time.Now().Add(-time.Hour).Unix()
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+	}{
+		{"stale timestamp", map[string]string{
+			HeaderCredential: "signed",
+			HeaderTimestamp:  strconv.FormatInt(stale, 10),
+			HeaderSignature:  SignRequest("hmac-secret", stale, []byte(body)),
+		}},
+		{"wrong secret", map[string]string{
+			HeaderCredential: "signed",
+			HeaderTimestamp:  strconv.FormatInt(now, 10),
+			HeaderSignature:  SignRequest("other", now, []byte(body)),
+		}},
+		{"tampered body", map[string]string{
+			HeaderCredential: "signed",
+			HeaderTimestamp:  strconv.FormatInt(now, 10),
+			HeaderSignature:  SignRequest("hmac-secret", now, []byte(body+" ")),
+		}},
+		{"unknown credential", map[string]string{
+			HeaderCredential: "nobody",
+			HeaderTimestamp:  strconv.FormatInt(now, 10),
+			HeaderSignature:  SignRequest("hmac-secret", now, []byte(body)),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := postEvent(h, body, tt.headers); rec.Code != http.StatusUnauthorized {
+				t.Errorf("expected 401, got %d: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+	if len(submitted) != 0 {
+		t.Fatalf("expected no submissions, got %d", len(submitted))
+	}
+}
@@ -0,0 +1,93 @@
+package intake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDedupBackend is an in-memory DedupBackend standing in for the store.
+type fakeDedupBackend struct {
+	mu   sync.Mutex
+	keys map[string]time.Time
+	err  error
+}
+
+func (b *fakeDedupBackend) ClaimDedupKey(_ context.Context, key string, window time.Duration, now time.Time) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return false, b.err
+	}
+	if at, ok := b.keys[key]; ok && now.Sub(at) < window {
+		return false, nil
+	}
+	b.keys[key] = now
+	return true, nil
+}
+
+func (b *fakeDedupBackend) PurgeDedupKeys(_ context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestStoreDeduper_SharedAcrossHandlers(t *testing.T) {
+	backend := &fakeDedupBackend{keys: make(map[string]time.Time)}
+
+	// Two handlers sharing one backend behave like two replicas (or one
+	// process before and after a restart).
+	h1 := newTestHandler(HandlerConfig{RateLimit: 100, DedupWindow: 10 * time.Minute, Deduper: NewStoreDeduper(backend)})
+	defer h1.StopCleanup()
+	h2 := newTestHandler(HandlerConfig{RateLimit: 100, DedupWindow: 10 * time.Minute, Deduper: NewStoreDeduper(backend)})
+	defer h2.StopCleanup()
+
+	body := `{"project_key":"proj-a","payload":{"error":"shared-dup"},"severity":"warning"}`
+
+	rec1 := httptest.NewRecorder()
+	h1.ServeHTTP(rec1, httptest.NewRequest(http.MethodPost, "/api/v1/events", strings.NewReader(body)))
+	if rec1.Code != http.StatusAccepted {
+		t.Fatalf("first request: expected 202, got %d: %s", rec1.Code, rec1.Body.String())
+	}
+
+	rec2 := httptest.NewRecorder()
+	h2.ServeHTTP(rec2, httptest.NewRequest(http.MethodPost, "/api/v1/events", strings.NewReader(body)))
+	var resp map[string]any
+	if err := json.Unmarshal(rec2.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+	if resp["status"] != "deduplicated" {
+		t.Errorf("expected status=deduplicated on second handler, got %v", resp["status"])
+	}
+}
+
+func TestStoreDeduper_BackendErrorFailsOpen(t *testing.T) {
+	backend := &fakeDedupBackend{keys: make(map[string]time.Time), err: errors.New("db down")}
+	h := newTestHandler(HandlerConfig{RateLimit: 100, DedupWindow: 10 * time.Minute, Deduper: NewStoreDeduper(backend)})
+	defer h.StopCleanup()
+
+	if !h.checkDedupWithWindow("k", time.Minute) {
+		t.Error("expected event to be allowed when the dedup backend fails")
+	}
+}
+
+func TestHandler_OnDuplicate(t *testing.T) {
+	var submitted, duplicates []*RawEvent
+	h := newCapturingTestHandler(HandlerConfig{RateLimit: 100, OnDuplicate: func(e *RawEvent) { duplicates = append(duplicates, e) }}, &submitted)
+	defer h.StopCleanup()
+
+	body := `{"project_key":"proj-a","payload":{"error":"dup"}}`
+	postEvent(h, body, nil)
+	postEvent(h, body, nil)
+
+	if len(submitted) != 1 || len(duplicates) != 1 {
+		t.Fatalf("expected 1 submitted and 1 duplicate, got %d and %d", len(submitted), len(duplicates))
+	}
+	if submitted[0].GroupKey == "" || duplicates[0].GroupKey != submitted[0].GroupKey {
+		t.Errorf("group keys differ: %q vs %q", submitted[0].GroupKey, duplicates[0].GroupKey)
+	}
+}
@@ -3,13 +3,13 @@ package intake
 import (
 	"bufio"
 	"crypto/sha256"
-	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"hash/fnv"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -25,52 +25,46 @@ type ProjectValidator func(key string) bool
 
 // Handler handles incoming event reports via HTTP.
 type Handler struct {
-	authToken       string
-	dedupWindow     time.Duration
-	dedupFields     []string
-	rateLimit       int
-	minSeverity     string
-	maxPayloadSize  int
-	log             logger.Logger
-	onEvent         func(*RawEvent) (string, error)
-	validateProject ProjectValidator
-	dedupConfig     func(projectKey string) *DedupConfig
-
-	// dedup: fingerprint -> last reported time
-	dedup     sync.Map
-	dedupSize atomic.Int64 // approximate count for OOM protection
-	// rate limit: sharded by project key to avoid global mutex
-	rateShards  [rateLimitShards]rateShard
+	authToken         string
+	dedupWindow       time.Duration
+	dedupFields       []string
+	minSeverity       string
+	maxPayloadSize    int
+	log               logger.Logger
+	onEvent           func(*RawEvent) (string, error)
+	validateProject   ProjectValidator
+	dedupConfig       func(projectKey string) *DedupConfig
+	alertmanager      AlertmanagerConfig
+	resolveEvent      ResolveFunc
+	otlp              OTLPConfig
+	cloudEvents       CloudEventsConfig
+	credentials       map[string]*Credential
+	signatureWindow   time.Duration
+	deduper           Deduper
+	loadSilences      SilenceLoader
+	onSuppressed      func(*RawEvent)
+	onDuplicate       func(*RawEvent)
+	redactor          *Redactor
+	idempotency       IdempotencyStore
+	idempotencyWindow time.Duration
+	profiles          map[string]*MappingProfile
+
+	// silences: compiled rules, swapped wholesale on reload
+	silences atomic.Pointer[[]*compiledSilence]
+	// seenSignatures: credential:signature -> first seen, for replay protection
+	seenSignatures sync.Map
+	// limiter: token buckets for global/project/credential/source quotas
+	limiter     *rateLimiter
 	stopCleanup chan struct{}
 	stopOnce    sync.Once
 }
 
-const rateLimitShards = 64
-
-type rateShard struct {
-	mu    sync.Mutex
-	count map[string]*rateEntry
-}
-
-// maxDedupEntries is a safety cap to prevent OOM under high-cardinality traffic.
-const maxDedupEntries = 500_000
-
 // DedupConfig holds per-project deduplication settings.
 type DedupConfig struct {
 	Fields []string
 	Window time.Duration
 }
 
-type rateEntry struct {
-	count    int
-	windowAt time.Time
-}
-
-type dedupEntry struct {
-	at     time.Time
-	window time.Duration
-}
-
 // HandlerConfig configures the intake handler.
 type HandlerConfig struct {
 	AuthToken      string
@@ -79,6 +73,46 @@ type HandlerConfig struct {
 	RateLimit      int
 	MinSeverity    string
 	MaxPayloadSize int
+
+	// RateLimits configures the global, per-project, per-credential and
+	// per-source token buckets. RateLimit (events per hour) is used for the
+	// project bucket when RateLimits.Project is unset.
+	RateLimits RateLimitConfig
+
+	// Alertmanager configures the /api/v1/alertmanager receiver.
+	Alertmanager AlertmanagerConfig
+	// ResolveEvent is called for resolved Alertmanager alerts (may be nil).
+	ResolveEvent ResolveFunc
+	// OTLP configures the /v1/logs receiver.
+	OTLP OTLPConfig
+	// CloudEvents configures the /api/v1/cloudevents receiver.
+	CloudEvents CloudEventsConfig
+	// Credentials are named per-reporter secrets, accepted alongside AuthToken.
+	Credentials []Credential
+	// SignatureWindow is the allowed clock skew for signed requests; a
+	// signature cannot be reused within it (default 5m).
+	SignatureWindow time.Duration
+	// Deduper holds dedup state (default: in-memory).
+	Deduper Deduper
+	// LoadSilences returns the active silence rules (may be nil). Rules are
+	// loaded at startup, every minute, and on ReloadSilences.
+	LoadSilences SilenceLoader
+	// OnSuppressed is called for events muted by a silence rule so they can
+	// be recorded for auditing (may be nil).
+	OnSuppressed func(*RawEvent)
+	// OnDuplicate is called for events dropped by dedup so they can still be
+	// counted against their incident (may be nil).
+	OnDuplicate func(*RawEvent)
+	// Redactor masks PII and secrets in the payload and title before any
+	// other stage sees the event (nil disables redaction).
+	Redactor *Redactor
+	// Profiles map per-source payload shapes and severities onto Sentinel's
+	// fields (see MappingProfile).
+	Profiles []MappingProfile
+	// Idempotency persists Idempotency-Key mappings (nil disables keys).
+	Idempotency IdempotencyStore
+	// IdempotencyWindow is how long a key is remembered (default 24h).
+	IdempotencyWindow time.Duration
 }
 
 // NewHandler creates an intake handler.
@@ -96,6 +130,9 @@ func NewHandler(
 	if cfg.RateLimit == 0 {
 		cfg.RateLimit = 10
 	}
+	if cfg.RateLimits.Project.PerHour == 0 {
+		cfg.RateLimits.Project = RateLimit{PerHour: float64(cfg.RateLimit), Burst: cfg.RateLimit}
+	}
 	if cfg.MinSeverity == "" {
 		cfg.MinSeverity = "warning"
 	}
@@ -105,26 +142,87 @@ func NewHandler(
 	if len(cfg.DedupFields) == 0 {
 		cfg.DedupFields = []string{"error_msg", "error", "message", "msg"}
 	}
+	if cfg.Alertmanager.ProjectLabel == "" {
+		cfg.Alertmanager.ProjectLabel = "project"
+	}
+	if cfg.Alertmanager.SeverityLabel == "" {
+		cfg.Alertmanager.SeverityLabel = "severity"
+	}
+	cfg.Alertmanager.SeverityMap = lowerKeys(cfg.Alertmanager.SeverityMap)
+	if cfg.OTLP.MinSeverityNumber == 0 {
+		cfg.OTLP.MinSeverityNumber = otlpSeverityError
+	}
+	if cfg.CloudEvents.ProjectExtension == "" {
+		cfg.CloudEvents.ProjectExtension = "project"
+	}
+	if cfg.CloudEvents.SeverityExtension == "" {
+		cfg.CloudEvents.SeverityExtension = "severity"
+	}
+	cfg.CloudEvents.SeverityMap = lowerKeys(cfg.CloudEvents.SeverityMap)
+	if cfg.SignatureWindow == 0 {
+		cfg.SignatureWindow = 5 * time.Minute
+	}
+	if cfg.Deduper == nil {
+		cfg.Deduper = NewMemoryDeduper()
+	}
+	if cfg.IdempotencyWindow == 0 {
+		cfg.IdempotencyWindow = 24 * time.Hour
+	}
+	creds := make(map[string]*Credential, len(cfg.Credentials))
+	for i := range cfg.Credentials {
+		c := cfg.Credentials[i]
+		creds[c.Name] = &c
+	}
 	h := &Handler{
-		authToken:       cfg.AuthToken,
-		dedupWindow:     cfg.DedupWindow,
-		dedupFields:     cfg.DedupFields,
-		rateLimit:       cfg.RateLimit,
-		minSeverity:     cfg.MinSeverity,
-		maxPayloadSize:  cfg.MaxPayloadSize,
-		log:             log,
-		onEvent:         onEvent,
-		validateProject: validateProject,
-		dedupConfig:     dedupConfig,
-		stopCleanup:     make(chan struct{}),
-	}
-	for i := range h.rateShards {
-		h.rateShards[i].count = make(map[string]*rateEntry)
+		authToken:         cfg.AuthToken,
+		dedupWindow:       cfg.DedupWindow,
+		dedupFields:       cfg.DedupFields,
+		limiter:           newRateLimiter(cfg.RateLimits),
+		minSeverity:       cfg.MinSeverity,
+		maxPayloadSize:    cfg.MaxPayloadSize,
+		log:               log,
+		onEvent:           onEvent,
+		validateProject:   validateProject,
+		dedupConfig:       dedupConfig,
+		alertmanager:      cfg.Alertmanager,
+		resolveEvent:      cfg.ResolveEvent,
+		otlp:              cfg.OTLP,
+		cloudEvents:       cfg.CloudEvents,
+		credentials:       creds,
+		signatureWindow:   cfg.SignatureWindow,
+		deduper:           cfg.Deduper,
+		loadSilences:      cfg.LoadSilences,
+		onSuppressed:      cfg.OnSuppressed,
+		onDuplicate:       cfg.OnDuplicate,
+		redactor:          cfg.Redactor,
+		profiles:          compileProfiles(cfg.Profiles),
+		idempotency:       cfg.Idempotency,
+		idempotencyWindow: cfg.IdempotencyWindow,
+		stopCleanup:       make(chan struct{}),
+	}
+	if err := h.ReloadSilences(); err != nil {
+		log.Error("silence.load_failed", logger.Err(err))
 	}
 	go h.cleanupLoop()
 	return h
 }
 
+// redactEvent masks the event payload and title in place.
+func (h *Handler) redactEvent(event *RawEvent) {
+	payload, n := h.redactor.RedactPayload(event.Payload)
+	title, m := h.redactor.redactString(event.Title)
+	if n+m == 0 {
+		return
+	}
+	event.Payload = payload
+	event.Title = title
+	h.log.Info("event.redacted",
+		logger.String("project", event.ProjectKey),
+		logger.String("event_id", event.ID),
+		logger.Int("masked", n+m),
+	)
+}
+
 // StopCleanup stops the background cleanup goroutine. Safe to call multiple times.
 func (h *Handler) StopCleanup() {
 	h.stopOnce.Do(func() { close(h.stopCleanup) })
@@ -139,40 +237,38 @@ func (h *Handler) cleanupLoop() {
 			return
 		case <-ticker.C:
 			now := time.Now()
-			var deleted int64
-			h.dedup.Range(func(key, value any) bool {
-				entry := value.(dedupEntry)
-				if now.Sub(entry.at) >= entry.window {
-					h.dedup.Delete(key)
-					deleted++
+			if err := h.ReloadSilences(); err != nil {
+				h.log.Warn("silence.load_failed", logger.Err(err))
+			}
+			h.deduper.Cleanup(now)
+			h.seenSignatures.Range(func(key, value any) bool {
+				if now.Sub(value.(time.Time)) > 2*h.signatureWindow {
+					h.seenSignatures.Delete(key)
 				}
 				return true
 			})
-			if deleted > 0 {
-				h.dedupSize.Add(-deleted)
-			}
-			for i := range h.rateShards {
-				shard := &h.rateShards[i]
-				shard.mu.Lock()
-				for k, entry := range shard.count {
-					if now.Sub(entry.windowAt) >= time.Hour {
-						delete(shard.count, k)
-					}
-				}
-				shard.mu.Unlock()
+			h.limiter.cleanup(now)
+			if h.idempotency != nil {
+				h.idempotency.Cleanup(now)
 			}
 		}
 	}
 }
 
 // ServeHTTP handles POST /api/v1/events (standard and simple modes).
+// CloudEvents posted here are handed to ServeCloudEvents.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if isCloudEventsRequest(r) {
+		h.ServeCloudEvents(w, r)
+		return
+	}
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	if !h.checkAuth(w, r) {
+	cred, ok := h.checkAuth(w, r)
+	if !ok {
 		return
 	}
 
@@ -188,6 +284,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	severityFromQuery := q.Get("severity")
 
 	var event *RawEvent
+	var envelopeKey string
 
 	if projectFromQuery != "" {
 		// Simple mode: query params provide envelope, body = payload
@@ -195,7 +292,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			ProjectKey: projectFromQuery,
 			Severity:   severityFromQuery,
 			Payload:    json.RawMessage(rawBody),
-			Source:     "custom",
+			Source:     q.Get("source"),
 		}
 	} else {
 		// Standard mode: body contains {project_key, payload, ...}
@@ -205,11 +302,14 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			Source     string          `json:"source"`
 			Severity   string          `json:"severity"`
 			Title      string          `json:"title"`
+			// IdempotencyKey is an alternative to the Idempotency-Key header
+			IdempotencyKey string `json:"idempotency_key"`
 		}
 		if err := json.Unmarshal(rawBody, &envelope); err != nil {
 			http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
 			return
 		}
+		envelopeKey = envelope.IdempotencyKey
 		event = &RawEvent{
 			ProjectKey: envelope.ProjectKey,
 			Payload:    envelope.Payload,
@@ -223,6 +323,8 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	event.Credential = cred
+	event.IdempotencyKey = idempotencyKey(r, envelopeKey)
 	h.processEvent(w, event)
 }
 
@@ -233,7 +335,8 @@ func (h *Handler) ServeBatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !h.checkAuth(w, r) {
+	cred, ok := h.checkAuth(w, r)
+	if !ok {
 		return
 	}
 
@@ -244,6 +347,16 @@ func (h *Handler) ServeBatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	severity := q.Get("severity")
+	source := q.Get("source")
+	if source == "" {
+		source = "batch"
+	}
+	// A batch key covers every line: line N is submitted as "<key>:N"
+	batchKey := idempotencyKey(r, "")
+	if !validIdempotencyKey(batchKey) {
+		http.Error(w, fmt.Sprintf("idempotency key too long (max %d bytes)", maxIdempotencyKeyLen), http.StatusBadRequest)
+		return
+	}
 
 	body := http.MaxBytesReader(w, r.Body, 10<<20) // 10MB for batch
 	scanner := bufio.NewScanner(body)
@@ -251,6 +364,7 @@ func (h *Handler) ServeBatch(w http.ResponseWriter, r *http.Request) {
 
 	var results []map[string]any
 	lineNum := 0
+	var maxRetryAfter time.Duration
 	accepted := 0
 	for scanner.Scan() {
 		lineNum++
@@ -281,7 +395,11 @@ func (h *Handler) ServeBatch(w http.ResponseWriter, r *http.Request) {
 			ProjectKey: projectKey,
 			Severity:   severity,
 			Payload:    json.RawMessage(append([]byte(nil), line...)),
-			Source:     "batch",
+			Source:     source,
+			Credential: cred,
+		}
+		if batchKey != "" {
+			event.IdempotencyKey = batchKey + ":" + strconv.Itoa(lineNum)
 		}
 		h.fillDefaults(event)
 
@@ -319,24 +437,12 @@ func (h *Handler) ServeBatch(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
-		taskID, submitErr := h.submitEvent(event)
-		if submitErr != nil {
-			results = append(results, map[string]any{
-				"line":     lineNum,
-				"event_id": event.ID,
-				"status":   "error",
-				"error":    submitErr.Error(),
-			})
-			continue
+		result, wait := h.submitItem(event, map[string]any{"line": lineNum})
+		if result["status"] == "queued" {
+			accepted++
 		}
-
-		accepted++
-		results = append(results, map[string]any{
-			"line":     lineNum,
-			"event_id": event.ID,
-			"task_id":  taskID,
-			"status":   "queued",
-		})
+		maxRetryAfter = max(maxRetryAfter, wait)
+		results = append(results, result)
 	}
 
 	if scanErr := scanner.Err(); scanErr != nil {
@@ -347,6 +453,9 @@ func (h *Handler) ServeBatch(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
+	if maxRetryAfter > 0 {
+		setRetryAfter(w, maxRetryAfter)
+	}
 	writeJSON(w, http.StatusOK, map[string]any{
 		"total":    lineNum,
 		"accepted": accepted,
@@ -362,7 +471,8 @@ func (h *Handler) ServeIncidentCompat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !h.checkAuth(w, r) {
+	cred, ok := h.checkAuth(w, r)
+	if !ok {
 		return
 	}
 
@@ -374,9 +484,10 @@ func (h *Handler) ServeIncidentCompat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var envelope struct {
-		ProjectKey string `json:"project_key"`
-		Severity   string `json:"severity"`
-		Title      string `json:"title"`
+		ProjectKey     string `json:"project_key"`
+		Severity       string `json:"severity"`
+		Title          string `json:"title"`
+		IdempotencyKey string `json:"idempotency_key"`
 	}
 	if err := json.Unmarshal(rawBody, &envelope); err != nil {
 		http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
@@ -389,6 +500,9 @@ func (h *Handler) ServeIncidentCompat(w http.ResponseWriter, r *http.Request) {
 		Title:      envelope.Title,
 		Payload:    json.RawMessage(rawBody),
 		Source:     "legacy",
+		Credential: cred,
+
+		IdempotencyKey: idempotencyKey(r, envelope.IdempotencyKey),
 	}
 
 	h.processLegacyEvent(w, event)
@@ -403,6 +517,10 @@ func (h *Handler) processLegacyEvent(w http.ResponseWriter, event *RawEvent) {
 		http.Error(w, "project_key is required", http.StatusBadRequest)
 		return
 	}
+	if !validIdempotencyKey(event.IdempotencyKey) {
+		http.Error(w, fmt.Sprintf("idempotency key too long (max %d bytes)", maxIdempotencyKeyLen), http.StatusBadRequest)
+		return
+	}
 	if !ValidSeverities[event.Severity] {
 		http.Error(w, fmt.Sprintf("invalid severity: %s (must be critical, warning, or info)", event.Severity), http.StatusBadRequest)
 		return
@@ -430,17 +548,28 @@ func (h *Handler) processLegacyEvent(w http.ResponseWriter, event *RawEvent) {
 
 	taskID, submitErr := h.submitEvent(event)
 	if submitErr != nil {
-		if strings.Contains(submitErr.Error(), "deduplicated") {
+		if replay, ok := asReplay(submitErr); ok {
+			writeReplay(w, "incident_id", event.ID, replay)
+			return
+		}
+		if skipped, ok := skippedStatus(submitErr); ok {
 			writeJSON(w, http.StatusOK, map[string]any{
 				"incident_id": event.ID,
-				"status":      "deduplicated",
+				"status":      skipped,
 				"message":     submitErr.Error(),
 			})
 			return
 		}
 		status := http.StatusServiceUnavailable
-		if strings.Contains(submitErr.Error(), "rate_limited") {
+		if d, ok := retryAfter(submitErr); ok {
 			status = http.StatusTooManyRequests
+			setRetryAfter(w, d)
+		}
+		if errors.Is(submitErr, ErrForbidden) {
+			status = http.StatusForbidden
+		}
+		if errors.Is(submitErr, errIdempotencyInFlight) {
+			status = http.StatusConflict
 		}
 		writeJSON(w, status, map[string]any{
 			"incident_id": event.ID,
@@ -467,6 +596,10 @@ func (h *Handler) processEvent(w http.ResponseWriter, event *RawEvent) {
 		http.Error(w, "project_key is required", http.StatusBadRequest)
 		return
 	}
+	if !validIdempotencyKey(event.IdempotencyKey) {
+		http.Error(w, fmt.Sprintf("idempotency key too long (max %d bytes)", maxIdempotencyKeyLen), http.StatusBadRequest)
+		return
+	}
 
 	// Validate severity
 	if !ValidSeverities[event.Severity] {
@@ -508,17 +641,27 @@ func (h *Handler) processEvent(w http.ResponseWriter, event *RawEvent) {
 	taskID, submitErr := h.submitEvent(event)
 	if submitErr != nil {
 		status := http.StatusServiceUnavailable
-		if strings.Contains(submitErr.Error(), "deduplicated") {
-			status = http.StatusOK
-			writeJSON(w, status, map[string]any{
+		if replay, ok := asReplay(submitErr); ok {
+			writeReplay(w, "event_id", event.ID, replay)
+			return
+		}
+		if skipped, ok := skippedStatus(submitErr); ok {
+			writeJSON(w, http.StatusOK, map[string]any{
 				"event_id": event.ID,
-				"status":   "deduplicated",
+				"status":   skipped,
 				"message":  submitErr.Error(),
 			})
 			return
 		}
-		if strings.Contains(submitErr.Error(), "rate_limited") {
+		if d, ok := retryAfter(submitErr); ok {
 			status = http.StatusTooManyRequests
+			setRetryAfter(w, d)
+		}
+		if errors.Is(submitErr, ErrForbidden) {
+			status = http.StatusForbidden
+		}
+		if errors.Is(submitErr, errIdempotencyInFlight) {
+			status = http.StatusConflict
 		}
 		writeJSON(w, status, map[string]any{
 			"event_id": event.ID,
@@ -536,13 +679,45 @@ func (h *Handler) processEvent(w http.ResponseWriter, event *RawEvent) {
 	})
 }
 
-func (h *Handler) submitEvent(event *RawEvent) (string, error) {
+func (h *Handler) submitEvent(event *RawEvent) (taskID string, err error) {
+	// Credential scope check
+	if !h.credentialAllows(event.Credential, event.ProjectKey) {
+		h.log.Warn("event.forbidden",
+			logger.String("project", event.ProjectKey),
+			logger.String("credential", event.Credential),
+			logger.String("event_id", event.ID),
+		)
+		return "", fmt.Errorf("%w: credential %s may not submit events for project %s", ErrForbidden, event.Credential, event.ProjectKey)
+	}
+
+	// Idempotency runs before every other stage so a retried request gets
+	// the original outcome rather than a dedup hit or a second event
+	claimed, err := h.claimIdempotency(event)
+	if err != nil {
+		return "", err
+	}
+	if claimed {
+		defer func() { h.finishIdempotency(event, taskID, err) }()
+	}
+
+	// Redact once, up front, so silence matching, dedup, storage, the prompt
+	// and notifications all see the same masked payload
+	if h.redactor != nil {
+		h.redactEvent(event)
+	}
+
+	// Silence rules run before dedup so muted events don't consume the window
+	if h.applySilence(event) {
+		return "", fmt.Errorf("%w: event muted by silence rule %s", ErrSuppressed, event.SilenceRuleID)
+	}
+
 	// Dedup check
 	var projDedup *DedupConfig
 	if h.dedupConfig != nil {
 		projDedup = h.dedupConfig(event.ProjectKey)
 	}
-	dedupKey := ComputeFingerprint(event.ProjectKey, event.Payload, projDedup, h.dedupFields)
+	dedupKey := h.dedupKey(event, projDedup)
+	event.GroupKey = dedupKey
 	dedupWindow := h.dedupWindow
 	if projDedup != nil && projDedup.Window > 0 {
 		dedupWindow = projDedup.Window
@@ -552,16 +727,20 @@ func (h *Handler) submitEvent(event *RawEvent) (string, error) {
 			logger.String("project", event.ProjectKey),
 			logger.String("event_id", event.ID),
 		)
-		return "", fmt.Errorf("deduplicated: duplicate event within dedup window")
+		if h.onDuplicate != nil {
+			h.onDuplicate(event)
+		}
+		return "", fmt.Errorf("%w: duplicate event within dedup window", ErrDeduplicated)
 	}
 
 	// Rate limit check
-	if !h.checkRateLimit(event.ProjectKey) {
+	if rlErr := h.limiter.allow(event.ProjectKey, event.Credential, event.Source, time.Now()); rlErr != nil {
 		h.log.Warn("event.rate_limited",
 			logger.String("project", event.ProjectKey),
 			logger.String("event_id", event.ID),
+			logger.String("scope", rlErr.Scope),
 		)
-		return "", fmt.Errorf("rate_limited: project %s exceeded %d diagnoses per hour", event.ProjectKey, h.rateLimit)
+		return "", rlErr
 	}
 
 	h.log.Info("event.received",
@@ -569,10 +748,15 @@ func (h *Handler) submitEvent(event *RawEvent) (string, error) {
 		logger.String("project", event.ProjectKey),
 		logger.String("severity", event.Severity),
 		logger.String("source", event.Source),
+		logger.String("credential", event.Credential),
 	)
 
-	taskID, err := h.onEvent(event)
+	taskID, err = h.onEvent(event)
 	if err != nil {
+		// A skipped event may still report a task (e.g. its incident's)
+		if _, skipped := skippedStatus(err); skipped {
+			return taskID, err
+		}
 		h.log.Error("event.submit_failed",
 			logger.String("event_id", event.ID),
 			logger.Err(err),
@@ -583,91 +767,137 @@ func (h *Handler) submitEvent(event *RawEvent) (string, error) {
 	return taskID, nil
 }
 
+var (
+	// ErrForbidden is returned when the credential may not submit events
+	// for the event's project.
+	ErrForbidden = errors.New("forbidden")
+	// ErrDeduplicated is returned for a duplicate within the dedup window.
+	ErrDeduplicated = errors.New("deduplicated")
+	// ErrSuppressed is returned for an event muted by a silence rule.
+	ErrSuppressed = errors.New("suppressed")
+	// ErrGrouped is wrapped by the event callback when the event joined an
+	// open incident instead of starting a diagnosis.
+	ErrGrouped = errors.New("grouped")
+	// ErrCoalesced is wrapped by the event callback when the event was
+	// attached to a task already diagnosing the same fingerprint.
+	ErrCoalesced = errors.New("coalesced")
+)
+
+// submitItem submits one event of a multi-event request and fills in its
+// result: the task and status, the error of a failed or skipped event, and
+// the original outcome of an idempotent replay. It also returns how long a
+// rate-limited event must wait; the request's Retry-After is the longest
+// wait among its events, so the reporter resends them in one go.
+func (h *Handler) submitItem(event *RawEvent, result map[string]any) (map[string]any, time.Duration) {
+	taskID, err := h.submitEvent(event)
+	// A replay switches the event to the original submission's ID
+	result["event_id"] = event.ID
+	if replay, ok := asReplay(err); ok {
+		result["task_id"] = replay.TaskID
+		result["status"] = replay.Status
+		result["replayed"] = true
+		return result, 0
+	}
+	if err != nil {
+		status := "error"
+		if skipped, ok := skippedStatus(err); ok {
+			status = skipped
+		}
+		result["status"] = status
+		result["error"] = err.Error()
+		wait, _ := retryAfter(err)
+		return result, wait
+	}
+	result["task_id"] = taskID
+	result["status"] = "queued"
+	return result, 0
+}
+
+// skippedStatus reports whether a submitEvent error means the event was
+// intentionally not diagnosed, and returns the response status for it.
+func skippedStatus(err error) (string, bool) {
+	switch {
+	case errors.Is(err, ErrDeduplicated):
+		return "deduplicated", true
+	case errors.Is(err, ErrSuppressed):
+		return "suppressed", true
+	case errors.Is(err, ErrGrouped):
+		return "grouped", true
+	case errors.Is(err, ErrCoalesced):
+		return "coalesced", true
+	}
+	return "", false
+}
+
+// lowerKeys returns a copy of a severity map with its keys lowercased and
+// trimmed, matching the normalized label values it is looked up with.
+func lowerKeys(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for from, to := range m {
+		out[strings.ToLower(strings.TrimSpace(from))] = to
+	}
+	return out
+}
+
+// dedupKey returns the dedup key for an event. A reporter-supplied
+// fingerprint takes precedence over the payload-derived one.
+func (h *Handler) dedupKey(event *RawEvent, projDedup *DedupConfig) string {
+	if event.Fingerprint != "" {
+		return event.ProjectKey + ":" + event.Source + ":" + event.Fingerprint
+	}
+	return ComputeFingerprint(event.ProjectKey, event.Payload, projDedup, h.dedupFields)
+}
+
 func (h *Handler) fillDefaults(event *RawEvent) {
 	if event.ID == "" {
 		event.ID = "evt-" + uuid.New().String()[:8]
 	}
-	if event.Severity == "" {
-		event.Severity = "warning"
-	}
 	if event.Source == "" {
 		event.Source = "custom"
 	}
+	h.applyProfile(event)
+	if event.Severity == "" {
+		event.Severity = "warning"
+	}
 	if event.ReceivedAt.IsZero() {
 		event.ReceivedAt = time.Now()
 	}
 }
 
-func (h *Handler) checkAuth(w http.ResponseWriter, r *http.Request) bool {
-	if h.authToken == "" {
-		return true
-	}
-	token := r.Header.Get("Authorization")
-	expected := "Bearer " + h.authToken
-	if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
-		return false
-	}
-	return true
-}
-
 func (h *Handler) meetsMinSeverity(severity string) bool {
 	return SeverityPriority(severity) >= SeverityPriority(h.minSeverity)
 }
 
+// checkDedupWithWindow reports whether the event should be let through.
+// Deduper errors fail open: a duplicate diagnosis beats a dropped event.
 func (h *Handler) checkDedupWithWindow(dedupKey string, window time.Duration) bool {
-	now := time.Now()
-	entry := dedupEntry{at: now, window: window}
-	existing, loaded := h.dedup.LoadOrStore(dedupKey, entry)
-	if !loaded {
-		// New entry — check OOM safety cap
-		if h.dedupSize.Add(1) > maxDedupEntries {
-			// Over cap: accept the entry (already stored) but log once
-			// The cleanup loop will bring it back down
-		}
-		return true // first occurrence — allow
-	}
-	prev := existing.(dedupEntry)
-	if time.Since(prev.at) >= window {
-		// Window expired — use CompareAndSwap so only one goroutine wins
-		if h.dedup.CompareAndSwap(dedupKey, existing, entry) {
-			return true
-		}
-		// Another goroutine already refreshed — this one is a duplicate
-		return false
-	}
-	return false
-}
-
-func (h *Handler) checkRateLimit(projectKey string) bool {
-	shard := &h.rateShards[shardIndex(projectKey)]
-	shard.mu.Lock()
-	defer shard.mu.Unlock()
-
-	now := time.Now()
-	entry, ok := shard.count[projectKey]
-	if !ok || now.Sub(entry.windowAt) >= time.Hour {
-		shard.count[projectKey] = &rateEntry{count: 1, windowAt: now}
+	allowed, err := h.deduper.Allow(dedupKey, window)
+	if err != nil {
+		h.log.Warn("event.dedup_failed", logger.String("key", dedupKey), logger.Err(err))
 		return true
 	}
-	if entry.count >= h.rateLimit {
-		return false
-	}
-	entry.count++
-	return true
-}
-
-func shardIndex(key string) uint32 {
-	h := fnv.New32a()
-	h.Write([]byte(key))
-	return h.Sum32() % rateLimitShards
+	return allowed
 }
 
 // ComputeFingerprint calculates a dedup fingerprint for the given event.
 func ComputeFingerprint(projectKey string, payload json.RawMessage, cfg *DedupConfig, defaultFields []string) string {
-	// Fast path: when using default top-level fields (no nested paths),
-	// use incremental hashing to avoid full json.Unmarshal + json.Marshal overhead.
 	if cfg == nil || len(cfg.Fields) == 0 {
+		// Normalized stack traces identify an error better than its message,
+		// which often embeds request-specific values.
+		if hasStackTraceKey(payload) {
+			var m map[string]any
+			if json.Unmarshal(payload, &m) == nil {
+				if fp, ok := stackFingerprint(m); ok {
+					return projectKey + ":" + fp
+				}
+			}
+		}
+
+		// Fast path: when using default top-level fields (no nested paths),
+		// use incremental hashing to avoid full json.Unmarshal + json.Marshal overhead.
 		if fp, ok := fastFingerprint(projectKey, payload, defaultFields); ok {
 			return fp
 		}
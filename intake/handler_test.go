@@ -2,6 +2,8 @@ package intake
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -13,15 +15,41 @@ import (
 )
 
 func newTestHandler(cfg HandlerConfig) *Handler {
+	return newSubmitTestHandler(cfg, func(e *RawEvent) (string, error) { return "task-123", nil })
+}
+
+// newCapturingTestHandler is newTestHandler that records every event handed
+// to the scheduler in submitted.
+func newCapturingTestHandler(cfg HandlerConfig, submitted *[]*RawEvent) *Handler {
+	return newSubmitTestHandler(cfg, func(e *RawEvent) (string, error) {
+		*submitted = append(*submitted, e)
+		return "task-1", nil
+	})
+}
+
+// newSubmitTestHandler builds a handler for projects proj-a and proj-b that
+// hands accepted events to submit.
+func newSubmitTestHandler(cfg HandlerConfig, submit func(*RawEvent) (string, error)) *Handler {
 	return NewHandler(
 		cfg,
 		logger.Nop(),
-		func(key string) bool { return key == "proj-a" },
-		func(e *RawEvent) (string, error) { return "task-123", nil },
+		func(key string) bool { return key == "proj-a" || key == "proj-b" },
+		submit,
 		func(projectKey string) *DedupConfig { return nil },
 	)
 }
 
+// postEvent sends body to h as a POST request with the given headers.
+func postEvent(h http.Handler, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/events", strings.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
 func TestHandlerServeHTTP_StandardMode(t *testing.T) {
 	h := newTestHandler(HandlerConfig{RateLimit: 100})
 	defer h.StopCleanup()
@@ -236,6 +264,42 @@ func TestHandlerServeHTTP_UnknownProject(t *testing.T) {
 	}
 }
 
+func TestHandlerServeHTTP_SkippedByCallback(t *testing.T) {
+	cases := []struct {
+		name       string
+		err        error
+		wantCode   int
+		wantStatus string
+	}{
+		{"grouped", fmt.Errorf("%w: attached to incident inc-1 (task task-9)", ErrGrouped), http.StatusOK, "grouped"},
+		{"coalesced", fmt.Errorf("%w: attached to task task-9", ErrCoalesced), http.StatusOK, "coalesced"},
+		// Only the sentinel marks a skip, not the wording of the message.
+		{"unwrapped", errors.New("grouped events store unavailable"), http.StatusServiceUnavailable, "error"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newSubmitTestHandler(HandlerConfig{RateLimit: 100}, func(e *RawEvent) (string, error) { return "task-9", tc.err })
+			defer h.StopCleanup()
+
+			body := `{"project_key":"proj-a","payload":{"error":"test"},"severity":"warning"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/events", strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, rec.Code, rec.Body.String())
+			}
+			var resp map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid json response: %v", err)
+			}
+			if resp["status"] != tc.wantStatus {
+				t.Errorf("expected status=%s, got %v", tc.wantStatus, resp["status"])
+			}
+		})
+	}
+}
+
 func TestComputeFingerprint_SamePayload(t *testing.T) {
 	payload1 := json.RawMessage(`{"error":"connection refused"}`)
 	payload2 := json.RawMessage(`{"error":"connection refused"}`)
@@ -0,0 +1,155 @@
+package intake
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"amp-sentinel/logger"
+)
+
+// IdempotencyHeader carries the reporter's idempotency key. Standard and
+// legacy envelopes may send it as "idempotency_key" instead.
+const IdempotencyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLen bounds keys so they fit the store column.
+const maxIdempotencyKeyLen = 255
+
+// IdempotencyRecord is the outcome remembered for an idempotency key.
+type IdempotencyRecord struct {
+	EventID string
+	TaskID  string
+	// Status is the original response status ("queued", "deduplicated", ...)
+	// or "pending" while the first request is still being processed.
+	Status string
+}
+
+// idempotencyPending is the status of a claimed key whose request is in flight.
+const idempotencyPending = "pending"
+
+// idempotencyPendingTTL bounds a pending claim. A request that dies between
+// Claim and Complete must not block the reporter's retries for the whole
+// window; Complete extends the key to the full window.
+const idempotencyPendingTTL = time.Minute
+
+// IdempotencyStore persists idempotency keys so retried submissions return
+// the original event and task, across restarts and replicas.
+type IdempotencyStore interface {
+	// Claim records key as pending for eventID for ttl, unless an unexpired
+	// record holds it. It returns nil if the caller claimed the key,
+	// otherwise the earlier record.
+	Claim(key, eventID string, ttl time.Duration) (*IdempotencyRecord, error)
+	// Complete records the outcome of the request that claimed key and keeps
+	// the key for window from now.
+	Complete(key, taskID, status string, window time.Duration) error
+	// Release forgets key so a failed request can be retried.
+	Release(key string) error
+	// Cleanup drops expired keys. Called periodically.
+	Cleanup(now time.Time)
+}
+
+// IdempotentReplay is returned by submitEvent when the event's idempotency
+// key was already used. The event ID is rewritten to the original one.
+type IdempotentReplay struct {
+	TaskID string
+	Status string
+}
+
+func (e *IdempotentReplay) Error() string {
+	return fmt.Sprintf("replayed: idempotency key already used (status %s)", e.Status)
+}
+
+// errIdempotencyInFlight is returned when the first request with a key has
+// not finished yet.
+var errIdempotencyInFlight = errors.New("conflict: a request with this idempotency key is still being processed")
+
+// idempotencyKey returns the request's key: the header wins over the
+// envelope field.
+func idempotencyKey(r *http.Request, envelopeKey string) string {
+	if k := strings.TrimSpace(r.Header.Get(IdempotencyHeader)); k != "" {
+		return k
+	}
+	return strings.TrimSpace(envelopeKey)
+}
+
+// claimIdempotency claims the event's idempotency key. It returns a non-nil
+// error when the submission must stop (a replay or an in-flight duplicate),
+// and reports whether the key was claimed and must be finished. Store errors
+// fail open: the event is processed without idempotency.
+func (h *Handler) claimIdempotency(event *RawEvent) (bool, error) {
+	if event.IdempotencyKey == "" || h.idempotency == nil {
+		return false, nil
+	}
+	rec, err := h.idempotency.Claim(h.scopedIdempotencyKey(event), event.ID, idempotencyPendingTTL)
+	if err != nil {
+		h.log.Warn("event.idempotency_failed", logger.String("event_id", event.ID), logger.Err(err))
+		return false, nil
+	}
+	if rec == nil {
+		return true, nil
+	}
+	if rec.Status == idempotencyPending {
+		return false, errIdempotencyInFlight
+	}
+	h.log.Info("event.idempotent_replay",
+		logger.String("project", event.ProjectKey),
+		logger.String("event_id", rec.EventID),
+	)
+	event.ID = rec.EventID
+	return false, &IdempotentReplay{TaskID: rec.TaskID, Status: rec.Status}
+}
+
+// finishIdempotency records the submission outcome for a claimed key.
+// Events that were accepted or deliberately skipped keep their key; failed
+// ones release it so the reporter's retry is processed normally.
+func (h *Handler) finishIdempotency(event *RawEvent, taskID string, err error) {
+	key := h.scopedIdempotencyKey(event)
+	status := "queued"
+	if err != nil {
+		skipped, ok := skippedStatus(err)
+		if !ok {
+			if relErr := h.idempotency.Release(key); relErr != nil {
+				h.log.Warn("event.idempotency_release_failed", logger.String("event_id", event.ID), logger.Err(relErr))
+			}
+			return
+		}
+		status = skipped
+	}
+	if cErr := h.idempotency.Complete(key, taskID, status, h.idempotencyWindow); cErr != nil {
+		h.log.Warn("event.idempotency_complete_failed", logger.String("event_id", event.ID), logger.Err(cErr))
+	}
+}
+
+// scopedIdempotencyKey namespaces keys by credential so reporters cannot
+// collide with (or probe) each other's keys.
+func (h *Handler) scopedIdempotencyKey(event *RawEvent) string {
+	return event.Credential + ":" + event.IdempotencyKey
+}
+
+// asReplay reports whether err is an idempotent replay.
+func asReplay(err error) (*IdempotentReplay, bool) {
+	var rep *IdempotentReplay
+	if errors.As(err, &rep) {
+		return rep, true
+	}
+	return nil, false
+}
+
+// writeReplay answers a repeated idempotency key with the original outcome.
+// idField is "event_id", or "incident_id" on the legacy endpoint.
+func writeReplay(w http.ResponseWriter, idField, eventID string, replay *IdempotentReplay) {
+	w.Header().Set("Idempotent-Replayed", "true")
+	writeJSON(w, http.StatusOK, map[string]any{
+		idField:   eventID,
+		"task_id": replay.TaskID,
+		"status":  replay.Status,
+		"message": "重复请求，返回首次提交的结果",
+	})
+}
+
+// validIdempotencyKey reports whether key is acceptable.
+func validIdempotencyKey(key string) bool {
+	return len(key) <= maxIdempotencyKeyLen
+}
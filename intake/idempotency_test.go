@@ -0,0 +1,211 @@
+package intake
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memIdempotency is an in-memory IdempotencyStore for tests.
+type memIdempotency struct {
+	mu      sync.Mutex
+	keys    map[string]*IdempotencyRecord
+	expires map[string]time.Time
+	// now is the store's clock, moved forward by tests.
+	now time.Time
+}
+
+func (m *memIdempotency) Claim(key, eventID string, ttl time.Duration) (*IdempotencyRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rec, ok := m.keys[key]; ok && m.expires[key].After(m.now) {
+		cp := *rec
+		return &cp, nil
+	}
+	m.keys[key] = &IdempotencyRecord{EventID: eventID, Status: idempotencyPending}
+	m.expires[key] = m.now.Add(ttl)
+	return nil, nil
+}
+
+func (m *memIdempotency) Complete(key, taskID, status string, window time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys[key].TaskID = taskID
+	m.keys[key].Status = status
+	m.expires[key] = m.now.Add(window)
+	return nil
+}
+
+func (m *memIdempotency) Release(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.keys, key)
+	delete(m.expires, key)
+	return nil
+}
+
+func (m *memIdempotency) Cleanup(time.Time) {}
+
+func newIdempotencyTestHandler(submit func(*RawEvent) (string, error)) (*Handler, *memIdempotency) {
+	store := &memIdempotency{keys: make(map[string]*IdempotencyRecord), expires: make(map[string]time.Time), now: time.Now()}
+	return newSubmitTestHandler(HandlerConfig{RateLimit: 100, MinSeverity: "info", Idempotency: store}, submit), store
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var m map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &m); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+	return m
+}
+
+func TestIdempotency_ReplayReturnsOriginal(t *testing.T) {
+	calls := 0
+	h, _ := newIdempotencyTestHandler(func(e *RawEvent) (string, error) {
+		calls++
+		return "task-1", nil
+	})
+	defer h.StopCleanup()
+
+	// Each retry carries a different payload, so dedup alone would not catch it.
+	first := postEvent(h, `{"project_key":"proj-a","payload":{"error":"a"}}`, map[string]string{IdempotencyHeader: "k1"})
+	if first.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", first.Code, first.Body.String())
+	}
+	orig := decodeBody(t, first)
+
+	for _, tc := range []struct {
+		name    string
+		body    string
+		headers map[string]string
+	}{
+		{"header", `{"project_key":"proj-a","payload":{"error":"b"}}`, map[string]string{IdempotencyHeader: "k1"}},
+		{"envelope", `{"project_key":"proj-a","idempotency_key":"k1","payload":{"error":"c"}}`, nil},
+	} {
+		rec := postEvent(h, tc.body, tc.headers)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", tc.name, rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("Idempotent-Replayed") != "true" {
+			t.Errorf("%s: missing Idempotent-Replayed header", tc.name)
+		}
+		resp := decodeBody(t, rec)
+		if resp["event_id"] != orig["event_id"] || resp["task_id"] != "task-1" || resp["status"] != "queued" {
+			t.Errorf("%s: replay = %v, original = %v", tc.name, resp, orig)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 submission, got %d", calls)
+	}
+
+	// Keys are scoped: a different key is a new submission.
+	if rec := postEvent(h, `{"project_key":"proj-a","payload":{"error":"d"}}`, map[string]string{IdempotencyHeader: "k2"}); rec.Code != http.StatusAccepted {
+		t.Fatalf("new key: expected 202, got %d", rec.Code)
+	}
+}
+
+func TestIdempotency_ReleasedOnFailure(t *testing.T) {
+	fail := true
+	h, store := newIdempotencyTestHandler(func(e *RawEvent) (string, error) {
+		if fail {
+			return "", errors.New("queue full")
+		}
+		return "task-2", nil
+	})
+	defer h.StopCleanup()
+
+	body := `{"project_key":"proj-a","payload":{"error":"a"}}`
+	headers := map[string]string{IdempotencyHeader: "k1"}
+	if rec := postEvent(h, body, headers); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+	if len(store.keys) != 0 {
+		t.Fatalf("failed submission kept its key: %v", store.keys)
+	}
+	fail = false
+	// A new payload so the retry is not caught by the dedup window.
+	if rec := postEvent(h, `{"project_key":"proj-a","payload":{"error":"b"}}`, headers); rec.Code != http.StatusAccepted {
+		t.Fatalf("retry: expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestIdempotency_InFlightConflict(t *testing.T) {
+	h, store := newIdempotencyTestHandler(func(e *RawEvent) (string, error) { return "task-1", nil })
+	defer h.StopCleanup()
+
+	store.Claim(":k1", "evt-1", idempotencyPendingTTL)
+	rec := postEvent(h, `{"project_key":"proj-a","payload":{"error":"a"}}`, map[string]string{IdempotencyHeader: "k1"})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	long := strings.Repeat("x", maxIdempotencyKeyLen+1)
+	if rec := postEvent(h, `{"project_key":"proj-a","payload":{}}`, map[string]string{IdempotencyHeader: long}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("long key: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestIdempotency_StalePendingReclaimed(t *testing.T) {
+	h, store := newIdempotencyTestHandler(func(e *RawEvent) (string, error) { return "task-2", nil })
+	defer h.StopCleanup()
+
+	// The first request claimed the key and died before completing it.
+	store.Claim(":k1", "evt-1", idempotencyPendingTTL)
+	body := `{"project_key":"proj-a","payload":{"error":"a"}}`
+	headers := map[string]string{IdempotencyHeader: "k1"}
+	if rec := postEvent(h, body, headers); rec.Code != http.StatusConflict {
+		t.Fatalf("while pending: expected 409, got %d", rec.Code)
+	}
+
+	// Once the pending claim lapses the reporter's retry goes through, and
+	// its outcome is kept for the full window.
+	store.now = store.now.Add(idempotencyPendingTTL + time.Second)
+	if rec := postEvent(h, body, headers); rec.Code != http.StatusAccepted {
+		t.Fatalf("after the claim lapsed: expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := store.keys[":k1"]; rec.Status != "queued" || !store.expires[":k1"].After(store.now.Add(23*time.Hour)) {
+		t.Errorf("completed key = %+v, expires %v", rec, store.expires[":k1"])
+	}
+}
+
+func TestIdempotency_Batch(t *testing.T) {
+	calls := 0
+	h, _ := newIdempotencyTestHandler(func(e *RawEvent) (string, error) {
+		calls++
+		return "task-" + e.ID[:8], nil
+	})
+	defer h.StopCleanup()
+
+	post := func() []any {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/events/batch?project=proj-a&severity=warning",
+			strings.NewReader("{\"error\":\"line1\"}\n{\"error\":\"line2\"}"))
+		req.Header.Set(IdempotencyHeader, "batch-1")
+		rec := httptest.NewRecorder()
+		h.ServeBatch(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		results, _ := decodeBody(t, rec)["results"].([]any)
+		if len(results) != 2 {
+			t.Fatalf("expected 2 results, got %v", results)
+		}
+		return results
+	}
+
+	first, second := post(), post()
+	if calls != 2 {
+		t.Errorf("expected 2 submissions, got %d", calls)
+	}
+	for i := range first {
+		a, b := first[i].(map[string]any), second[i].(map[string]any)
+		if a["event_id"] != b["event_id"] || a["task_id"] != b["task_id"] || b["replayed"] != true {
+			t.Errorf("line %d: first=%v replay=%v", i+1, a, b)
+		}
+	}
+}
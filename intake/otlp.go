@@ -0,0 +1,391 @@
+package intake
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OTLPConfig controls how OTLP log records are mapped onto RawEvent envelopes.
+type OTLPConfig struct {
+	MinSeverityNumber int               // records below this SeverityNumber are skipped (default 17 = ERROR)
+	ServiceProjects   map[string]string // service.name -> project key
+	DefaultProject    string            // project key for unmapped services
+}
+
+// OTLP severity number ranges (opentelemetry logs data model).
+const (
+	otlpSeverityWarn  = 13
+	otlpSeverityError = 17
+	otlpSeverityFatal = 21
+)
+
+// otlpLogsRequest is the JSON encoding of ExportLogsServiceRequest.
+type otlpLogsRequest struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []otlpKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			Scope struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"scope"`
+			LogRecords []otlpLogRecord `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         json.RawMessage `json:"timeUnixNano"`
+	ObservedTimeUnixNano json.RawMessage `json:"observedTimeUnixNano"`
+	SeverityNumber       int             `json:"severityNumber"`
+	SeverityText         string          `json:"severityText"`
+	Body                 *otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue  `json:"attributes"`
+	TraceID              string          `json:"traceId"`
+	SpanID               string          `json:"spanId"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string         `json:"stringValue"`
+	BoolValue   *bool           `json:"boolValue"`
+	IntValue    json.RawMessage `json:"intValue"` // int64 is encoded as a JSON string
+	DoubleValue *float64        `json:"doubleValue"`
+	BytesValue  *string         `json:"bytesValue"`
+	ArrayValue  *struct {
+		Values []otlpAnyValue `json:"values"`
+	} `json:"arrayValue"`
+	KvlistValue *struct {
+		Values []otlpKeyValue `json:"values"`
+	} `json:"kvlistValue"`
+}
+
+// value converts an AnyValue into a plain Go value.
+func (v *otlpAnyValue) value() any {
+	switch {
+	case v == nil:
+		return nil
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case len(v.IntValue) > 0:
+		if n, err := strconv.ParseInt(strings.Trim(string(v.IntValue), `"`), 10, 64); err == nil {
+			return n
+		}
+		return strings.Trim(string(v.IntValue), `"`)
+	case v.DoubleValue != nil:
+		return *v.DoubleValue
+	case v.BytesValue != nil:
+		return *v.BytesValue
+	case v.ArrayValue != nil:
+		out := make([]any, 0, len(v.ArrayValue.Values))
+		for i := range v.ArrayValue.Values {
+			out = append(out, v.ArrayValue.Values[i].value())
+		}
+		return out
+	case v.KvlistValue != nil:
+		return otlpAttributes(v.KvlistValue.Values)
+	}
+	return nil
+}
+
+func otlpAttributes(kvs []otlpKeyValue) map[string]any {
+	if len(kvs) == 0 {
+		return nil
+	}
+	m := make(map[string]any, len(kvs))
+	for i := range kvs {
+		m[kvs[i].Key] = kvs[i].Value.value()
+	}
+	return m
+}
+
+// otlpPayload is the per-record payload handed to diagnosis. Attribute maps
+// keep their dotted OTel keys (e.g. "exception.type").
+type otlpPayload struct {
+	ErrorType      string         `json:"error_type,omitempty"`
+	ErrorMsg       string         `json:"error_msg,omitempty"`
+	Body           any            `json:"body,omitempty"`
+	SeverityText   string         `json:"severity_text,omitempty"`
+	SeverityNumber int            `json:"severity_number"`
+	Timestamp      string         `json:"timestamp,omitempty"`
+	Service        string         `json:"service,omitempty"`
+	Environment    string         `json:"environment,omitempty"`
+	TraceID        string         `json:"trace_id,omitempty"`
+	SpanID         string         `json:"span_id,omitempty"`
+	Exception      map[string]any `json:"exception,omitempty"`
+	Scope          string         `json:"scope,omitempty"`
+	Attributes     map[string]any `json:"attributes,omitempty"`
+	Resource       map[string]any `json:"resource,omitempty"`
+}
+
+// ServeOTLPLogs handles POST /v1/logs (OTLP/HTTP, JSON encoding only).
+// Each record at or above the configured severity number becomes an event;
+// the response reports per-record results and an OTLP partialSuccess block.
+func (h *Handler) ServeOTLPLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cred, ok := h.checkAuth(w, r)
+	if !ok {
+		return
+	}
+
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		if mt, _, _ := mime.ParseMediaType(ct); mt != "application/json" {
+			http.Error(w, "unsupported content type: only OTLP/HTTP JSON is accepted", http.StatusUnsupportedMediaType)
+			return
+		}
+	}
+
+	body := http.MaxBytesReader(w, r.Body, 10<<20) // 10MB, same as batch
+	rawBody, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var req otlpLogsRequest
+	if err := json.Unmarshal(rawBody, &req); err != nil {
+		http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var results []map[string]any
+	index := 0
+	accepted := 0
+	skipped := 0
+	var maxRetryAfter time.Duration
+	for _, rl := range req.ResourceLogs {
+		resource := otlpAttributes(rl.Resource.Attributes)
+		service, _ := resource["service.name"].(string)
+		projectKey := h.otlpProject(service)
+		env, _ := resource["deployment.environment.name"].(string)
+		if env == "" {
+			env, _ = resource["deployment.environment"].(string)
+		}
+
+		for _, sl := range rl.ScopeLogs {
+			for i := range sl.LogRecords {
+				rec := &sl.LogRecords[i]
+				idx := index
+				index++
+
+				num := otlpSeverityNumber(rec.SeverityNumber, rec.SeverityText)
+				if num < h.otlp.MinSeverityNumber {
+					skipped++
+					continue
+				}
+
+				attrs := otlpAttributes(rec.Attributes)
+				p := otlpPayload{
+					Body:           rec.Body.value(),
+					SeverityText:   rec.SeverityText,
+					SeverityNumber: num,
+					Timestamp:      otlpTimestamp(rec.TimeUnixNano, rec.ObservedTimeUnixNano),
+					Service:        service,
+					Environment:    env,
+					TraceID:        rec.TraceID,
+					SpanID:         rec.SpanID,
+					Exception:      otlpException(attrs),
+					Scope:          sl.Scope.Name,
+					Attributes:     attrs,
+					Resource:       resource,
+				}
+				p.ErrorType, _ = p.Exception["type"].(string)
+				p.ErrorMsg, _ = p.Exception["message"].(string)
+				if p.ErrorMsg == "" {
+					p.ErrorMsg, _ = p.Body.(string)
+				}
+				payload, _ := json.Marshal(p)
+
+				event := &RawEvent{
+					ProjectKey: projectKey,
+					Severity:   otlpSeverity(num),
+					Payload:    json.RawMessage(payload),
+					Source:     "otlp",
+					Credential: cred,
+				}
+				h.fillDefaults(event)
+
+				if event.ProjectKey == "" {
+					results = append(results, map[string]any{
+						"index":    idx,
+						"event_id": event.ID,
+						"status":   "error",
+						"error":    fmt.Sprintf("no project mapped for service.name %q", service),
+					})
+					continue
+				}
+
+				if h.maxPayloadSize > 0 && len(payload) > h.maxPayloadSize {
+					results = append(results, map[string]any{
+						"index":    idx,
+						"event_id": event.ID,
+						"status":   "error",
+						"error":    fmt.Sprintf("payload too large: %d bytes (max %d)", len(payload), h.maxPayloadSize),
+					})
+					continue
+				}
+
+				if !h.meetsMinSeverity(event.Severity) {
+					results = append(results, map[string]any{
+						"index":    idx,
+						"event_id": event.ID,
+						"status":   "filtered",
+						"message":  fmt.Sprintf("severity %s is below minimum %s", event.Severity, h.minSeverity),
+					})
+					continue
+				}
+
+				if h.validateProject != nil && !h.validateProject(event.ProjectKey) {
+					results = append(results, map[string]any{
+						"index":    idx,
+						"event_id": event.ID,
+						"status":   "error",
+						"error":    fmt.Sprintf("unknown project: %s", event.ProjectKey),
+					})
+					continue
+				}
+
+				event.Title = otlpTitle(p)
+				if event.Title == "" {
+					event.Title = ExtractTitle(event.Payload)
+				}
+
+				result, wait := h.submitItem(event, map[string]any{"index": idx})
+				if result["status"] == "queued" {
+					accepted++
+				}
+				maxRetryAfter = max(maxRetryAfter, wait)
+				results = append(results, result)
+			}
+		}
+	}
+
+	// Only records that failed count as rejected: deduplicated, grouped,
+	// filtered and similar records were handled and must not be resent.
+	rejected := 0
+	for _, r := range results {
+		if r["status"] == "error" {
+			rejected++
+		}
+	}
+	resp := map[string]any{
+		"total":    index,
+		"accepted": accepted,
+		"rejected": rejected,
+		"skipped":  skipped,
+		"results":  results,
+	}
+	if rejected > 0 {
+		resp["partialSuccess"] = map[string]any{
+			"rejectedLogRecords": strconv.Itoa(rejected),
+			"errorMessage":       fmt.Sprintf("%d log records were not accepted, see results", rejected),
+		}
+	}
+	if maxRetryAfter > 0 {
+		setRetryAfter(w, maxRetryAfter)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// otlpProject maps service.name to a project key: explicit mapping first,
+// then the default project, then the service name itself.
+func (h *Handler) otlpProject(service string) string {
+	if p, ok := h.otlp.ServiceProjects[service]; ok && p != "" {
+		return p
+	}
+	if h.otlp.DefaultProject != "" {
+		return h.otlp.DefaultProject
+	}
+	return service
+}
+
+// otlpSeverityNumber returns the record's SeverityNumber, deriving it from
+// SeverityText when the exporter left the number unset.
+func otlpSeverityNumber(num int, text string) int {
+	if num > 0 {
+		return num
+	}
+	switch strings.ToUpper(strings.TrimSpace(text)) {
+	case "FATAL", "CRITICAL", "EMERGENCY", "ALERT":
+		return otlpSeverityFatal
+	case "ERROR", "ERR":
+		return otlpSeverityError
+	case "WARN", "WARNING":
+		return otlpSeverityWarn
+	case "INFO", "NOTICE":
+		return 9
+	case "DEBUG":
+		return 5
+	}
+	return 0
+}
+
+// otlpSeverity maps a SeverityNumber onto a Sentinel severity.
+func otlpSeverity(num int) string {
+	switch {
+	case num >= otlpSeverityFatal:
+		return "critical"
+	case num >= otlpSeverityWarn:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// otlpException collects the exception.* semantic attributes.
+func otlpException(attrs map[string]any) map[string]any {
+	var exc map[string]any
+	for k, v := range attrs {
+		if name, ok := strings.CutPrefix(k, "exception."); ok {
+			if exc == nil {
+				exc = make(map[string]any)
+			}
+			exc[name] = v
+		}
+	}
+	return exc
+}
+
+// otlpTimestamp converts a unix-nano value (JSON string or number) to RFC3339.
+func otlpTimestamp(values ...json.RawMessage) string {
+	for _, raw := range values {
+		s := string(bytes.Trim(raw, `"`))
+		if s == "" || s == "0" {
+			continue
+		}
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Unix(0, n).UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return ""
+}
+
+// otlpTitle prefers "exception.type: exception.message", then the body.
+func otlpTitle(p otlpPayload) string {
+	title := p.ErrorMsg
+	if p.ErrorType != "" {
+		title = p.ErrorType
+		if p.ErrorMsg != "" {
+			title = p.ErrorType + ": " + p.ErrorMsg
+		}
+	}
+	return TruncateRunes(SanitizeDisplayText(title), 100)
+}
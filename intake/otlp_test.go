@@ -0,0 +1,161 @@
+package intake
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const otlpLogsBody = `{
+  "resourceLogs": [{
+    "resource": {"attributes": [
+      {"key": "service.name", "value": {"stringValue": "order-api"}},
+      {"key": "deployment.environment", "value": {"stringValue": "production"}}
+    ]},
+    "scopeLogs": [{
+      "scope": {"name": "order.checkout"},
+      "logRecords": [
+        {
+          "timeUnixNano": "1735725600000000000",
+          "severityNumber": 17,
+          "severityText": "ERROR",
+          "body": {"stringValue": "checkout failed"},
+          "traceId": "5b8efff798038103d269b633813fc60c",
+          "spanId": "eee19b7ec3c1b174",
+          "attributes": [
+            {"key": "exception.type", "value": {"stringValue": "TimeoutError"}},
+            {"key": "exception.message", "value": {"stringValue": "upstream timed out"}},
+            {"key": "exception.stacktrace", "value": {"stringValue": "at checkout (checkout.js:10)"}},
+            {"key": "http.status_code", "value": {"intValue": "504"}}
+          ]
+        },
+        {"severityNumber": 9, "severityText": "INFO", "body": {"stringValue": "order placed"}},
+        {"severityText": "FATAL", "body": {"stringValue": "out of memory"}}
+      ]
+    }]
+  }, {
+    "resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "unknown-svc"}}]},
+    "scopeLogs": [{"logRecords": [{"severityNumber": 18, "body": {"stringValue": "boom"}}]}]
+  }]
+}`
+
+func TestServeOTLPLogs(t *testing.T) {
+	var submitted []*RawEvent
+	h := newCapturingTestHandler(HandlerConfig{
+		RateLimit: 100,
+		OTLP: OTLPConfig{
+			ServiceProjects: map[string]string{"order-api": "proj-a"},
+		},
+	}, &submitted)
+	defer h.StopCleanup()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/logs", strings.NewReader(otlpLogsBody))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeOTLPLogs(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+	if resp["total"] != float64(4) || resp["accepted"] != float64(2) || resp["skipped"] != float64(1) || resp["rejected"] != float64(1) {
+		t.Errorf("unexpected counts: %v", resp)
+	}
+	if _, ok := resp["partialSuccess"]; !ok {
+		t.Error("expected partialSuccess for rejected records")
+	}
+
+	if len(submitted) != 2 {
+		t.Fatalf("expected 2 submitted events, got %d", len(submitted))
+	}
+	ev := submitted[0]
+	if ev.ProjectKey != "proj-a" || ev.Source != "otlp" || ev.Severity != "warning" {
+		t.Errorf("unexpected envelope: %+v", ev)
+	}
+	if ev.Title != "TimeoutError: upstream timed out" {
+		t.Errorf("Title = %q", ev.Title)
+	}
+	if submitted[1].Severity != "critical" {
+		t.Errorf("FATAL severity text should map to critical, got %q", submitted[1].Severity)
+	}
+
+	var p map[string]any
+	if err := json.Unmarshal(ev.Payload, &p); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if p["trace_id"] != "5b8efff798038103d269b633813fc60c" || p["span_id"] != "eee19b7ec3c1b174" {
+		t.Errorf("trace context not kept: %v", p)
+	}
+	exc, _ := p["exception"].(map[string]any)
+	if exc["stacktrace"] != "at checkout (checkout.js:10)" {
+		t.Errorf("exception attributes not kept: %v", p["exception"])
+	}
+	res, _ := p["resource"].(map[string]any)
+	if res["service.name"] != "order-api" {
+		t.Errorf("resource attributes not kept: %v", p["resource"])
+	}
+	attrs, _ := p["attributes"].(map[string]any)
+	if attrs["http.status_code"] != float64(504) {
+		t.Errorf("intValue not decoded: %v", attrs["http.status_code"])
+	}
+	df := ExtractDisplayFields(p)
+	if df.Environment != "production" || df.OccurredAt == "" {
+		t.Errorf("expected display fields, got %+v", df)
+	}
+}
+
+func TestServeOTLPLogs_DedupIsNotRejected(t *testing.T) {
+	var submitted []*RawEvent
+	h := newCapturingTestHandler(HandlerConfig{
+		RateLimit: 100,
+		OTLP:      OTLPConfig{DefaultProject: "proj-a"},
+	}, &submitted)
+	defer h.StopCleanup()
+
+	// The same error logged twice in one batch: the second is a dedup hit.
+	body := `{"resourceLogs": [{"scopeLogs": [{"logRecords": [
+		{"severityNumber": 17, "body": {"stringValue": "checkout failed"}},
+		{"severityNumber": 17, "body": {"stringValue": "checkout failed"}}
+	]}]}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/logs", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeOTLPLogs(rec, req)
+
+	var resp map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+	results, _ := resp["results"].([]any)
+	if len(results) != 2 || results[1].(map[string]any)["status"] != "deduplicated" {
+		t.Fatalf("expected a dedup hit, got %v", resp["results"])
+	}
+	if resp["accepted"] != float64(1) || resp["rejected"] != float64(0) {
+		t.Errorf("unexpected counts: %v", resp)
+	}
+	if _, ok := resp["partialSuccess"]; ok {
+		t.Error("a deduplicated record must not be reported as rejected")
+	}
+	if len(submitted) != 1 {
+		t.Errorf("expected 1 submitted event, got %d", len(submitted))
+	}
+}
+
+func TestServeOTLPLogs_RejectsProtobuf(t *testing.T) {
+	h := newTestHandler(HandlerConfig{RateLimit: 100})
+	defer h.StopCleanup()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/logs", strings.NewReader("\x0a\x00"))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	rec := httptest.NewRecorder()
+	h.ServeOTLPLogs(rec, req)
+
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,153 @@
+package intake
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MappingProfile declares how events from one source are mapped onto the
+// fields Sentinel understands. Paths use the same dotted syntax as dedup
+// fields ("error.message", "exception.values.0.type"). Mapped values are
+// copied into the payload under the default candidate keys (title, error_msg,
+// stacktrace, environment) so dedup, fingerprints, titles and Feishu cards
+// pick them up exactly as they would for a Sentry or OTLP event.
+type MappingProfile struct {
+	// Source selects the events the profile applies to (RawEvent.Source).
+	Source      string
+	Title       string
+	Message     string
+	Stack       string
+	Environment string
+	// Severity is the payload path of the reporter's severity, used when the
+	// event doesn't carry one.
+	Severity string
+	// SeverityMap maps reporter severities (case-insensitive, e.g. "P0",
+	// "FATAL", "sev2") onto critical/warning/info.
+	SeverityMap map[string]string
+}
+
+// ValidateMappingProfile checks that a profile is well-formed.
+func ValidateMappingProfile(p MappingProfile) error {
+	if p.Source == "" {
+		return fmt.Errorf("source is required")
+	}
+	for from, to := range p.SeverityMap {
+		if !ValidSeverities[to] {
+			return fmt.Errorf("severity_map %q: invalid severity %q (must be critical, warning, or info)", from, to)
+		}
+	}
+	return nil
+}
+
+// compileProfiles indexes profiles by source and lower-cases severity map keys.
+func compileProfiles(profiles []MappingProfile) map[string]*MappingProfile {
+	if len(profiles) == 0 {
+		return nil
+	}
+	out := make(map[string]*MappingProfile, len(profiles))
+	for i := range profiles {
+		p := profiles[i]
+		sevMap := make(map[string]string, len(p.SeverityMap))
+		for from, to := range p.SeverityMap {
+			sevMap[strings.ToLower(strings.TrimSpace(from))] = to
+		}
+		p.SeverityMap = sevMap
+		out[p.Source] = &p
+	}
+	return out
+}
+
+// applyProfile maps the event through the profile for its source, if any.
+// It runs before severity defaults and validation.
+func (h *Handler) applyProfile(event *RawEvent) {
+	p := h.profiles[event.Source]
+	if p == nil {
+		return
+	}
+
+	if event.Severity != "" {
+		if mapped, ok := p.mapSeverity(event.Severity); ok {
+			event.Severity = mapped
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(event.Payload))
+	dec.UseNumber()
+	var m map[string]any
+	if dec.Decode(&m) != nil || m == nil {
+		return
+	}
+
+	// A payload severity is only used when it maps cleanly; unknown values
+	// fall through to the default rather than rejecting the event.
+	if event.Severity == "" && p.Severity != "" {
+		if mapped, ok := p.mapSeverity(scalarString(resolveField(m, p.Severity))); ok {
+			event.Severity = mapped
+		}
+	}
+
+	changed := false
+	set := func(key, path string, scalarOnly bool) string {
+		if path == "" {
+			return ""
+		}
+		v := resolveField(m, path)
+		s := scalarString(v)
+		if v == nil || (scalarOnly && s == "") {
+			return ""
+		}
+		if scalarOnly {
+			v = s
+		}
+		m[key] = v
+		changed = true
+		return s
+	}
+	title := set("title", p.Title, true)
+	set("error_msg", p.Message, true)
+	// Stacks may be plain text or a normalized StackTrace block; the latter
+	// is used for the stack fingerprint.
+	set("stacktrace", p.Stack, false)
+	set("environment", p.Environment, true)
+
+	if event.Title == "" && title != "" {
+		event.Title = TruncateRunes(SanitizeDisplayText(title), 100)
+	}
+	if !changed {
+		return
+	}
+	if payload, err := json.Marshal(m); err == nil {
+		event.Payload = payload
+	}
+}
+
+// mapSeverity maps a reporter severity. Values that are already valid
+// Sentinel severities pass through unless the map overrides them.
+func (p *MappingProfile) mapSeverity(v string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(v))
+	if key == "" {
+		return "", false
+	}
+	if mapped, ok := p.SeverityMap[key]; ok {
+		return mapped, true
+	}
+	if ValidSeverities[key] {
+		return key, true
+	}
+	return "", false
+}
+
+// scalarString renders a decoded scalar as a string ("" for non-scalars).
+func scalarString(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case float64, bool:
+		return fmt.Sprint(val)
+	}
+	return ""
+}
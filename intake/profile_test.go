@@ -0,0 +1,92 @@
+package intake
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func newProfileTestHandler(submitted *[]*RawEvent) *Handler {
+	return newCapturingTestHandler(HandlerConfig{
+		RateLimit:   100,
+		MinSeverity: "info",
+		Profiles: []MappingProfile{{
+			Source:      "sls",
+			Title:       "alert.name",
+			Message:     "content.err",
+			Stack:       "content.trace",
+			Environment: "labels.cluster",
+			Severity:    "alert.level",
+			SeverityMap: map[string]string{"P0": "critical", "sev2": "warning", "FATAL": "critical"},
+		}},
+	}, submitted)
+}
+
+func TestProfile_MapsFieldsAndSeverity(t *testing.T) {
+	var submitted []*RawEvent
+	h := newProfileTestHandler(&submitted)
+	defer h.StopCleanup()
+
+	body := `{"project_key":"proj-a","source":"sls","payload":{"alert":{"name":"Order API 5xx","level":"p0"},` +
+		`"content":{"err":"db timeout","trace":"at OrderService.create"},"labels":{"cluster":"prod-sh"}}}`
+	if rec := postEvent(h, body, nil); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(submitted) != 1 {
+		t.Fatalf("expected 1 submission, got %d", len(submitted))
+	}
+	e := submitted[0]
+	if e.Severity != "critical" || e.Title != "Order API 5xx" {
+		t.Errorf("severity=%q title=%q", e.Severity, e.Title)
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(e.Payload, &m); err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	if m["error_msg"] != "db timeout" || m["stacktrace"] != "at OrderService.create" || m["environment"] != "prod-sh" {
+		t.Errorf("canonical fields not set: %v", m)
+	}
+	if df := ExtractDisplayFields(m); df.Environment != "prod-sh" || df.ErrorMsg != "db timeout" {
+		t.Errorf("display fields = %+v", df)
+	}
+	// The mapped message drives dedup like a native error_msg would.
+	if got, want := ComputeFingerprint("proj-a", e.Payload, nil, []string{"error_msg"}),
+		ComputeFingerprint("proj-a", json.RawMessage(`{"error_msg":"db timeout"}`), nil, []string{"error_msg"}); got != want {
+		t.Errorf("fingerprint %q, want %q", got, want)
+	}
+}
+
+func TestProfile_EnvelopeSeverity(t *testing.T) {
+	var submitted []*RawEvent
+	h := newProfileTestHandler(&submitted)
+	defer h.StopCleanup()
+
+	// Envelope severities are mapped too; unknown values are still rejected.
+	if rec := postEvent(h, `{"project_key":"proj-a","source":"sls","severity":"SEV2","payload":{"x":1}}`, nil); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if submitted[0].Severity != "warning" {
+		t.Errorf("severity = %q, want warning", submitted[0].Severity)
+	}
+	if rec := postEvent(h, `{"project_key":"proj-a","source":"sls","severity":"P9","payload":{"x":2}}`, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unmapped severity, got %d", rec.Code)
+	}
+
+	// Other sources are not mapped.
+	if rec := postEvent(h, `{"project_key":"proj-a","severity":"P0","payload":{"x":3}}`, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a profile, got %d", rec.Code)
+	}
+}
+
+func TestValidateMappingProfile(t *testing.T) {
+	if err := ValidateMappingProfile(MappingProfile{SeverityMap: map[string]string{"P0": "critical"}}); err == nil {
+		t.Error("expected error for missing source")
+	}
+	if err := ValidateMappingProfile(MappingProfile{Source: "sls", SeverityMap: map[string]string{"P0": "page"}}); err == nil {
+		t.Error("expected error for invalid target severity")
+	}
+	if err := ValidateMappingProfile(MappingProfile{Source: "sls", SeverityMap: map[string]string{"P0": "critical"}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
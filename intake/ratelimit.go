@@ -0,0 +1,222 @@
+package intake
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit configures one token bucket. PerHour is the refill rate and Burst
+// the bucket capacity (default: PerHour rounded up). A zero PerHour disables
+// the bucket.
+type RateLimit struct {
+	PerHour float64
+	Burst   int
+}
+
+// RateLimitConfig configures the intake limiter. An event must find a token
+// in every bucket that applies to it: the global bucket, its project bucket,
+// and the buckets of its credential and source, if configured.
+type RateLimitConfig struct {
+	Global RateLimit
+	// Project is the default per-project bucket; Projects overrides it.
+	Project  RateLimit
+	Projects map[string]RateLimit
+	// Credentials and Sources limit individual reporters. Unlisted ones are
+	// only subject to the global and project buckets.
+	Credentials map[string]RateLimit
+	Sources     map[string]RateLimit
+}
+
+// RateLimitError is returned by submitEvent when a bucket is empty.
+type RateLimitError struct {
+	Scope      string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate_limited: %s quota exhausted, retry after %ds", e.Scope, retryAfterSeconds(e.RetryAfter))
+}
+
+// RateLimitStat is a snapshot of one bucket for the stats endpoint.
+type RateLimitStat struct {
+	Scope   string  `json:"scope"`
+	PerHour float64 `json:"per_hour"`
+	Burst   int     `json:"burst"`
+	Tokens  float64 `json:"tokens"`
+	// Utilization is the share of the burst currently consumed (0..1).
+	Utilization float64 `json:"utilization"`
+	// Allowed and Rejected count events since the bucket was created; idle
+	// buckets are dropped once they refill.
+	Allowed  int64 `json:"allowed"`
+	Rejected int64 `json:"rejected"`
+}
+
+type tokenBucket struct {
+	limit    RateLimit
+	tokens   float64
+	updated  time.Time
+	allowed  int64
+	rejected int64
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated).Hours(); elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.PerHour)
+		b.updated = now
+	}
+}
+
+// wait returns how long until the bucket holds a whole token.
+func (b *tokenBucket) wait() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.limit.PerHour * float64(time.Hour))
+}
+
+type rateScope struct {
+	key   string
+	limit RateLimit
+}
+
+// rateLimiter holds the token buckets of all scopes. A single mutex guards
+// them: an event takes tokens from several buckets at once (all or nothing),
+// and the global bucket serializes every event anyway.
+type rateLimiter struct {
+	cfg     RateLimitConfig
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func newRateLimiter(cfg RateLimitConfig) *rateLimiter {
+	return &rateLimiter{cfg: cfg, buckets: make(map[string]*tokenBucket)}
+}
+
+// scopes lists the buckets that apply to an event, in report order.
+func (l *rateLimiter) scopes(projectKey, credential, source string) []rateScope {
+	scopes := make([]rateScope, 0, 4)
+	add := func(key string, limit RateLimit) {
+		if limit.PerHour > 0 {
+			scopes = append(scopes, rateScope{key: key, limit: limit})
+		}
+	}
+	add("global", l.cfg.Global)
+	if limit, ok := l.cfg.Projects[projectKey]; ok {
+		add("project:"+projectKey, limit)
+	} else {
+		add("project:"+projectKey, l.cfg.Project)
+	}
+	if credential != "" {
+		add("credential:"+credential, l.cfg.Credentials[credential])
+	}
+	add("source:"+source, l.cfg.Sources[source])
+	return scopes
+}
+
+// allow takes one token from every applicable bucket, or none if any of them
+// is empty. The returned error carries the longest wait among empty buckets.
+func (l *rateLimiter) allow(projectKey, credential, source string, now time.Time) *RateLimitError {
+	scopes := l.scopes(projectKey, credential, source)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	buckets := make([]*tokenBucket, len(scopes))
+	var denied *RateLimitError
+	for i, s := range scopes {
+		b := l.buckets[s.key]
+		if b == nil {
+			limit := s.limit
+			if limit.Burst <= 0 {
+				limit.Burst = int(math.Max(1, math.Ceil(limit.PerHour)))
+			}
+			b = &tokenBucket{limit: limit, tokens: float64(limit.Burst), updated: now}
+			l.buckets[s.key] = b
+		}
+		b.refill(now)
+		buckets[i] = b
+		if b.tokens < 1 {
+			b.rejected++
+			if d := b.wait(); denied == nil || d > denied.RetryAfter {
+				denied = &RateLimitError{Scope: s.key, RetryAfter: d}
+			}
+		}
+	}
+	if denied != nil {
+		return denied
+	}
+	for _, b := range buckets {
+		b.tokens--
+		b.allowed++
+	}
+	return nil
+}
+
+// cleanup drops buckets that have been idle long enough to refill; a full
+// bucket behaves exactly like a fresh one. The global bucket is kept so its
+// counters survive quiet periods.
+func (l *rateLimiter) cleanup(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, b := range l.buckets {
+		b.refill(now)
+		if key != "global" && b.tokens >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// stats returns a snapshot of all buckets, most utilized first.
+func (l *rateLimiter) stats(now time.Time) []RateLimitStat {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]RateLimitStat, 0, len(l.buckets))
+	for key, b := range l.buckets {
+		b.refill(now)
+		out = append(out, RateLimitStat{
+			Scope:       key,
+			PerHour:     b.limit.PerHour,
+			Burst:       b.limit.Burst,
+			Tokens:      math.Round(b.tokens*100) / 100,
+			Utilization: math.Round((1-b.tokens/float64(b.limit.Burst))*1000) / 1000,
+			Allowed:     b.allowed,
+			Rejected:    b.rejected,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Utilization != out[j].Utilization {
+			return out[i].Utilization > out[j].Utilization
+		}
+		return out[i].Scope < out[j].Scope
+	})
+	return out
+}
+
+// RateLimitStats returns the current state of the intake token buckets.
+func (h *Handler) RateLimitStats() []RateLimitStat {
+	return h.limiter.stats(time.Now())
+}
+
+// retryAfter extracts the wait from a rate limit error.
+func retryAfter(err error) (time.Duration, bool) {
+	var rl *RateLimitError
+	if errors.As(err, &rl) {
+		return rl.RetryAfter, true
+	}
+	return 0, false
+}
+
+// setRetryAfter sets the Retry-After header, in whole seconds (at least 1).
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(d)))
+}
+
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
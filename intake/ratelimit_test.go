@@ -0,0 +1,121 @@
+package intake
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_BurstAndRefill(t *testing.T) {
+	l := newRateLimiter(RateLimitConfig{Project: RateLimit{PerHour: 60, Burst: 2}})
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if err := l.allow("proj-a", "", "custom", now); err != nil {
+			t.Fatalf("event %d within burst rejected: %v", i, err)
+		}
+	}
+	err := l.allow("proj-a", "", "custom", now)
+	if err == nil || err.Scope != "project:proj-a" {
+		t.Fatalf("expected project bucket to reject, got %v", err)
+	}
+	// 60/h refills one token per minute.
+	if err.RetryAfter != time.Minute {
+		t.Errorf("RetryAfter = %v, want 1m", err.RetryAfter)
+	}
+	// Other projects have their own bucket.
+	if err := l.allow("proj-b", "", "custom", now); err != nil {
+		t.Errorf("proj-b rejected: %v", err)
+	}
+	if err := l.allow("proj-a", "", "custom", now.Add(time.Minute)); err != nil {
+		t.Errorf("expected a token after refill, got %v", err)
+	}
+}
+
+func TestRateLimiter_Levels(t *testing.T) {
+	l := newRateLimiter(RateLimitConfig{
+		Global:      RateLimit{PerHour: 3600, Burst: 3},
+		Project:     RateLimit{PerHour: 100},
+		Projects:    map[string]RateLimit{"proj-b": {PerHour: 1, Burst: 1}},
+		Credentials: map[string]RateLimit{"ci": {PerHour: 1, Burst: 1}},
+	})
+	now := time.Now()
+
+	// Per-project override.
+	l.allow("proj-b", "", "custom", now)
+	if err := l.allow("proj-b", "", "custom", now); err == nil || err.Scope != "project:proj-b" {
+		t.Fatalf("expected project override to reject, got %v", err)
+	}
+
+	// Credential bucket; a rejection takes no tokens from the other buckets.
+	l.allow("proj-a", "ci", "custom", now)
+	if err := l.allow("proj-a", "ci", "custom", now); err == nil || err.Scope != "credential:ci" {
+		t.Fatalf("expected credential bucket to reject, got %v", err)
+	}
+
+	// Global: proj-b and the first ci event took two of three tokens.
+	if err := l.allow("proj-a", "", "custom", now); err != nil {
+		t.Fatalf("unexpected rejection: %v", err)
+	}
+	if err := l.allow("proj-a", "", "custom", now); err == nil || err.Scope != "global" {
+		t.Fatalf("expected global bucket to reject, got %v", err)
+	}
+
+	stats := l.stats(now)
+	if len(stats) == 0 || stats[0].Utilization != 1 {
+		t.Fatalf("expected exhausted bucket first, got %+v", stats)
+	}
+	for _, s := range stats {
+		if s.Scope == "global" && (s.Allowed != 3 || s.Rejected != 1) {
+			t.Errorf("global stats = %+v", s)
+		}
+	}
+}
+
+func TestHandler_RateLimitedRetryAfter(t *testing.T) {
+	h := newTestHandler(HandlerConfig{RateLimits: RateLimitConfig{Project: RateLimit{PerHour: 1, Burst: 1}}})
+	defer h.StopCleanup()
+
+	postEvent(h, `{"project_key":"proj-a","payload":{"error":"first"}}`, nil)
+	rec := postEvent(h, `{"project_key":"proj-a","payload":{"error":"second"}}`, nil)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d: %s", rec.Code, rec.Body.String())
+	}
+	secs, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil || secs < 3500 || secs > 3600 {
+		t.Errorf("Retry-After = %q, want about an hour", rec.Header().Get("Retry-After"))
+	}
+	if stats := h.RateLimitStats(); len(stats) != 1 || stats[0].Rejected != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestHandler_BatchRetryAfter(t *testing.T) {
+	h := newTestHandler(HandlerConfig{RateLimits: RateLimitConfig{Project: RateLimit{PerHour: 1, Burst: 1}}})
+	defer h.StopCleanup()
+
+	body := `{"version":"4","alerts":[` +
+		`{"status":"firing","labels":{"project":"proj-a","severity":"critical"},"fingerprint":"fp-1"},` +
+		`{"status":"firing","labels":{"project":"proj-a","severity":"critical"},"fingerprint":"fp-2"}]}`
+	rec := postEvent(http.HandlerFunc(h.ServeAlertmanager), body, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Accepted int              `json:"accepted"`
+		Results  []map[string]any `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+	if resp.Accepted != 1 || len(resp.Results) != 2 || resp.Results[1]["status"] != "error" {
+		t.Fatalf("unexpected response: %s", rec.Body.String())
+	}
+	// The rate-limited alert tells the reporter when to resend it.
+	secs, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil || secs < 3500 || secs > 3600 {
+		t.Errorf("Retry-After = %q, want about an hour", rec.Header().Get("Retry-After"))
+	}
+}
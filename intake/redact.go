@@ -0,0 +1,255 @@
+package intake
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// redactedValue replaces values of denied fields and matches of custom rules
+// that don't set their own replacement.
+const redactedValue = "[REDACTED]"
+
+// RedactConfig configures the payload redaction stage.
+type RedactConfig struct {
+	// Detectors lists the built-in detectors to enable: "email", "phone",
+	// "card", "token". Nil enables all of them.
+	Detectors []string
+	// Rules are additional regex replacements applied after the detectors.
+	Rules []RedactRule
+	// DenyFields are dotted payload paths whose values are replaced wholesale
+	// (objects and arrays included). A "*" segment matches any key or index.
+	DenyFields []string
+}
+
+// RedactRule is a custom regex redaction. Replace may reference capture
+// groups ($1); it defaults to "[REDACTED]".
+type RedactRule struct {
+	Name    string
+	Pattern string
+	Replace string
+}
+
+type redactPattern struct {
+	name    string
+	re      *regexp.Regexp
+	replace string
+	// check, if set, must accept the match for it to be replaced.
+	check func(string) bool
+}
+
+// builtinDetectors maps detector names to their patterns. Order within a
+// detector matters: more specific patterns come first.
+var builtinDetectors = map[string][]redactPattern{
+	"email": {
+		{re: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`), replace: "[EMAIL]"},
+	},
+	"phone": {
+		// E.164 international numbers
+		{re: regexp.MustCompile(`\+[1-9]\d{0,2}[- ]?\d{6,14}\b`), replace: "[PHONE]"},
+		// Mainland China mobile numbers, without a country code
+		{re: regexp.MustCompile(`\b1[3-9]\d{9}\b`), replace: "[PHONE]"},
+	},
+	"card": {
+		// 13-19 digit card numbers starting with a card network prefix,
+		// optionally grouped by spaces or dashes, validated with Luhn.
+		{re: regexp.MustCompile(`\b[3-6]\d{3}(?:[ -]?\d){9,15}\b`), replace: "[CARD]", check: luhnValid},
+	},
+	"token": {
+		{re: regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9\-._~+/]{8,}=*`), replace: "$1 [TOKEN]"},
+		{re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]+`), replace: "[TOKEN]"},
+		{re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), replace: "[TOKEN]"},
+		{re: regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`), replace: "[TOKEN]"},
+		{re: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`), replace: "[TOKEN]"},
+		{re: regexp.MustCompile(`\b(?:sk|pk|rk)_(?:live|test)_[A-Za-z0-9]{16,}\b`), replace: "[TOKEN]"},
+		{re: regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`), replace: "[TOKEN]"},
+		{re: regexp.MustCompile(`(?i)\b(password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key)(\s*[=:]\s*)[^\s&,;"']+`), replace: "$1$2[REDACTED]"},
+	},
+}
+
+// Redactor masks PII and secrets in event payloads and free text.
+// It is safe for concurrent use.
+type Redactor struct {
+	patterns   []redactPattern
+	denyFields [][]string
+}
+
+// NewRedactor compiles a Redactor from cfg.
+func NewRedactor(cfg RedactConfig) (*Redactor, error) {
+	detectors := cfg.Detectors
+	if detectors == nil {
+		detectors = []string{"email", "phone", "card", "token"}
+	}
+
+	r := &Redactor{}
+	// Tokens first: a JWT or key=value secret may contain an email-like or
+	// digit-heavy substring that another detector would only partially mask.
+	for _, name := range []string{"token", "card", "email", "phone"} {
+		if !containsString(detectors, name) {
+			continue
+		}
+		for _, p := range builtinDetectors[name] {
+			p.name = name
+			r.patterns = append(r.patterns, p)
+		}
+	}
+	for _, name := range detectors {
+		if _, ok := builtinDetectors[name]; !ok {
+			return nil, fmt.Errorf("unknown redaction detector %q", name)
+		}
+	}
+
+	for i, rule := range cfg.Rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redaction rule %d (%s): %w", i, rule.Name, err)
+		}
+		replace := rule.Replace
+		if replace == "" {
+			replace = redactedValue
+		}
+		r.patterns = append(r.patterns, redactPattern{name: rule.Name, re: re, replace: replace})
+	}
+
+	for _, path := range cfg.DenyFields {
+		if path = strings.TrimSpace(path); path != "" {
+			r.denyFields = append(r.denyFields, strings.Split(path, "."))
+		}
+	}
+	return r, nil
+}
+
+// RedactString masks every detector and rule match in s.
+func (r *Redactor) RedactString(s string) string {
+	out, _ := r.redactString(s)
+	return out
+}
+
+func (r *Redactor) redactString(s string) (string, int) {
+	n := 0
+	for _, p := range r.patterns {
+		s = p.re.ReplaceAllStringFunc(s, func(m string) string {
+			if p.check != nil && !p.check(m) {
+				return m
+			}
+			n++
+			return p.re.ReplaceAllString(m, p.replace)
+		})
+	}
+	return s, n
+}
+
+// RedactPayload masks denied fields and sensitive string values in a JSON
+// payload. It returns the original bytes unchanged when nothing matched, and
+// the number of values masked. Non-JSON payloads are treated as text.
+func (r *Redactor) RedactPayload(payload json.RawMessage) (json.RawMessage, int) {
+	if len(payload) == 0 {
+		return payload, 0
+	}
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		out, n := r.redactString(string(payload))
+		if n == 0 {
+			return payload, 0
+		}
+		return json.RawMessage(out), n
+	}
+
+	masked, n := r.walk(v, nil)
+	if n == 0 {
+		return payload, 0
+	}
+	out, err := json.Marshal(masked)
+	if err != nil {
+		return payload, 0
+	}
+	return out, n
+}
+
+func (r *Redactor) walk(v any, path []string) (any, int) {
+	if len(path) > 0 && r.denied(path) {
+		return redactedValue, 1
+	}
+	switch val := v.(type) {
+	case map[string]any:
+		total := 0
+		for k, child := range val {
+			masked, n := r.walk(child, append(path, k))
+			if n > 0 {
+				val[k] = masked
+				total += n
+			}
+		}
+		return val, total
+	case []any:
+		total := 0
+		for i, child := range val {
+			masked, n := r.walk(child, append(path, strconv.Itoa(i)))
+			if n > 0 {
+				val[i] = masked
+				total += n
+			}
+		}
+		return val, total
+	case string:
+		return r.redactString(val)
+	default:
+		return v, 0
+	}
+}
+
+func (r *Redactor) denied(path []string) bool {
+	for _, deny := range r.denyFields {
+		if len(deny) != len(path) {
+			continue
+		}
+		match := true
+		for i, seg := range deny {
+			if seg != "*" && seg != path[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum.
+func luhnValid(s string) bool {
+	sum, digits := 0, 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		digits++
+	}
+	return digits >= 13 && digits <= 19 && sum%10 == 0
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,356 @@
+package intake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"amp-sentinel/logger"
+)
+
+// sentryWebhook covers the Sentry webhook shapes we accept:
+//   - integration alerts: {"action": "...", "data": {"event": {...}}}
+//   - issue webhooks:     {"action": "...", "data": {"issue": {...}}}
+//   - legacy plugin:      {"project_slug": "...", "event": {...}, ...}
+//   - a bare event body forwarded as-is
+type sentryWebhook struct {
+	Action string `json:"action"`
+	Data   struct {
+		Event *sentryEvent `json:"event"`
+		Issue *sentryIssue `json:"issue"`
+	} `json:"data"`
+	Event       *sentryEvent `json:"event"`
+	Project     string       `json:"project"`
+	ProjectSlug string       `json:"project_slug"`
+	URL         string       `json:"url"`
+	sentryEvent
+}
+
+type sentryEvent struct {
+	EventID     string          `json:"event_id"`
+	Title       string          `json:"title"`
+	Message     string          `json:"message"`
+	Culprit     string          `json:"culprit"`
+	Level       string          `json:"level"`
+	Platform    string          `json:"platform"`
+	Environment string          `json:"environment"`
+	Release     string          `json:"release"`
+	Timestamp   json.RawMessage `json:"timestamp"`
+	WebURL      string          `json:"web_url"`
+	Tags        json.RawMessage `json:"tags"`
+	Logentry    struct {
+		Formatted string `json:"formatted"`
+		Message   string `json:"message"`
+	} `json:"logentry"`
+	Request struct {
+		URL    string `json:"url"`
+		Method string `json:"method"`
+	} `json:"request"`
+	Exception struct {
+		Values []sentryException `json:"values"`
+	} `json:"exception"`
+	Stacktrace *sentryStacktrace `json:"stacktrace"`
+}
+
+type sentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Module     string            `json:"module"`
+	Stacktrace *sentryStacktrace `json:"stacktrace"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Filename string `json:"filename"`
+	AbsPath  string `json:"abs_path"`
+	Module   string `json:"module"`
+	Function string `json:"function"`
+	Lineno   int    `json:"lineno"`
+	InApp    *bool  `json:"in_app"`
+}
+
+type sentryIssue struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Culprit   string `json:"culprit"`
+	Level     string `json:"level"`
+	Permalink string `json:"permalink"`
+	Metadata  struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"metadata"`
+	Project struct {
+		Slug string `json:"slug"`
+	} `json:"project"`
+}
+
+// sentryPayload is the normalized payload handed to diagnosis. Top-level
+// field names match the default title/dedup/display candidates.
+type sentryPayload struct {
+	Title       string            `json:"title,omitempty"`
+	ErrorType   string            `json:"error_type,omitempty"`
+	ErrorMsg    string            `json:"error_msg,omitempty"`
+	Culprit     string            `json:"culprit,omitempty"`
+	Level       string            `json:"level,omitempty"`
+	Platform    string            `json:"platform,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Timestamp   json.RawMessage   `json:"timestamp,omitempty"`
+	URL         string            `json:"url,omitempty"`
+	SentryURL   string            `json:"sentry_url,omitempty"`
+	EventID     string            `json:"sentry_event_id,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Stacktrace  *StackTrace       `json:"stacktrace,omitempty"`
+}
+
+// sentryLevelSeverity maps Sentry levels onto Sentinel severities.
+var sentryLevelSeverity = map[string]string{
+	"fatal":   "critical",
+	"error":   "warning",
+	"warning": "warning",
+	"info":    "info",
+	"debug":   "info",
+}
+
+// ServeSentry handles POST /api/v1/sentry (Sentry-compatible error webhooks).
+// The project comes from the ?project= query parameter, falling back to the
+// Sentry project slug; ?severity= overrides the level mapping.
+func (h *Handler) ServeSentry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cred, ok := h.checkAuth(w, r)
+	if !ok {
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, 1<<20)
+	rawBody, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var msg sentryWebhook
+	if err := json.Unmarshal(rawBody, &msg); err != nil {
+		http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p, slug := msg.normalize()
+	if p.ErrorType == "" && p.ErrorMsg == "" && p.Title == "" {
+		http.Error(w, "no sentry event or issue found in body", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	projectKey := q.Get("project")
+	if projectKey == "" {
+		projectKey = slug
+	}
+	severity := strings.ToLower(q.Get("severity"))
+	if severity == "" {
+		severity = sentryLevelSeverity[strings.ToLower(p.Level)]
+	}
+
+	payload, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, "encode payload: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	event := &RawEvent{
+		ProjectKey: projectKey,
+		Severity:   severity,
+		Payload:    json.RawMessage(payload),
+		Source:     "sentry",
+		Title:      sentryTitle(p),
+		Credential: cred,
+	}
+
+	frames := 0
+	if p.Stacktrace != nil {
+		frames = len(p.Stacktrace.Frames)
+	}
+	h.log.Debug("sentry.normalized",
+		logger.String("project", projectKey),
+		logger.String("type", p.ErrorType),
+		logger.Int("in_app_frames", frames),
+	)
+
+	h.processEvent(w, event)
+}
+
+// normalize picks the event (or issue) out of the webhook body and maps it
+// onto a sentryPayload. Also returns the Sentry project slug, if any.
+func (msg *sentryWebhook) normalize() (*sentryPayload, string) {
+	slug := msg.ProjectSlug
+	if slug == "" {
+		slug = msg.Project
+	}
+
+	ev := msg.Data.Event
+	if ev == nil {
+		ev = msg.Event
+	}
+	if ev == nil && (len(msg.Exception.Values) > 0 || msg.Message != "" || msg.Title != "") {
+		ev = &msg.sentryEvent
+	}
+
+	if ev == nil {
+		issue := msg.Data.Issue
+		if issue == nil {
+			return &sentryPayload{}, slug
+		}
+		if slug == "" {
+			slug = issue.Project.Slug
+		}
+		return &sentryPayload{
+			Title:     issue.Title,
+			ErrorType: issue.Metadata.Type,
+			ErrorMsg:  issue.Metadata.Value,
+			Culprit:   issue.Culprit,
+			Level:     issue.Level,
+			SentryURL: issue.Permalink,
+			Stacktrace: &StackTrace{
+				ExceptionType: issue.Metadata.Type,
+				Message:       issue.Metadata.Value,
+				Culprit:       issue.Culprit,
+				Frames:        []StackFrame{},
+			},
+		}, slug
+	}
+
+	p := &sentryPayload{
+		Title:       ev.Title,
+		Culprit:     ev.Culprit,
+		Level:       ev.Level,
+		Platform:    ev.Platform,
+		Environment: ev.Environment,
+		Release:     ev.Release,
+		Timestamp:   ev.Timestamp,
+		URL:         ev.Request.URL,
+		SentryURL:   ev.WebURL,
+		EventID:     ev.EventID,
+		Tags:        parseSentryTags(ev.Tags),
+	}
+	if p.SentryURL == "" {
+		p.SentryURL = msg.URL
+	}
+
+	// Sentry lists chained exceptions oldest first; the last one was raised.
+	var stack *sentryStacktrace
+	if n := len(ev.Exception.Values); n > 0 {
+		exc := ev.Exception.Values[n-1]
+		p.ErrorType = exc.Type
+		p.ErrorMsg = exc.Value
+		stack = exc.Stacktrace
+	}
+	if stack == nil {
+		stack = ev.Stacktrace
+	}
+	if p.ErrorMsg == "" {
+		p.ErrorMsg = firstNonEmpty(ev.Logentry.Formatted, ev.Message, ev.Logentry.Message)
+	}
+
+	p.Stacktrace = &StackTrace{
+		ExceptionType: p.ErrorType,
+		Message:       p.ErrorMsg,
+		Culprit:       p.Culprit,
+		Frames:        normalizeSentryFrames(stack),
+	}
+	return p, slug
+}
+
+// normalizeSentryFrames keeps in-app frames, innermost first. Sentry orders
+// frames outermost first, so the slice is walked backwards.
+func normalizeSentryFrames(st *sentryStacktrace) []StackFrame {
+	frames := []StackFrame{}
+	if st == nil {
+		return frames
+	}
+	for i := len(st.Frames) - 1; i >= 0 && len(frames) < maxStackFrames; i-- {
+		f := st.Frames[i]
+		if f.InApp == nil || !*f.InApp {
+			continue
+		}
+		file := f.Filename
+		if file == "" {
+			file = f.AbsPath
+		}
+		frames = append(frames, StackFrame{
+			File:     file,
+			Module:   f.Module,
+			Function: f.Function,
+			Line:     f.Lineno,
+			InApp:    true,
+		})
+	}
+	return frames
+}
+
+// parseSentryTags accepts the three tag encodings Sentry emits:
+// [["k","v"]], [{"key":"k","value":"v"}] and {"k":"v"}.
+func parseSentryTags(raw json.RawMessage) map[string]string {
+	if len(raw) == 0 {
+		return nil
+	}
+	tags := make(map[string]string)
+
+	var pairs [][]string
+	if json.Unmarshal(raw, &pairs) == nil {
+		for _, kv := range pairs {
+			if len(kv) == 2 {
+				tags[kv[0]] = kv[1]
+			}
+		}
+		return tags
+	}
+
+	var objs []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+	if json.Unmarshal(raw, &objs) == nil {
+		for _, kv := range objs {
+			tags[kv.Key] = kv.Value
+		}
+		return tags
+	}
+
+	if json.Unmarshal(raw, &tags) == nil {
+		return tags
+	}
+	return nil
+}
+
+// sentryTitle builds "Type: message" and falls back to the Sentry title.
+func sentryTitle(p *sentryPayload) string {
+	title := p.Title
+	if p.ErrorType != "" {
+		title = p.ErrorType
+		if p.ErrorMsg != "" {
+			title = fmt.Sprintf("%s: %s", p.ErrorType, p.ErrorMsg)
+		}
+	}
+	if title == "" {
+		title = p.ErrorMsg
+	}
+	return TruncateRunes(SanitizeDisplayText(title), 100)
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+package intake
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const sentryAlertBody = `{
+  "action": "triggered",
+  "data": {
+    "event": {
+      "event_id": "abc123",
+      "title": "ZeroDivisionError: division by zero",
+      "culprit": "orders.views in checkout",
+      "level": "error",
+      "platform": "python",
+      "environment": "production",
+      "web_url": "https://sentry.example.com/issues/1/events/abc123/",
+      "tags": [["release", "1.2.3"], ["env", "production"]],
+      "request": {"url": "https://shop.example.com/checkout", "method": "POST"},
+      "exception": {
+        "values": [
+          {
+            "type": "ZeroDivisionError",
+            "value": "division by zero",
+            "stacktrace": {
+              "frames": [
+                {"filename": "django/core/handlers/base.py", "function": "_get_response", "lineno": 181, "in_app": false},
+                {"filename": "orders/views.py", "module": "orders.views", "function": "checkout", "lineno": 42, "in_app": true},
+                {"filename": "orders/pricing.py", "module": "orders.pricing", "function": "unit_price", "lineno": 17, "in_app": true}
+              ]
+            }
+          }
+        ]
+      }
+    }
+  }
+}`
+
+func TestServeSentry_NormalizesEvent(t *testing.T) {
+	var submitted []*RawEvent
+	h := newCapturingTestHandler(HandlerConfig{RateLimit: 100}, &submitted)
+	defer h.StopCleanup()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sentry?project=proj-a", strings.NewReader(sentryAlertBody))
+	rec := httptest.NewRecorder()
+	h.ServeSentry(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(submitted) != 1 {
+		t.Fatalf("expected 1 submitted event, got %d", len(submitted))
+	}
+	ev := submitted[0]
+	if ev.Source != "sentry" {
+		t.Errorf("Source = %q, want sentry", ev.Source)
+	}
+	if ev.Severity != "warning" {
+		t.Errorf("Severity = %q, want warning (mapped from error)", ev.Severity)
+	}
+	if ev.Title != "ZeroDivisionError: division by zero" {
+		t.Errorf("Title = %q", ev.Title)
+	}
+
+	var p struct {
+		ErrorType  string            `json:"error_type"`
+		Tags       map[string]string `json:"tags"`
+		URL        string            `json:"url"`
+		Stacktrace StackTrace        `json:"stacktrace"`
+	}
+	if err := json.Unmarshal(ev.Payload, &p); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if p.ErrorType != "ZeroDivisionError" || p.URL != "https://shop.example.com/checkout" {
+		t.Errorf("unexpected payload fields: %+v", p)
+	}
+	if p.Tags["release"] != "1.2.3" {
+		t.Errorf("expected tags to be parsed, got %v", p.Tags)
+	}
+	st := p.Stacktrace
+	if st.Culprit != "orders.views in checkout" || st.ExceptionType != "ZeroDivisionError" {
+		t.Errorf("unexpected stacktrace header: %+v", st)
+	}
+	if len(st.Frames) != 2 {
+		t.Fatalf("expected 2 in-app frames, got %+v", st.Frames)
+	}
+	if st.Frames[0].Function != "unit_price" || st.Frames[0].Line != 17 {
+		t.Errorf("expected innermost frame first, got %+v", st.Frames[0])
+	}
+}
+
+func TestServeSentry_ProjectFromSlugAndIssue(t *testing.T) {
+	var submitted []*RawEvent
+	h := newCapturingTestHandler(HandlerConfig{RateLimit: 100}, &submitted)
+	defer h.StopCleanup()
+
+	body := `{"action":"created","data":{"issue":{"title":"KeyError: 'sku'","culprit":"cart.add","level":"fatal",
+		"metadata":{"type":"KeyError","value":"'sku'"},"project":{"slug":"proj-a"}}}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sentry", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeSentry(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(submitted) != 1 || submitted[0].ProjectKey != "proj-a" || submitted[0].Severity != "critical" {
+		t.Fatalf("unexpected submission: %+v", submitted)
+	}
+}
+
+func TestServeSentry_NoEvent(t *testing.T) {
+	h := newTestHandler(HandlerConfig{RateLimit: 100})
+	defer h.StopCleanup()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sentry?project=proj-a", strings.NewReader(`{"action":"resolved","data":{}}`))
+	rec := httptest.NewRecorder()
+	h.ServeSentry(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestComputeFingerprint_StackFrames(t *testing.T) {
+	defaults := []string{"error_msg", "error", "message", "msg"}
+	stack := `"stacktrace":{"exception_type":"ValueError","frames":[
+		{"module":"orders.pricing","function":"unit_price","line":%d,"in_app":true},
+		{"module":"orders.views","function":"checkout","line":42,"in_app":true}]}`
+
+	a := json.RawMessage(`{"error_msg":"bad value for order 1001",` + strings.Replace(stack, "%d", "17", 1) + `}`)
+	b := json.RawMessage(`{"error_msg":"bad value for order 2002",` + strings.Replace(stack, "%d", "19", 1) + `}`)
+	if ComputeFingerprint("p", a, nil, defaults) != ComputeFingerprint("p", b, nil, defaults) {
+		t.Error("same top frames should dedupe regardless of message and line numbers")
+	}
+
+	c := json.RawMessage(`{"error_msg":"bad value for order 1001","stacktrace":{"exception_type":"ValueError","frames":[
+		{"module":"orders.tax","function":"rate","in_app":true}]}}`)
+	if ComputeFingerprint("p", a, nil, defaults) == ComputeFingerprint("p", c, nil, defaults) {
+		t.Error("different top frames should not dedupe")
+	}
+
+	// A plain-string stacktrace keeps the message-based fingerprint.
+	d := json.RawMessage(`{"error_msg":"timeout","stacktrace":"at com.example..."}`)
+	e := json.RawMessage(`{"error_msg":"timeout"}`)
+	if ComputeFingerprint("p", d, nil, defaults) != ComputeFingerprint("p", e, nil, defaults) {
+		t.Error("string stacktrace should fall back to default fields")
+	}
+
+	// Explicit project fields still win over the stack.
+	cfg := &DedupConfig{Fields: []string{"error_msg"}}
+	if ComputeFingerprint("p", a, cfg, defaults) == ComputeFingerprint("p", b, cfg, defaults) {
+		t.Error("explicit dedup fields should take precedence over stack frames")
+	}
+}
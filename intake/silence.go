@@ -0,0 +1,184 @@
+package intake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"amp-sentinel/logger"
+)
+
+// Silence rule actions.
+const (
+	// SilenceDrop records the event as suppressed and does not diagnose it.
+	SilenceDrop = "drop"
+	// SilenceDowngrade lowers the event severity. If the new severity falls
+	// below the intake minimum the event is suppressed like SilenceDrop.
+	SilenceDowngrade = "downgrade"
+)
+
+// SilenceMatcher matches a payload field against a regular expression.
+// Field is a dotted path resolved the same way as dedup fields.
+type SilenceMatcher struct {
+	Field   string `json:"field"`
+	Pattern string `json:"pattern"`
+}
+
+// SilenceRule mutes or downgrades events until ExpiresAt. An event matches
+// when it belongs to ProjectKey (empty = any project) and every matcher matches.
+type SilenceRule struct {
+	ID         string
+	ProjectKey string
+	Matchers   []SilenceMatcher
+	Action     string
+	Severity   string
+	ExpiresAt  time.Time
+}
+
+// SilenceLoader returns the silence rules that are currently active.
+type SilenceLoader func() ([]SilenceRule, error)
+
+type compiledSilence struct {
+	rule     SilenceRule
+	patterns []*regexp.Regexp
+}
+
+// ValidateSilenceRule checks that a rule is well-formed and its patterns compile.
+func ValidateSilenceRule(rule SilenceRule) error {
+	_, err := compileSilence(rule)
+	return err
+}
+
+func compileSilence(rule SilenceRule) (*compiledSilence, error) {
+	if len(rule.Matchers) == 0 {
+		return nil, errors.New("at least one matcher is required")
+	}
+	switch rule.Action {
+	case SilenceDrop:
+	case SilenceDowngrade:
+		if !ValidSeverities[rule.Severity] {
+			return nil, fmt.Errorf("invalid severity for downgrade: %q", rule.Severity)
+		}
+	default:
+		return nil, fmt.Errorf("invalid action: %q (must be drop or downgrade)", rule.Action)
+	}
+	c := &compiledSilence{rule: rule, patterns: make([]*regexp.Regexp, len(rule.Matchers))}
+	for i, m := range rule.Matchers {
+		if m.Field == "" {
+			return nil, fmt.Errorf("matchers[%d]: field is required", i)
+		}
+		re, err := regexp.Compile(m.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("matchers[%d]: invalid pattern: %w", i, err)
+		}
+		c.patterns[i] = re
+	}
+	return c, nil
+}
+
+func (c *compiledSilence) matches(projectKey string, payload map[string]any) bool {
+	if c.rule.ProjectKey != "" && c.rule.ProjectKey != projectKey {
+		return false
+	}
+	for i, m := range c.rule.Matchers {
+		v := resolveField(payload, m.Field)
+		if v == nil {
+			return false
+		}
+		var s string
+		switch val := v.(type) {
+		case string:
+			s = val
+		default:
+			b, _ := json.Marshal(val)
+			s = string(b)
+		}
+		if !c.patterns[i].MatchString(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReloadSilences refreshes the silence rules from the configured loader.
+// Rules that fail to compile are skipped and logged.
+func (h *Handler) ReloadSilences() error {
+	if h.loadSilences == nil {
+		return nil
+	}
+	rules, err := h.loadSilences()
+	if err != nil {
+		return err
+	}
+	compiled := make([]*compiledSilence, 0, len(rules))
+	for _, rule := range rules {
+		c, err := compileSilence(rule)
+		if err != nil {
+			h.log.Warn("silence.invalid_rule", logger.String("rule_id", rule.ID), logger.Err(err))
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+	h.silences.Store(&compiled)
+	return nil
+}
+
+// matchSilence returns the first unexpired rule matching the event, or nil.
+func (h *Handler) matchSilence(event *RawEvent) *SilenceRule {
+	rules := h.silences.Load()
+	if rules == nil || len(*rules) == 0 {
+		return nil
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return nil
+	}
+	now := time.Now()
+	for _, c := range *rules {
+		if !c.rule.ExpiresAt.After(now) {
+			continue
+		}
+		if c.matches(event.ProjectKey, payload) {
+			rule := c.rule
+			return &rule
+		}
+	}
+	return nil
+}
+
+// applySilence checks the event against silence rules. It returns true if
+// the event is suppressed; a downgrade rule may instead lower its severity.
+func (h *Handler) applySilence(event *RawEvent) bool {
+	rule := h.matchSilence(event)
+	if rule == nil {
+		return false
+	}
+	event.SilenceRuleID = rule.ID
+
+	if rule.Action == SilenceDowngrade {
+		if SeverityPriority(rule.Severity) < SeverityPriority(event.Severity) {
+			event.Severity = rule.Severity
+		}
+		if h.meetsMinSeverity(event.Severity) {
+			h.log.Info("event.downgraded",
+				logger.String("project", event.ProjectKey),
+				logger.String("event_id", event.ID),
+				logger.String("rule_id", rule.ID),
+				logger.String("severity", event.Severity),
+			)
+			return false
+		}
+	}
+
+	h.log.Info("event.suppressed",
+		logger.String("project", event.ProjectKey),
+		logger.String("event_id", event.ID),
+		logger.String("rule_id", rule.ID),
+	)
+	if h.onSuppressed != nil {
+		h.onSuppressed(event)
+	}
+	return true
+}
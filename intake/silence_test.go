@@ -0,0 +1,136 @@
+package intake
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newSilenceTestHandler(rules []SilenceRule, submitted, suppressed *[]*RawEvent) *Handler {
+	return newCapturingTestHandler(HandlerConfig{
+		RateLimit:    100,
+		LoadSilences: func() ([]SilenceRule, error) { return rules, nil },
+		OnSuppressed: func(e *RawEvent) { *suppressed = append(*suppressed, e) },
+	}, submitted)
+}
+
+func TestSilence_Drop(t *testing.T) {
+	rules := []SilenceRule{{
+		ID:         "sil-1",
+		ProjectKey: "proj-a",
+		Matchers:   []SilenceMatcher{{Field: "error", Pattern: `^redis: connection pool timeout`}},
+		Action:     SilenceDrop,
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}}
+	var submitted, suppressed []*RawEvent
+	h := newSilenceTestHandler(rules, &submitted, &suppressed)
+	defer h.StopCleanup()
+
+	body := `{"project_key":"proj-a","payload":{"error":"redis: connection pool timeout after 3s"}}`
+	for i := 0; i < 2; i++ {
+		rec := postEvent(h, body, nil)
+		var resp map[string]any
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if rec.Code != http.StatusOK || resp["status"] != "suppressed" {
+			t.Fatalf("request %d: expected 200 suppressed, got %d %v", i, rec.Code, resp)
+		}
+	}
+	if len(submitted) != 0 {
+		t.Fatalf("expected no submissions, got %d", len(submitted))
+	}
+	// Every occurrence is recorded: silence runs before dedup.
+	if len(suppressed) != 2 || suppressed[0].SilenceRuleID != "sil-1" {
+		t.Fatalf("expected two suppressed events from sil-1, got %+v", suppressed)
+	}
+
+	// Other projects and non-matching payloads are unaffected.
+	postEvent(h, `{"project_key":"proj-b","payload":{"error":"redis: connection pool timeout"}}`, nil)
+	postEvent(h, `{"project_key":"proj-a","payload":{"error":"nil pointer"}}`, nil)
+	if len(submitted) != 2 {
+		t.Fatalf("expected 2 submissions, got %d", len(submitted))
+	}
+}
+
+func TestSilence_Downgrade(t *testing.T) {
+	rules := []SilenceRule{
+		{
+			ID:        "sil-warn",
+			Matchers:  []SilenceMatcher{{Field: "request.path", Pattern: `^/api/export`}},
+			Action:    SilenceDowngrade,
+			Severity:  "warning",
+			ExpiresAt: time.Now().Add(time.Hour),
+		},
+		{
+			ID:        "sil-info",
+			Matchers:  []SilenceMatcher{{Field: "error", Pattern: `timeout`}},
+			Action:    SilenceDowngrade,
+			Severity:  "info",
+			ExpiresAt: time.Now().Add(time.Hour),
+		},
+	}
+	var submitted, suppressed []*RawEvent
+	h := newSilenceTestHandler(rules, &submitted, &suppressed)
+	defer h.StopCleanup()
+
+	// Downgraded but still above min severity: diagnosed at the lower severity.
+	rec := postEvent(h, `{"project_key":"proj-a","severity":"critical","payload":{"error":"boom","request":{"path":"/api/export/csv"}}}`, nil)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(submitted) != 1 || submitted[0].Severity != "warning" || submitted[0].SilenceRuleID != "sil-warn" {
+		t.Fatalf("expected event downgraded to warning by sil-warn, got %+v", submitted)
+	}
+
+	// Downgraded below min severity: suppressed.
+	rec = postEvent(h, `{"project_key":"proj-a","severity":"critical","payload":{"error":"upstream timeout"}}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(suppressed) != 1 || suppressed[0].Severity != "info" || suppressed[0].SilenceRuleID != "sil-info" {
+		t.Fatalf("expected event suppressed as info by sil-info, got %+v", suppressed)
+	}
+}
+
+func TestSilence_Expired(t *testing.T) {
+	rules := []SilenceRule{{
+		ID:        "sil-old",
+		Matchers:  []SilenceMatcher{{Field: "error", Pattern: `.*`}},
+		Action:    SilenceDrop,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}}
+	var submitted, suppressed []*RawEvent
+	h := newSilenceTestHandler(rules, &submitted, &suppressed)
+	defer h.StopCleanup()
+
+	if rec := postEvent(h, `{"project_key":"proj-a","payload":{"error":"x"}}`, nil); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rec.Code)
+	}
+	if len(suppressed) != 0 {
+		t.Fatalf("expired rule should not suppress, got %d", len(suppressed))
+	}
+}
+
+func TestValidateSilenceRule(t *testing.T) {
+	match := []SilenceMatcher{{Field: "error", Pattern: "x"}}
+	tests := []struct {
+		name    string
+		rule    SilenceRule
+		wantErr bool
+	}{
+		{"drop", SilenceRule{Matchers: match, Action: SilenceDrop}, false},
+		{"downgrade", SilenceRule{Matchers: match, Action: SilenceDowngrade, Severity: "info"}, false},
+		{"no matchers", SilenceRule{Action: SilenceDrop}, true},
+		{"bad action", SilenceRule{Matchers: match, Action: "mute"}, true},
+		{"downgrade without severity", SilenceRule{Matchers: match, Action: SilenceDowngrade}, true},
+		{"empty field", SilenceRule{Matchers: []SilenceMatcher{{Pattern: "x"}}, Action: SilenceDrop}, true},
+		{"bad pattern", SilenceRule{Matchers: []SilenceMatcher{{Field: "error", Pattern: "("}}, Action: SilenceDrop}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateSilenceRule(tt.rule); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSilenceRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
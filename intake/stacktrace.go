@@ -0,0 +1,94 @@
+package intake
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// StackTrace is the normalized stack-trace block adapters place under the
+// "stacktrace" key of an event payload. Frames are ordered innermost first
+// (the frame that raised comes first).
+type StackTrace struct {
+	ExceptionType string       `json:"exception_type,omitempty"`
+	Message       string       `json:"message,omitempty"`
+	Culprit       string       `json:"culprit,omitempty"`
+	Frames        []StackFrame `json:"frames"`
+}
+
+// StackFrame is a single normalized stack frame.
+type StackFrame struct {
+	File     string `json:"file,omitempty"`
+	Module   string `json:"module,omitempty"`
+	Function string `json:"function,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	InApp    bool   `json:"in_app"`
+}
+
+const (
+	// stackFingerprintDepth is how many top in-app frames identify an error.
+	stackFingerprintDepth = 3
+	// maxStackFrames caps the frames kept in a normalized block.
+	maxStackFrames = 30
+)
+
+// hasStackTraceKey is a cheap pre-check so payloads without a stacktrace
+// block keep using the fast fingerprint path.
+func hasStackTraceKey(payload json.RawMessage) bool {
+	return bytes.Contains(payload, []byte(`"stacktrace"`))
+}
+
+// stackFingerprint hashes the exception type and the top in-app frames of a
+// normalized stacktrace block. Line numbers are left out so the fingerprint
+// survives unrelated edits to the same file. Returns false when the payload
+// has no usable block (e.g. "stacktrace" is a plain string).
+func stackFingerprint(m map[string]any) (string, bool) {
+	st, ok := m["stacktrace"].(map[string]any)
+	if !ok {
+		return "", false
+	}
+	frames, ok := st["frames"].([]any)
+	if !ok || len(frames) == 0 {
+		return "", false
+	}
+
+	h := sha256.New()
+	if t, ok := st["exception_type"].(string); ok {
+		h.Write([]byte("type="))
+		h.Write([]byte(t))
+		h.Write([]byte("|"))
+	}
+
+	used := 0
+	for _, f := range frames {
+		if used >= stackFingerprintDepth {
+			break
+		}
+		frame, ok := f.(map[string]any)
+		if !ok {
+			continue
+		}
+		if inApp, ok := frame["in_app"].(bool); ok && !inApp {
+			continue
+		}
+		loc, _ := frame["module"].(string)
+		if loc == "" {
+			loc, _ = frame["file"].(string)
+		}
+		fn, _ := frame["function"].(string)
+		if loc == "" && fn == "" {
+			continue
+		}
+		h.Write([]byte("frame="))
+		h.Write([]byte(loc))
+		h.Write([]byte(":"))
+		h.Write([]byte(fn))
+		h.Write([]byte("|"))
+		used++
+	}
+	if used == 0 {
+		return "", false
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8]), true
+}
@@ -18,8 +18,32 @@ type RawEvent struct {
 	Severity   string          `json:"severity"`
 	Title      string          `json:"title"`
 	ReceivedAt time.Time       `json:"received_at"`
+
+	// Fingerprint is an optional reporter-supplied identity for the event
+	// (e.g. Alertmanager's alert fingerprint). When set it replaces the
+	// payload-derived dedup fingerprint.
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	// Credential is the name of the intake credential that submitted the
+	// event ("default" for the shared auth token, empty when auth is off).
+	Credential string `json:"credential,omitempty"`
+
+	// SilenceRuleID is set when a silence rule suppressed or downgraded the event.
+	SilenceRuleID string `json:"silence_rule_id,omitempty"`
+
+	// GroupKey identifies events that belong to the same incident. It is the
+	// dedup key and is set by the handler before the event is submitted.
+	GroupKey string `json:"group_key,omitempty"`
+
+	// IdempotencyKey is the reporter's Idempotency-Key, if any. A repeated
+	// key returns the original event and task instead of submitting again.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
 }
 
+// SourceSchedule is the source of synthetic events enqueued by scheduled
+// proactive diagnoses rather than reported by an external system.
+const SourceSchedule = "schedule"
+
 // ValidSeverities is the set of accepted severity values.
 var ValidSeverities = map[string]bool{
 	"critical": true,
@@ -84,6 +108,19 @@ func ExtractTitle(payload json.RawMessage) string {
 	return ""
 }
 
+// ExtractField returns the first non-empty scalar of the payload at the
+// given dotted paths ("release", "tags.git_sha"), or "".
+func ExtractField(payload json.RawMessage, paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	var m map[string]any
+	if json.Unmarshal(payload, &m) != nil {
+		return ""
+	}
+	return strings.TrimSpace(findScalarField(m, paths))
+}
+
 // SanitizeDisplayText removes control characters and collapses whitespace,
 // producing a single-line string safe for Feishu card display.
 func SanitizeDisplayText(s string) string {
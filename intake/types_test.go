@@ -208,6 +208,24 @@ func TestResolveField(t *testing.T) {
 	}
 }
 
+func TestExtractField(t *testing.T) {
+	payload := json.RawMessage(`{"release":"","tags":{"git_sha":" a1b2c3d "},"build":{"number":1042}}`)
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{[]string{"release", "tags.git_sha"}, "a1b2c3d"},
+		{[]string{"build.number"}, "1042"},
+		{[]string{"missing"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := ExtractField(payload, tt.paths); got != tt.want {
+			t.Errorf("ExtractField(%v) = %q, want %q", tt.paths, got, tt.want)
+		}
+	}
+}
+
 func TestExtractDisplayFields(t *testing.T) {
 	tests := []struct {
 		name string
@@ -3,18 +3,23 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sync"
 	"syscall"
 	"time"
 
 	"amp-sentinel/amp"
 	"amp-sentinel/api"
+	"amp-sentinel/budget"
+	"amp-sentinel/cron"
 	"amp-sentinel/diagnosis"
+	"amp-sentinel/incident"
 	"amp-sentinel/intake"
 	"amp-sentinel/logger"
 	"amp-sentinel/notify"
@@ -118,7 +123,7 @@ func main() {
 	// Initialize components
 	ampClient := amp.NewClient(cfg.Amp.Binary, apiKey, log)
 	registry := project.NewRegistry(cfg.Projects)
-	sources := project.NewSourceManager(cfg.Source.BaseDir, cfg.Source.GitSSHKey, log)
+	sources := project.NewSourceManager(cfg.Source.BaseDir, cfg.Source.GitSSHKey, ParseDuration(cfg.Source.HistoryWindow, 0), log)
 
 	feishuNotifier := notify.NewFeishuNotifier(notify.FeishuConfig{
 		DefaultWebhook: cfg.Feishu.DefaultWebhook,
@@ -193,10 +198,28 @@ func main() {
 					report.QualityScore = qs
 				}
 			}
+			if len(storeReport.Review) > 0 {
+				var review diagnosis.Review
+				if err := json.Unmarshal(storeReport.Review, &review); err == nil {
+					report.Review = &review
+				}
+			}
 			return report, nil
 		}
 	}
 
+	// Redaction: payloads are masked once at intake, reports post-scrubbed
+	var redactor *intake.Redactor
+	var scrub func(string) string
+	if cfg.Intake.Redaction.Enabled {
+		redactor, err = intake.NewRedactor(cfg.Intake.Redaction.redactConfig())
+		if err != nil {
+			log.Error("redaction.init_failed", logger.Err(err))
+			os.Exit(1)
+		}
+		scrub = redactor.RedactString
+	}
+
 	engine := diagnosis.NewEngine(ampClient, sources, registry, skillMgr, log, diagnosis.EngineConfig{
 		Mode:             cfg.Amp.DefaultMode,
 		SkillDir:         cfg.Skill.Dir,
@@ -204,6 +227,7 @@ func main() {
 		StructuredOutput: cfg.Diagnosis.StructuredOutput,
 		JSONFixerEnabled: cfg.Diagnosis.JSONFixerEnabled,
 		PromptVersion:    cfg.Diagnosis.PromptVersion,
+		Scrub:            scrub,
 		FingerprintLookup: fpLookup,
 		FingerprintConfig: diagnosis.FingerprintConfig{
 			Enabled:            fpReuseEnabled,
@@ -211,6 +235,18 @@ func main() {
 			MinScore:           cfg.Diagnosis.FingerprintReuseMinScore,
 			DefaultDedupFields: cfg.Intake.Dedup.DefaultFields,
 		},
+		VersionFields: cfg.Diagnosis.Version.Fields,
+		RecentChanges: diagnosis.RecentChangesConfig{
+			Enabled:    cfg.Diagnosis.RecentChanges.Enabled,
+			Window:     ParseDuration(cfg.Diagnosis.RecentChanges.Window, 24*time.Hour),
+			MaxCommits: cfg.Diagnosis.RecentChanges.MaxCommits,
+		},
+		Review: diagnosis.ReviewConfig{
+			Enabled:    cfg.Diagnosis.Review.Enabled,
+			Mode:       cfg.Diagnosis.Review.Mode,
+			Timeout:    ParseDuration(cfg.Diagnosis.Review.Timeout, 5*time.Minute),
+			Severities: cfg.Diagnosis.Review.Severities,
+		},
 	})
 
 	// storeCtx creates an independent context for store writes that must
@@ -219,41 +255,40 @@ func main() {
 		return context.WithTimeout(context.Background(), 10*time.Second)
 	}
 
-	// Define the diagnosis function used by the scheduler
-	diagnoseFn := func(ctx context.Context, taskID string, event *intake.RawEvent) error {
-		// Persist task record (use independent context in case of retry —
-		// CreateTask may fail with duplicate key, fall back to UpdateTask)
-		now := time.Now()
-		storeTask := &store.DiagnosisTask{
-			ID:         taskID,
-			EventID:    event.ID,
-			ProjectKey: event.ProjectKey,
-			Status:     store.StatusRunning,
-			Priority:   intake.SeverityPriority(event.Severity),
-			CreatedAt:  now,
-			StartedAt:  &now,
-		}
-		sCtx, sCancel := storeCtx()
-		if createErr := dataStore.CreateTask(sCtx, storeTask); createErr != nil {
-			// Retry: task already exists from a previous attempt, update instead
-			if updateErr := dataStore.UpdateTask(sCtx, storeTask); updateErr != nil {
-				log.Error("store.create_task_failed", logger.Err(updateErr))
+	// Initialize token budgets. Usage is loaded from the store so budgets
+	// survive restarts and count tokens spent by other replicas.
+	var budgets *budget.Manager
+	if cfg.Budget.Enabled {
+		budgetCfg := cfg.budgetConfig()
+		budgetCfg.Source = dataStore
+		budgetCfg.Warn = func(w budget.Warning) {
+			var proj *project.Project
+			if w.ProjectKey != "" {
+				proj, _ = registry.Lookup(w.ProjectKey)
+			}
+			notifyCtx, notifyCancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer notifyCancel()
+			if err := feishuNotifier.NotifyBudget(notifyCtx, proj, w); err != nil {
+				log.Error("feishu.budget_failed", logger.String("project", w.ProjectKey), logger.Err(err))
 			}
 		}
-		sCancel()
+		budgets = budget.NewManager(budgetCfg, log)
+		budgets.Start()
+		defer budgets.Stop()
+	}
 
+	// Define the diagnosis function used by the scheduler.
+	// The scheduler persists the task lifecycle (status, retries, timestamps);
+	// the diagnosis function records the event status, report and usage.
+	diagnoseFn := func(ctx context.Context, taskID string, event *intake.RawEvent) error {
+		if scheduler.Degraded(ctx) {
+			ctx = diagnosis.WithMode(ctx, cfg.Budget.DegradeMode)
+		}
 		report, err := engine.Diagnose(ctx, event)
 		if err != nil {
-			// Update task as failed — use independent context because
-			// the diagnosis context may be cancelled (timeout).
-			finishedAt := time.Now()
-			storeTask.Status = store.StatusFailed
-			storeTask.Error = err.Error()
-			storeTask.FinishedAt = &finishedAt
+			// Use an independent context because the diagnosis context may
+			// be cancelled (timeout).
 			sCtx, sCancel := storeCtx()
-			if updateErr := dataStore.UpdateTask(sCtx, storeTask); updateErr != nil {
-				log.Error("store.update_task_failed", logger.Err(updateErr))
-			}
 			storeEvt, _ := dataStore.GetEvent(sCtx, event.ID)
 			if storeEvt != nil {
 				storeEvt.Status = "failed"
@@ -294,6 +329,9 @@ func main() {
 		if qsBytes, err := json.Marshal(report.QualityScore); err == nil {
 			storeReport.QualityScore = qsBytes
 		}
+		if report.Review != nil {
+			storeReport.Review, _ = json.Marshal(report.Review)
+		}
 
 		// Send Feishu notification with a separate context so it
 		// isn't cancelled by scheduler shutdown after diagnosis completes.
@@ -314,7 +352,7 @@ func main() {
 
 		// Save report and update task — use independent context because
 		// the diagnosis context may be cancelled by this point.
-		sCtx, sCancel = storeCtx()
+		sCtx, sCancel := storeCtx()
 		if saveErr := dataStore.SaveReport(sCtx, storeReport); saveErr != nil {
 			log.Error("store.save_report_failed", logger.Err(saveErr))
 		}
@@ -322,27 +360,38 @@ func main() {
 		// Update event status
 		storeEvt, _ := dataStore.GetEvent(sCtx, event.ID)
 		if storeEvt != nil {
-			storeEvt.Status = "completed"
+			// Keep "resolved" set by an Alertmanager resolve notification
+			// that arrived while the diagnosis was running.
+			if storeEvt.Status != "resolved" {
+				storeEvt.Status = "completed"
+			}
+			storeEvt.ReportID = storeReport.ID
+			if storeReport.Notified {
+				storeEvt.NotificationRef = notificationRef(storeReport)
+			}
 			if updateErr := dataStore.UpdateEvent(sCtx, storeEvt); updateErr != nil {
 				log.Error("store.update_event_status_failed", logger.Err(updateErr))
 			}
 		}
 
-		finishedAt := time.Now()
-		storeTask.Status = store.StatusCompleted
-		storeTask.SessionID = report.SessionID
-		storeTask.DurationMs = report.DurationMs
-		storeTask.NumTurns = report.NumTurns
-		storeTask.FinishedAt = &finishedAt
-		if report.Usage != nil {
-			storeTask.InputTokens = report.Usage.InputTokens
-			storeTask.OutputTokens = report.Usage.OutputTokens
-		}
-		if updateErr := dataStore.UpdateTask(sCtx, storeTask); updateErr != nil {
-			log.Error("store.update_task_failed", logger.Err(updateErr))
+		if storeTask, _ := dataStore.GetTask(sCtx, taskID); storeTask != nil {
+			storeTask.SessionID = report.SessionID
+			storeTask.DurationMs = report.DurationMs
+			storeTask.NumTurns = report.NumTurns
+			if report.Usage != nil {
+				storeTask.InputTokens = report.Usage.InputTokens
+				storeTask.OutputTokens = report.Usage.OutputTokens
+			}
+			if updateErr := dataStore.UpdateTask(sCtx, storeTask); updateErr != nil {
+				log.Error("store.update_task_failed", logger.Err(updateErr))
+			}
 		}
 		sCancel()
 
+		if budgets != nil && report.Usage != nil {
+			budgets.Record(report.ProjectKey, int64(report.Usage.InputTokens+report.Usage.OutputTokens))
+		}
+
 		log.Info("diagnosis.report",
 			logger.String("incident_id", event.ID),
 			logger.String("task_id", taskID),
@@ -356,15 +405,106 @@ func main() {
 	}
 
 	// Initialize scheduler
+	var admit func(projectKey, severity string) scheduler.Admission
+	if budgets != nil {
+		admit = func(projectKey, severity string) scheduler.Admission {
+			action, reason := budgets.Decide(projectKey, severity)
+			return scheduler.Admission{
+				Defer:   action == budget.ActionDefer,
+				Degrade: action == budget.ActionDegrade,
+				Reason:  reason,
+			}
+		}
+	}
+	tasks := &storeTasks{store: dataStore, log: log}
+	var leases scheduler.LeaseStore
+	if cfg.Scheduler.Lease.Enabled {
+		leases = tasks
+	}
 	sched := scheduler.New(scheduler.Config{
-		MaxConcurrency: cfg.Scheduler.MaxConcurrency,
-		QueueSize:      cfg.Scheduler.QueueSize,
-		DefaultTimeout: ParseDuration(cfg.Scheduler.DefaultTimeout, 15*time.Minute),
-		RetryCount:     cfg.Scheduler.RetryCount,
-		RetryDelay:     ParseDuration(cfg.Scheduler.RetryDelay, 10*time.Second),
+		MaxConcurrency:     cfg.Scheduler.MaxConcurrency,
+		QueueSize:          cfg.Scheduler.QueueSize,
+		DefaultTimeout:     ParseDuration(cfg.Scheduler.DefaultTimeout, 15*time.Minute),
+		RetryCount:         cfg.Scheduler.RetryCount,
+		RetryDelay:         ParseDuration(cfg.Scheduler.RetryDelay, 10*time.Second),
+		RetryMaxDelay:      ParseDuration(cfg.Scheduler.RetryMaxDelay, 5*time.Minute),
+		Store:              tasks,
+		ProjectConcurrency: cfg.Scheduler.ProjectConcurrency,
+		AgingInterval:      ParseDuration(cfg.Scheduler.AgingInterval, 0),
+		MaxQueueWait:       cfg.Scheduler.queueWaitLimits(),
+		ProjectPolicy: func(projectKey string) scheduler.ProjectPolicy {
+			proj, err := registry.Lookup(projectKey)
+			if err != nil {
+				return scheduler.ProjectPolicy{}
+			}
+			return scheduler.ProjectPolicy{
+				MaxConcurrency: proj.Scheduler.MaxConcurrency,
+				Weight:         proj.Scheduler.Weight,
+			}
+		},
+		Admit:         admit,
+		Leases:        leases,
+		ReplicaID:     cfg.Scheduler.Lease.ReplicaID,
+		LeaseDuration: ParseDuration(cfg.Scheduler.Lease.Duration, 30*time.Second),
+		PollInterval:  ParseDuration(cfg.Scheduler.Lease.PollInterval, 2*time.Second),
+		Fingerprint: func(event *intake.RawEvent) string {
+			// Scheduled sweeps are never duplicates of one another.
+			if event.Source == intake.SourceSchedule {
+				return ""
+			}
+			proj, err := registry.Lookup(event.ProjectKey)
+			if err != nil {
+				return ""
+			}
+			return diagnosis.ComputeDiagnosisFingerprint(proj.Key, event.Payload, proj.Dedup.Fields, cfg.Intake.Dedup.DefaultFields)
+		},
+		OnAttach:  tasks.attach,
+		OnSettle:  tasks.settle,
+		OnDiscard: tasks.discard,
 	}, diagnoseFn, log)
 	sched.Start()
 
+	// Scheduled proactive diagnoses enqueue synthetic events through the
+	// scheduler, so their reports are stored and notified like any other.
+	// Event IDs derive from the job and due time: replicas sharing a store
+	// enqueue each run once.
+	cronJobs, _ := cfg.cronJobs() // validated when the config was loaded
+	cronRunner := cron.NewRunner(cronJobs, func(run cron.Run) {
+		jobLog := log.WithFields(logger.String("project", run.Job.ProjectKey), logger.String("job", run.Job.Name))
+		event, err := run.Event()
+		if err != nil {
+			jobLog.Error("cron.render_failed", logger.Err(err))
+			return
+		}
+		ctx, cancel := storeCtx()
+		defer cancel()
+		if existing, _ := dataStore.GetEvent(ctx, event.ID); existing != nil {
+			jobLog.Info("cron.already_enqueued", logger.String("event_id", event.ID))
+			return
+		}
+		if err := dataStore.CreateEvent(ctx, &store.Event{
+			ID:         event.ID,
+			ProjectKey: event.ProjectKey,
+			Payload:    event.Payload,
+			Source:     event.Source,
+			Severity:   event.Severity,
+			Title:      event.Title,
+			Status:     "pending",
+			ReceivedAt: event.ReceivedAt,
+		}); err != nil {
+			// Most likely another replica enqueued the same run first.
+			jobLog.Warn("cron.create_event_failed", logger.String("event_id", event.ID), logger.Err(err))
+			return
+		}
+		taskID, err := sched.Submit(event)
+		if err != nil {
+			jobLog.Error("cron.submit_failed", logger.String("event_id", event.ID), logger.Err(err))
+			return
+		}
+		jobLog.Info("cron.enqueued", logger.String("event_id", event.ID), logger.String("task_id", taskID))
+	}, log)
+	cronRunner.Start()
+
 	// Resolve intake auth token
 	intakeToken := cfg.Intake.AuthToken
 	if intakeToken == "" {
@@ -387,34 +527,214 @@ func main() {
 		return cfg
 	}
 
+	// resolveEvent marks stored events for a resolved Alertmanager alert.
+	resolveEvent := func(projectKey, fingerprint string) (int, error) {
+		ctx, cancel := storeCtx()
+		defer cancel()
+		events, err := dataStore.ListEvents(ctx, store.EventFilter{
+			ProjectKey:  projectKey,
+			Fingerprint: fingerprint,
+			Limit:       100,
+		})
+		if err != nil {
+			return 0, err
+		}
+		resolved := 0
+		for _, evt := range events {
+			if evt.Status == "resolved" {
+				continue
+			}
+			evt.Status = "resolved"
+			if err := dataStore.UpdateEvent(ctx, evt); err != nil {
+				return resolved, err
+			}
+			resolved++
+			if evt.IncidentID == "" {
+				continue
+			}
+			if inc, err := dataStore.GetIncident(ctx, evt.IncidentID); err == nil && inc != nil && inc.Status == store.IncidentStatusOpen {
+				inc.Status = store.IncidentStatusResolved
+				if err := dataStore.UpdateIncident(ctx, inc); err != nil {
+					return resolved, err
+				}
+			}
+		}
+		return resolved, nil
+	}
+
+	credentials := make([]intake.Credential, 0, len(cfg.Intake.Credentials))
+	for _, c := range cfg.Intake.Credentials {
+		credentials = append(credentials, intake.Credential{
+			Name:             c.Name,
+			Secrets:          c.Secrets,
+			Projects:         c.Projects,
+			RequireSignature: c.RequireSignature,
+		})
+	}
+
+	loadSilences := func() ([]intake.SilenceRule, error) {
+		ctx, cancel := storeCtx()
+		defer cancel()
+		rules, err := dataStore.ListSilenceRules(ctx, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		out := make([]intake.SilenceRule, 0, len(rules))
+		for _, r := range rules {
+			matchers := make([]intake.SilenceMatcher, len(r.Matchers))
+			for i, m := range r.Matchers {
+				matchers[i] = intake.SilenceMatcher{Field: m.Field, Pattern: m.Pattern}
+			}
+			out = append(out, intake.SilenceRule{
+				ID:         r.ID,
+				ProjectKey: r.ProjectKey,
+				Matchers:   matchers,
+				Action:     r.Action,
+				Severity:   r.Severity,
+				ExpiresAt:  r.ExpiresAt,
+			})
+		}
+		return out, nil
+	}
+
+	recordSuppressed := func(event *intake.RawEvent) {
+		ctx, cancel := storeCtx()
+		defer cancel()
+		if err := dataStore.CreateEvent(ctx, &store.Event{
+			ID:            event.ID,
+			ProjectKey:    event.ProjectKey,
+			Payload:       event.Payload,
+			Source:        event.Source,
+			Severity:      event.Severity,
+			Title:         event.Title,
+			Status:        store.EventStatusSuppressed,
+			ReceivedAt:    event.ReceivedAt,
+			Fingerprint:   event.Fingerprint,
+			Credential:    event.Credential,
+			SilenceRuleID: event.SilenceRuleID,
+		}); err != nil {
+			log.Error("store.create_event_failed", logger.Err(err))
+		}
+	}
+
+	grouper := incident.NewGrouper(dataStore, ParseDuration(cfg.Intake.GroupWindow, 30*time.Minute))
+
+	// countDuplicate attributes deduplicated events to their open incident.
+	countDuplicate := func(event *intake.RawEvent) {
+		ctx, cancel := storeCtx()
+		defer cancel()
+		if err := grouper.Touch(ctx, event.ProjectKey, event.GroupKey, event.Severity, event.ReceivedAt); err != nil {
+			log.Warn("incident.touch_failed", logger.String("event_id", event.ID), logger.Err(err))
+		}
+	}
+
+	var deduper intake.Deduper
+	if cfg.Intake.Dedup.Backend == "store" {
+		deduper = intake.NewStoreDeduper(dataStore)
+	}
+
+	idempotency := &storeIdempotency{store: dataStore}
+
+	profiles := make([]intake.MappingProfile, 0, len(cfg.Intake.Profiles))
+	for _, p := range cfg.Intake.Profiles {
+		profiles = append(profiles, p.mappingProfile())
+	}
+
 	handler := intake.NewHandler(intake.HandlerConfig{
 		AuthToken:      intakeToken,
 		DedupWindow:    ParseDuration(cfg.Intake.Dedup.DefaultWindow, 10*time.Minute),
 		DedupFields:    cfg.Intake.Dedup.DefaultFields,
 		RateLimit:      cfg.Intake.RateLimit,
+		RateLimits:     cfg.Intake.RateLimits.rateLimitConfig(),
 		MinSeverity:    cfg.Intake.MinSeverity,
 		MaxPayloadSize: cfg.Intake.MaxPayloadSize,
+		Alertmanager: intake.AlertmanagerConfig{
+			ProjectLabel:   cfg.Intake.Alertmanager.ProjectLabel,
+			SeverityLabel:  cfg.Intake.Alertmanager.SeverityLabel,
+			DefaultProject: cfg.Intake.Alertmanager.DefaultProject,
+			SeverityMap:    cfg.Intake.Alertmanager.SeverityMap,
+		},
+		ResolveEvent: resolveEvent,
+		OTLP: intake.OTLPConfig{
+			MinSeverityNumber: cfg.Intake.OTLP.MinSeverityNumber,
+			ServiceProjects:   cfg.Intake.OTLP.ServiceProjects,
+			DefaultProject:    cfg.Intake.OTLP.DefaultProject,
+		},
+		CloudEvents: intake.CloudEventsConfig{
+			ProjectExtension:  cfg.Intake.CloudEvents.ProjectExtension,
+			SeverityExtension: cfg.Intake.CloudEvents.SeverityExtension,
+			DefaultProject:    cfg.Intake.CloudEvents.DefaultProject,
+			SeverityMap:       cfg.Intake.CloudEvents.SeverityMap,
+			TypeSeverity:      cfg.Intake.CloudEvents.TypeSeverity,
+		},
+		Credentials:       credentials,
+		SignatureWindow:   ParseDuration(cfg.Intake.SignatureWindow, 5*time.Minute),
+		Deduper:           deduper,
+		LoadSilences:      loadSilences,
+		OnSuppressed:      recordSuppressed,
+		OnDuplicate:       countDuplicate,
+		Redactor:          redactor,
+		Profiles:          profiles,
+		Idempotency:       idempotency,
+		IdempotencyWindow: ParseDuration(cfg.Intake.IdempotencyWindow, 24*time.Hour),
 	}, log, registry.Exists, func(event *intake.RawEvent) (string, error) {
 		storeEvt := &store.Event{
-			ID:         event.ID,
-			ProjectKey: event.ProjectKey,
-			Payload:    event.Payload,
-			Source:     event.Source,
-			Severity:   event.Severity,
-			Title:      event.Title,
-			Status:     "pending",
-			ReceivedAt: event.ReceivedAt,
+			ID:            event.ID,
+			ProjectKey:    event.ProjectKey,
+			Payload:       event.Payload,
+			Source:        event.Source,
+			Severity:      event.Severity,
+			Title:         event.Title,
+			Status:        "pending",
+			ReceivedAt:    event.ReceivedAt,
+			Fingerprint:   event.Fingerprint,
+			Credential:    event.Credential,
+			SilenceRuleID: event.SilenceRuleID,
 		}
 		evtCtx, evtCancel := storeCtx()
-		if createErr := dataStore.CreateEvent(evtCtx, storeEvt); createErr != nil {
-			log.Error("store.create_event_failed", logger.Err(createErr))
+		defer evtCancel()
+		inc, isNew, groupErr := grouper.Group(evtCtx, storeEvt, event.GroupKey)
+		if groupErr != nil {
+			// Grouping is best-effort: diagnose the event on its own.
+			log.Error("incident.group_failed", logger.String("event_id", event.ID), logger.Err(groupErr))
+			if existing, _ := dataStore.GetEvent(evtCtx, storeEvt.ID); existing == nil {
+				storeEvt.IncidentID = ""
+				if createErr := dataStore.CreateEvent(evtCtx, storeEvt); createErr != nil {
+					log.Error("store.create_event_failed", logger.Err(createErr))
+				}
+			}
+		} else if !isNew {
+			log.Info("incident.event_grouped",
+				logger.String("event_id", event.ID),
+				logger.String("incident_id", inc.ID),
+				logger.Int("event_count", inc.EventCount),
+			)
+			return inc.TaskID, fmt.Errorf("%w: attached to incident %s (task %s)", intake.ErrGrouped, inc.ID, inc.TaskID)
 		}
-		evtCancel()
 
 		taskID, err := sched.Submit(event)
+		if errors.Is(err, scheduler.ErrCoalesced) {
+			// The incident shares the task the event was attached to.
+			if inc != nil {
+				if setErr := grouper.SetTask(evtCtx, inc.ID, taskID); setErr != nil {
+					log.Warn("incident.set_task_failed", logger.String("incident_id", inc.ID), logger.Err(setErr))
+				}
+			}
+			return taskID, fmt.Errorf("%w: attached to task %s", intake.ErrCoalesced, taskID)
+		}
 		if err != nil {
+			if inc != nil {
+				if abandonErr := grouper.Abandon(evtCtx, inc.ID); abandonErr != nil {
+					log.Warn("incident.abandon_failed", logger.String("incident_id", inc.ID), logger.Err(abandonErr))
+				}
+			}
 			return "", err
 		}
+		if inc != nil {
+			if err := grouper.SetTask(evtCtx, inc.ID, taskID); err != nil {
+				log.Warn("incident.set_task_failed", logger.String("incident_id", inc.ID), logger.Err(err))
+			}
+		}
 		return taskID, nil
 	}, dedupConfig)
 
@@ -423,6 +743,10 @@ func main() {
 	mux.Handle("/api/v1/events", handler)
 	mux.HandleFunc("/api/v1/events/batch", handler.ServeBatch)
 	mux.HandleFunc("/api/v1/incidents", handler.ServeIncidentCompat)
+	mux.HandleFunc("/api/v1/alertmanager", handler.ServeAlertmanager)
+	mux.HandleFunc("/api/v1/sentry", handler.ServeSentry)
+	mux.HandleFunc("/v1/logs", handler.ServeOTLPLogs)
+	mux.HandleFunc("/api/v1/cloudevents", handler.ServeCloudEvents)
 	mux.HandleFunc("/api/v1/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		fmt.Fprintf(w, `{"status":"ok","projects":%d}`, registry.Len())
@@ -458,9 +782,26 @@ func main() {
 		if adminToken == "" {
 			adminToken = os.Getenv("ADMIN_API_TOKEN")
 		}
+		var budgetUsage func() []budget.Status
+		if budgets != nil {
+			budgetUsage = budgets.Usage
+		}
+		// Follow-up questions continue the diagnosis' Amp thread; their
+		// tokens count against the project budget.
+		ask := func(ctx context.Context, projectKey, sessionID, revision, question string) (*diagnosis.FollowUp, error) {
+			answer, err := engine.Ask(ctx, projectKey, sessionID, revision, question)
+			if err == nil && budgets != nil && answer.Usage != nil {
+				budgets.Record(projectKey, int64(answer.Usage.InputTokens+answer.Usage.OutputTokens))
+			}
+			return answer, err
+		}
 		adminAPI := api.NewServer(dataStore, registry, sched, log, func(event *intake.RawEvent) (string, error) {
-			return sched.Submit(event)
-		}, adminToken)
+			taskID, err := sched.Submit(event)
+			if errors.Is(err, scheduler.ErrCoalesced) {
+				return taskID, nil // the event takes the outcome of that task
+			}
+			return taskID, err
+		}, adminToken, handler.ReloadSilences, handler.RateLimitStats, budgetUsage, ask)
 		adminServer = &http.Server{
 			Addr:              cfg.AdminAPI.Listen,
 			Handler:           adminAPI.Handler(),
@@ -506,7 +847,368 @@ func main() {
 	}
 	server.Shutdown(ctx)
 	handler.StopCleanup()
+	cronRunner.Stop()
 	sched.Stop()
 
 	log.Info("sentinel.stopped")
 }
+
+// storeIdempotency adapts the store's idempotency keys to intake.IdempotencyStore.
+type storeIdempotency struct {
+	store store.Store
+}
+
+func (s *storeIdempotency) ctx() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 5*time.Second)
+}
+
+func (s *storeIdempotency) Claim(key, eventID string, ttl time.Duration) (*intake.IdempotencyRecord, error) {
+	ctx, cancel := s.ctx()
+	defer cancel()
+	now := time.Now()
+	existing, err := s.store.ClaimIdempotencyKey(ctx, &store.IdempotencyRecord{
+		Key:       key,
+		EventID:   eventID,
+		Status:    store.IdempotencyStatusPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil || existing == nil {
+		return nil, err
+	}
+	return &intake.IdempotencyRecord{EventID: existing.EventID, TaskID: existing.TaskID, Status: existing.Status}, nil
+}
+
+func (s *storeIdempotency) Complete(key, taskID, status string, window time.Duration) error {
+	ctx, cancel := s.ctx()
+	defer cancel()
+	return s.store.CompleteIdempotencyKey(ctx, key, taskID, status, time.Now().Add(window))
+}
+
+func (s *storeIdempotency) Release(key string) error {
+	ctx, cancel := s.ctx()
+	defer cancel()
+	return s.store.ReleaseIdempotencyKey(ctx, key)
+}
+
+func (s *storeIdempotency) Cleanup(now time.Time) {
+	ctx, cancel := s.ctx()
+	defer cancel()
+	_, _ = s.store.PurgeIdempotencyKeys(ctx, now)
+}
+
+// storeTasks persists the scheduler queue in the store. It also serves as
+// the shared queue of distributed mode.
+type storeTasks struct {
+	store store.Store
+	log   logger.Logger
+
+	mu sync.Mutex
+	// events caches the events of the last claimable tasks.
+	events map[string]*intake.RawEvent
+}
+
+func (s *storeTasks) SaveTask(task *scheduler.Task) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rec, err := s.store.GetTask(ctx, task.ID)
+	if err != nil {
+		return err
+	}
+	create := rec == nil
+	if create {
+		rec = &store.DiagnosisTask{
+			ID:         task.ID,
+			EventID:    task.Event.ID,
+			ProjectKey: task.Event.ProjectKey,
+			CreatedAt:  task.CreatedAt,
+		}
+	}
+	rec.Status = store.TaskStatus(task.Status)
+	rec.Priority = task.Priority
+	rec.RetryCount = task.RetryCount
+	rec.Error = task.Error
+	rec.StartedAt = optionalTime(task.StartedAt)
+	rec.FinishedAt = optionalTime(task.FinishedAt)
+	rec.Actions = make([]store.TaskAction, len(task.Actions))
+	for i, a := range task.Actions {
+		rec.Actions[i] = store.TaskAction{Action: a.Action, By: a.By, Reason: a.Reason, Detail: a.Detail, At: a.At}
+	}
+	rec.FailureClass = task.FailureClass
+	rec.Attempts = make([]store.TaskAttempt, len(task.Attempts))
+	for i, a := range task.Attempts {
+		rec.Attempts[i] = store.TaskAttempt{
+			Number:     a.Number,
+			Class:      a.Class,
+			Error:      a.Error,
+			StartedAt:  a.StartedAt,
+			FinishedAt: a.FinishedAt,
+			BackoffMs:  a.Backoff.Milliseconds(),
+		}
+	}
+	if create {
+		return s.store.CreateTask(ctx, rec)
+	}
+	return s.store.UpdateTask(ctx, rec)
+}
+
+func (s *storeTasks) LoadUnfinished() ([]*scheduler.Task, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var tasks []*scheduler.Task
+	for _, status := range []store.TaskStatus{store.StatusPending, store.StatusQueued, store.StatusRunning} {
+		for offset := 0; ; offset += 500 {
+			recs, err := s.store.ListTasks(ctx, store.TaskFilter{Status: status, Limit: 500, Offset: offset})
+			if err != nil {
+				return nil, err
+			}
+			for _, rec := range recs {
+				task := s.toTask(ctx, rec)
+				task.Attached = s.attached(ctx, rec.ID)
+				tasks = append(tasks, task)
+			}
+			if len(recs) < 500 {
+				break
+			}
+		}
+	}
+	return tasks, nil
+}
+
+// toTask rebuilds a queued task and its event from the store.
+func (s *storeTasks) toTask(ctx context.Context, rec *store.DiagnosisTask) *scheduler.Task {
+	task := &scheduler.Task{
+		ID:         rec.ID,
+		Priority:   rec.Priority,
+		Status:     scheduler.TaskStatus(rec.Status),
+		RetryCount: rec.RetryCount,
+		CreatedAt:  rec.CreatedAt,
+		Error:      rec.Error,
+	}
+	if rec.StartedAt != nil {
+		task.StartedAt = *rec.StartedAt
+	}
+	for _, a := range rec.Actions {
+		task.Actions = append(task.Actions, scheduler.TaskAction{Action: a.Action, By: a.By, Reason: a.Reason, Detail: a.Detail, At: a.At})
+	}
+	task.FailureClass = rec.FailureClass
+	task.Attempts = taskAttempts(rec.Attempts)
+	task.Event = s.event(ctx, rec)
+	return task
+}
+
+// taskAttempts converts stored attempts; the last one's backoff tells the
+// scheduler when the task may run again.
+func taskAttempts(recs []store.TaskAttempt) []scheduler.Attempt {
+	var attempts []scheduler.Attempt
+	for _, a := range recs {
+		attempts = append(attempts, scheduler.Attempt{
+			Number:     a.Number,
+			Class:      a.Class,
+			Error:      a.Error,
+			StartedAt:  a.StartedAt,
+			FinishedAt: a.FinishedAt,
+			Backoff:    time.Duration(a.BackoffMs) * time.Millisecond,
+		})
+	}
+	return attempts
+}
+
+// event loads a task's event, or returns nil if it is gone.
+func (s *storeTasks) event(ctx context.Context, rec *store.DiagnosisTask) *intake.RawEvent {
+	evt, err := s.store.GetEvent(ctx, rec.EventID)
+	if err != nil || evt == nil {
+		s.log.Warn("scheduler.recover_event_missing", logger.String("task_id", rec.ID), logger.String("event_id", rec.EventID))
+		return nil
+	}
+	return &intake.RawEvent{
+		ID:            evt.ID,
+		ProjectKey:    evt.ProjectKey,
+		Payload:       evt.Payload,
+		Source:        evt.Source,
+		Severity:      evt.Severity,
+		Title:         evt.Title,
+		ReceivedAt:    evt.ReceivedAt,
+		Fingerprint:   evt.Fingerprint,
+		Credential:    evt.Credential,
+		SilenceRuleID: evt.SilenceRuleID,
+	}
+}
+
+// Claimable lists tasks free to lease. Idle replicas poll it, so events of
+// tasks seen on the previous poll are reused rather than reloaded.
+func (s *storeTasks) Claimable(now time.Time) ([]*scheduler.Task, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	recs, err := s.store.ListClaimableTasks(ctx, now, 100)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := make(map[string]*intake.RawEvent, len(recs))
+	tasks := make([]*scheduler.Task, 0, len(recs))
+	for _, rec := range recs {
+		evt, ok := s.events[rec.EventID]
+		if !ok {
+			evt = s.event(ctx, rec)
+		}
+		if evt != nil {
+			events[rec.EventID] = evt
+		}
+		// Only what ranking needs, attempts included so tasks backing off
+		// are skipped: the lease holder reloads the task.
+		tasks = append(tasks, &scheduler.Task{
+			ID:        rec.ID,
+			Event:     evt,
+			Priority:  rec.Priority,
+			Status:    scheduler.TaskStatus(rec.Status),
+			CreatedAt: rec.CreatedAt,
+			Attempts:  taskAttempts(rec.Attempts),
+		})
+	}
+	s.events = events
+	return tasks, nil
+}
+
+func (s *storeTasks) Leased(now time.Time) (map[string]int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return s.store.CountLeasedTasks(ctx, now)
+}
+
+func (s *storeTasks) Load(taskID string) (*scheduler.Task, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	rec, err := s.store.GetTask(ctx, taskID)
+	if err != nil || rec == nil {
+		return nil, err
+	}
+	return s.toTask(ctx, rec), nil
+}
+
+func (s *storeTasks) Acquire(taskID, owner string, now, until time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.store.AcquireTaskLease(ctx, taskID, owner, now, until)
+}
+
+func (s *storeTasks) Renew(taskID, owner string, until time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.store.RenewTaskLease(ctx, taskID, owner, until)
+}
+
+func (s *storeTasks) Release(taskID, owner string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.store.ReleaseTaskLease(ctx, taskID, owner)
+}
+
+// attach records an event coalesced into task; the event stays
+// "coalesced" until the task settles.
+func (s *storeTasks) attach(task *scheduler.Task, event *intake.RawEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	evt, err := s.store.GetEvent(ctx, event.ID)
+	if err != nil {
+		return err
+	}
+	if evt == nil {
+		return fmt.Errorf("event %s not found", event.ID)
+	}
+	evt.Status = store.EventStatusCoalesced
+	evt.TaskID = task.ID
+	return s.store.UpdateEvent(ctx, evt)
+}
+
+// attached lists the events coalesced into a task that has not settled.
+func (s *storeTasks) attached(ctx context.Context, taskID string) []string {
+	evts, err := s.store.ListEvents(ctx, store.EventFilter{TaskID: taskID, Status: store.EventStatusCoalesced, Limit: 1000})
+	if err != nil {
+		s.log.Warn("scheduler.recover_attached_failed", logger.String("task_id", taskID), logger.Err(err))
+		return nil
+	}
+	ids := make([]string, 0, len(evts))
+	for _, evt := range evts {
+		ids = append(ids, evt.ID)
+	}
+	return ids
+}
+
+// settle gives the events coalesced into a finished task the task's
+// outcome: its status, its report and the notification sent for it.
+func (s *storeTasks) settle(task *scheduler.Task) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	status := eventStatus(task)
+	report, err := s.store.GetReport(ctx, task.ID)
+	if err != nil {
+		s.log.Warn("store.get_report_failed", logger.String("task_id", task.ID), logger.Err(err))
+	}
+	for _, id := range task.Attached {
+		evt, err := s.store.GetEvent(ctx, id)
+		if err != nil || evt == nil {
+			s.log.Warn("task.settle_event_missing", logger.String("task_id", task.ID), logger.String("event_id", id), logger.Err(err))
+			continue
+		}
+		if evt.Status != "resolved" {
+			evt.Status = status
+		}
+		if report != nil {
+			evt.ReportID = report.ID
+			if report.Notified {
+				evt.NotificationRef = notificationRef(report)
+			}
+		}
+		if err := s.store.UpdateEvent(ctx, evt); err != nil {
+			s.log.Error("store.update_event_status_failed", logger.String("event_id", id), logger.Err(err))
+		}
+	}
+	s.log.Info("task.coalesced_settled",
+		logger.String("task_id", task.ID),
+		logger.String("status", status),
+		logger.Int("events", len(task.Attached)),
+	)
+}
+
+// discard records the outcome of a task that finished without a diagnosis
+// (expired in the queue or cancelled) on the task's own event.
+func (s *storeTasks) discard(task *scheduler.Task) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	evt, err := s.store.GetEvent(ctx, task.Event.ID)
+	if err != nil || evt == nil {
+		s.log.Warn("task.discard_event_missing", logger.String("task_id", task.ID), logger.String("event_id", task.Event.ID), logger.Err(err))
+		return
+	}
+	if evt.Status == "resolved" {
+		return
+	}
+	evt.Status = eventStatus(task)
+	if err := s.store.UpdateEvent(ctx, evt); err != nil {
+		s.log.Error("store.update_event_status_failed", logger.String("event_id", evt.ID), logger.Err(err))
+	}
+}
+
+// eventStatus is the event status matching a finished task: "completed",
+// "expired", "cancelled", or "failed" for anything else.
+func eventStatus(task *scheduler.Task) string {
+	switch task.Status {
+	case scheduler.StatusCompleted, scheduler.StatusExpired, scheduler.StatusCancelled:
+		return string(task.Status)
+	}
+	return "failed"
+}
+
+// notificationRef identifies the notification sent for a report.
+func notificationRef(report *store.DiagnosisReport) string {
+	return "feishu:" + report.ID
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"amp-sentinel/amp"
+	"amp-sentinel/intake"
+	"amp-sentinel/logger"
+	"amp-sentinel/scheduler"
+	"amp-sentinel/store"
+)
+
+// newReplica opens the shared SQLite file as a separate replica would.
+func newReplica(t *testing.T, dbPath, id string, diagnose scheduler.DiagnoseFunc) (*scheduler.Scheduler, store.Store) {
+	t.Helper()
+	st, err := store.NewSQLiteStore(dbPath, logger.Nop())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	tasks := &storeTasks{store: st, log: logger.Nop()}
+	sched := scheduler.New(scheduler.Config{
+		MaxConcurrency: 3,
+		RetryCount:     1,
+		RetryDelay:     10 * time.Millisecond,
+		Leases:         tasks,
+		ReplicaID:      id,
+		LeaseDuration:  600 * time.Millisecond,
+		PollInterval:   20 * time.Millisecond,
+		OnDiscard:      tasks.discard,
+	}, diagnose, logger.Nop())
+	return sched, st
+}
+
+func createEvent(t *testing.T, st store.Store, id, projectKey string) *intake.RawEvent {
+	t.Helper()
+	evt := &store.Event{ID: id, ProjectKey: projectKey, Payload: []byte(`{}`), Source: "test", Severity: "warning", Status: "pending", ReceivedAt: time.Now()}
+	if err := st.CreateEvent(context.Background(), evt); err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	return &intake.RawEvent{ID: id, ProjectKey: projectKey, Severity: "warning", ReceivedAt: evt.ReceivedAt}
+}
+
+func waitUntil(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLeases_TwoReplicasShareQueue(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "shared.db")
+
+	var mu sync.Mutex
+	runs := make(map[string]int)
+	byReplica := make(map[string]int)
+	running := make(map[string]int) // per project, across replicas
+	overCap := false
+	diagnoseAs := func(replica string) scheduler.DiagnoseFunc {
+		return func(ctx context.Context, taskID string, event *intake.RawEvent) error {
+			mu.Lock()
+			runs[taskID]++
+			byReplica[replica]++
+			running[event.ProjectKey]++
+			if running[event.ProjectKey] > 1 {
+				overCap = true
+			}
+			mu.Unlock()
+			time.Sleep(15 * time.Millisecond)
+			mu.Lock()
+			running[event.ProjectKey]--
+			mu.Unlock()
+			return nil
+		}
+	}
+
+	a, stA := newReplica(t, dbPath, "replica-a", diagnoseAs("replica-a"))
+	b, _ := newReplica(t, dbPath, "replica-b", diagnoseAs("replica-b"))
+	a.Start()
+	b.Start()
+
+	const n = 24
+	var ids []string
+	for i := 0; i < n; i++ {
+		evt := createEvent(t, stA, fmt.Sprintf("evt-%d", i), fmt.Sprintf("proj-%d", i%4))
+		submit := a.Submit
+		if i%2 == 1 {
+			submit = b.Submit
+		}
+		id, err := submit(evt)
+		if err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	waitUntil(t, 10*time.Second, func() bool {
+		counts, err := stA.CountByStatus(context.Background())
+		return err == nil && counts[store.StatusCompleted] == n
+	})
+	a.Stop()
+	b.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, id := range ids {
+		if runs[id] != 1 {
+			t.Errorf("task %s ran %d times, want once", id, runs[id])
+		}
+	}
+	if overCap {
+		t.Error("a project ran more than one task at once across replicas")
+	}
+	if byReplica["replica-a"] == 0 || byReplica["replica-b"] == 0 {
+		t.Errorf("work not shared between replicas: %v", byReplica)
+	}
+	rec, err := stA.GetTask(context.Background(), ids[0])
+	if err != nil || rec == nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+	if rec.LeaseOwner != "" || rec.LeaseExpiresAt != nil {
+		t.Errorf("finished task still leased: owner=%q until=%v", rec.LeaseOwner, rec.LeaseExpiresAt)
+	}
+}
+
+func TestLeases_TakeOverExpiredLease(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "shared.db")
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var ranAt time.Time
+	b, st := newReplica(t, dbPath, "replica-b", func(ctx context.Context, taskID string, event *intake.RawEvent) error {
+		mu.Lock()
+		ranAt = time.Now()
+		mu.Unlock()
+		return nil
+	})
+
+	// A replica that died mid-diagnosis: the task is running under its lease.
+	evt := createEvent(t, st, "evt-1", "proj-a")
+	now := time.Now()
+	if err := st.CreateTask(ctx, &store.DiagnosisTask{ID: "task-1", EventID: evt.ID, ProjectKey: "proj-a", Status: store.StatusRunning, Priority: 50, CreatedAt: now}); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	expires := now.Add(300 * time.Millisecond)
+	if ok, err := st.AcquireTaskLease(ctx, "task-1", "replica-a", now, expires); err != nil || !ok {
+		t.Fatalf("AcquireTaskLease = %v, %v", ok, err)
+	}
+
+	b.Start()
+	defer b.Stop()
+
+	// Admin actions on a task another replica holds are refused.
+	if _, err := b.Cancel("task-1", "admin", ""); err != scheduler.ErrTaskLeased {
+		t.Errorf("Cancel of leased task: err = %v, want ErrTaskLeased", err)
+	}
+
+	waitUntil(t, 5*time.Second, func() bool {
+		rec, err := st.GetTask(ctx, "task-1")
+		return err == nil && rec != nil && rec.Status == store.StatusCompleted && b.Stats()["running"] == int32(0)
+	})
+	mu.Lock()
+	if ranAt.Before(expires) {
+		t.Errorf("task taken over at %v, before the lease expired at %v", ranAt, expires)
+	}
+	mu.Unlock()
+
+	rec, _ := st.GetTask(ctx, "task-1")
+	if rec.RetryCount != 1 {
+		t.Errorf("retry_count = %d, want 1: the dead replica's attempt counts", rec.RetryCount)
+	}
+	if _, err := b.Cancel("task-1", "admin", ""); err != scheduler.ErrTaskNotActive {
+		t.Errorf("Cancel of finished task: err = %v, want ErrTaskNotActive", err)
+	}
+}
+
+func TestLeases_RetryWaitsOutBackoff(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "shared.db")
+	var mu sync.Mutex
+	var calls []time.Time
+	a, st := newReplica(t, dbPath, "replica-a", func(ctx context.Context, taskID string, event *intake.RawEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, time.Now())
+		if len(calls) == 1 {
+			return &amp.Error{Class: amp.ClassRateLimited, Hint: 300 * time.Millisecond, Err: fmt.Errorf("rate limited")}
+		}
+		return nil
+	})
+	a.Start()
+	defer a.Stop()
+	if _, err := a.Submit(createEvent(t, st, "evt-1", "proj-a")); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	waitUntil(t, 5*time.Second, func() bool {
+		counts, err := st.CountByStatus(context.Background())
+		return err == nil && counts[store.StatusCompleted] == 1
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 2 || calls[1].Sub(calls[0]) < 300*time.Millisecond {
+		t.Errorf("retry claimed before its backoff ended: %v", calls)
+	}
+}
+
+func TestLeases_CancelQueuedTask(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "shared.db")
+	a, st := newReplica(t, dbPath, "replica-a", func(ctx context.Context, taskID string, event *intake.RawEvent) error {
+		return nil
+	})
+	// Not started: the task stays queued in the store.
+	id, err := a.Submit(createEvent(t, st, "evt-1", "proj-a"))
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if err := a.SetPriority(id, 100, "admin", "urgent"); err != nil {
+		t.Fatalf("SetPriority: %v", err)
+	}
+	status, err := a.Cancel(id, "admin", "duplicate")
+	if err != nil || status != scheduler.StatusCancelled {
+		t.Fatalf("Cancel = %s, %v", status, err)
+	}
+	rec, err := st.GetTask(context.Background(), id)
+	if err != nil || rec == nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+	if rec.Status != store.StatusCancelled || rec.Priority != 100 || len(rec.Actions) != 2 || rec.LeaseOwner != "" {
+		t.Errorf("task = status %s priority %d actions %d owner %q", rec.Status, rec.Priority, len(rec.Actions), rec.LeaseOwner)
+	}
+	if evt, _ := st.GetEvent(context.Background(), "evt-1"); evt == nil || evt.Status != "cancelled" {
+		t.Errorf("event = %+v, want status cancelled", evt)
+	}
+}
+
+func TestStoreTasks_SettleCoalescedEvents(t *testing.T) {
+	ctx := context.Background()
+	st, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "sentinel.db"), logger.Nop())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	defer st.Close()
+	tasks := &storeTasks{store: st, log: logger.Nop()}
+
+	createEvent(t, st, "evt-1", "proj-a")
+	attachedEvt := createEvent(t, st, "evt-2", "proj-a")
+	task := &scheduler.Task{ID: "task-1", Event: &intake.RawEvent{ID: "evt-1", ProjectKey: "proj-a"}, Status: scheduler.StatusQueued, CreatedAt: time.Now()}
+	if err := tasks.SaveTask(task); err != nil {
+		t.Fatalf("SaveTask: %v", err)
+	}
+	if err := tasks.attach(task, attachedEvt); err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	if got := tasks.attached(ctx, "task-1"); len(got) != 1 || got[0] != "evt-2" {
+		t.Fatalf("attached = %v, want [evt-2]", got)
+	}
+
+	if err := st.SaveReport(ctx, &store.DiagnosisReport{ID: "rpt-task-1", TaskID: "task-1", EventID: "evt-1", ProjectKey: "proj-a", Notified: true, DiagnosedAt: time.Now()}); err != nil {
+		t.Fatalf("SaveReport: %v", err)
+	}
+	task.Status = scheduler.StatusCompleted
+	task.Attached = []string{"evt-2"}
+	tasks.settle(task)
+
+	evt, err := st.GetEvent(ctx, "evt-2")
+	if err != nil || evt == nil {
+		t.Fatalf("GetEvent: %v", err)
+	}
+	if evt.Status != "completed" || evt.TaskID != "task-1" || evt.ReportID != "rpt-task-1" || evt.NotificationRef != "feishu:rpt-task-1" {
+		t.Errorf("event = status %s task %s report %s notification %q", evt.Status, evt.TaskID, evt.ReportID, evt.NotificationRef)
+	}
+	if got := tasks.attached(ctx, "task-1"); len(got) != 0 {
+		t.Errorf("settled task still has attached events %v", got)
+	}
+}
+
+func TestStoreTasks_DiscardExpiredTask(t *testing.T) {
+	ctx := context.Background()
+	st, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "sentinel.db"), logger.Nop())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	defer st.Close()
+	tasks := &storeTasks{store: st, log: logger.Nop()}
+
+	evt := createEvent(t, st, "evt-1", "proj-a")
+	createEvent(t, st, "evt-2", "proj-a")
+	task := &scheduler.Task{ID: "task-1", Event: evt, Status: scheduler.StatusExpired, Attached: []string{"evt-2"}, CreatedAt: time.Now()}
+	tasks.discard(task)
+	tasks.settle(task)
+
+	for _, id := range []string{"evt-1", "evt-2"} {
+		if got, _ := st.GetEvent(ctx, id); got == nil || got.Status != "expired" {
+			t.Errorf("%s = %+v, want status expired", id, got)
+		}
+	}
+}
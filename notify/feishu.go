@@ -14,6 +14,7 @@ import (
 	"strings"
 	"time"
 
+	"amp-sentinel/budget"
 	"amp-sentinel/diagnosis"
 	"amp-sentinel/intake"
 	"amp-sentinel/logger"
@@ -70,6 +71,39 @@ func (f *FeishuNotifier) Notify(ctx context.Context, proj *project.Project, even
 	}
 
 	card := f.buildCard(proj, event, report)
+	if err := f.send(ctx, webhook, card); err != nil {
+		return err
+	}
+	f.log.Info("feishu.sent",
+		logger.String("project", proj.Key),
+		logger.String("event_id", event.ID),
+	)
+	return nil
+}
+
+// NotifyBudget warns that token usage crossed a budget threshold. proj is
+// nil for the global budget, which goes to the default webhook.
+func (f *FeishuNotifier) NotifyBudget(ctx context.Context, proj *project.Project, w budget.Warning) error {
+	webhook := f.defaultWebhook
+	if proj != nil && proj.FeishuWebhook != "" {
+		webhook = proj.FeishuWebhook
+	}
+	if webhook == "" {
+		return fmt.Errorf("no feishu webhook configured for budget warnings")
+	}
+	if err := f.send(ctx, webhook, f.buildBudgetCard(proj, w)); err != nil {
+		return err
+	}
+	f.log.Info("feishu.budget_sent",
+		logger.String("project", w.ProjectKey),
+		logger.String("period", w.Period),
+		logger.Int("threshold", w.Threshold),
+	)
+	return nil
+}
+
+// send posts an interactive card to the webhook, retrying on failure.
+func (f *FeishuNotifier) send(ctx context.Context, webhook string, card map[string]any) error {
 	payload := map[string]any{
 		"msg_type": "interactive",
 		"card":     card,
@@ -124,10 +158,6 @@ func (f *FeishuNotifier) Notify(ctx context.Context, proj *project.Project, even
 				f.log.Warn("feishu.api_error", logger.Int("code", feishuResp.Code), logger.String("msg", feishuResp.Msg))
 				continue
 			}
-			f.log.Info("feishu.sent",
-				logger.String("project", proj.Key),
-				logger.String("event_id", event.ID),
-			)
 			return nil
 		}
 
@@ -253,6 +283,16 @@ func (f *FeishuNotifier) buildCard(proj *project.Project, event *intake.RawEvent
 			durationStr, report.NumTurns)
 	}
 
+	if d := report.StructuredResult; d != nil && d.SuspectedCommit != nil {
+		diagContent += fmt.Sprintf("\n**疑似提交**: `%s` %s",
+			intake.EscapeLarkMD(d.SuspectedCommit.Hash), intake.EscapeLarkMD(d.SuspectedCommit.Reason))
+	}
+	if r := report.Review; r != nil {
+		diagContent += fmt.Sprintf("\n**复核**: 置信度 %.2f → %.2f", report.OriginalConfidence, report.FinalConfidence)
+		if r.Comment != "" {
+			diagContent += "，" + intake.EscapeLarkMD(r.Comment)
+		}
+	}
 	if report.QualityScore.Normalized > 0 {
 		diagContent += fmt.Sprintf("\n**质量评分**: %d/100", report.QualityScore.Normalized)
 	}
@@ -334,6 +374,61 @@ func (f *FeishuNotifier) buildCard(proj *project.Project, event *intake.RawEvent
 	}
 }
 
+func (f *FeishuNotifier) buildBudgetCard(proj *project.Project, w budget.Warning) map[string]any {
+	scope := "全局"
+	if proj != nil {
+		scope = proj.Name
+	} else if w.ProjectKey != "" {
+		scope = w.ProjectKey
+	}
+	period := "今日"
+	if w.Period == budget.PeriodMonthly {
+		period = "本月"
+	}
+
+	template, title := "orange", "🟠 Token 预算预警"
+	if w.Used >= w.Limit {
+		template, title = "red", "🔴 Token 预算已用尽"
+	}
+
+	lines := []string{
+		fmt.Sprintf("**范围**: %s", intake.EscapeLarkMD(scope)),
+		fmt.Sprintf("**周期**: %s", period),
+		fmt.Sprintf("**已用**: %d / %d tokens（%.1f%%）", w.Used, w.Limit, float64(w.Used)*100/float64(w.Limit)),
+		fmt.Sprintf("**告警阈值**: %d%%", w.Threshold),
+	}
+	if w.Used >= w.Limit {
+		lines = append(lines, "超出预算期间，新任务将按严重程度延后或降级运行，直至预算周期重置。")
+	}
+
+	elements := []map[string]any{
+		{
+			"tag": "div",
+			"text": map[string]any{
+				"tag":     "lark_md",
+				"content": strings.Join(lines, "\n"),
+			},
+		},
+	}
+	if proj != nil && len(proj.Owners) > 0 {
+		elements = append(elements, map[string]any{
+			"tag": "div",
+			"text": map[string]any{
+				"tag":     "lark_md",
+				"content": fmt.Sprintf("**👤 负责人**: %s", strings.Join(proj.Owners, ", ")),
+			},
+		})
+	}
+
+	return map[string]any{
+		"header": map[string]any{
+			"title":    map[string]any{"tag": "plain_text", "content": fmt.Sprintf("%s — %s", title, scope)},
+			"template": template,
+		},
+		"elements": elements,
+	}
+}
+
 func (f *FeishuNotifier) genSign(timestamp string) string {
 	stringToSign := timestamp + "\n" + f.signKey
 	h := hmac.New(sha256.New, []byte(stringToSign))
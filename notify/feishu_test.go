@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"amp-sentinel/budget"
 	"amp-sentinel/diagnosis"
 	"amp-sentinel/intake"
 	"amp-sentinel/logger"
@@ -278,3 +279,47 @@ func TestBuildCard_QualityScore(t *testing.T) {
 		t.Error("card should display the normalized score as 85/100")
 	}
 }
+
+func TestBuildCard_Review(t *testing.T) {
+	n := newTestNotifier("")
+	report := &diagnosis.Report{
+		HasIssue:           true,
+		Confidence:         "low",
+		Summary:            "Issue found",
+		OriginalConfidence: 0.9,
+		FinalConfidence:    0.35,
+		Review: &diagnosis.Review{
+			Verdicts:           []diagnosis.HypothesisVerdict{{Rank: 1, Verdict: diagnosis.VerdictRefuted}},
+			AdjustedConfidence: 0.35,
+			Comment:            "首要假设与代码不符",
+		},
+	}
+	s := cardJSON(n.buildCard(baseProject(), baseEvent(), report))
+	for _, want := range []string{`"template":"orange"`, "复核", "0.90 → 0.35", "首要假设与代码不符"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("reviewed card missing %q: %s", want, s)
+		}
+	}
+}
+
+func TestBuildBudgetCard(t *testing.T) {
+	n := newTestNotifier("")
+
+	card := n.buildBudgetCard(baseProject(), budget.Warning{
+		ProjectKey: "proj-1", Period: budget.PeriodDaily, Used: 800, Limit: 1000, Threshold: 80,
+	})
+	s := cardJSON(card)
+	for _, want := range []string{`"template":"orange"`, "TestProject", "今日", "800 / 1000", "80%"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("warning card missing %q: %s", want, s)
+		}
+	}
+
+	card = n.buildBudgetCard(nil, budget.Warning{Period: budget.PeriodMonthly, Used: 1200, Limit: 1000, Threshold: 100})
+	s = cardJSON(card)
+	for _, want := range []string{`"template":"red"`, "全局", "本月", "延后或降级"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("exhausted card missing %q: %s", want, s)
+		}
+	}
+}
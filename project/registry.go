@@ -14,6 +14,11 @@ type Project struct {
 	Owners        []string `json:"owners" yaml:"owners"`
 	FeishuWebhook string             `json:"feishu_webhook" yaml:"feishu_webhook"`
 	Dedup         ProjectDedupConfig `json:"dedup" yaml:"dedup"`
+	Scheduler     ProjectSchedulerConfig `json:"scheduler" yaml:"scheduler"`
+	Budget        ProjectBudgetConfig    `json:"budget" yaml:"budget"`
+	Schedules     []ProjectSchedule      `json:"schedules" yaml:"schedules"`
+	Review        ProjectReviewConfig    `json:"review" yaml:"review"`
+	Version       ProjectVersionConfig   `json:"version" yaml:"version"`
 }
 
 // ProjectDedupConfig holds per-project deduplication settings.
@@ -22,6 +27,55 @@ type ProjectDedupConfig struct {
 	Window string   `yaml:"window" json:"window"`
 }
 
+// ProjectSchedulerConfig holds per-project scheduling settings.
+type ProjectSchedulerConfig struct {
+	// MaxConcurrency caps the project's concurrently running diagnoses
+	// (0 = scheduler.project_concurrency).
+	MaxConcurrency int `yaml:"max_concurrency" json:"max_concurrency"`
+	// Weight is the project's round-robin share among projects with queued
+	// tasks of the same severity (0 = 1).
+	Weight int `yaml:"weight" json:"weight"`
+}
+
+// ProjectBudgetConfig holds per-project token budgets (input + output
+// tokens, 0 = unlimited).
+type ProjectBudgetConfig struct {
+	DailyTokens   int64 `yaml:"daily_tokens" json:"daily_tokens"`
+	MonthlyTokens int64 `yaml:"monthly_tokens" json:"monthly_tokens"`
+}
+
+// ProjectSchedule is a recurring proactive diagnosis of the project, e.g.
+// a morning review of yesterday's error logs.
+type ProjectSchedule struct {
+	Name string `yaml:"name" json:"name"`
+	// Cron is a five-field cron expression in local time, or a macro
+	// such as @daily.
+	Cron string `yaml:"cron" json:"cron"`
+	// Severity of the enqueued task (default info).
+	Severity string `yaml:"severity" json:"severity"`
+	// Prompt is a text/template of the instructions; see cron.PromptData.
+	Prompt string `yaml:"prompt" json:"prompt"`
+}
+
+// ProjectReviewConfig overrides the global reviewer settings
+// (diagnosis.review) for the project.
+type ProjectReviewConfig struct {
+	// Enabled turns the reviewer on or off for the project (nil = global).
+	Enabled *bool `yaml:"enabled" json:"enabled,omitempty"`
+	// Severities limits the review to these severities (empty = global).
+	Severities []string `yaml:"severities" json:"severities,omitempty"`
+}
+
+// ProjectVersionConfig locates the deployed version of the project in event
+// payloads, so the diagnosis reads the code that actually ran.
+type ProjectVersionConfig struct {
+	// Fields are payload paths of the version (commit SHA, tag or build
+	// ID), tried in order (empty = diagnosis.version.fields).
+	Fields []string `yaml:"fields" json:"fields,omitempty"`
+	// Builds maps build IDs to the commits they were built from.
+	Builds map[string]string `yaml:"builds" json:"builds,omitempty"`
+}
+
 // Registry holds all registered projects and provides lookup by key.
 type Registry struct {
 	projects map[string]*Project
@@ -6,8 +6,11 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"amp-sentinel/logger"
 )
@@ -17,6 +20,7 @@ import (
 type SourceManager struct {
 	baseDir   string
 	sshKey    string
+	history   time.Duration // commit history kept in the shallow clones (0 = tip only)
 	log       logger.Logger
 	mu        sync.Map // per-project locks: project_key -> *sync.Mutex
 }
@@ -31,10 +35,11 @@ func (s *SourceManager) Lock(projectKey string) func() {
 }
 
 // NewSourceManager creates a source manager that stores repos under baseDir.
-// The baseDir is created automatically if it does not exist.
-func NewSourceManager(baseDir, sshKey string, log logger.Logger) *SourceManager {
+// The baseDir is created automatically if it does not exist. Clones keep
+// the commits of the last history (0 = only the branch tip).
+func NewSourceManager(baseDir, sshKey string, history time.Duration, log logger.Logger) *SourceManager {
 	_ = os.MkdirAll(baseDir, 0755)
-	return &SourceManager{baseDir: baseDir, sshKey: sshKey, log: log}
+	return &SourceManager{baseDir: baseDir, sshKey: sshKey, history: history, log: log}
 }
 
 // Prepare ensures the project source is available and up-to-date.
@@ -77,6 +82,48 @@ func (s *SourceManager) CommitHash(ctx context.Context, projectKey string) (stri
 	return out, nil
 }
 
+// revisionPattern matches commit hashes and tag or branch names. It leaves
+// out what git would read as an option, a refspec (":", a leading "+", "*")
+// or a revision expression ("^", "~", "@{", "..").
+var revisionPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._/-]*$`)
+
+// validRevision reports whether rev is safe to hand to git as a revision.
+func validRevision(rev string) bool {
+	return revisionPattern.MatchString(rev) && !strings.Contains(rev, "..")
+}
+
+// ResolveCommit returns the abbreviated hash of rev (a commit hash, tag or
+// branch) if the repo has that commit.
+func (s *SourceManager) ResolveCommit(ctx context.Context, projectKey, rev string) (string, error) {
+	if !validRevision(rev) {
+		return "", fmt.Errorf("invalid revision %q", rev)
+	}
+	repoDir := filepath.Join(s.baseDir, projectKey)
+	return s.git(ctx, repoDir, "rev-parse", "--verify", "--quiet", "--short", rev+"^{commit}")
+}
+
+// Checkout switches the repo to rev (a commit hash, tag or branch) on a
+// detached HEAD, fetching it first if the shallow clone lacks it, and
+// returns its abbreviated hash. The next Prepare returns to the branch tip.
+// Callers hold Lock(p.Key) and have called Prepare.
+func (s *SourceManager) Checkout(ctx context.Context, p *Project, rev string) (string, error) {
+	repoDir := filepath.Join(s.baseDir, p.Key)
+	target := rev
+	if _, err := s.ResolveCommit(ctx, p.Key, rev); err != nil {
+		if !validRevision(rev) {
+			return "", err
+		}
+		if _, err := s.git(ctx, repoDir, "fetch", "--depth=1", "--end-of-options", "origin", rev); err != nil {
+			return "", fmt.Errorf("revision %q not found: %w", rev, err)
+		}
+		target = "FETCH_HEAD"
+	}
+	if _, err := s.git(ctx, repoDir, "checkout", "--force", "--detach", target+"^{commit}"); err != nil {
+		return "", err
+	}
+	return s.CommitHash(ctx, p.Key)
+}
+
 // HasChanges returns true if the repo has uncommitted changes (safety check).
 func (s *SourceManager) HasChanges(ctx context.Context, projectKey string) (bool, error) {
 	repoDir := filepath.Join(s.baseDir, projectKey)
@@ -99,6 +146,105 @@ func (s *SourceManager) ResetChanges(ctx context.Context, projectKey string) err
 	return err
 }
 
+// Commit is one commit of a project's recent history.
+type Commit struct {
+	Hash    string       `json:"hash"` // abbreviated
+	Author  string       `json:"author"`
+	Time    time.Time    `json:"time"`
+	Subject string       `json:"subject"`
+	Files   []FileChange `json:"files,omitempty"`
+	// Boundary marks the oldest commit of a shallow clone: its parent is
+	// missing, so its files are unknown.
+	Boundary bool `json:"boundary,omitempty"`
+}
+
+// FileChange is the diff stat of one file in a commit. Binary files have
+// Added and Deleted -1.
+type FileChange struct {
+	Path    string `json:"path"`
+	Added   int    `json:"added"`
+	Deleted int    `json:"deleted"`
+}
+
+// RecentChanges lists up to limit commits of the checked-out branch made
+// in [since, until], newest first, with their diff stats. It deepens the
+// shallow clone to since first if needed. Callers hold Lock(p.Key) and
+// have called Prepare.
+func (s *SourceManager) RecentChanges(ctx context.Context, p *Project, since, until time.Time, limit int) ([]Commit, error) {
+	repoDir := filepath.Join(s.baseDir, p.Key)
+	if err := s.deepen(ctx, repoDir, p.Branch, since); err != nil {
+		s.log.Warn("source.deepen_failed", logger.String("project", p.Key), logger.Err(err))
+	}
+	args := []string{"log", "--no-merges", "--numstat", "--format=%x1e%H%x1f%h%x1f%an%x1f%cI%x1f%s",
+		"--since=" + since.Format(time.RFC3339), "--until=" + until.Format(time.RFC3339)}
+	if limit > 0 {
+		args = append(args, "--max-count="+strconv.Itoa(limit))
+	}
+	out, err := s.git(ctx, repoDir, append(args, "HEAD")...)
+	if err != nil {
+		return nil, err
+	}
+	return parseLog(out, s.shallowBoundaries(repoDir)), nil
+}
+
+// deepen extends a shallow clone back to since. A full clone is left as is.
+func (s *SourceManager) deepen(ctx context.Context, repoDir, branch string, since time.Time) error {
+	if len(s.shallowBoundaries(repoDir)) == 0 {
+		return nil
+	}
+	// Commits after the newest boundary are all present.
+	boundary, err := s.git(ctx, repoDir, "log", "-1", "--format=%cI", "--max-parents=0", "HEAD")
+	if err == nil {
+		if t, perr := time.Parse(time.RFC3339, boundary); perr == nil && t.Before(since) {
+			return nil
+		}
+	}
+	_, err = s.git(ctx, repoDir, "fetch", "--shallow-since="+since.Format(time.RFC3339), "origin", branch)
+	return err
+}
+
+// shallowBoundaries returns the full hashes of the commits whose parents a
+// shallow clone lacks (empty for a full clone).
+func (s *SourceManager) shallowBoundaries(repoDir string) map[string]bool {
+	data, err := os.ReadFile(filepath.Join(repoDir, ".git", "shallow"))
+	if err != nil {
+		return nil
+	}
+	out := make(map[string]bool)
+	for _, line := range strings.Fields(string(data)) {
+		out[line] = true
+	}
+	return out
+}
+
+// parseLog parses the output of RecentChanges' git log format.
+func parseLog(out string, boundaries map[string]bool) []Commit {
+	var commits []Commit
+	for _, record := range strings.Split(out, "\x1e") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		fields := strings.Split(lines[0], "\x1f")
+		if len(fields) != 5 {
+			continue
+		}
+		c := Commit{Hash: fields[1], Author: fields[2], Subject: fields[4], Boundary: boundaries[fields[0]]}
+		c.Time, _ = time.Parse(time.RFC3339, fields[3])
+		for _, line := range lines[1:] {
+			parts := strings.SplitN(line, "\t", 3)
+			if c.Boundary || len(parts) != 3 {
+				continue
+			}
+			added, errA := strconv.Atoi(parts[0])
+			deleted, errD := strconv.Atoi(parts[1])
+			if errA != nil || errD != nil {
+				added, deleted = -1, -1 // binary
+			}
+			c.Files = append(c.Files, FileChange{Path: parts[2], Added: added, Deleted: deleted})
+		}
+		commits = append(commits, c)
+	}
+	return commits
+}
+
 func (s *SourceManager) cloneAndReturn(ctx context.Context, p *Project, repoDir, srcDir string) (string, error) {
 	s.log.Info("source.cloning",
 		logger.String("project", p.Key),
@@ -114,10 +260,17 @@ func (s *SourceManager) cloneAndReturn(ctx context.Context, p *Project, repoDir,
 }
 
 func (s *SourceManager) gitClone(ctx context.Context, repoURL, branch, dest string) error {
-	args := []string{"clone", "--depth=1", "--branch", branch, repoURL, dest}
+	args := []string{"clone", s.depthArg(), "--branch", branch, repoURL, dest}
 	cmd := exec.CommandContext(ctx, "git", args...)
 	s.applySSH(cmd)
 	out, err := cmd.CombinedOutput()
+	if err != nil && s.history > 0 {
+		// No commits in the history window: fall back to the tip.
+		_ = os.RemoveAll(dest)
+		cmd = exec.CommandContext(ctx, "git", "clone", "--depth=1", "--branch", branch, repoURL, dest)
+		s.applySSH(cmd)
+		out, err = cmd.CombinedOutput()
+	}
 	if err != nil {
 		return fmt.Errorf("%w: %s", err, string(out))
 	}
@@ -126,13 +279,26 @@ func (s *SourceManager) gitClone(ctx context.Context, repoURL, branch, dest stri
 
 func (s *SourceManager) gitPull(ctx context.Context, repoDir, branch string) error {
 	// Fetch + reset to handle shallow clones properly
-	if _, err := s.git(ctx, repoDir, "fetch", "--depth=1", "origin", branch); err != nil {
+	_, err := s.git(ctx, repoDir, "fetch", s.depthArg(), "origin", branch)
+	if err != nil && s.history > 0 {
+		_, err = s.git(ctx, repoDir, "fetch", "--depth=1", "origin", branch)
+	}
+	if err != nil {
 		return err
 	}
-	_, err := s.git(ctx, repoDir, "reset", "--hard", "FETCH_HEAD")
+	_, err = s.git(ctx, repoDir, "reset", "--hard", "FETCH_HEAD")
 	return err
 }
 
+// depthArg limits clones and fetches to the tip, or to the history window
+// so the diagnosis can see recent commits.
+func (s *SourceManager) depthArg() string {
+	if s.history <= 0 {
+		return "--depth=1"
+	}
+	return "--shallow-since=" + time.Now().Add(-s.history).Format(time.RFC3339)
+}
+
 func (s *SourceManager) git(ctx context.Context, dir string, args ...string) (string, error) {
 	cmd := exec.CommandContext(ctx, "git", args...)
 	cmd.Dir = dir
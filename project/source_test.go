@@ -0,0 +1,195 @@
+package project
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"amp-sentinel/logger"
+)
+
+// newTestRepo creates a git repository with one commit per entry of
+// commits (file name → commit age), oldest first, and returns its URL.
+func newTestRepo(t *testing.T, now time.Time, commits []struct {
+	file string
+	age  time.Duration
+}) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	run := func(env []string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), env...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	run(nil, "init", "-q", "-b", "main")
+	for _, c := range commits {
+		if err := os.WriteFile(filepath.Join(dir, c.file), []byte(c.file+"\nline\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		date := now.Add(-c.age).Format(time.RFC3339)
+		env := []string{
+			"GIT_AUTHOR_NAME=dev", "GIT_AUTHOR_EMAIL=dev@example.com", "GIT_AUTHOR_DATE=" + date,
+			"GIT_COMMITTER_NAME=dev", "GIT_COMMITTER_EMAIL=dev@example.com", "GIT_COMMITTER_DATE=" + date,
+		}
+		run(env, "add", c.file)
+		run(env, "commit", "-q", "-m", "add "+c.file)
+	}
+	return "file://" + dir
+}
+
+func TestSourceManager_RecentChanges(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	url := newTestRepo(t, now, []struct {
+		file string
+		age  time.Duration
+	}{
+		{"old.go", 30 * 24 * time.Hour},
+		{"deploy.go", 3 * time.Hour},
+		{"hotfix.go", time.Hour},
+	})
+
+	// A tip-only clone is deepened on demand.
+	sm := NewSourceManager(t.TempDir(), "", 0, logger.Nop())
+	p := &Project{Key: "svc", RepoURL: url, Branch: "main", SourceRoot: "."}
+	ctx := context.Background()
+	if _, err := sm.Prepare(ctx, p); err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+
+	commits, err := sm.RecentChanges(ctx, p, now.Add(-24*time.Hour), now.Add(-30*time.Minute), 10)
+	if err != nil {
+		t.Fatalf("RecentChanges: %v", err)
+	}
+	if len(commits) != 2 || commits[0].Subject != "add hotfix.go" || commits[1].Subject != "add deploy.go" {
+		t.Fatalf("commits = %+v, want hotfix and deploy, newest first", commits)
+	}
+	if got := commits[0].Files; len(got) != 1 || got[0].Path != "hotfix.go" || got[0].Added != 2 {
+		t.Errorf("hotfix files = %+v", got)
+	}
+	if !commits[0].Time.Equal(now.Add(-time.Hour)) || commits[0].Author != "dev" {
+		t.Errorf("hotfix commit = %+v", commits[0])
+	}
+
+	if got, err := sm.ResolveCommit(ctx, p.Key, commits[1].Hash); err != nil || got != commits[1].Hash {
+		t.Errorf("ResolveCommit(%s) = %q, %v", commits[1].Hash, got, err)
+	}
+	for _, rev := range []string{"deadbeef", "--all", ""} {
+		if _, err := sm.ResolveCommit(ctx, p.Key, rev); err == nil {
+			t.Errorf("ResolveCommit(%q) should fail", rev)
+		}
+	}
+
+	// The window ends before the hotfix.
+	commits, err = sm.RecentChanges(ctx, p, now.Add(-24*time.Hour), now.Add(-2*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("RecentChanges: %v", err)
+	}
+	if len(commits) != 1 || commits[0].Subject != "add deploy.go" {
+		t.Errorf("commits = %+v, want only deploy", commits)
+	}
+}
+
+func TestSourceManager_PrepareKeepsHistory(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	url := newTestRepo(t, now, []struct {
+		file string
+		age  time.Duration
+	}{
+		{"old.go", 30 * 24 * time.Hour},
+		{"deploy.go", 3 * time.Hour},
+	})
+
+	sm := NewSourceManager(t.TempDir(), "", 48*time.Hour, logger.Nop())
+	p := &Project{Key: "svc", RepoURL: url, Branch: "main", SourceRoot: "."}
+	ctx := context.Background()
+	for i := 0; i < 2; i++ { // clone, then pull
+		if _, err := sm.Prepare(ctx, p); err != nil {
+			t.Fatalf("Prepare #%d: %v", i, err)
+		}
+	}
+	out, err := sm.git(ctx, filepath.Join(sm.baseDir, p.Key), "log", "--format=%s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "add deploy.go" {
+		t.Errorf("history = %q, want the commits of the last 48h", out)
+	}
+}
+
+func TestSourceManager_Checkout(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	url := newTestRepo(t, now, []struct {
+		file string
+		age  time.Duration
+	}{
+		{"v1.go", 48 * time.Hour},
+		{"v2.go", 24 * time.Hour},
+		{"v3.go", time.Hour},
+	})
+	remote := strings.TrimPrefix(url, "file://")
+	rev := func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", append([]string{"-C", remote}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	rev("tag", "v1.0.0", "HEAD~2")
+	v2 := rev("rev-parse", "HEAD~1")
+
+	sm := NewSourceManager(t.TempDir(), "", 0, logger.Nop())
+	p := &Project{Key: "svc", RepoURL: url, Branch: "main", SourceRoot: "."}
+	ctx := context.Background()
+	srcDir, err := sm.Prepare(ctx, p)
+	if err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	tip, _ := sm.CommitHash(ctx, p.Key)
+
+	// Neither revision is in the tip-only clone: both are fetched.
+	got, err := sm.Checkout(ctx, p, v2)
+	if err != nil || !strings.HasPrefix(v2, got) {
+		t.Fatalf("Checkout(%s) = %q, %v", v2, got, err)
+	}
+	if _, err := os.Stat(filepath.Join(srcDir, "v3.go")); !os.IsNotExist(err) {
+		t.Error("v3.go should not exist at v2")
+	}
+	got, err = sm.Checkout(ctx, p, "v1.0.0")
+	if err != nil || !strings.HasPrefix(rev("rev-parse", "v1.0.0^{commit}"), got) {
+		t.Fatalf("Checkout(v1.0.0) = %q, %v", got, err)
+	}
+	if _, err := sm.Checkout(ctx, p, "no-such-tag"); err == nil {
+		t.Error("Checkout of an unknown revision should fail")
+	}
+
+	// A version string from the payload must not act as a refspec or option.
+	for _, hostile := range []string{"a:refs/heads/x", "+refs/*:refs/*", "main..v1.0.0", "v1 --upload-pack=x", "--all"} {
+		if _, err := sm.Checkout(ctx, p, hostile); err == nil {
+			t.Errorf("Checkout(%q) should fail", hostile)
+		}
+	}
+	refs, err := exec.Command("git", "-C", filepath.Join(sm.baseDir, p.Key), "for-each-ref").CombinedOutput()
+	if err != nil || strings.Contains(string(refs), "refs/heads/x") || strings.Contains(string(refs), "refs/tags/") {
+		t.Errorf("hostile revisions created refs: %s %v", refs, err)
+	}
+
+	// Prepare returns to the branch tip.
+	if _, err := sm.Prepare(ctx, p); err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	if got, _ := sm.CommitHash(ctx, p.Key); got != tip {
+		t.Errorf("CommitHash after Prepare = %q, want tip %q", got, tip)
+	}
+}
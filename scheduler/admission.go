@@ -0,0 +1,30 @@
+package scheduler
+
+import "context"
+
+// ActionDegrade is recorded on tasks dispatched in degraded mode.
+const ActionDegrade = "degrade"
+
+// Admission is the verdict of Config.Admit on a project's next task.
+type Admission struct {
+	// Defer keeps the project's tasks queued; admission is asked again on
+	// every dispatch attempt, at least every sweep interval.
+	Defer bool
+	// Degrade dispatches the task in a cheaper mode, see Degraded.
+	Degrade bool
+	// Reason explains a deferral or degradation.
+	Reason string
+}
+
+type degradedKey struct{}
+
+// Degraded reports whether the diagnosis running under ctx was admitted in
+// degraded mode, e.g. because its project is over budget.
+func Degraded(ctx context.Context) bool {
+	v, _ := ctx.Value(degradedKey{}).(bool)
+	return v
+}
+
+func withDegraded(ctx context.Context) context.Context {
+	return context.WithValue(ctx, degradedKey{}, true)
+}
@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+
+	"amp-sentinel/intake"
+	"amp-sentinel/logger"
+)
+
+// ErrCoalesced is returned by Submit when the event was attached to a
+// queued or running task with the same diagnosis fingerprint instead of
+// starting another diagnosis. The returned task ID is that task's.
+var ErrCoalesced = errors.New("coalesced")
+
+// fingerprint returns the coalescing key of an event, or "" if it must
+// not be coalesced. Distributed mode does not coalesce: the tasks in flight
+// are spread over replicas.
+func (s *Scheduler) fingerprint(event *intake.RawEvent) string {
+	if s.cfg.Fingerprint == nil || s.leases != nil {
+		return ""
+	}
+	return s.cfg.Fingerprint(event)
+}
+
+// attach adds event to an unfinished task with its fingerprint. Callers
+// hold coalesceMu. Returns false if OnAttach failed to persist the link.
+func (s *Scheduler) attach(task *Task, event *intake.RawEvent) bool {
+	if s.cfg.OnAttach != nil {
+		if err := s.cfg.OnAttach(task, event); err != nil {
+			s.log.Warn("task.attach_failed",
+				logger.String("task_id", task.ID),
+				logger.String("incident_id", event.ID),
+				logger.Err(err),
+			)
+			return false
+		}
+	}
+	task.Attached = append(task.Attached, event.ID)
+	s.coalesced.Add(1)
+	s.log.Info("task.coalesced",
+		logger.String("task_id", task.ID),
+		logger.String("incident_id", event.ID),
+		logger.Int("attached", len(task.Attached)),
+	)
+	return true
+}
+
+// index registers an unfinished task so later events with its fingerprint
+// attach to it.
+func (s *Scheduler) index(task *Task) {
+	if task.Fingerprint == "" {
+		return
+	}
+	s.coalesceMu.Lock()
+	defer s.coalesceMu.Unlock()
+	if s.inflightFP[task.Fingerprint] == nil {
+		s.inflightFP[task.Fingerprint] = task
+	}
+}
+
+// settle closes a finished task to further attachments and hands its
+// attached events to OnSettle. Removing the task from the index under
+// coalesceMu orders every attach before it.
+func (s *Scheduler) settle(task *Task) {
+	if task.Fingerprint == "" {
+		return
+	}
+	s.coalesceMu.Lock()
+	if s.inflightFP[task.Fingerprint] == task {
+		delete(s.inflightFP, task.Fingerprint)
+	}
+	attached := len(task.Attached)
+	s.coalesceMu.Unlock()
+
+	if attached > 0 && s.cfg.OnSettle != nil {
+		s.cfg.OnSettle(task)
+	}
+}
+
+// coalescedError reports the task an event was attached to.
+func coalescedError(taskID string) error {
+	return fmt.Errorf("%w: attached to in-flight task %s", ErrCoalesced, taskID)
+}
@@ -0,0 +1,153 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"amp-sentinel/logger"
+)
+
+// Admin actions recorded on tasks.
+const (
+	ActionCancel   = "cancel"
+	ActionPriority = "priority"
+	ActionPause    = "pause"
+	ActionResume   = "resume"
+)
+
+// TaskAction is an admin action taken on a task.
+type TaskAction struct {
+	Action string
+	By     string
+	Reason string
+	// Detail describes the change, e.g. "priority 10 -> 100".
+	Detail string
+	At     time.Time
+}
+
+var (
+	// ErrTaskNotActive is returned for tasks that are neither queued nor running.
+	ErrTaskNotActive = errors.New("task is not queued or running")
+	// ErrTaskNotQueued is returned when changing the priority of a task
+	// that has already been dispatched.
+	ErrTaskNotQueued = errors.New("task is not queued")
+)
+
+// Cancel cancels a task. A queued task is removed from the queue and marked
+// cancelled at once. A running task has its context cancelled, which kills
+// the Amp process; it is marked cancelled when the worker returns, so the
+// returned status is StatusRunning. In distributed mode a task running on
+// another replica cannot be cancelled here: ErrTaskLeased.
+func (s *Scheduler) Cancel(taskID, by, reason string) (TaskStatus, error) {
+	action := TaskAction{Action: ActionCancel, By: by, Reason: reason, At: time.Now()}
+	if s.leases != nil {
+		local, err := s.withLease(taskID, func(task *Task) {
+			action.Detail = "removed from queue"
+			s.finishCancelled(task, action)
+			s.settle(task)
+		})
+		if err != nil {
+			return "", err
+		}
+		if !local {
+			return StatusCancelled, nil
+		}
+	}
+	if task := s.pq.remove(taskID); task != nil {
+		action.Detail = "removed from queue"
+		s.finishCancelled(task, action)
+		s.settle(task)
+		return StatusCancelled, nil
+	}
+	action.Detail = "running task interrupted"
+	if s.pq.requestCancel(taskID, action) {
+		s.log.Info("task.cancel_requested", logger.String("task_id", taskID), logger.String("by", by))
+		return StatusRunning, nil
+	}
+	return "", ErrTaskNotActive
+}
+
+// SetPriority changes the priority of a queued task.
+func (s *Scheduler) SetPriority(taskID string, priority int, by, reason string) error {
+	action := TaskAction{Action: ActionPriority, By: by, Reason: reason, At: time.Now()}
+	if s.leases != nil {
+		local, err := s.withLease(taskID, func(task *Task) {
+			action.Detail = fmt.Sprintf("priority %d -> %d", task.Priority, priority)
+			task.Priority = priority
+			task.Actions = append(task.Actions, action)
+			s.save(task)
+		})
+		if err != nil {
+			return err
+		}
+		if local {
+			return ErrTaskNotQueued
+		}
+		s.log.Info("task.priority_changed",
+			logger.String("task_id", taskID),
+			logger.Int("priority", priority),
+			logger.String("by", by),
+		)
+		return nil
+	}
+	task := s.pq.setPriority(taskID, priority, action, s.save)
+	if task == nil {
+		if s.pq.isInflight(taskID) {
+			return ErrTaskNotQueued
+		}
+		return ErrTaskNotActive
+	}
+	s.log.Info("task.priority_changed",
+		logger.String("task_id", taskID),
+		logger.Int("priority", priority),
+		logger.String("by", by),
+	)
+	return nil
+}
+
+// PauseProject stops dispatching the project's tasks; new tasks are still
+// queued. Returns false if the project was already paused.
+func (s *Scheduler) PauseProject(projectKey, by, reason string) bool {
+	return s.setPaused(projectKey, true, TaskAction{Action: ActionPause, By: by, Reason: reason, At: time.Now()})
+}
+
+// ResumeProject resumes dispatching a paused project. Returns false if the
+// project was not paused.
+func (s *Scheduler) ResumeProject(projectKey, by, reason string) bool {
+	return s.setPaused(projectKey, false, TaskAction{Action: ActionResume, By: by, Reason: reason, At: time.Now()})
+}
+
+func (s *Scheduler) setPaused(projectKey string, paused bool, action TaskAction) bool {
+	action.Detail = "project " + projectKey + " " + action.Action + "d"
+	queued, changed := s.pq.setPaused(projectKey, paused, action, s.save)
+	if !changed {
+		return false
+	}
+	s.log.Info("scheduler.project_"+action.Action+"d",
+		logger.String("project", projectKey),
+		logger.Int("queued", queued),
+		logger.String("by", action.By),
+	)
+	return true
+}
+
+// finishCancelled records the cancel action and marks the task cancelled.
+func (s *Scheduler) finishCancelled(task *Task, action TaskAction) {
+	task.Status = StatusCancelled
+	task.Error = "cancelled"
+	if action.Reason != "" {
+		task.Error += ": " + action.Reason
+	}
+	task.Actions = append(task.Actions, action)
+	task.FinishedAt = time.Now()
+	s.save(task)
+	s.discard(task)
+	s.cancelled.Add(1)
+	s.log.Warn("task.admin_cancelled",
+		logger.String("task_id", task.ID),
+		logger.String("project", task.Event.ProjectKey),
+		logger.String("by", action.By),
+		logger.String("reason", action.Reason),
+	)
+}
@@ -0,0 +1,267 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"amp-sentinel/logger"
+
+	"github.com/google/uuid"
+)
+
+// LeaseStore shares one task queue between scheduler replicas. Tasks stay
+// in the store; a replica works on a task only while it holds the task's
+// lease, renewing it by heartbeat. When a replica dies its leases expire and
+// another replica takes the tasks over.
+type LeaseStore interface {
+	TaskStore
+	// Claimable returns unfinished tasks that no live lease holds, best
+	// first. Event is nil for tasks whose event can no longer be loaded.
+	Claimable(now time.Time) ([]*Task, error)
+	// Leased counts tasks under a live lease, per project.
+	Leased(now time.Time) (map[string]int, error)
+	// Load returns the current state of a task, or nil if it does not exist.
+	Load(taskID string) (*Task, error)
+	// Acquire leases an unfinished task to owner until the given time.
+	// Returns false if another owner holds a live lease.
+	Acquire(taskID, owner string, now, until time.Time) (bool, error)
+	// Renew extends owner's lease. Returns false if owner lost it.
+	Renew(taskID, owner string, until time.Time) (bool, error)
+	// Release gives up owner's lease.
+	Release(taskID, owner string) error
+}
+
+// ErrTaskLeased is returned for admin actions on a task that another
+// replica holds.
+var ErrTaskLeased = errors.New("task is held by another replica")
+
+// leaseLostReason is recorded on tasks taken over from an expired lease
+// with no attempts left.
+const leaseLostReason = "interrupted: replica lease expired"
+
+// defaultReplicaID identifies this process among replicas.
+func defaultReplicaID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "sentinel"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.New().String()[:4])
+}
+
+// wake nudges idle lease workers to claim a task now instead of at the
+// next poll.
+func (s *Scheduler) wake() {
+	select {
+	case s.claimWake <- struct{}{}:
+	default:
+	}
+}
+
+// leaseWorker claims tasks from the shared store until shutdown.
+func (s *Scheduler) leaseWorker(id int) {
+	defer s.wg.Done()
+
+	poll := time.NewTimer(0)
+	defer poll.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-poll.C:
+		case <-s.claimWake:
+		}
+		for s.ctx.Err() == nil {
+			task := s.claim()
+			if task == nil {
+				break
+			}
+			s.running.Add(1)
+			s.runLeased(task)
+			s.running.Add(-1)
+		}
+		if !poll.Stop() {
+			select {
+			case <-poll.C:
+			default:
+			}
+		}
+		poll.Reset(s.cfg.PollInterval)
+	}
+}
+
+// claim leases the best claimable task, or returns nil if there is none.
+func (s *Scheduler) claim() *Task {
+	// Admin actions lease tasks too; keep them from slipping in between
+	// acquiring a task and tracking it as in flight.
+	s.claimMu.Lock()
+	defer s.claimMu.Unlock()
+
+	now := time.Now()
+	tasks, err := s.leases.Claimable(now)
+	if err != nil {
+		s.log.Warn("scheduler.claim_failed", logger.Err(err))
+		return nil
+	}
+	if len(tasks) == 0 {
+		return nil
+	}
+	leased, err := s.leases.Leased(now)
+	if err != nil {
+		s.log.Warn("scheduler.claim_failed", logger.Err(err))
+		return nil
+	}
+
+	for _, c := range s.pq.rank(tasks, leased) {
+		ok, err := s.leases.Acquire(c.task.ID, s.replicaID, now, now.Add(s.cfg.LeaseDuration))
+		if err != nil {
+			s.log.Warn("scheduler.acquire_failed", logger.String("task_id", c.task.ID), logger.Err(err))
+			continue
+		}
+		if !ok {
+			continue // another replica was faster
+		}
+		// Reload: the candidate may be stale by the time the lease is ours.
+		task, err := s.leases.Load(c.task.ID)
+		if err != nil || task == nil {
+			s.log.Warn("scheduler.claim_load_failed", logger.String("task_id", c.task.ID), logger.Err(err))
+			s.release(c.task.ID)
+			continue
+		}
+		if !task.Status.unfinished() || task.backingOff(time.Now()) {
+			s.release(task.ID)
+			continue
+		}
+		if s.takeOver(task) {
+			continue
+		}
+		if c.degraded {
+			task.Degraded = true
+			task.Actions = append(task.Actions, TaskAction{Action: ActionDegrade, By: "admission", Reason: c.degrade, At: now})
+		}
+		s.pq.track(task)
+		return task
+	}
+	return nil
+}
+
+// takeOver settles a freshly leased task. A task left running lost its
+// attempt with its previous replica; once a task has no attempts left it is
+// marked failed and released. Returns true if the task must not run.
+func (s *Scheduler) takeOver(task *Task) bool {
+	task.MaxRetries = s.cfg.RetryCount
+	if task.Status == StatusRunning {
+		task.RetryCount++
+		s.log.Warn("task.taken_over",
+			logger.String("task_id", task.ID),
+			logger.Int("retry_count", task.RetryCount),
+		)
+	}
+	if task.Event != nil && task.RetryCount <= task.MaxRetries {
+		return false
+	}
+	task.Status = StatusFailed
+	task.Error = leaseLostReason
+	if task.Event == nil {
+		task.Error = interruptedReason + ": event not found"
+	}
+	task.FinishedAt = time.Now()
+	s.save(task)
+	s.failed.Add(1)
+	s.release(task.ID)
+	return true
+}
+
+// runLeased processes a leased task, renewing the lease until it finishes.
+func (s *Scheduler) runLeased(task *Task) {
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		s.heartbeat(task.ID, stop)
+	}()
+
+	s.safeProcessTask(task)
+
+	close(stop)
+	<-stopped
+	if !s.pq.leaseLost(task.ID) {
+		s.release(task.ID)
+	}
+	s.pq.done(task)
+}
+
+// heartbeat renews the task's lease every third of its duration. If the
+// lease was lost (this replica stalled past expiry and another took the task
+// over), the task's context is cancelled and its state is no longer saved.
+func (s *Scheduler) heartbeat(taskID string, stop <-chan struct{}) {
+	ticker := time.NewTicker(s.cfg.LeaseDuration / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		ok, err := s.leases.Renew(taskID, s.replicaID, time.Now().Add(s.cfg.LeaseDuration))
+		if err != nil {
+			// Keep trying: the lease is only lost once another replica takes it.
+			s.log.Warn("task.lease_renew_failed", logger.String("task_id", taskID), logger.Err(err))
+			continue
+		}
+		if !ok {
+			s.log.Error("task.lease_lost", logger.String("task_id", taskID), logger.String("replica", s.replicaID))
+			s.pq.loseLease(taskID)
+			return
+		}
+	}
+}
+
+func (s *Scheduler) release(taskID string) {
+	if err := s.leases.Release(taskID, s.replicaID); err != nil {
+		s.log.Warn("task.lease_release_failed", logger.String("task_id", taskID), logger.Err(err))
+	}
+}
+
+// withLease runs fn on a task that is not running on any replica, holding
+// its lease meanwhile. Returns local=true without calling fn if the task
+// runs on this replica. Returns ErrTaskLeased if another replica holds it
+// and ErrTaskNotActive if it is finished.
+func (s *Scheduler) withLease(taskID string, fn func(task *Task)) (local bool, err error) {
+	s.claimMu.Lock()
+	defer s.claimMu.Unlock()
+	if s.pq.isInflight(taskID) {
+		return true, nil
+	}
+	now := time.Now()
+	ok, err := s.leases.Acquire(taskID, s.replicaID, now, now.Add(s.cfg.LeaseDuration))
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		task, err := s.leases.Load(taskID)
+		if err != nil {
+			return false, err
+		}
+		if task == nil || !task.Status.unfinished() {
+			return false, ErrTaskNotActive
+		}
+		return false, ErrTaskLeased
+	}
+	defer s.release(taskID)
+	task, err := s.leases.Load(taskID)
+	if err != nil {
+		return false, err
+	}
+	if task == nil || task.Event == nil || !task.Status.unfinished() {
+		return false, ErrTaskNotActive
+	}
+	fn(task)
+	return false, nil
+}
+
+// unfinished reports whether a task in this status may still run.
+func (st TaskStatus) unfinished() bool {
+	return st == StatusPending || st == StatusQueued || st == StatusRunning
+}
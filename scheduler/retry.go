@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// Failure classes recorded on attempts. Errors returned by DiagnoseFunc
+// choose their class by implementing classifiedError (see amp.Error);
+// unclassified errors count as retryable.
+const (
+	FailureRetryable    = "retryable"
+	FailureNonRetryable = "non_retryable"
+	FailureRateLimited  = "rate_limited"
+	FailureTimeout      = "timeout"
+)
+
+// classifiedError is implemented by errors that know whether a retry can help.
+type classifiedError interface {
+	error
+	FailureClass() string
+	Retryable() bool
+}
+
+// retryAfterError is implemented by errors carrying an upstream wait hint.
+type retryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+// Attempt records the outcome of one failed diagnosis attempt.
+type Attempt struct {
+	Number     int
+	Class      string
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// Backoff is the wait before the next attempt (0 if none follows).
+	Backoff time.Duration
+}
+
+// retryAt is when a task that failed an attempt may run again: the end of
+// the backoff recorded on its last attempt (zero if there is none).
+func (t *Task) retryAt() time.Time {
+	n := len(t.Attempts)
+	if n == 0 || t.Attempts[n-1].Backoff <= 0 {
+		return time.Time{}
+	}
+	last := t.Attempts[n-1]
+	return last.FinishedAt.Add(last.Backoff)
+}
+
+// backingOff reports whether a queued task is still waiting out the
+// backoff before its next attempt. Backing-off tasks stay in the queue
+// without holding a worker or a project slot.
+func (t *Task) backingOff(now time.Time) bool {
+	return t.Status == StatusQueued && now.Before(t.retryAt())
+}
+
+// classifyFailure returns the failure class of err, whether a retry may
+// succeed, and the upstream wait hint.
+func classifyFailure(err error) (class string, retryable bool, hint time.Duration) {
+	class, retryable = FailureRetryable, true
+	var ce classifiedError
+	if errors.Is(err, context.DeadlineExceeded) {
+		class = FailureTimeout
+	} else if errors.As(err, &ce) {
+		class, retryable = ce.FailureClass(), ce.Retryable()
+	}
+	var re retryAfterError
+	if errors.As(err, &re) {
+		hint = re.RetryAfter()
+	}
+	return class, retryable, hint
+}
+
+// backoff returns the wait before retry n (1-based): RetryDelay doubled per
+// retry, capped at RetryMaxDelay, with equal jitter so tasks that failed
+// together do not retry in lockstep. A rate-limit hint is a lower bound,
+// itself capped at RetryMaxDelay.
+func (s *Scheduler) backoff(n int, hint time.Duration) time.Duration {
+	d := s.cfg.RetryDelay
+	for i := 1; i < n && d < s.cfg.RetryMaxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, s.cfg.RetryMaxDelay)
+	if half := d / 2; half > 0 {
+		d = half + rand.N(half+1)
+	}
+	return max(d, min(hint, s.cfg.RetryMaxDelay))
+}
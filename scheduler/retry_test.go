@@ -0,0 +1,184 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"amp-sentinel/intake"
+	"amp-sentinel/logger"
+)
+
+// testError is a classified error like amp.Error.
+type testError struct {
+	class string
+	hint  time.Duration
+}
+
+func (e *testError) Error() string             { return e.class }
+func (e *testError) FailureClass() string      { return e.class }
+func (e *testError) Retryable() bool           { return e.class != FailureNonRetryable }
+func (e *testError) RetryAfter() time.Duration { return e.hint }
+
+func TestClassifyFailure(t *testing.T) {
+	cases := []struct {
+		err       error
+		class     string
+		retryable bool
+		hint      time.Duration
+	}{
+		{errors.New("boom"), FailureRetryable, true, 0},
+		{fmt.Errorf("amp execution: %w", context.DeadlineExceeded), FailureTimeout, true, 0},
+		{fmt.Errorf("project lookup: %w", &testError{class: FailureNonRetryable}), FailureNonRetryable, false, 0},
+		{fmt.Errorf("amp execution: %w", &testError{class: FailureRateLimited, hint: time.Minute}), FailureRateLimited, true, time.Minute},
+	}
+	for _, tc := range cases {
+		class, retryable, hint := classifyFailure(tc.err)
+		if class != tc.class || retryable != tc.retryable || hint != tc.hint {
+			t.Errorf("%v: got (%s, %v, %s), want (%s, %v, %s)", tc.err, class, retryable, hint, tc.class, tc.retryable, tc.hint)
+		}
+	}
+}
+
+func TestScheduler_Backoff(t *testing.T) {
+	s := New(Config{RetryDelay: time.Second, RetryMaxDelay: 10 * time.Second}, nil, logger.Nop())
+	for _, tc := range []struct {
+		n        int
+		min, max time.Duration
+	}{
+		{1, 500 * time.Millisecond, time.Second},
+		{2, time.Second, 2 * time.Second},
+		{3, 2 * time.Second, 4 * time.Second},
+		{10, 5 * time.Second, 10 * time.Second},
+	} {
+		for i := 0; i < 20; i++ {
+			if d := s.backoff(tc.n, 0); d < tc.min || d > tc.max {
+				t.Fatalf("backoff(%d) = %s, want in [%s, %s]", tc.n, d, tc.min, tc.max)
+			}
+		}
+	}
+	if d := s.backoff(1, 3*time.Second); d != 3*time.Second {
+		t.Errorf("rate-limit hint should be a lower bound, got %s", d)
+	}
+	if d := s.backoff(1, time.Hour); d != 10*time.Second {
+		t.Errorf("rate-limit hint should be capped at the max delay, got %s", d)
+	}
+}
+
+func TestScheduler_ClassifiedRetries(t *testing.T) {
+	st := newMemTaskStore()
+	var mu sync.Mutex
+	calls := map[string][]time.Time{}
+	s := New(Config{MaxConcurrency: 2, ProjectConcurrency: 2, RetryCount: 3, RetryDelay: time.Millisecond, Store: st},
+		func(ctx context.Context, taskID string, event *intake.RawEvent) error {
+			mu.Lock()
+			calls[event.ID] = append(calls[event.ID], time.Now())
+			n := len(calls[event.ID])
+			mu.Unlock()
+			switch {
+			case event.ID == "evt-perm":
+				return fmt.Errorf("project lookup: %w", &testError{class: FailureNonRetryable})
+			case n == 1:
+				return &testError{class: FailureRateLimited, hint: 50 * time.Millisecond}
+			}
+			return nil
+		}, logger.Nop())
+	s.Start()
+	defer s.Stop()
+
+	permID, _ := s.Submit(testEvent("evt-perm", "critical"))
+	limitedID, _ := s.Submit(testEvent("evt-limited", "critical"))
+	waitFor(t, func() bool { return s.failed.Load() == 1 && s.completed.Load() == 1 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	if n := len(calls["evt-perm"]); n != 1 {
+		t.Errorf("non-retryable error was attempted %d times", n)
+	}
+	perm := st.get(permID)
+	if perm.Status != StatusFailed || perm.FailureClass != FailureNonRetryable || len(perm.Attempts) != 1 || perm.Attempts[0].Backoff != 0 {
+		t.Errorf("non-retryable task: status=%s class=%s attempts=%+v", perm.Status, perm.FailureClass, perm.Attempts)
+	}
+
+	limited := calls["evt-limited"]
+	if len(limited) != 2 || limited[1].Sub(limited[0]) < 50*time.Millisecond {
+		t.Errorf("rate-limit hint not honoured: calls=%v", limited)
+	}
+	got := st.get(limitedID)
+	if got.Status != StatusCompleted || got.FailureClass != "" || len(got.Attempts) != 1 || got.Attempts[0].Class != FailureRateLimited {
+		t.Errorf("rate-limited task: status=%s class=%q attempts=%+v", got.Status, got.FailureClass, got.Attempts)
+	}
+}
+
+func TestScheduler_BackoffReleasesSlot(t *testing.T) {
+	st := newMemTaskStore()
+	var mu sync.Mutex
+	var order []string
+	s := New(Config{MaxConcurrency: 1, RetryCount: 1, RetryDelay: time.Millisecond, Store: st},
+		func(ctx context.Context, taskID string, event *intake.RawEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, event.ID)
+			if event.ID == "evt-limited" && len(order) == 1 {
+				return &testError{class: FailureRateLimited, hint: 200 * time.Millisecond}
+			}
+			return nil
+		}, logger.Nop())
+
+	limitedID, _ := s.Submit(testEvent("evt-limited", "critical"))
+	s.Submit(testEvent("evt-other", "info"))
+	s.Start()
+	defer s.Stop()
+
+	// The only worker and the project slot are free while the rate-limited
+	// task backs off, so the other task runs in between.
+	waitFor(t, func() bool { return s.completed.Load() == 2 })
+	mu.Lock()
+	defer mu.Unlock()
+	if len(order) != 3 || order[1] != "evt-other" || order[2] != "evt-limited" {
+		t.Errorf("run order = %v, want the other task during the backoff", order)
+	}
+	if got := st.get(limitedID); got.Status != StatusCompleted || len(got.Attempts) != 1 || got.Attempts[0].Backoff < 200*time.Millisecond {
+		t.Errorf("rate-limited task: status=%s attempts=%+v", got.Status, got.Attempts)
+	}
+}
+
+func TestScheduler_LeasedBackoff(t *testing.T) {
+	st := newMemLeaseStore()
+	var mu sync.Mutex
+	var calls []time.Time
+	s := New(Config{
+		MaxConcurrency: 1,
+		RetryCount:     1,
+		RetryDelay:     time.Millisecond,
+		Leases:         st,
+		PollInterval:   5 * time.Millisecond,
+	}, func(ctx context.Context, taskID string, event *intake.RawEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, time.Now())
+		if len(calls) == 1 {
+			return &testError{class: FailureRateLimited, hint: 200 * time.Millisecond}
+		}
+		return nil
+	}, logger.Nop())
+
+	id, _ := s.Submit(testEvent("evt-limited", "critical"))
+	s.Start()
+	defer s.Stop()
+
+	waitFor(t, func() bool { return s.completed.Load() == 1 })
+	mu.Lock()
+	defer mu.Unlock()
+	got := st.get(id)
+	if len(got.Attempts) != 1 {
+		t.Fatalf("attempts = %+v", got.Attempts)
+	}
+	// The retry is not claimed before the backoff recorded on the attempt ends.
+	if retryAt := got.Attempts[0].FinishedAt.Add(got.Attempts[0].Backoff); len(calls) != 2 || calls[1].Before(retryAt) {
+		t.Errorf("retried at %v, backoff ends at %v", calls, retryAt)
+	}
+}
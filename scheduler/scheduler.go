@@ -2,7 +2,9 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -17,6 +19,20 @@ import (
 // taskID is the scheduler-assigned task identifier for tracking.
 type DiagnoseFunc func(ctx context.Context, taskID string, event *intake.RawEvent) error
 
+// TaskStore persists tasks so queued and running work survives a restart
+// or crash. The scheduler owns the task lifecycle fields (status, priority,
+// retry count, error, timestamps) and saves them on every transition.
+type TaskStore interface {
+	// SaveTask creates the task record or updates its lifecycle fields.
+	SaveTask(task *Task) error
+	// LoadUnfinished returns tasks left pending, queued or running. Event is
+	// nil for tasks whose event can no longer be loaded.
+	LoadUnfinished() ([]*Task, error)
+}
+
+// interruptedReason is recorded on tasks a restart left with no attempts.
+const interruptedReason = "interrupted by restart"
+
 // Config holds scheduler configuration.
 type Config struct {
 	MaxConcurrency int
@@ -24,12 +40,73 @@ type Config struct {
 	DefaultTimeout time.Duration
 	RetryCount     int
 	RetryDelay     time.Duration
+	// RetryMaxDelay caps the exponential backoff between attempts
+	// (default 5m), including rate-limit hints from the error. A task
+	// backing off waits in the queue, not in a worker.
+	RetryMaxDelay time.Duration
+	// Store persists the queue (nil keeps it in memory only).
+	Store TaskStore
+	// ProjectConcurrency caps the tasks running at once per project
+	// (default 1: diagnoses of one project serialize on its source checkout).
+	ProjectConcurrency int
+	// ProjectPolicy returns per-project overrides (may be nil).
+	ProjectPolicy func(projectKey string) ProjectPolicy
+	// AgingInterval raises a queued task's effective priority one level
+	// (info -> warning -> critical) per interval waited, so a stream of
+	// higher-severity tasks cannot starve it (0 disables aging).
+	AgingInterval time.Duration
+	// MaxQueueWait bounds the queue wait per severity; overdue tasks are
+	// promoted to critical or expired.
+	MaxQueueWait map[string]QueueWaitLimit
+	// Admit is asked before each project's next task is dispatched and may
+	// defer the project or degrade the task (nil admits everything).
+	Admit func(projectKey, severity string) Admission
+	// Leases switches the scheduler to distributed mode: tasks are queued
+	// in the shared store and claimed by lease, so several replicas can
+	// serve one queue (nil keeps the in-process queue). Store defaults to it.
+	Leases LeaseStore
+	// ReplicaID names this replica as lease owner (default host, pid and a
+	// random suffix).
+	ReplicaID string
+	// LeaseDuration is how long a claimed task stays leased without a
+	// heartbeat (default 30s); heartbeats renew it every third of that.
+	LeaseDuration time.Duration
+	// PollInterval is how often idle replicas look for claimable tasks
+	// (default 2s).
+	PollInterval time.Duration
+	// Fingerprint returns an event's diagnosis fingerprint. An event whose
+	// fingerprint matches a queued or running task is attached to that task
+	// instead of being diagnosed again (nil or "" disables coalescing).
+	// Ignored in distributed mode, which does not coalesce.
+	Fingerprint func(event *intake.RawEvent) string
+	// OnAttach persists an event attached to task (may be nil). If it
+	// fails the event gets a task of its own.
+	OnAttach func(task *Task, event *intake.RawEvent) error
+	// OnSettle is called once a task with attached events is finished, so
+	// the attached events can take its outcome (may be nil).
+	OnSettle func(task *Task)
+	// OnDiscard is called when a task is finished without the diagnosis
+	// function reporting on its event (it expired in the queue or was
+	// cancelled), so the event can record the outcome (may be nil).
+	OnDiscard func(task *Task)
+}
+
+// ProjectPolicy overrides scheduling for one project. Zero fields keep the
+// defaults.
+type ProjectPolicy struct {
+	// MaxConcurrency caps the project's running tasks.
+	MaxConcurrency int
+	// Weight is the project's share in round-robin among projects with
+	// tasks of the same severity (default 1).
+	Weight int
 }
 
 // Scheduler manages a pool of workers that process diagnosis tasks
-// using a priority queue (critical > warning > info).
+// using a priority queue (critical > warning > info), with per-project
+// concurrency caps and weighted round-robin between projects.
 type Scheduler struct {
 	cfg       Config
+	store     TaskStore
 	diagnose  DiagnoseFunc
 	log       logger.Logger
 	pq        *priorityQueue
@@ -41,6 +118,19 @@ type Scheduler struct {
 	running   atomic.Int32
 	completed atomic.Int64
 	failed    atomic.Int64
+	expired   atomic.Int64
+	cancelled atomic.Int64
+	coalesced atomic.Int64
+	// sweepEvery is how often max queue waits are enforced.
+	sweepEvery time.Duration
+	// leases, replicaID and claimWake serve distributed mode.
+	leases    LeaseStore
+	replicaID string
+	claimWake chan struct{}
+	claimMu   sync.Mutex
+	// inflightFP indexes unfinished tasks by fingerprint for coalescing.
+	coalesceMu sync.Mutex
+	inflightFP map[string]*Task
 }
 
 // New creates a scheduler with the given config and diagnosis function.
@@ -60,20 +150,94 @@ func New(cfg Config, diagnose DiagnoseFunc, log logger.Logger) *Scheduler {
 	if cfg.RetryDelay <= 0 {
 		cfg.RetryDelay = 10 * time.Second
 	}
+	if cfg.RetryMaxDelay <= 0 {
+		cfg.RetryMaxDelay = 5 * time.Minute
+	}
+	if cfg.RetryMaxDelay < cfg.RetryDelay {
+		cfg.RetryMaxDelay = cfg.RetryDelay
+	}
+	if cfg.ProjectConcurrency <= 0 {
+		cfg.ProjectConcurrency = 1
+	}
+	if cfg.Leases != nil {
+		if cfg.Store == nil {
+			cfg.Store = cfg.Leases
+		}
+		if cfg.ReplicaID == "" {
+			cfg.ReplicaID = defaultReplicaID()
+		}
+		if cfg.LeaseDuration <= 0 {
+			cfg.LeaseDuration = 30 * time.Second
+		}
+		if cfg.PollInterval <= 0 {
+			cfg.PollInterval = 2 * time.Second
+		}
+	}
+	pq := newPriorityQueue(cfg.QueueSize, cfg.AgingInterval, cfg.projectPolicy)
+	pq.admit = cfg.Admit
 	return &Scheduler{
-		cfg:      cfg,
-		diagnose: diagnose,
-		log:      log,
-		pq:       newPriorityQueue(cfg.QueueSize),
+		cfg:        cfg,
+		store:      cfg.Store,
+		diagnose:   diagnose,
+		log:        log,
+		pq:         pq,
+		sweepEvery: 10 * time.Second,
+		leases:     cfg.Leases,
+		replicaID:  cfg.ReplicaID,
+		claimWake:  make(chan struct{}, cfg.MaxConcurrency),
+		inflightFP: make(map[string]*Task),
+	}
+}
+
+// projectPolicy resolves a project's concurrency cap and weight.
+func (cfg Config) projectPolicy(projectKey string) (limit, weight int) {
+	limit, weight = cfg.ProjectConcurrency, 1
+	if cfg.ProjectPolicy != nil {
+		p := cfg.ProjectPolicy(projectKey)
+		if p.MaxConcurrency > 0 {
+			limit = p.MaxConcurrency
+		}
+		if p.Weight > 0 {
+			weight = p.Weight
+		}
 	}
+	return limit, weight
 }
 
-// Start launches the worker goroutines. Call Stop to shut down.
+// Start recovers unfinished tasks from the store and launches the worker
+// goroutines. Call Stop to shut down. In distributed mode nothing is
+// recovered: the workers claim from the store, taking over expired leases.
 func (s *Scheduler) Start() {
 	s.ctx, s.cancel = context.WithCancel(context.Background())
+	if s.leases != nil {
+		s.log.Info("scheduler.started",
+			logger.Int("max_concurrency", s.cfg.MaxConcurrency),
+			logger.Int("project_concurrency", s.cfg.ProjectConcurrency),
+			logger.String("replica", s.replicaID),
+			logger.String("lease_duration", s.cfg.LeaseDuration.String()),
+		)
+		if s.cfg.Fingerprint != nil {
+			s.log.Warn("scheduler.coalescing_disabled",
+				logger.String("reason", "in-flight tasks are spread over replicas"),
+			)
+		}
+		for i := 0; i < s.cfg.MaxConcurrency; i++ {
+			s.wg.Add(1)
+			go s.leaseWorker(i)
+		}
+		// Admission is re-asked on every claim poll; only queue waits
+		// need the sweep.
+		if len(s.cfg.MaxQueueWait) > 0 {
+			s.wg.Add(1)
+			go s.sweepLoop()
+		}
+		return
+	}
+	s.reconcile()
 
 	s.log.Info("scheduler.started",
 		logger.Int("max_concurrency", s.cfg.MaxConcurrency),
+		logger.Int("project_concurrency", s.cfg.ProjectConcurrency),
 		logger.Int("queue_size", s.cfg.QueueSize),
 	)
 
@@ -81,6 +245,118 @@ func (s *Scheduler) Start() {
 		s.wg.Add(1)
 		go s.worker(i)
 	}
+	if len(s.cfg.MaxQueueWait) > 0 || s.cfg.Admit != nil {
+		s.wg.Add(1)
+		go s.sweepLoop()
+	}
+}
+
+// sweepLoop periodically enforces the per-severity max queue wait and
+// re-asks admission about deferred projects.
+func (s *Scheduler) sweepLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.sweepEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if s.leases != nil {
+				s.sweepLeased()
+				continue
+			}
+			s.sweep()
+			if s.cfg.Admit != nil {
+				s.pq.wake()
+			}
+		}
+	}
+}
+
+func (s *Scheduler) sweep() {
+	promoted, expired := s.pq.sweep(s.cfg.MaxQueueWait)
+	for _, task := range promoted {
+		s.promote(task)
+	}
+	for _, task := range expired {
+		s.expire(task)
+	}
+}
+
+// sweepLeased enforces the max queue waits on the shared queue. Every
+// replica sweeps; an overdue task is changed under its lease, so only one
+// replica acts on it and none can claim it meanwhile.
+func (s *Scheduler) sweepLeased() {
+	tasks, err := s.leases.Claimable(time.Now())
+	if err != nil {
+		s.log.Warn("scheduler.sweep_failed", logger.Err(err))
+		return
+	}
+	top := priorityLevels[len(priorityLevels)-1]
+	for _, c := range tasks {
+		if !s.overdue(c) {
+			continue
+		}
+		_, err := s.withLease(c.ID, func(task *Task) {
+			if !s.overdue(task) {
+				return
+			}
+			if s.cfg.MaxQueueWait[task.Event.Severity].Action == WaitActionExpire {
+				s.expire(task)
+			} else if task.Priority < top {
+				task.Priority = top
+				s.promote(task)
+			}
+		})
+		if err != nil && !errors.Is(err, ErrTaskLeased) && !errors.Is(err, ErrTaskNotActive) {
+			s.log.Warn("scheduler.sweep_failed", logger.String("task_id", c.ID), logger.Err(err))
+		}
+	}
+}
+
+// overdue reports whether a queued task has waited past its severity's
+// max queue wait.
+func (s *Scheduler) overdue(task *Task) bool {
+	if task.Event == nil || task.Status != StatusQueued {
+		return false
+	}
+	limit, ok := s.cfg.MaxQueueWait[task.Event.Severity]
+	return ok && limit.Max > 0 && time.Since(task.CreatedAt) >= limit.Max
+}
+
+// discard reports a task finished without a diagnosis to OnDiscard.
+func (s *Scheduler) discard(task *Task) {
+	if s.cfg.OnDiscard != nil {
+		s.cfg.OnDiscard(task)
+	}
+}
+
+// promote records a task raised to the top priority for waiting too long.
+func (s *Scheduler) promote(task *Task) {
+	s.save(task)
+	s.log.Warn("task.promoted",
+		logger.String("task_id", task.ID),
+		logger.String("severity", task.Event.Severity),
+		logger.Int64("waited_ms", time.Since(task.CreatedAt).Milliseconds()),
+	)
+}
+
+// expire finishes a task that waited too long to be worth diagnosing.
+func (s *Scheduler) expire(task *Task) {
+	wait := s.cfg.MaxQueueWait[task.Event.Severity].Max
+	task.Status = StatusExpired
+	task.Error = fmt.Sprintf("expired: queued longer than %s", wait)
+	task.FinishedAt = time.Now()
+	s.save(task)
+	s.discard(task)
+	s.settle(task)
+	s.expired.Add(1)
+	s.log.Warn("task.expired",
+		logger.String("task_id", task.ID),
+		logger.String("severity", task.Event.Severity),
+		logger.String("max_wait", wait.String()),
+	)
 }
 
 // Submit enqueues an incident for diagnosis. Returns the task ID.
@@ -93,17 +369,55 @@ func (s *Scheduler) Submit(event *intake.RawEvent) (string, error) {
 		return "", fmt.Errorf("scheduler is stopped, cannot accept new tasks")
 	}
 
+	// Hold coalesceMu until the new task is indexed, so identical events
+	// submitted together share one task.
+	fp := s.fingerprint(event)
+	if fp != "" {
+		s.coalesceMu.Lock()
+		defer s.coalesceMu.Unlock()
+		if existing := s.inflightFP[fp]; existing != nil && s.attach(existing, event) {
+			return existing.ID, coalescedError(existing.ID)
+		}
+	}
+
 	task := &Task{
-		ID:         "task-" + uuid.New().String()[:8],
-		Event:      event,
-		Priority:   intake.SeverityPriority(event.Severity),
-		Status:     StatusPending,
-		MaxRetries: s.cfg.RetryCount,
-		CreatedAt:  time.Now(),
+		ID:          "task-" + uuid.New().String()[:8],
+		Event:       event,
+		Priority:    intake.SeverityPriority(event.Severity),
+		Status:      StatusQueued,
+		MaxRetries:  s.cfg.RetryCount,
+		CreatedAt:   time.Now(),
+		Fingerprint: fp,
+	}
+
+	if s.leases != nil {
+		// The store is the queue: the task must be persisted to exist.
+		if err := s.store.SaveTask(task); err != nil {
+			return "", fmt.Errorf("queue task: %w", err)
+		}
+		s.wake()
+		s.log.Info("task.submitted",
+			logger.String("task_id", task.ID),
+			logger.String("incident_id", event.ID),
+			logger.String("project", event.ProjectKey),
+			logger.Int("priority", task.Priority),
+		)
+		return task.ID, nil
 	}
 
+	// Persist before queueing so a worker's later updates cannot be
+	// overwritten by this insert.
+	s.save(task)
 	if !s.pq.push(task) {
-		return "", fmt.Errorf("diagnosis queue is full (capacity: %d)", s.cfg.QueueSize)
+		err := fmt.Errorf("diagnosis queue is full (capacity: %d)", s.cfg.QueueSize)
+		task.Status = StatusFailed
+		task.Error = err.Error()
+		task.FinishedAt = time.Now()
+		s.save(task)
+		return "", err
+	}
+	if fp != "" {
+		s.inflightFP[fp] = task
 	}
 
 	s.log.Info("task.submitted",
@@ -138,12 +452,101 @@ func (s *Scheduler) Stop() {
 
 // Stats returns current scheduler statistics.
 func (s *Scheduler) Stats() map[string]any {
-	return map[string]any{
+	oldestWait := make(map[string]float64)
+	for severity, wait := range s.pq.oldestWait() {
+		oldestWait[severity] = math.Round(wait.Seconds()*10) / 10
+	}
+	stats := map[string]any{
 		"queue_length": s.pq.len(),
 		"running":      s.running.Load(),
 		"completed":    s.completed.Load(),
 		"failed":       s.failed.Load(),
+		"expired":      s.expired.Load(),
+		"cancelled":    s.cancelled.Load(),
+		"coalesced":    s.coalesced.Load(),
+		"projects":     s.pq.projectStats(),
+		// oldest_wait_seconds: per severity, the wait of the oldest queued task
+		"oldest_wait_seconds": oldestWait,
+	}
+	if s.leases != nil {
+		// The queue lives in the store; counts here are this replica's.
+		stats["replica_id"] = s.replicaID
+	}
+	return stats
+}
+
+// reconcile re-enqueues tasks a previous process left unfinished. A task
+// that was running lost its attempt; once a task has no attempts left it is
+// marked failed instead.
+func (s *Scheduler) reconcile() {
+	if s.store == nil {
+		return
+	}
+	tasks, err := s.store.LoadUnfinished()
+	if err != nil {
+		s.log.Error("scheduler.reconcile_failed", logger.Err(err))
+		return
 	}
+
+	requeued, failed := 0, 0
+	for _, task := range tasks {
+		if task.Status == StatusRunning {
+			task.RetryCount++
+		}
+		task.MaxRetries = s.cfg.RetryCount
+		if task.Event == nil || task.RetryCount > task.MaxRetries {
+			task.Status = StatusFailed
+			task.Error = interruptedReason
+			if task.Event == nil {
+				task.Error += ": event not found"
+			}
+			task.FinishedAt = time.Now()
+			s.save(task)
+			s.failed.Add(1)
+			failed++
+			continue
+		}
+		task.Status = StatusQueued
+		task.Fingerprint = s.fingerprint(task.Event)
+		s.save(task)
+		s.pq.requeue(task)
+		s.index(task)
+		requeued++
+	}
+	if requeued+failed > 0 {
+		s.log.Info("scheduler.reconciled",
+			logger.Int("requeued", requeued),
+			logger.Int("failed", failed),
+		)
+	}
+}
+
+// save persists the task. Store errors are logged: the in-memory queue
+// keeps working, only crash recovery of this task is affected. A task whose
+// lease was taken over belongs to another replica and is not saved.
+func (s *Scheduler) save(task *Task) {
+	if s.store == nil || (s.leases != nil && s.pq.leaseLost(task.ID)) {
+		return
+	}
+	if err := s.store.SaveTask(task); err != nil {
+		s.log.Warn("task.save_failed", logger.String("task_id", task.ID), logger.Err(err))
+	}
+}
+
+// interrupt handles a task cut short by shutdown. With a store it stays
+// queued for the next start; otherwise it is lost and counted as failed.
+func (s *Scheduler) interrupt(task *Task, log logger.Logger) {
+	if s.store != nil {
+		task.Status = StatusQueued
+		s.save(task)
+		log.Info("task.left_queued")
+		return
+	}
+	task.Status = StatusFailed
+	task.Error = "scheduler shutting down"
+	task.FinishedAt = time.Now()
+	s.failed.Add(1)
+	log.Warn("task.cancelled")
 }
 
 func (s *Scheduler) worker(id int) {
@@ -155,18 +558,33 @@ func (s *Scheduler) worker(id int) {
 			// Queue is closed and drained
 			return
 		}
+		if s.store != nil && s.ctx.Err() != nil {
+			// Shutting down: leave the rest of the queue persisted.
+			s.pq.done(task)
+			continue
+		}
 		s.running.Add(1)
-		s.safeProcessTask(task)
+		retry := s.safeProcessTask(task)
 		s.running.Add(-1)
+		s.pq.done(task)
+		if retry && !s.pq.requeue(task) {
+			// Shutting down: the queue no longer takes the retry.
+			s.interrupt(task, s.log.WithFields(logger.String("task_id", task.ID)))
+		}
+		if task.Status != StatusQueued {
+			s.settle(task)
+		}
 	}
 }
 
-func (s *Scheduler) safeProcessTask(task *Task) {
+func (s *Scheduler) safeProcessTask(task *Task) (retry bool) {
 	defer func() {
 		if r := recover(); r != nil {
+			retry = false
 			task.Status = StatusFailed
 			task.Error = fmt.Sprintf("panic: %v", r)
 			task.FinishedAt = time.Now()
+			s.save(task)
 			s.failed.Add(1)
 			s.log.Error("task.panic",
 				logger.String("task_id", task.ID),
@@ -174,67 +592,115 @@ func (s *Scheduler) safeProcessTask(task *Task) {
 			)
 		}
 	}()
-	s.processTask(task)
+	return s.processTask(task)
 }
 
-func (s *Scheduler) processTask(task *Task) {
+// processTask runs one attempt of the task. It returns true if the attempt
+// failed with retries left: the task is then queued, backing off, and the
+// caller puts it back in the queue.
+func (s *Scheduler) processTask(task *Task) bool {
 	log := s.log.WithFields(
 		logger.String("task_id", task.ID),
 		logger.String("incident_id", task.Event.ID),
 		logger.String("project", task.Event.ProjectKey),
 	)
 
-	for attempt := 0; attempt <= task.MaxRetries; attempt++ {
-		if attempt > 0 {
-			log.Warn("task.retrying",
-				logger.Int("attempt", attempt),
-				logger.Int("max_retries", task.MaxRetries),
-			)
-			select {
-			case <-s.ctx.Done():
-				task.Status = StatusFailed
-				task.Error = "scheduler shutting down"
-				task.FinishedAt = time.Now()
-				s.failed.Add(1)
-				log.Warn("task.cancelled_during_retry")
-				return
-			case <-time.After(s.cfg.RetryDelay):
-			}
-		}
-
-		task.Status = StatusRunning
-		task.StartedAt = time.Now()
-
-		taskCtx, cancel := context.WithTimeout(s.ctx, s.cfg.DefaultTimeout)
-		err := s.diagnose(taskCtx, task.ID, task.Event)
-		cancel()
+	// ctx is cancelled by shutdown or by an admin cancelling the task.
+	ctx, cancelTask := context.WithCancel(s.ctx)
+	defer cancelTask()
+	s.pq.attach(task.ID, cancelTask)
+	if task.Degraded {
+		ctx = withDegraded(ctx)
+		log.Warn("task.degraded", logger.String("reason", task.Actions[len(task.Actions)-1].Reason))
+	}
 
-		if err == nil {
-			task.Status = StatusCompleted
-			task.FinishedAt = time.Now()
-			s.completed.Add(1)
-			log.Info("task.completed",
-				logger.Int64("duration_ms", time.Since(task.StartedAt).Milliseconds()),
-			)
-			return
-		}
+	// One attempt per dispatch: a retry goes back to the queue and waits
+	// out its backoff there, so it holds neither a worker nor the project's
+	// slot meanwhile. Recovered tasks resume at their next attempt.
+	attempt := task.RetryCount
+	if attempt > 0 {
+		log.Warn("task.retrying",
+			logger.Int("attempt", attempt),
+			logger.Int("max_retries", task.MaxRetries),
+		)
+	}
 
-		task.Error = err.Error()
-		task.RetryCount = attempt + 1
+	task.Status = StatusRunning
+	task.StartedAt = time.Now()
+	s.save(task)
+
+	taskCtx, cancel := context.WithTimeout(ctx, s.cfg.DefaultTimeout)
+	err := s.diagnose(taskCtx, task.ID, task.Event)
+	cancel()
+
+	if err == nil {
+		task.Status = StatusCompleted
+		task.Error = ""
+		task.FailureClass = ""
+		task.FinishedAt = time.Now()
+		s.save(task)
+		s.completed.Add(1)
+		log.Info("task.completed",
+			logger.Int64("duration_ms", time.Since(task.StartedAt).Milliseconds()),
+		)
+		return false
+	}
 
-		if s.ctx.Err() != nil {
-			task.Status = StatusFailed
-			task.FinishedAt = time.Now()
-			s.failed.Add(1)
-			log.Warn("task.cancelled", logger.Err(err))
-			return
-		}
+	if action := s.pq.cancelRequest(task.ID); action != nil {
+		log.Warn("task.interrupted", logger.Err(err))
+		s.finishCancelled(task, *action)
+		return false
+	}
+	if s.pq.leaseLost(task.ID) {
+		// Another replica took the task over; its outcome is theirs.
+		log.Warn("task.abandoned", logger.Err(err))
+		return false
+	}
+	if s.ctx.Err() != nil {
+		// The attempt was cut short by shutdown, not by the diagnosis.
+		log.Warn("task.interrupted", logger.Err(err))
+		s.interrupt(task, log)
+		return false
+	}
 
-		log.Error("task.attempt_failed", logger.Int("attempt", attempt), logger.Err(err))
+	class, retryable, hint := classifyFailure(err)
+	task.Error = err.Error()
+	task.FailureClass = class
+	task.RetryCount = attempt + 1
+	record := Attempt{
+		Number:     attempt,
+		Class:      class,
+		Error:      task.Error,
+		StartedAt:  task.StartedAt,
+		FinishedAt: time.Now(),
+	}
+	more := retryable && task.RetryCount <= task.MaxRetries
+	if more {
+		record.Backoff = s.backoff(task.RetryCount, hint)
+		task.Status = StatusQueued
+	}
+	task.Attempts = append(task.Attempts, record)
+	log.Error("task.attempt_failed",
+		logger.Int("attempt", attempt),
+		logger.String("failure_class", class),
+		logger.Err(err),
+	)
+	if more {
+		s.save(task)
+		log.Warn("task.retry_scheduled",
+			logger.Int("retry_count", task.RetryCount),
+			logger.Int64("backoff_ms", record.Backoff.Milliseconds()),
+		)
+		return true
 	}
 
 	task.Status = StatusFailed
 	task.FinishedAt = time.Now()
+	s.save(task)
 	s.failed.Add(1)
-	log.Error("task.failed", logger.String("error", task.Error))
+	log.Error("task.failed",
+		logger.String("error", task.Error),
+		logger.String("failure_class", task.FailureClass),
+	)
+	return false
 }
@@ -0,0 +1,573 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"amp-sentinel/intake"
+	"amp-sentinel/logger"
+)
+
+// memTaskStore is an in-memory TaskStore for tests.
+type memTaskStore struct {
+	mu    sync.Mutex
+	tasks map[string]Task
+	// unfinished is returned by LoadUnfinished.
+	unfinished []*Task
+}
+
+func newMemTaskStore(unfinished ...*Task) *memTaskStore {
+	return &memTaskStore{tasks: make(map[string]Task), unfinished: unfinished}
+}
+
+func (m *memTaskStore) SaveTask(task *Task) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tasks[task.ID] = *task
+	return nil
+}
+
+func (m *memTaskStore) LoadUnfinished() ([]*Task, error) {
+	return m.unfinished, nil
+}
+
+func (m *memTaskStore) get(id string) Task {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tasks[id]
+}
+
+// memLeaseStore is an in-memory LeaseStore for tests.
+type memLeaseStore struct {
+	*memTaskStore
+	events map[string]*intake.RawEvent
+	owners map[string]string
+	until  map[string]time.Time
+}
+
+func newMemLeaseStore() *memLeaseStore {
+	return &memLeaseStore{
+		memTaskStore: newMemTaskStore(),
+		events:       make(map[string]*intake.RawEvent),
+		owners:       make(map[string]string),
+		until:        make(map[string]time.Time),
+	}
+}
+
+func (m *memLeaseStore) SaveTask(task *Task) error {
+	m.mu.Lock()
+	m.events[task.ID] = task.Event
+	m.mu.Unlock()
+	return m.memTaskStore.SaveTask(task)
+}
+
+func (m *memLeaseStore) load(id string) *Task {
+	t, ok := m.tasks[id]
+	if !ok {
+		return nil
+	}
+	t.Event = m.events[id]
+	return &t
+}
+
+func (m *memLeaseStore) Claimable(now time.Time) ([]*Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []*Task
+	for id, t := range m.tasks {
+		if t.Status.unfinished() && !m.until[id].After(now) {
+			out = append(out, m.load(id))
+		}
+	}
+	return out, nil
+}
+
+func (m *memLeaseStore) Leased(now time.Time) (map[string]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]int)
+	for id, until := range m.until {
+		if until.After(now) {
+			out[m.events[id].ProjectKey]++
+		}
+	}
+	return out, nil
+}
+
+func (m *memLeaseStore) Load(id string) (*Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.load(id), nil
+}
+
+func (m *memLeaseStore) Acquire(id, owner string, now, until time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.until[id].After(now) && m.owners[id] != owner {
+		return false, nil
+	}
+	m.owners[id], m.until[id] = owner, until
+	return true, nil
+}
+
+func (m *memLeaseStore) Renew(id, owner string, until time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.owners[id] != owner {
+		return false, nil
+	}
+	m.until[id] = until
+	return true, nil
+}
+
+func (m *memLeaseStore) Release(id, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.owners[id] == owner {
+		delete(m.owners, id)
+		delete(m.until, id)
+	}
+	return nil
+}
+
+func testEvent(id, severity string) *intake.RawEvent {
+	return &intake.RawEvent{ID: id, ProjectKey: "proj-a", Severity: severity}
+}
+
+// waitFor polls until cond holds or the test times out.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestScheduler_ReconcileOnStart(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+	st := newMemTaskStore(
+		&Task{ID: "task-queued", Event: testEvent("evt-1", "info"), Priority: 1, Status: StatusQueued, CreatedAt: old},
+		&Task{ID: "task-running", Event: testEvent("evt-2", "critical"), Priority: 3, Status: StatusRunning, RetryCount: 1, CreatedAt: old},
+		&Task{ID: "task-exhausted", Event: testEvent("evt-3", "warning"), Priority: 2, Status: StatusRunning, RetryCount: 2, CreatedAt: old},
+		&Task{ID: "task-orphan", Priority: 2, Status: StatusQueued, CreatedAt: old},
+	)
+
+	var mu sync.Mutex
+	var ran []string
+	s := New(Config{MaxConcurrency: 1, RetryCount: 2, RetryDelay: time.Millisecond, Store: st},
+		func(ctx context.Context, taskID string, event *intake.RawEvent) error {
+			mu.Lock()
+			ran = append(ran, taskID)
+			mu.Unlock()
+			return nil
+		}, logger.Nop())
+	s.Start()
+	defer s.Stop()
+
+	waitFor(t, func() bool { return s.completed.Load() == 2 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	// Recovered tasks keep their priority: the critical one runs first.
+	if len(ran) != 2 || ran[0] != "task-running" || ran[1] != "task-queued" {
+		t.Fatalf("ran = %v", ran)
+	}
+	if got := st.get("task-running"); got.Status != StatusCompleted || got.RetryCount != 2 {
+		t.Errorf("task-running: status=%s retries=%d", got.Status, got.RetryCount)
+	}
+	for _, id := range []string{"task-exhausted", "task-orphan"} {
+		got := st.get(id)
+		if got.Status != StatusFailed || got.Error == "" || got.FinishedAt.IsZero() {
+			t.Errorf("%s: status=%s error=%q", id, got.Status, got.Error)
+		}
+	}
+	if st.get("task-exhausted").Error != interruptedReason {
+		t.Errorf("task-exhausted error = %q", st.get("task-exhausted").Error)
+	}
+}
+
+func TestScheduler_PersistsLifecycle(t *testing.T) {
+	st := newMemTaskStore()
+	release := make(chan struct{})
+	s := New(Config{MaxConcurrency: 1, RetryCount: 1, RetryDelay: time.Millisecond, Store: st},
+		func(ctx context.Context, taskID string, event *intake.RawEvent) error {
+			<-release
+			if event.ID == "evt-bad" {
+				return errors.New("amp exited")
+			}
+			return nil
+		}, logger.Nop())
+	s.Start()
+	defer s.Stop()
+
+	okID, err := s.Submit(testEvent("evt-ok", "warning"))
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	waitFor(t, func() bool { return st.get(okID).Status == StatusRunning })
+	badID, _ := s.Submit(testEvent("evt-bad", "warning"))
+	if got := st.get(badID); got.Status != StatusQueued {
+		t.Fatalf("submitted task status = %s, want queued", got.Status)
+	}
+	close(release)
+
+	waitFor(t, func() bool { return s.failed.Load() == 1 && s.completed.Load() == 1 })
+	if got := st.get(okID); got.Status != StatusCompleted {
+		t.Errorf("ok task status = %s", got.Status)
+	}
+	if got := st.get(badID); got.Status != StatusFailed || got.RetryCount != 2 || got.Error != "amp exited" {
+		t.Errorf("bad task: status=%s retries=%d error=%q", got.Status, got.RetryCount, got.Error)
+	}
+}
+
+func TestScheduler_StopLeavesQueuedTasks(t *testing.T) {
+	st := newMemTaskStore()
+	started := make(chan struct{})
+	s := New(Config{MaxConcurrency: 1, Store: st},
+		func(ctx context.Context, taskID string, event *intake.RawEvent) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		}, logger.Nop())
+	s.Start()
+
+	runningID, _ := s.Submit(testEvent("evt-1", "critical"))
+	<-started
+	queuedID, _ := s.Submit(testEvent("evt-2", "info"))
+	s.Stop()
+
+	// Both stay queued for the next start; the interrupted attempt is not counted.
+	for _, id := range []string{runningID, queuedID} {
+		if got := st.get(id); got.Status != StatusQueued || got.RetryCount != 0 {
+			t.Errorf("%s: status=%s retries=%d", id, got.Status, got.RetryCount)
+		}
+	}
+	if s.failed.Load() != 0 {
+		t.Errorf("failed = %d, want 0", s.failed.Load())
+	}
+}
+
+func TestScheduler_Cancel(t *testing.T) {
+	st := newMemTaskStore()
+	started := make(chan struct{}, 1)
+	var calls atomic.Int32
+	var discardMu sync.Mutex
+	var discarded []string
+	onDiscard := func(task *Task) {
+		discardMu.Lock()
+		discarded = append(discarded, task.ID)
+		discardMu.Unlock()
+	}
+	s := New(Config{MaxConcurrency: 1, RetryCount: 2, RetryDelay: time.Millisecond, Store: st, OnDiscard: onDiscard},
+		func(ctx context.Context, taskID string, event *intake.RawEvent) error {
+			calls.Add(1)
+			started <- struct{}{}
+			<-ctx.Done()
+			return ctx.Err()
+		}, logger.Nop())
+	s.Start()
+	defer s.Stop()
+
+	runningID, _ := s.Submit(testEvent("evt-1", "critical"))
+	<-started
+	queuedID, _ := s.Submit(testEvent("evt-2", "info"))
+
+	if status, err := s.Cancel(queuedID, "alice", "noise"); err != nil || status != StatusCancelled {
+		t.Fatalf("cancel queued: status=%s err=%v", status, err)
+	}
+	if got := st.get(queuedID); got.Status != StatusCancelled || len(got.Actions) != 1 || got.Actions[0].By != "alice" {
+		t.Errorf("queued task: status=%s actions=%v", got.Status, got.Actions)
+	}
+
+	if status, err := s.Cancel(runningID, "bob", ""); err != nil || status != StatusRunning {
+		t.Fatalf("cancel running: status=%s err=%v", status, err)
+	}
+	waitFor(t, func() bool { return st.get(runningID).Status == StatusCancelled })
+	if got := st.get(runningID); got.RetryCount != 0 || len(got.Actions) != 1 || got.Actions[0].Action != ActionCancel {
+		t.Errorf("running task: retries=%d actions=%v", got.RetryCount, got.Actions)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("diagnose called %d times, a cancelled task must not retry", calls.Load())
+	}
+	if _, err := s.Cancel(runningID, "", ""); !errors.Is(err, ErrTaskNotActive) {
+		t.Errorf("cancel finished task: err=%v", err)
+	}
+	if s.cancelled.Load() != 2 || s.failed.Load() != 0 {
+		t.Errorf("cancelled=%d failed=%d", s.cancelled.Load(), s.failed.Load())
+	}
+	discardMu.Lock()
+	defer discardMu.Unlock()
+	if len(discarded) != 2 || discarded[0] != queuedID || discarded[1] != runningID {
+		t.Errorf("discarded = %v, want both cancelled tasks", discarded)
+	}
+}
+
+func TestScheduler_PauseAndPriority(t *testing.T) {
+	st := newMemTaskStore()
+	var mu sync.Mutex
+	var ran []string
+	s := New(Config{MaxConcurrency: 1, Store: st},
+		func(ctx context.Context, taskID string, event *intake.RawEvent) error {
+			mu.Lock()
+			ran = append(ran, event.ID)
+			mu.Unlock()
+			return nil
+		}, logger.Nop())
+
+	infoID, _ := s.Submit(testEvent("evt-info", "info"))
+	warnID, _ := s.Submit(testEvent("evt-warn", "warning"))
+	if !s.PauseProject("proj-a", "alice", "deploy freeze") || s.PauseProject("proj-a", "alice", "") {
+		t.Fatal("pause should change state exactly once")
+	}
+	s.Start()
+	defer s.Stop()
+
+	time.Sleep(20 * time.Millisecond)
+	if s.completed.Load() != 0 {
+		t.Fatal("paused project was dispatched")
+	}
+	if !s.Stats()["projects"].(map[string]ProjectStat)["proj-a"].Paused {
+		t.Error("stats should report the project as paused")
+	}
+
+	if err := s.SetPriority(infoID, intake.SeverityPriority("critical"), "alice", ""); err != nil {
+		t.Fatalf("SetPriority: %v", err)
+	}
+	if got := st.get(infoID); got.Priority != intake.SeverityPriority("critical") {
+		t.Errorf("stored priority = %d", got.Priority)
+	}
+	if !s.ResumeProject("proj-a", "alice", "") {
+		t.Fatal("resume should change state")
+	}
+	waitFor(t, func() bool { return s.completed.Load() == 2 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ran) != 2 || ran[0] != "evt-info" {
+		t.Errorf("ran = %v, want the reprioritized task first", ran)
+	}
+	var actions []string
+	for _, a := range st.get(infoID).Actions {
+		actions = append(actions, a.Action)
+	}
+	if strings.Join(actions, ",") != "pause,priority,resume" {
+		t.Errorf("info task actions = %v", actions)
+	}
+	if len(st.get(warnID).Actions) != 2 {
+		t.Errorf("warning task actions = %v", st.get(warnID).Actions)
+	}
+	if err := s.SetPriority(warnID, 100, "", ""); !errors.Is(err, ErrTaskNotActive) {
+		t.Errorf("SetPriority on finished task: err=%v", err)
+	}
+}
+
+func TestScheduler_Admission(t *testing.T) {
+	st := newMemTaskStore()
+	var over atomic.Bool
+	over.Store(true)
+	var degraded atomic.Int32
+	s := New(Config{
+		MaxConcurrency: 1,
+		Store:          st,
+		Admit: func(projectKey, severity string) Admission {
+			switch {
+			case !over.Load():
+				return Admission{}
+			case severity == "info":
+				return Admission{Defer: true, Reason: "over budget"}
+			default:
+				return Admission{Degrade: true, Reason: "over budget"}
+			}
+		},
+	}, func(ctx context.Context, taskID string, event *intake.RawEvent) error {
+		if Degraded(ctx) {
+			degraded.Add(1)
+		}
+		return nil
+	}, logger.Nop())
+	s.sweepEvery = 10 * time.Millisecond
+
+	infoID, _ := s.Submit(testEvent("evt-info", "info"))
+	critID, _ := s.Submit(testEvent("evt-crit", "critical"))
+	s.Start()
+	defer s.Stop()
+
+	waitFor(t, func() bool { return s.completed.Load() == 1 })
+	if degraded.Load() != 1 {
+		t.Errorf("degraded runs = %d, want 1", degraded.Load())
+	}
+	if a := st.get(critID).Actions; len(a) != 1 || a[0].Action != ActionDegrade {
+		t.Errorf("critical task actions = %v", a)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if got := st.get(infoID).Status; got != StatusQueued {
+		t.Fatalf("info task status = %s, want queued while deferred", got)
+	}
+	if s.Stats()["projects"].(map[string]ProjectStat)["proj-a"].Deferred == "" {
+		t.Error("stats should report the deferral")
+	}
+
+	over.Store(false)
+	waitFor(t, func() bool { return s.completed.Load() == 2 })
+	if degraded.Load() != 1 {
+		t.Errorf("task admitted after the budget freed up ran degraded")
+	}
+}
+
+func TestScheduler_StopWithDeferredTasks(t *testing.T) {
+	s := New(Config{
+		MaxConcurrency: 2,
+		Store:          newMemTaskStore(),
+		Admit: func(string, string) Admission {
+			return Admission{Defer: true}
+		},
+	}, func(context.Context, string, *intake.RawEvent) error { return nil }, logger.Nop())
+	s.Submit(testEvent("evt-1", "info"))
+	s.Start()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop hung on a deferred task")
+	}
+}
+
+func TestScheduler_Coalesce(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	settled := make(chan Task, 1)
+	s := New(Config{
+		MaxConcurrency: 1,
+		Fingerprint:    func(event *intake.RawEvent) string { return event.Title },
+		OnSettle:       func(task *Task) { settled <- *task },
+	}, func(ctx context.Context, taskID string, event *intake.RawEvent) error {
+		calls.Add(1)
+		<-release
+		return nil
+	}, logger.Nop())
+	s.Start()
+	defer s.Stop()
+
+	event := func(id, fp string) *intake.RawEvent {
+		evt := testEvent(id, "warning")
+		evt.Title = fp
+		return evt
+	}
+	first, err := s.Submit(event("evt-1", "db-down"))
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	waitFor(t, func() bool { return calls.Load() == 1 })
+
+	id, err := s.Submit(event("evt-2", "db-down"))
+	if !errors.Is(err, ErrCoalesced) || id != first {
+		t.Fatalf("same fingerprint: id=%s err=%v, want %s and ErrCoalesced", id, err, first)
+	}
+	if !strings.HasPrefix(err.Error(), "coalesced") {
+		t.Errorf("error %q must start with \"coalesced\"", err)
+	}
+	other, err := s.Submit(event("evt-3", "disk-full"))
+	if err != nil || other == first {
+		t.Fatalf("other fingerprint: id=%s err=%v", other, err)
+	}
+
+	close(release)
+	select {
+	case task := <-settled:
+		if task.ID != first || task.Status != StatusCompleted || len(task.Attached) != 1 || task.Attached[0] != "evt-2" {
+			t.Errorf("settled task %s: status=%s attached=%v", task.ID, task.Status, task.Attached)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnSettle not called")
+	}
+
+	// A finished task takes no more events.
+	waitFor(t, func() bool { return calls.Load() == 2 && s.Stats()["running"] == int32(0) })
+	if id, err := s.Submit(event("evt-4", "db-down")); err != nil || id == first {
+		t.Errorf("after finish: id=%s err=%v, want a new task", id, err)
+	}
+	if s.coalesced.Load() != 1 {
+		t.Errorf("coalesced=%d, want 1", s.coalesced.Load())
+	}
+}
+
+func TestScheduler_CoalesceIntoCancelledTask(t *testing.T) {
+	var attachErr error
+	settled := make(chan Task, 1)
+	s := New(Config{
+		MaxConcurrency: 1,
+		Fingerprint:    func(event *intake.RawEvent) string { return "same" },
+		OnAttach:       func(task *Task, event *intake.RawEvent) error { return attachErr },
+		OnSettle:       func(task *Task) { settled <- *task },
+	}, func(ctx context.Context, taskID string, event *intake.RawEvent) error {
+		return nil
+	}, logger.Nop())
+	// Not started: tasks stay queued.
+	first, _ := s.Submit(testEvent("evt-1", "warning"))
+	if _, err := s.Submit(testEvent("evt-2", "warning")); !errors.Is(err, ErrCoalesced) {
+		t.Fatalf("Submit: err=%v, want ErrCoalesced", err)
+	}
+
+	// An attachment that cannot be recorded gets a task of its own.
+	attachErr = errors.New("store down")
+	if id, err := s.Submit(testEvent("evt-3", "warning")); err != nil || id == first {
+		t.Fatalf("failed attach: id=%s err=%v, want a new task", id, err)
+	}
+
+	if _, err := s.Cancel(first, "alice", "noise"); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	select {
+	case task := <-settled:
+		if task.Status != StatusCancelled || len(task.Attached) != 1 {
+			t.Errorf("settled: status=%s attached=%v", task.Status, task.Attached)
+		}
+	default:
+		t.Fatal("cancelling a queued task must settle its attached events")
+	}
+}
+
+func TestScheduler_LeasedMaxQueueWait(t *testing.T) {
+	st := newMemLeaseStore()
+	s := New(Config{
+		MaxConcurrency: 1,
+		Leases:         st,
+		PollInterval:   5 * time.Millisecond,
+		MaxQueueWait: map[string]QueueWaitLimit{
+			"info":    {Max: 20 * time.Millisecond, Action: WaitActionExpire},
+			"warning": {Max: 20 * time.Millisecond},
+		},
+	}, func(ctx context.Context, taskID string, event *intake.RawEvent) error {
+		return nil
+	}, logger.Nop())
+	s.sweepEvery = 10 * time.Millisecond
+
+	// Paused: the tasks stay queued in the store until the sweep acts.
+	s.PauseProject("proj-a", "admin", "test")
+	infoID, _ := s.Submit(testEvent("evt-info", "info"))
+	warnID, _ := s.Submit(testEvent("evt-warn", "warning"))
+	s.Start()
+	defer s.Stop()
+
+	waitFor(t, func() bool { return st.get(infoID).Status == StatusExpired })
+	waitFor(t, func() bool { return st.get(warnID).Priority == intake.SeverityPriority("critical") })
+	if got := st.get(warnID).Status; got != StatusQueued {
+		t.Errorf("promoted task status = %s, want queued", got)
+	}
+	if n := s.expired.Load(); n != 1 {
+		t.Errorf("expired = %d, want 1", n)
+	}
+}
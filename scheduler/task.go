@@ -2,6 +2,9 @@ package scheduler
 
 import (
 	"container/heap"
+	"context"
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -13,12 +16,47 @@ type TaskStatus string
 
 const (
 	StatusPending   TaskStatus = "pending"
+	StatusQueued    TaskStatus = "queued"
 	StatusRunning   TaskStatus = "running"
 	StatusCompleted TaskStatus = "completed"
 	StatusFailed    TaskStatus = "failed"
 	StatusTimeout   TaskStatus = "timeout"
+	// StatusExpired marks a task dropped after exceeding its max queue wait.
+	StatusExpired TaskStatus = "expired"
+	// StatusCancelled marks a task cancelled through the admin API.
+	StatusCancelled TaskStatus = "cancelled"
 )
 
+// priorityLevels are the severity priorities in ascending order; aging
+// raises a task one level per interval waited.
+var priorityLevels = []int{
+	intake.SeverityPriority("info"),
+	intake.SeverityPriority("warning"),
+	intake.SeverityPriority("critical"),
+}
+
+// effectivePriority is the task priority raised one level for every aging
+// interval it has waited, up to critical. aging <= 0 disables aging.
+func (t *Task) effectivePriority(now time.Time, aging time.Duration) int {
+	p := t.Priority
+	if aging <= 0 {
+		return p
+	}
+	for steps := int(now.Sub(t.CreatedAt) / aging); steps > 0; steps-- {
+		raised := false
+		for _, level := range priorityLevels {
+			if level > p {
+				p, raised = level, true
+				break
+			}
+		}
+		if !raised {
+			break
+		}
+	}
+	return p
+}
+
 // Task wraps an incident as a schedulable diagnosis unit.
 type Task struct {
 	ID         string
@@ -31,91 +69,566 @@ type Task struct {
 	StartedAt  time.Time
 	FinishedAt time.Time
 	Error      string
+	// FailureClass classifies the last failed attempt.
+	FailureClass string
+	// Attempts records every failed attempt, oldest first.
+	Attempts []Attempt
+	// Actions records admin actions taken on the task, oldest first.
+	Actions []TaskAction
+	// Degraded is set when admission dispatched the task in degraded mode.
+	Degraded bool
+	// Fingerprint is the diagnosis fingerprint events coalesce on.
+	Fingerprint string
+	// Attached lists the IDs of events coalesced into the task, which take
+	// its outcome. Guarded by the scheduler until the task is settled.
+	Attached []string
 
 	index int // position in heap, managed by container/heap
 }
 
 // taskHeap implements heap.Interface for priority-based task scheduling.
-// Higher Priority values are dequeued first (max-heap).
-type taskHeap []*Task
+// Higher effective priorities are dequeued first (max-heap). Aging makes
+// the order time-dependent, so the heap is ordered as of now and must be
+// re-initialized whenever now moves.
+type taskHeap struct {
+	tasks []*Task
+	now   time.Time
+	aging time.Duration
+}
 
-func (h taskHeap) Len() int { return len(h) }
+func (h *taskHeap) Len() int { return len(h.tasks) }
 
-func (h taskHeap) Less(i, j int) bool {
+func (h *taskHeap) Less(i, j int) bool {
 	// Higher priority first; break ties by earlier creation time (FIFO within same priority)
-	if h[i].Priority != h[j].Priority {
-		return h[i].Priority > h[j].Priority
+	pi, pj := h.tasks[i].effectivePriority(h.now, h.aging), h.tasks[j].effectivePriority(h.now, h.aging)
+	if pi != pj {
+		return pi > pj
 	}
-	return h[i].CreatedAt.Before(h[j].CreatedAt)
+	return h.tasks[i].CreatedAt.Before(h.tasks[j].CreatedAt)
 }
 
-func (h taskHeap) Swap(i, j int) {
-	h[i], h[j] = h[j], h[i]
-	h[i].index = i
-	h[j].index = j
+func (h *taskHeap) Swap(i, j int) {
+	h.tasks[i], h.tasks[j] = h.tasks[j], h.tasks[i]
+	h.tasks[i].index = i
+	h.tasks[j].index = j
 }
 
 func (h *taskHeap) Push(x any) {
 	t := x.(*Task)
-	t.index = len(*h)
-	*h = append(*h, t)
+	t.index = len(h.tasks)
+	h.tasks = append(h.tasks, t)
 }
 
 func (h *taskHeap) Pop() any {
-	old := *h
+	old := h.tasks
 	n := len(old)
 	t := old[n-1]
 	old[n-1] = nil // avoid memory leak
 	t.index = -1
-	*h = old[:n-1]
+	h.tasks = old[:n-1]
 	return t
 }
 
-// priorityQueue is a thread-safe priority queue for tasks.
+// ready returns the best task that is not backing off before a retry, or
+// nil if every task is.
+func (h *taskHeap) ready(now time.Time) *Task {
+	if t := h.tasks[0]; !t.backingOff(now) {
+		return t
+	}
+	var best *Task
+	for i, t := range h.tasks {
+		if !t.backingOff(now) && (best == nil || h.Less(i, best.index)) {
+			best = t
+		}
+	}
+	return best
+}
+
+// reorder re-establishes the heap order as of now. A no-op without aging,
+// where the order does not depend on time.
+func (h *taskHeap) reorder(now time.Time) {
+	h.now = now
+	if h.aging > 0 {
+		heap.Init(h)
+	}
+}
+
+// priorityQueue is a thread-safe priority queue for tasks. Tasks are kept
+// in one heap per project so a worker can skip projects that are at their
+// concurrency cap. Among the projects whose best task is in the highest
+// eligible tier, one is chosen by smooth weighted round-robin, so a storm
+// from one project cannot starve the others of the same severity.
 type priorityQueue struct {
-	mu      sync.Mutex
-	cond    *sync.Cond
-	heap    taskHeap
-	maxSize int
-	closed  bool
+	mu       sync.Mutex
+	cond     *sync.Cond
+	projects map[string]*taskHeap
+	size     int
+	maxSize  int
+	closed   bool
+	// running counts dispatched tasks per project, released by done.
+	running map[string]int
+	// policy returns a project's concurrency cap and round-robin weight.
+	policy func(projectKey string) (limit, weight int)
+	// credit is the weighted round-robin state, per tier and project.
+	credit map[int]map[string]int
+	// aging raises waiting tasks one priority level per interval (0 = off).
+	aging time.Duration
+	// clock is time.Now, replaceable in tests.
+	clock func() time.Time
+	// paused projects keep queuing tasks but are not dispatched.
+	paused map[string]bool
+	// inflight tracks dispatched tasks by ID until done, so they can be cancelled.
+	inflight map[string]*inflightTask
+	// admit vets each project's next task before dispatch (may be nil).
+	admit func(projectKey, severity string) Admission
+	// deferred holds the reason of projects held back by admit.
+	deferred map[string]string
+}
+
+// inflightTask is the cancellation state of a dispatched task.
+type inflightTask struct {
+	cancel context.CancelFunc
+	// cancelled is the admin action that requested cancellation, if any.
+	cancelled *TaskAction
+	// lost is set when another replica took over the task's lease.
+	lost bool
 }
 
-func newPriorityQueue(maxSize int) *priorityQueue {
+func newPriorityQueue(maxSize int, aging time.Duration, policy func(projectKey string) (limit, weight int)) *priorityQueue {
 	pq := &priorityQueue{
-		heap:    make(taskHeap, 0, maxSize),
-		maxSize: maxSize,
+		projects: make(map[string]*taskHeap),
+		maxSize:  maxSize,
+		running:  make(map[string]int),
+		policy:   policy,
+		credit:   make(map[int]map[string]int),
+		aging:    aging,
+		clock:    time.Now,
+		paused:   make(map[string]bool),
+		inflight: make(map[string]*inflightTask),
+		deferred: make(map[string]string),
 	}
 	pq.cond = sync.NewCond(&pq.mu)
-	heap.Init(&pq.heap)
 	return pq
 }
 
 // push adds a task to the queue. Returns false if the queue is full or closed.
 func (pq *priorityQueue) push(t *Task) bool {
+	return pq.pushCapped(t, true)
+}
+
+// restore adds a recovered task, ignoring the size limit: tasks accepted
+// before a restart must not be dropped. Returns false if the queue is closed.
+func (pq *priorityQueue) restore(t *Task) bool {
+	return pq.pushCapped(t, false)
+}
+
+func (pq *priorityQueue) pushCapped(t *Task, capped bool) bool {
 	pq.mu.Lock()
 	defer pq.mu.Unlock()
 
-	if pq.closed || pq.heap.Len() >= pq.maxSize {
+	if pq.closed || (capped && pq.size >= pq.maxSize) {
 		return false
 	}
-	heap.Push(&pq.heap, t)
+	key := t.Event.ProjectKey
+	h := pq.projects[key]
+	if h == nil {
+		h = &taskHeap{aging: pq.aging}
+		pq.projects[key] = h
+	}
+	h.reorder(pq.clock())
+	heap.Push(h, t)
+	pq.size++
 	pq.cond.Signal()
 	return true
 }
 
-// pop removes and returns the highest-priority task, blocking until one is available.
-// Returns nil when the queue is closed and drained.
+// requeue puts back a task that failed an attempt and waits out its
+// backoff in the queue, ignoring the size limit like restore. Workers are
+// woken when the backoff ends. Returns false if the queue is closed.
+func (pq *priorityQueue) requeue(t *Task) bool {
+	if !pq.restore(t) {
+		return false
+	}
+	if wait := t.retryAt().Sub(pq.clock()); wait > 0 {
+		time.AfterFunc(wait, pq.wake)
+	}
+	return true
+}
+
+// pop removes and returns the next task whose project is below its
+// concurrency cap and that is not backing off, blocking until one is
+// available. The caller must call
+// done when the task finishes. Returns nil when the queue is closed and
+// drained, or closed with only paused or deferred tasks left.
 func (pq *priorityQueue) pop() *Task {
 	pq.mu.Lock()
 	defer pq.mu.Unlock()
 
-	for pq.heap.Len() == 0 && !pq.closed {
+	for {
+		if t := pq.next(); t != nil {
+			return t
+		}
+		if pq.closed && (pq.size == 0 || len(pq.running) == 0) {
+			return nil
+		}
 		pq.cond.Wait()
 	}
-	if pq.heap.Len() == 0 {
+}
+
+// wake makes blocked workers re-evaluate the queue, e.g. after admission
+// may have changed its mind about deferred projects.
+func (pq *priorityQueue) wake() {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	pq.cond.Broadcast()
+}
+
+// next dispatches the best eligible task, or returns nil. Callers hold mu.
+func (pq *priorityQueue) next() *Task {
+	now := pq.clock()
+	tier := 0
+	var candidates []string
+	var degrade map[string]string
+	for key := range pq.deferred {
+		if pq.projects[key] == nil {
+			delete(pq.deferred, key)
+		}
+	}
+	for key, h := range pq.projects {
+		if pq.paused[key] {
+			continue
+		}
+		if limit, _ := pq.policy(key); limit > 0 && pq.running[key] >= limit {
+			continue
+		}
+		h.reorder(now)
+		t := h.ready(now)
+		if t == nil {
+			continue
+		}
+		if pq.admit != nil {
+			adm := pq.admit(key, t.Event.Severity)
+			if adm.Defer {
+				pq.deferred[key] = adm.Reason
+				continue
+			}
+			delete(pq.deferred, key)
+			if adm.Degrade {
+				if degrade == nil {
+					degrade = make(map[string]string)
+				}
+				degrade[key] = adm.Reason
+			}
+		}
+		p := t.effectivePriority(now, pq.aging)
+		if len(candidates) == 0 || p > tier {
+			tier = p
+			candidates = candidates[:0]
+		}
+		if p == tier {
+			candidates = append(candidates, key)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	key := pq.pick(tier, candidates)
+	h := pq.projects[key]
+	t := heap.Remove(h, h.ready(now).index).(*Task)
+	if h.Len() == 0 {
+		delete(pq.projects, key)
+	}
+	pq.size--
+	pq.running[key]++
+	pq.inflight[t.ID] = &inflightTask{}
+	reason, degraded := degrade[key]
+	t.Degraded = degraded
+	if degraded {
+		t.Actions = append(t.Actions, TaskAction{Action: ActionDegrade, By: "admission", Reason: reason, At: now})
+	}
+	return t
+}
+
+// pick chooses a project by smooth weighted round-robin: every candidate
+// earns its weight in credit, the richest one wins and pays the total.
+func (pq *priorityQueue) pick(tier int, candidates []string) string {
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+	sort.Strings(candidates) // deterministic tie-breaking
+	credit := pq.credit[tier]
+	if credit == nil {
+		credit = make(map[string]int)
+		pq.credit[tier] = credit
+	}
+	total, best := 0, ""
+	for _, key := range candidates {
+		_, weight := pq.policy(key)
+		credit[key] += weight
+		total += weight
+		if best == "" || credit[key] > credit[best] {
+			best = key
+		}
+	}
+	credit[best] -= total
+	return best
+}
+
+// done releases the project slot taken by a dispatched task.
+func (pq *priorityQueue) done(t *Task) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	projectKey := t.Event.ProjectKey
+	if pq.running[projectKey]--; pq.running[projectKey] <= 0 {
+		delete(pq.running, projectKey)
+	}
+	delete(pq.inflight, t.ID)
+	pq.cond.Broadcast()
+}
+
+// attach registers the cancel function of a dispatched task. If a
+// cancellation was requested before the task started, it fires at once.
+func (pq *priorityQueue) attach(taskID string, cancel context.CancelFunc) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	if in := pq.inflight[taskID]; in != nil {
+		in.cancel = cancel
+		if in.cancelled != nil || in.lost {
+			cancel()
+		}
+	}
+}
+
+// requestCancel cancels a dispatched task. Returns false if the task is
+// not in flight.
+func (pq *priorityQueue) requestCancel(taskID string, action TaskAction) bool {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	in := pq.inflight[taskID]
+	if in == nil {
+		return false
+	}
+	if in.cancelled == nil {
+		in.cancelled = &action
+	}
+	if in.cancel != nil {
+		in.cancel()
+	}
+	return true
+}
+
+// cancelRequest returns the action that cancelled a dispatched task, or nil.
+func (pq *priorityQueue) cancelRequest(taskID string) *TaskAction {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	if in := pq.inflight[taskID]; in != nil {
+		return in.cancelled
+	}
+	return nil
+}
+
+// isInflight reports whether the task has been dispatched and not yet done.
+func (pq *priorityQueue) isInflight(taskID string) bool {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	return pq.inflight[taskID] != nil
+}
+
+// candidate is a claimable task ranked for a lease attempt.
+type candidate struct {
+	task     *Task
+	degraded bool
+	degrade  string // degradation reason
+}
+
+// rank orders tasks read from a shared store for claiming, applying the
+// same policy as next: paused projects and projects at their cap (counting
+// leases held by every replica) are skipped, admission is asked about each
+// project's best task, and projects in the highest tier take turns by
+// weighted round-robin. Tasks backing off before a retry are left out.
+// Tasks whose event is gone come first so they are settled at once.
+func (pq *priorityQueue) rank(tasks []*Task, leased map[string]int) []candidate {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	now := pq.clock()
+	var out []candidate
+	best := make(map[string]*Task)
+	for _, t := range tasks {
+		if t.Event == nil {
+			out = append(out, candidate{task: t})
+			continue
+		}
+		if t.backingOff(now) {
+			continue
+		}
+		key := t.Event.ProjectKey
+		if b := best[key]; b == nil || higher(t, b, now, pq.aging) {
+			best[key] = t
+		}
+	}
+	for key := range pq.deferred {
+		if best[key] == nil {
+			delete(pq.deferred, key)
+		}
+	}
+
+	tiers := make(map[int][]string)
+	degrade := make(map[string]string)
+	for key, t := range best {
+		if pq.paused[key] {
+			continue
+		}
+		if limit, _ := pq.policy(key); limit > 0 && leased[key] >= limit {
+			continue
+		}
+		if pq.admit != nil {
+			adm := pq.admit(key, t.Event.Severity)
+			if adm.Defer {
+				pq.deferred[key] = adm.Reason
+				continue
+			}
+			delete(pq.deferred, key)
+			if adm.Degrade {
+				degrade[key] = adm.Reason
+			}
+		}
+		p := t.effectivePriority(now, pq.aging)
+		tiers[p] = append(tiers[p], key)
+	}
+
+	levels := make([]int, 0, len(tiers))
+	for p := range tiers {
+		levels = append(levels, p)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(levels)))
+	for _, p := range levels {
+		// The round-robin winner first; the rest are fallbacks in case
+		// other replicas claim it first.
+		keys := tiers[p]
+		first := pq.pick(p, keys)
+		sort.SliceStable(keys, func(i, j int) bool { return keys[i] == first && keys[j] != first })
+		for _, key := range keys {
+			reason, degraded := degrade[key]
+			out = append(out, candidate{task: best[key], degraded: degraded, degrade: reason})
+		}
+	}
+	return out
+}
+
+// higher reports whether a goes before b in the queue order.
+func higher(a, b *Task, now time.Time, aging time.Duration) bool {
+	pa, pb := a.effectivePriority(now, aging), b.effectivePriority(now, aging)
+	if pa != pb {
+		return pa > pb
+	}
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+// track registers a task claimed from a shared store as dispatched; done
+// releases it.
+func (pq *priorityQueue) track(t *Task) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	pq.running[t.Event.ProjectKey]++
+	pq.inflight[t.ID] = &inflightTask{}
+}
+
+// loseLease marks a dispatched task as taken over by another replica and
+// cancels it.
+func (pq *priorityQueue) loseLease(taskID string) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	if in := pq.inflight[taskID]; in != nil {
+		in.lost = true
+		if in.cancel != nil {
+			in.cancel()
+		}
+	}
+}
+
+// leaseLost reports whether a dispatched task's lease was taken over.
+func (pq *priorityQueue) leaseLost(taskID string) bool {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	in := pq.inflight[taskID]
+	return in != nil && in.lost
+}
+
+// find returns a queued task and its project heap. Callers hold mu.
+func (pq *priorityQueue) find(taskID string) (*Task, *taskHeap, string) {
+	for key, h := range pq.projects {
+		for _, t := range h.tasks {
+			if t.ID == taskID {
+				return t, h, key
+			}
+		}
+	}
+	return nil, nil, ""
+}
+
+// remove takes a queued task out of the queue. Returns nil if it is not queued.
+func (pq *priorityQueue) remove(taskID string) *Task {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	t, h, key := pq.find(taskID)
+	if t == nil {
 		return nil
 	}
-	return heap.Pop(&pq.heap).(*Task)
+	heap.Remove(h, t.index)
+	if h.Len() == 0 {
+		delete(pq.projects, key)
+	}
+	pq.size--
+	return t
+}
+
+// setPriority changes the priority of a queued task and records action on
+// it. save runs under the lock, so a worker cannot dispatch the task and
+// persist it as running before this change is persisted. Returns the
+// updated task, or nil if the task is not queued.
+func (pq *priorityQueue) setPriority(taskID string, priority int, action TaskAction, save func(*Task)) *Task {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	t, h, _ := pq.find(taskID)
+	if t == nil {
+		return nil
+	}
+	action.Detail = fmt.Sprintf("priority %d -> %d", t.Priority, priority)
+	t.Priority = priority
+	t.Actions = append(t.Actions, action)
+	h.now = pq.clock()
+	heap.Fix(h, t.index)
+	save(t)
+	return t
+}
+
+// setPaused pauses or resumes dispatching for a project and records action
+// on its queued tasks, persisting each with save under the lock. Returns
+// the number of queued tasks, and false if the project was already in that
+// state.
+func (pq *priorityQueue) setPaused(projectKey string, paused bool, action TaskAction, save func(*Task)) (int, bool) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	if pq.paused[projectKey] == paused {
+		return 0, false
+	}
+	if paused {
+		pq.paused[projectKey] = true
+	} else {
+		delete(pq.paused, projectKey)
+		pq.cond.Broadcast()
+	}
+	h := pq.projects[projectKey]
+	if h == nil {
+		return 0, true
+	}
+	for _, t := range h.tasks {
+		t.Actions = append(t.Actions, action)
+		save(t)
+	}
+	return h.Len(), true
 }
 
 // close signals that no more tasks will be pushed.
@@ -130,5 +643,125 @@ func (pq *priorityQueue) close() {
 func (pq *priorityQueue) len() int {
 	pq.mu.Lock()
 	defer pq.mu.Unlock()
-	return pq.heap.Len()
+	return pq.size
+}
+
+// QueueWaitLimit bounds how long a task of one severity may stay queued.
+type QueueWaitLimit struct {
+	Max time.Duration
+	// Action is WaitActionPromote (default) or WaitActionExpire.
+	Action string
+}
+
+// Actions taken when a task exceeds its max queue wait.
+const (
+	WaitActionPromote = "promote"
+	WaitActionExpire  = "expire"
+)
+
+// sweep enforces max queue waits, keyed by the task's severity. Overdue
+// tasks are either promoted to critical or removed; both are returned so the
+// caller can persist them outside the lock.
+func (pq *priorityQueue) sweep(limits map[string]QueueWaitLimit) (promoted, expired []*Task) {
+	if len(limits) == 0 {
+		return nil, nil
+	}
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	now := pq.clock()
+	top := priorityLevels[len(priorityLevels)-1]
+	for key, h := range pq.projects {
+		kept := h.tasks[:0]
+		for _, t := range h.tasks {
+			limit, ok := limits[t.Event.Severity]
+			if !ok || limit.Max <= 0 || now.Sub(t.CreatedAt) < limit.Max {
+				kept = append(kept, t)
+				continue
+			}
+			if limit.Action == WaitActionExpire {
+				t.index = -1
+				expired = append(expired, t)
+				pq.size--
+				continue
+			}
+			if t.Priority < top {
+				t.Priority = top
+				promoted = append(promoted, t)
+			}
+			kept = append(kept, t)
+		}
+		for i := len(kept); i < len(h.tasks); i++ {
+			h.tasks[i] = nil
+		}
+		h.tasks = kept
+		if h.Len() == 0 {
+			delete(pq.projects, key)
+			continue
+		}
+		for i, t := range h.tasks {
+			t.index = i
+		}
+		h.now = now
+		heap.Init(h)
+	}
+	return promoted, expired
+}
+
+// oldestWait returns, per severity, how long the oldest queued task has waited.
+func (pq *priorityQueue) oldestWait() map[string]time.Duration {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	now := pq.clock()
+	out := make(map[string]time.Duration)
+	for _, h := range pq.projects {
+		for _, t := range h.tasks {
+			if wait := now.Sub(t.CreatedAt); wait > out[t.Event.Severity] {
+				out[t.Event.Severity] = wait
+			}
+		}
+	}
+	return out
+}
+
+// ProjectStat is the queue state of one project.
+type ProjectStat struct {
+	Queued  int  `json:"queued"`
+	Running int  `json:"running"`
+	Limit   int  `json:"limit"`
+	Paused  bool `json:"paused,omitempty"`
+	// Deferred is why admission holds the project's tasks back.
+	Deferred string `json:"deferred,omitempty"`
+}
+
+// projectStats returns queued and running counts per active project.
+func (pq *priorityQueue) projectStats() map[string]ProjectStat {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	out := make(map[string]ProjectStat, len(pq.projects))
+	for key, h := range pq.projects {
+		st := out[key]
+		st.Queued = h.Len()
+		out[key] = st
+	}
+	for key, n := range pq.running {
+		st := out[key]
+		st.Running = n
+		out[key] = st
+	}
+	for key := range pq.paused {
+		st := out[key]
+		st.Paused = true
+		out[key] = st
+	}
+	for key, reason := range pq.deferred {
+		st := out[key]
+		st.Deferred = reason
+		out[key] = st
+	}
+	for key, st := range out {
+		st.Limit, _ = pq.policy(key)
+		out[key] = st
+	}
+	return out
 }
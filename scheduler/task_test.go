@@ -0,0 +1,168 @@
+package scheduler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"amp-sentinel/intake"
+)
+
+func queueTask(id, project string, priority int, created time.Time) *Task {
+	return &Task{ID: id, Event: &intake.RawEvent{ID: id, ProjectKey: project}, Priority: priority, CreatedAt: created}
+}
+
+func newTestQueue(limits map[string]int, weights map[string]int) *priorityQueue {
+	return newPriorityQueue(100, 0, func(key string) (int, int) {
+		limit, weight := 1, 1
+		if l, ok := limits[key]; ok {
+			limit = l
+		}
+		if w, ok := weights[key]; ok {
+			weight = w
+		}
+		return limit, weight
+	})
+}
+
+func pushAll(t *testing.T, pq *priorityQueue, tasks ...*Task) {
+	t.Helper()
+	for _, task := range tasks {
+		if !pq.push(task) {
+			t.Fatalf("push %s failed", task.ID)
+		}
+	}
+}
+
+func projectOf(t *Task) string {
+	return t.Event.ProjectKey
+}
+
+func TestPriorityQueue_ProjectCap(t *testing.T) {
+	pq := newTestQueue(map[string]int{"a": 2}, nil)
+	now := time.Now()
+	pushAll(t, pq,
+		queueTask("a-1", "a", 3, now),
+		queueTask("a-2", "a", 3, now.Add(time.Second)),
+		queueTask("a-3", "a", 3, now.Add(2*time.Second)),
+		queueTask("b-1", "b", 1, now),
+	)
+
+	var got []string
+	var first *Task
+	for i := 0; i < 3; i++ {
+		task := pq.next()
+		if first == nil {
+			first = task
+		}
+		got = append(got, task.ID)
+	}
+	// a is capped at 2, so the info task of b runs before a's third critical.
+	if fmt.Sprint(got) != "[a-1 a-2 b-1]" {
+		t.Fatalf("dispatch order = %v", got)
+	}
+	if task := pq.next(); task != nil {
+		t.Fatalf("dispatched %s while every project is at its cap", task.ID)
+	}
+	pq.done(first)
+	if task := pq.next(); task == nil || task.ID != "a-3" {
+		t.Fatalf("after done: %v", task)
+	}
+}
+
+func TestPriorityQueue_WeightedRoundRobin(t *testing.T) {
+	pq := newTestQueue(map[string]int{"a": 100, "b": 100, "c": 100}, map[string]int{"a": 2})
+	now := time.Now()
+	var tasks []*Task
+	for i := 0; i < 6; i++ {
+		created := now.Add(time.Duration(i) * time.Second)
+		tasks = append(tasks,
+			queueTask(fmt.Sprintf("a-%d", i), "a", 2, created),
+			queueTask(fmt.Sprintf("b-%d", i), "b", 2, created),
+		)
+	}
+	// A lower tier is only served once the higher one is empty.
+	tasks = append(tasks, queueTask("c-0", "c", 1, now.Add(-time.Hour)))
+	pushAll(t, pq, tasks...)
+
+	counts := map[string]int{}
+	for i := 0; i < 6; i++ {
+		counts[projectOf(pq.next())]++
+	}
+	if counts["a"] != 4 || counts["b"] != 2 {
+		t.Fatalf("first 6 dispatches = %v, want a:4 b:2", counts)
+	}
+	for i := 0; i < 6; i++ {
+		if task := pq.next(); projectOf(task) == "c" {
+			t.Fatalf("lower tier dispatched before higher tier drained")
+		}
+	}
+	if task := pq.next(); task == nil || task.ID != "c-0" {
+		t.Fatalf("expected c-0 last, got %v", task)
+	}
+}
+
+func TestPriorityQueue_Aging(t *testing.T) {
+	pq := newTestQueue(map[string]int{"a": 100}, nil)
+	pq.aging = 10 * time.Minute
+	now := time.Now()
+	pq.clock = func() time.Time { return now }
+
+	// An info task that has waited two intervals ranks as critical and,
+	// being older, goes before fresh criticals.
+	pushAll(t, pq,
+		queueTask("a-crit", "a", intake.SeverityPriority("critical"), now.Add(-time.Minute)),
+		queueTask("a-info", "a", intake.SeverityPriority("info"), now.Add(-25*time.Minute)),
+		queueTask("a-warn", "a", intake.SeverityPriority("warning"), now.Add(-5*time.Minute)),
+	)
+	var got []string
+	for task := pq.next(); task != nil; task = pq.next() {
+		got = append(got, task.ID)
+	}
+	if fmt.Sprint(got) != "[a-info a-crit a-warn]" {
+		t.Fatalf("dispatch order = %v", got)
+	}
+
+	info := queueTask("x", "a", intake.SeverityPriority("info"), now.Add(-15*time.Minute))
+	if p := info.effectivePriority(now, pq.aging); p != intake.SeverityPriority("warning") {
+		t.Errorf("one interval: effective priority %d, want warning", p)
+	}
+	if p := info.effectivePriority(now, 0); p != intake.SeverityPriority("info") {
+		t.Errorf("aging off: effective priority %d, want info", p)
+	}
+}
+
+func TestPriorityQueue_SweepMaxWait(t *testing.T) {
+	pq := newTestQueue(nil, nil)
+	now := time.Now()
+	pq.clock = func() time.Time { return now }
+
+	old := now.Add(-time.Hour)
+	tasks := []*Task{
+		queueTask("a-1", "a", intake.SeverityPriority("info"), old),
+		queueTask("a-2", "a", intake.SeverityPriority("warning"), old),
+		queueTask("b-1", "b", intake.SeverityPriority("info"), now),
+	}
+	tasks[0].Event.Severity, tasks[1].Event.Severity, tasks[2].Event.Severity = "info", "warning", "info"
+	pushAll(t, pq, tasks...)
+
+	promoted, expired := pq.sweep(map[string]QueueWaitLimit{
+		"info":    {Max: 30 * time.Minute, Action: WaitActionExpire},
+		"warning": {Max: 30 * time.Minute},
+	})
+	if len(expired) != 1 || expired[0].ID != "a-1" {
+		t.Fatalf("expired = %v", expired)
+	}
+	if len(promoted) != 1 || promoted[0].ID != "a-2" || promoted[0].Priority != intake.SeverityPriority("critical") {
+		t.Fatalf("promoted = %v", promoted)
+	}
+	if pq.len() != 2 {
+		t.Fatalf("queue length = %d, want 2", pq.len())
+	}
+	if waits := pq.oldestWait(); waits["warning"] != time.Hour || waits["info"] != 0 {
+		t.Errorf("oldest waits = %v", waits)
+	}
+	if task := pq.next(); task.ID != "a-2" {
+		t.Errorf("promoted task should go first, got %s", task.ID)
+	}
+}
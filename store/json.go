@@ -24,9 +24,20 @@ type JSONStore struct {
 }
 
 type jsonData struct {
-	Events  map[string]*Event           `json:"events"`
-	Tasks   map[string]*DiagnosisTask   `json:"tasks"`
-	Reports map[string]*DiagnosisReport `json:"reports"`
+	Events    map[string]*Event           `json:"events"`
+	Tasks     map[string]*DiagnosisTask   `json:"tasks"`
+	Reports   map[string]*DiagnosisReport `json:"reports"`
+	DedupKeys map[string]*dedupKey        `json:"dedup_keys,omitempty"`
+	Silences  map[string]*SilenceRule     `json:"silences,omitempty"`
+	Incidents map[string]*Incident        `json:"incidents,omitempty"`
+	FollowUps map[string]*ReportFollowUp  `json:"follow_ups,omitempty"`
+
+	IdempotencyKeys map[string]*IdempotencyRecord `json:"idempotency_keys,omitempty"`
+}
+
+type dedupKey struct {
+	SeenAt    time.Time `json:"seen_at"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 // NewJSONStore creates a new JSONStore. If the file at path exists it is loaded.
@@ -38,9 +49,15 @@ func NewJSONStore(path string, flushInterval time.Duration, log logger.Logger) (
 		flushInterval: flushInterval,
 		stopFlush:     make(chan struct{}),
 		data: jsonData{
-			Events:  make(map[string]*Event),
-			Tasks:   make(map[string]*DiagnosisTask),
-			Reports: make(map[string]*DiagnosisReport),
+			Events:    make(map[string]*Event),
+			Tasks:     make(map[string]*DiagnosisTask),
+			Reports:   make(map[string]*DiagnosisReport),
+			DedupKeys: make(map[string]*dedupKey),
+			Silences:  make(map[string]*SilenceRule),
+			Incidents: make(map[string]*Incident),
+			FollowUps: make(map[string]*ReportFollowUp),
+
+			IdempotencyKeys: make(map[string]*IdempotencyRecord),
 		},
 	}
 
@@ -78,6 +95,21 @@ func (s *JSONStore) loadFromFile() error {
 	if d.Reports == nil {
 		d.Reports = make(map[string]*DiagnosisReport)
 	}
+	if d.DedupKeys == nil {
+		d.DedupKeys = make(map[string]*dedupKey)
+	}
+	if d.Silences == nil {
+		d.Silences = make(map[string]*SilenceRule)
+	}
+	if d.Incidents == nil {
+		d.Incidents = make(map[string]*Incident)
+	}
+	if d.FollowUps == nil {
+		d.FollowUps = make(map[string]*ReportFollowUp)
+	}
+	if d.IdempotencyKeys == nil {
+		d.IdempotencyKeys = make(map[string]*IdempotencyRecord)
+	}
 	s.data = d
 	return nil
 }
@@ -172,6 +204,15 @@ func (s *JSONStore) ListEvents(_ context.Context, filter EventFilter) ([]*Event,
 		if filter.Severity != "" && event.Severity != filter.Severity {
 			continue
 		}
+		if filter.Fingerprint != "" && event.Fingerprint != filter.Fingerprint {
+			continue
+		}
+		if filter.IncidentID != "" && event.IncidentID != filter.IncidentID {
+			continue
+		}
+		if filter.TaskID != "" && event.TaskID != filter.TaskID {
+			continue
+		}
 		clone := *event
 		if event.Payload != nil {
 			clone.Payload = append(json.RawMessage(nil), event.Payload...)
@@ -201,6 +242,105 @@ func (s *JSONStore) ListEvents(_ context.Context, filter EventFilter) ([]*Event,
 	return result[offset:end], nil
 }
 
+// ---------- Incident ----------
+
+func (s *JSONStore) CreateIncident(_ context.Context, incident *Incident) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.data.Incidents[incident.ID]; exists {
+		return fmt.Errorf("incident %s already exists", incident.ID)
+	}
+	clone := *incident
+	s.data.Incidents[incident.ID] = &clone
+	return nil
+}
+
+func (s *JSONStore) GetIncident(_ context.Context, id string) (*Incident, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	incident, ok := s.data.Incidents[id]
+	if !ok {
+		return nil, nil
+	}
+	clone := *incident
+	return &clone, nil
+}
+
+func (s *JSONStore) UpdateIncident(_ context.Context, incident *Incident) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.data.Incidents[incident.ID]; !exists {
+		return fmt.Errorf("incident %s not found", incident.ID)
+	}
+	clone := *incident
+	s.data.Incidents[incident.ID] = &clone
+	return nil
+}
+
+func (s *JSONStore) ListIncidents(_ context.Context, filter IncidentFilter) ([]*Incident, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*Incident
+	for _, incident := range s.data.Incidents {
+		if filter.ProjectKey != "" && incident.ProjectKey != filter.ProjectKey {
+			continue
+		}
+		if filter.Status != "" && incident.Status != filter.Status {
+			continue
+		}
+		if filter.Severity != "" && incident.Severity != filter.Severity {
+			continue
+		}
+		clone := *incident
+		result = append(result, &clone)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].LastSeen.After(result[j].LastSeen)
+	})
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	offset := filter.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(result) {
+		return nil, nil
+	}
+	end := offset + limit
+	if end > len(result) {
+		end = len(result)
+	}
+	return result[offset:end], nil
+}
+
+func (s *JSONStore) FindOpenIncident(_ context.Context, projectKey, fingerprint string, since time.Time) (*Incident, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var best *Incident
+	for _, incident := range s.data.Incidents {
+		if incident.ProjectKey != projectKey || incident.Fingerprint != fingerprint {
+			continue
+		}
+		if incident.Status != IncidentStatusOpen || incident.LastSeen.Before(since) {
+			continue
+		}
+		if best == nil || incident.LastSeen.After(best.LastSeen) {
+			best = incident
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	clone := *best
+	return &clone, nil
+}
+
 // ---------- Task ----------
 
 func (s *JSONStore) CreateTask(_ context.Context, task *DiagnosisTask) error {
@@ -210,6 +350,7 @@ func (s *JSONStore) CreateTask(_ context.Context, task *DiagnosisTask) error {
 		return fmt.Errorf("task %s already exists", task.ID)
 	}
 	clone := *task
+	clone.LeaseOwner, clone.LeaseExpiresAt = "", nil
 	s.data.Tasks[task.ID] = &clone
 	return nil
 }
@@ -236,10 +377,12 @@ func (s *JSONStore) GetTask(_ context.Context, id string) (*DiagnosisTask, error
 func (s *JSONStore) UpdateTask(_ context.Context, task *DiagnosisTask) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, exists := s.data.Tasks[task.ID]; !exists {
+	existing, exists := s.data.Tasks[task.ID]
+	if !exists {
 		return fmt.Errorf("task %s not found", task.ID)
 	}
 	clone := *task
+	clone.LeaseOwner, clone.LeaseExpiresAt = existing.LeaseOwner, existing.LeaseExpiresAt
 	s.data.Tasks[task.ID] = &clone
 	return nil
 }
@@ -293,6 +436,85 @@ func (s *JSONStore) ListTasks(_ context.Context, filter TaskFilter) ([]*Diagnosi
 	return result[offset:end], nil
 }
 
+// leaseLive reports whether the task is unfinished and under a lease at now.
+func leaseLive(task *DiagnosisTask, now time.Time) bool {
+	return task.LeaseExpiresAt != nil && !task.LeaseExpiresAt.Before(now)
+}
+
+func unfinishedTask(task *DiagnosisTask) bool {
+	return task.Status == StatusPending || task.Status == StatusQueued || task.Status == StatusRunning
+}
+
+func (s *JSONStore) ListClaimableTasks(_ context.Context, now time.Time, limit int) ([]*DiagnosisTask, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*DiagnosisTask
+	for _, task := range s.data.Tasks {
+		if !unfinishedTask(task) || leaseLive(task, now) {
+			continue
+		}
+		clone := *task
+		result = append(result, &clone)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Priority != result[j].Priority {
+			return result[i].Priority > result[j].Priority
+		}
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	if limit <= 0 {
+		limit = 100
+	}
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (s *JSONStore) CountLeasedTasks(_ context.Context, now time.Time) (map[string]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make(map[string]int)
+	for _, task := range s.data.Tasks {
+		if unfinishedTask(task) && leaseLive(task, now) {
+			result[task.ProjectKey]++
+		}
+	}
+	return result, nil
+}
+
+func (s *JSONStore) AcquireTaskLease(_ context.Context, id, owner string, now, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.data.Tasks[id]
+	if !ok || !unfinishedTask(task) || (leaseLive(task, now) && task.LeaseOwner != owner) {
+		return false, nil
+	}
+	task.LeaseOwner, task.LeaseExpiresAt = owner, &until
+	return true, nil
+}
+
+func (s *JSONStore) RenewTaskLease(_ context.Context, id, owner string, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.data.Tasks[id]
+	if !ok || task.LeaseOwner != owner {
+		return false, nil
+	}
+	task.LeaseExpiresAt = &until
+	return true, nil
+}
+
+func (s *JSONStore) ReleaseTaskLease(_ context.Context, id, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if task, ok := s.data.Tasks[id]; ok && task.LeaseOwner == owner {
+		task.LeaseOwner, task.LeaseExpiresAt = "", nil
+	}
+	return nil
+}
+
 func (s *JSONStore) CountByStatus(_ context.Context) (map[TaskStatus]int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -322,6 +544,9 @@ func (s *JSONStore) SaveReport(_ context.Context, report *DiagnosisReport) error
 	if clone.QualityScore != nil {
 		clone.QualityScore = append(json.RawMessage(nil), report.QualityScore...)
 	}
+	if report.Review != nil {
+		clone.Review = append(json.RawMessage(nil), report.Review...)
+	}
 	s.data.Reports[report.ID] = &clone
 	return nil
 }
@@ -344,6 +569,9 @@ func (s *JSONStore) GetReport(_ context.Context, taskID string) (*DiagnosisRepor
 			if report.QualityScore != nil {
 				clone.QualityScore = append(json.RawMessage(nil), report.QualityScore...)
 			}
+			if report.Review != nil {
+				clone.Review = append(json.RawMessage(nil), report.Review...)
+			}
 			return &clone, nil
 		}
 	}
@@ -383,6 +611,9 @@ func (s *JSONStore) FindRecentReportByFingerprint(_ context.Context, projectKey,
 			if report.QualityScore != nil {
 				clone.QualityScore = append(json.RawMessage(nil), report.QualityScore...)
 			}
+			if report.Review != nil {
+				clone.Review = append(json.RawMessage(nil), report.Review...)
+			}
 			best = &clone
 		}
 	}
@@ -391,6 +622,33 @@ func (s *JSONStore) FindRecentReportByFingerprint(_ context.Context, projectKey,
 
 // ---------- Queries ----------
 
+func (s *JSONStore) AddReportFollowUp(_ context.Context, fu *ReportFollowUp) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.data.FollowUps[fu.ID]; exists {
+		return fmt.Errorf("report follow-up %s already exists", fu.ID)
+	}
+	clone := *fu
+	s.data.FollowUps[fu.ID] = &clone
+	return nil
+}
+
+func (s *JSONStore) ListReportFollowUps(_ context.Context, reportID string) ([]*ReportFollowUp, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var followUps []*ReportFollowUp
+	for _, fu := range s.data.FollowUps {
+		if fu.ReportID == reportID {
+			clone := *fu
+			followUps = append(followUps, &clone)
+		}
+	}
+	sort.Slice(followUps, func(i, j int) bool {
+		return followUps[i].AskedAt.Before(followUps[j].AskedAt)
+	})
+	return followUps, nil
+}
+
 func (s *JSONStore) GetUsageSummary(_ context.Context) (*UsageSummary, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -414,6 +672,157 @@ func (s *JSONStore) GetUsageSummary(_ context.Context) (*UsageSummary, error) {
 	return summary, nil
 }
 
+func (s *JSONStore) SumTokens(_ context.Context, projectKey string, since time.Time) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var total int64
+	for _, task := range s.data.Tasks {
+		if projectKey != "" && task.ProjectKey != projectKey {
+			continue
+		}
+		at := task.CreatedAt
+		if task.FinishedAt != nil {
+			at = *task.FinishedAt
+		}
+		if at.Before(since) {
+			continue
+		}
+		total += int64(task.InputTokens) + int64(task.OutputTokens)
+	}
+	for _, fu := range s.data.FollowUps {
+		if (projectKey == "" || fu.ProjectKey == projectKey) && !fu.AskedAt.Before(since) {
+			total += int64(fu.InputTokens) + int64(fu.OutputTokens)
+		}
+	}
+	return total, nil
+}
+
+// ---------- Silence ----------
+
+func cloneSilenceRule(rule *SilenceRule) *SilenceRule {
+	clone := *rule
+	clone.Matchers = append([]SilenceMatcher(nil), rule.Matchers...)
+	return &clone
+}
+
+func (s *JSONStore) CreateSilenceRule(_ context.Context, rule *SilenceRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.data.Silences[rule.ID]; exists {
+		return fmt.Errorf("silence rule %s already exists", rule.ID)
+	}
+	s.data.Silences[rule.ID] = cloneSilenceRule(rule)
+	return nil
+}
+
+func (s *JSONStore) GetSilenceRule(_ context.Context, id string) (*SilenceRule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rule, ok := s.data.Silences[id]
+	if !ok {
+		return nil, nil
+	}
+	return cloneSilenceRule(rule), nil
+}
+
+func (s *JSONStore) UpdateSilenceRule(_ context.Context, rule *SilenceRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.data.Silences[rule.ID]; !exists {
+		return fmt.Errorf("silence rule %s not found", rule.ID)
+	}
+	s.data.Silences[rule.ID] = cloneSilenceRule(rule)
+	return nil
+}
+
+func (s *JSONStore) ListSilenceRules(_ context.Context, activeAt time.Time) ([]*SilenceRule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*SilenceRule
+	for _, rule := range s.data.Silences {
+		if !activeAt.IsZero() && !rule.ExpiresAt.After(activeAt) {
+			continue
+		}
+		result = append(result, cloneSilenceRule(rule))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// ---------- Dedup ----------
+
+func (s *JSONStore) ClaimDedupKey(_ context.Context, key string, window time.Duration, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prev, ok := s.data.DedupKeys[key]; ok && now.Sub(prev.SeenAt) < window {
+		return false, nil
+	}
+	s.data.DedupKeys[key] = &dedupKey{SeenAt: now, ExpiresAt: now.Add(window)}
+	return true, nil
+}
+
+func (s *JSONStore) PurgeDedupKeys(_ context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for k, v := range s.data.DedupKeys {
+		if !v.ExpiresAt.After(before) {
+			delete(s.data.DedupKeys, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// ---------- Idempotency ----------
+
+func (s *JSONStore) ClaimIdempotencyKey(_ context.Context, rec *IdempotencyRecord) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prev, ok := s.data.IdempotencyKeys[rec.Key]; ok && prev.ExpiresAt.After(rec.CreatedAt) {
+		cp := *prev
+		return &cp, nil
+	}
+	cp := *rec
+	s.data.IdempotencyKeys[rec.Key] = &cp
+	return nil, nil
+}
+
+func (s *JSONStore) CompleteIdempotencyKey(_ context.Context, key, taskID, status string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rec, ok := s.data.IdempotencyKeys[key]; ok {
+		rec.TaskID = taskID
+		rec.Status = status
+		rec.ExpiresAt = expiresAt
+	}
+	return nil
+}
+
+func (s *JSONStore) ReleaseIdempotencyKey(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data.IdempotencyKeys, key)
+	return nil
+}
+
+func (s *JSONStore) PurgeIdempotencyKeys(_ context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for k, v := range s.data.IdempotencyKeys {
+		if !v.ExpiresAt.After(before) {
+			delete(s.data.IdempotencyKeys, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
 // ---------- Lifecycle ----------
 
 func (s *JSONStore) Close() error {
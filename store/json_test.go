@@ -162,6 +162,31 @@ func TestJSONStore_ListEvents(t *testing.T) {
 	}
 }
 
+func TestJSONStore_ListEvents_Fingerprint(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	now := time.Now().Truncate(time.Millisecond)
+	events := []*Event{
+		{ID: "e1", ProjectKey: "proj-a", Fingerprint: "am-1", ReceivedAt: now},
+		{ID: "e2", ProjectKey: "proj-a", Fingerprint: "am-2", ReceivedAt: now},
+		{ID: "e3", ProjectKey: "proj-b", Fingerprint: "am-1", ReceivedAt: now},
+	}
+	for _, ev := range events {
+		if err := s.CreateEvent(ctx, ev); err != nil {
+			t.Fatalf("CreateEvent %s: %v", ev.ID, err)
+		}
+	}
+
+	got, err := s.ListEvents(ctx, EventFilter{ProjectKey: "proj-a", Fingerprint: "am-1"})
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "e1" {
+		t.Fatalf("expected [e1], got %d events", len(got))
+	}
+}
+
 func TestJSONStore_ListEvents_Pagination(t *testing.T) {
 	s := newTestStore(t)
 	ctx := context.Background()
@@ -386,6 +411,31 @@ func TestJSONStore_SaveReport_GetReport(t *testing.T) {
 	}
 }
 
+func TestJSONStore_ReportFollowUps(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	now := time.Now().Truncate(time.Millisecond)
+	second := &ReportFollowUp{ID: "fu-2", ReportID: "r-1", Question: "and the timeout?", AskedAt: now.Add(time.Minute)}
+	first := &ReportFollowUp{ID: "fu-1", ReportID: "r-1", Question: "what about the retry path?", AskedAt: now}
+	for _, fu := range []*ReportFollowUp{second, first, {ID: "fu-3", ReportID: "r-2", AskedAt: now}} {
+		if err := s.AddReportFollowUp(ctx, fu); err != nil {
+			t.Fatalf("AddReportFollowUp: %v", err)
+		}
+	}
+	if err := s.AddReportFollowUp(ctx, first); err == nil {
+		t.Error("duplicate follow-up ID accepted")
+	}
+
+	got, err := s.ListReportFollowUps(ctx, "r-1")
+	if err != nil {
+		t.Fatalf("ListReportFollowUps: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "fu-1" || got[1].ID != "fu-2" {
+		t.Fatalf("follow-ups not oldest first: %+v", got)
+	}
+}
+
 func TestJSONStore_GetReport_NotFound(t *testing.T) {
 	s := newTestStore(t)
 	ctx := context.Background()
@@ -536,3 +586,92 @@ func TestJSONStore_GetUsageSummary(t *testing.T) {
 		t.Errorf("TotalOutputTokens: got %d, want 150", summary.TotalOutputTokens)
 	}
 }
+
+func TestJSONStore_ClaimDedupKey(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	window := 10 * time.Minute
+	base := time.Now()
+
+	if ok, _ := s.ClaimDedupKey(ctx, "proj:abc", window, base); !ok {
+		t.Fatal("first claim should succeed")
+	}
+	if ok, _ := s.ClaimDedupKey(ctx, "proj:abc", window, base.Add(time.Minute)); ok {
+		t.Fatal("claim within window should be rejected")
+	}
+	if ok, _ := s.ClaimDedupKey(ctx, "proj:abc", window, base.Add(window)); !ok {
+		t.Fatal("claim after window should succeed")
+	}
+
+	if n, _ := s.PurgeDedupKeys(ctx, base.Add(window+time.Minute)); n != 0 {
+		t.Fatalf("purge before expiry removed %d keys", n)
+	}
+	if n, _ := s.PurgeDedupKeys(ctx, base.Add(3*window)); n != 1 {
+		t.Fatalf("purge after expiry removed %d keys, want 1", n)
+	}
+}
+
+func TestJSONStore_SilenceRules(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	rule := &SilenceRule{
+		ID:        "sil-1",
+		Matchers:  []SilenceMatcher{{Field: "error", Pattern: "timeout"}},
+		Action:    "drop",
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+	if err := s.CreateSilenceRule(ctx, rule); err != nil {
+		t.Fatalf("CreateSilenceRule: %v", err)
+	}
+	if err := s.CreateSilenceRule(ctx, rule); err == nil {
+		t.Fatal("expected error creating duplicate rule")
+	}
+
+	if rules, _ := s.ListSilenceRules(ctx, now); len(rules) != 1 {
+		t.Fatalf("expected 1 active rule, got %d", len(rules))
+	}
+	rule.ExpiresAt = now
+	if err := s.UpdateSilenceRule(ctx, rule); err != nil {
+		t.Fatalf("UpdateSilenceRule: %v", err)
+	}
+	if rules, _ := s.ListSilenceRules(ctx, now); len(rules) != 0 {
+		t.Fatalf("expected 0 active rules, got %d", len(rules))
+	}
+	if rules, _ := s.ListSilenceRules(ctx, time.Time{}); len(rules) != 1 {
+		t.Fatalf("expected 1 rule in total, got %d", len(rules))
+	}
+}
+
+func TestJSONStore_Incidents(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	inc := &Incident{
+		ID: "inc-1", ProjectKey: "proj-a", Fingerprint: "fp-1", Status: IncidentStatusOpen,
+		EventCount: 1, FirstSeen: now, LastSeen: now,
+	}
+	if err := s.CreateIncident(ctx, inc); err != nil {
+		t.Fatalf("CreateIncident: %v", err)
+	}
+	if got, _ := s.FindOpenIncident(ctx, "proj-a", "fp-1", now.Add(-time.Minute)); got == nil || got.ID != "inc-1" {
+		t.Fatalf("FindOpenIncident: %v", got)
+	}
+	if got, _ := s.FindOpenIncident(ctx, "proj-a", "fp-1", now.Add(time.Minute)); got != nil {
+		t.Errorf("incident outside window should not be found")
+	}
+
+	inc.Status = IncidentStatusResolved
+	if err := s.UpdateIncident(ctx, inc); err != nil {
+		t.Fatalf("UpdateIncident: %v", err)
+	}
+	if got, _ := s.FindOpenIncident(ctx, "proj-a", "fp-1", now.Add(-time.Minute)); got != nil {
+		t.Errorf("resolved incident should not be found")
+	}
+	if list, _ := s.ListIncidents(ctx, IncidentFilter{Status: IncidentStatusResolved}); len(list) != 1 {
+		t.Errorf("expected 1 resolved incident, got %d", len(list))
+	}
+}
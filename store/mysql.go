@@ -72,7 +72,14 @@ func (s *MySQLStore) initSchema() error {
     severity VARCHAR(32) NOT NULL DEFAULT 'warning',
     title VARCHAR(512) NOT NULL DEFAULT '',
     status VARCHAR(32) NOT NULL DEFAULT 'pending',
-    received_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3)
+    received_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    fingerprint VARCHAR(256) NOT NULL DEFAULT '',
+    credential VARCHAR(128) NOT NULL DEFAULT '',
+    silence_rule_id VARCHAR(64) NOT NULL DEFAULT '',
+    incident_id VARCHAR(64) NOT NULL DEFAULT '',
+    task_id VARCHAR(64) NOT NULL DEFAULT '',
+    report_id VARCHAR(64) NOT NULL DEFAULT '',
+    notification_ref VARCHAR(128) NOT NULL DEFAULT ''
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
 
 		`CREATE INDEX idx_events_project_key ON events(project_key)`,
@@ -96,6 +103,11 @@ func (s *MySQLStore) initSchema() error {
     created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
     started_at DATETIME(3) NULL,
     finished_at DATETIME(3) NULL,
+    actions JSON NOT NULL,
+    failure_class VARCHAR(32) NOT NULL DEFAULT '',
+    attempts JSON NOT NULL,
+    lease_owner VARCHAR(128) NOT NULL DEFAULT '',
+    lease_until BIGINT NOT NULL DEFAULT 0,
     CONSTRAINT fk_tasks_event FOREIGN KEY (incident_id) REFERENCES events(id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
 
@@ -124,6 +136,7 @@ func (s *MySQLStore) initSchema() error {
     original_confidence DOUBLE NOT NULL DEFAULT 0,
     final_confidence DOUBLE NOT NULL DEFAULT 0,
     final_confidence_label VARCHAR(32) NOT NULL DEFAULT '',
+    review JSON NOT NULL DEFAULT (CAST('null' AS JSON)),
     CONSTRAINT fk_reports_task FOREIGN KEY (task_id) REFERENCES diagnosis_tasks(id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
 
@@ -141,6 +154,90 @@ func (s *MySQLStore) initSchema() error {
 		`ALTER TABLE diagnosis_reports ADD COLUMN fingerprint VARCHAR(256) NOT NULL DEFAULT ''`,
 		`ALTER TABLE diagnosis_reports ADD COLUMN reused_from_id VARCHAR(64) NOT NULL DEFAULT ''`,
 		`CREATE INDEX idx_reports_fingerprint ON diagnosis_reports(project_key, fingerprint, diagnosed_at)`,
+		`ALTER TABLE events ADD COLUMN fingerprint VARCHAR(256) NOT NULL DEFAULT ''`,
+		`CREATE INDEX idx_events_fingerprint ON events(project_key, fingerprint)`,
+		`ALTER TABLE events ADD COLUMN credential VARCHAR(128) NOT NULL DEFAULT ''`,
+		`ALTER TABLE diagnosis_tasks ADD COLUMN actions JSON NOT NULL DEFAULT (CAST('[]' AS JSON))`,
+		`ALTER TABLE diagnosis_tasks ADD COLUMN failure_class VARCHAR(32) NOT NULL DEFAULT ''`,
+		`ALTER TABLE diagnosis_tasks ADD COLUMN attempts JSON NOT NULL DEFAULT (CAST('[]' AS JSON))`,
+		`ALTER TABLE diagnosis_tasks ADD COLUMN lease_owner VARCHAR(128) NOT NULL DEFAULT ''`,
+		`ALTER TABLE diagnosis_tasks ADD COLUMN lease_until BIGINT NOT NULL DEFAULT 0`,
+		`ALTER TABLE diagnosis_reports ADD COLUMN review JSON NOT NULL DEFAULT (CAST('null' AS JSON))`,
+		`CREATE INDEX idx_tasks_lease ON diagnosis_tasks(status, lease_until)`,
+
+		`CREATE TABLE IF NOT EXISTS report_follow_ups (
+    id VARCHAR(64) PRIMARY KEY,
+    report_id VARCHAR(64) NOT NULL,
+    task_id VARCHAR(64) NOT NULL DEFAULT '',
+    project_key VARCHAR(128) NOT NULL DEFAULT '',
+    question TEXT NOT NULL,
+    answer LONGTEXT NOT NULL,
+    asked_by VARCHAR(128) NOT NULL DEFAULT '',
+    session_id VARCHAR(128) NOT NULL DEFAULT '',
+    commit_hash VARCHAR(128) NOT NULL DEFAULT '',
+    tainted TINYINT(1) NOT NULL DEFAULT 0,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    input_tokens INT NOT NULL DEFAULT 0,
+    output_tokens INT NOT NULL DEFAULT 0,
+    asked_at DATETIME(3) NOT NULL,
+    CONSTRAINT fk_follow_ups_report FOREIGN KEY (report_id) REFERENCES diagnosis_reports(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+		`CREATE INDEX idx_follow_ups_report ON report_follow_ups(report_id, asked_at)`,
+		`CREATE INDEX idx_follow_ups_asked_at ON report_follow_ups(asked_at)`,
+
+		`CREATE TABLE IF NOT EXISTS dedup_keys (
+    dedup_key VARCHAR(512) PRIMARY KEY,
+    seen_at DATETIME(3) NOT NULL,
+    expires_at DATETIME(3) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+		`CREATE INDEX idx_dedup_keys_expires ON dedup_keys(expires_at)`,
+
+		`CREATE TABLE IF NOT EXISTS idempotency_keys (
+    idem_key VARCHAR(512) PRIMARY KEY,
+    event_id VARCHAR(64) NOT NULL DEFAULT '',
+    task_id VARCHAR(64) NOT NULL DEFAULT '',
+    status VARCHAR(32) NOT NULL DEFAULT '',
+    created_at DATETIME(3) NOT NULL,
+    expires_at DATETIME(3) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+		`CREATE INDEX idx_idempotency_keys_expires ON idempotency_keys(expires_at)`,
+
+		`ALTER TABLE events ADD COLUMN silence_rule_id VARCHAR(64) NOT NULL DEFAULT ''`,
+		`CREATE TABLE IF NOT EXISTS silence_rules (
+    id VARCHAR(64) PRIMARY KEY,
+    project_key VARCHAR(128) NOT NULL DEFAULT '',
+    matchers JSON NOT NULL,
+    action VARCHAR(32) NOT NULL DEFAULT 'drop',
+    severity VARCHAR(32) NOT NULL DEFAULT '',
+    reason TEXT NOT NULL,
+    created_by VARCHAR(128) NOT NULL DEFAULT '',
+    created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    expires_at DATETIME(3) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+		`CREATE INDEX idx_silence_rules_expires ON silence_rules(expires_at)`,
+
+		`ALTER TABLE events ADD COLUMN incident_id VARCHAR(64) NOT NULL DEFAULT ''`,
+		`CREATE INDEX idx_events_incident ON events(incident_id)`,
+		`ALTER TABLE events ADD COLUMN task_id VARCHAR(64) NOT NULL DEFAULT ''`,
+		`ALTER TABLE events ADD COLUMN report_id VARCHAR(64) NOT NULL DEFAULT ''`,
+		`ALTER TABLE events ADD COLUMN notification_ref VARCHAR(128) NOT NULL DEFAULT ''`,
+		`CREATE INDEX idx_events_task ON events(task_id)`,
+		`CREATE TABLE IF NOT EXISTS incidents (
+    id VARCHAR(64) PRIMARY KEY,
+    project_key VARCHAR(128) NOT NULL,
+    fingerprint VARCHAR(512) NOT NULL DEFAULT '',
+    title VARCHAR(512) NOT NULL DEFAULT '',
+    severity VARCHAR(32) NOT NULL DEFAULT 'warning',
+    source VARCHAR(64) NOT NULL DEFAULT '',
+    status VARCHAR(32) NOT NULL DEFAULT 'open',
+    first_event_id VARCHAR(64) NOT NULL DEFAULT '',
+    task_id VARCHAR(64) NOT NULL DEFAULT '',
+    event_count INT NOT NULL DEFAULT 0,
+    first_seen DATETIME(3) NOT NULL,
+    last_seen DATETIME(3) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+		`CREATE INDEX idx_incidents_group ON incidents(project_key, fingerprint(191), status, last_seen)`,
+		`CREATE INDEX idx_incidents_last_seen ON incidents(last_seen)`,
 	}
 
 	for _, stmt := range stmts {
@@ -168,10 +265,11 @@ func (s *MySQLStore) CreateEvent(ctx context.Context, event *Event) error {
 	}
 
 	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO events (id, project_key, payload, source, severity, title, status, received_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		`INSERT INTO events (id, project_key, payload, source, severity, title, status, received_at, fingerprint, credential, silence_rule_id, incident_id, task_id, report_id, notification_ref)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		event.ID, event.ProjectKey, string(payload), event.Source, event.Severity,
-		event.Title, event.Status, event.ReceivedAt,
+		event.Title, event.Status, event.ReceivedAt, event.Fingerprint, event.Credential, event.SilenceRuleID, event.IncidentID,
+		event.TaskID, event.ReportID, event.NotificationRef,
 	)
 	if err != nil {
 		return fmt.Errorf("insert event: %w", err)
@@ -181,7 +279,7 @@ func (s *MySQLStore) CreateEvent(ctx context.Context, event *Event) error {
 
 func (s *MySQLStore) GetEvent(ctx context.Context, id string) (*Event, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, project_key, payload, source, severity, title, status, received_at
+		`SELECT id, project_key, payload, source, severity, title, status, received_at, fingerprint, credential, silence_rule_id, incident_id, task_id, report_id, notification_ref
 		 FROM events WHERE id = ?`, id)
 
 	event, err := s.scanEvent(row)
@@ -198,10 +296,11 @@ func (s *MySQLStore) UpdateEvent(ctx context.Context, event *Event) error {
 	}
 
 	_, err := s.db.ExecContext(ctx,
-		`UPDATE events SET project_key=?, payload=?, source=?, severity=?, title=?, status=?, received_at=?
+		`UPDATE events SET project_key=?, payload=?, source=?, severity=?, title=?, status=?, received_at=?, fingerprint=?, credential=?, silence_rule_id=?, incident_id=?, task_id=?, report_id=?, notification_ref=?
 		 WHERE id=?`,
 		event.ProjectKey, string(payload), event.Source, event.Severity,
-		event.Title, event.Status, event.ReceivedAt, event.ID,
+		event.Title, event.Status, event.ReceivedAt, event.Fingerprint, event.Credential, event.SilenceRuleID, event.IncidentID,
+		event.TaskID, event.ReportID, event.NotificationRef, event.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("update event: %w", err)
@@ -210,7 +309,7 @@ func (s *MySQLStore) UpdateEvent(ctx context.Context, event *Event) error {
 }
 
 func (s *MySQLStore) ListEvents(ctx context.Context, filter EventFilter) ([]*Event, error) {
-	query := "SELECT id, project_key, payload, source, severity, title, status, received_at FROM events"
+	query := "SELECT id, project_key, payload, source, severity, title, status, received_at, fingerprint, credential, silence_rule_id, incident_id, task_id, report_id, notification_ref FROM events"
 	var conditions []string
 	var args []any
 
@@ -230,6 +329,18 @@ func (s *MySQLStore) ListEvents(ctx context.Context, filter EventFilter) ([]*Eve
 		conditions = append(conditions, "severity = ?")
 		args = append(args, filter.Severity)
 	}
+	if filter.Fingerprint != "" {
+		conditions = append(conditions, "fingerprint = ?")
+		args = append(args, filter.Fingerprint)
+	}
+	if filter.IncidentID != "" {
+		conditions = append(conditions, "incident_id = ?")
+		args = append(args, filter.IncidentID)
+	}
+	if filter.TaskID != "" {
+		conditions = append(conditions, "task_id = ?")
+		args = append(args, filter.TaskID)
+	}
 
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
@@ -264,6 +375,118 @@ func (s *MySQLStore) ListEvents(ctx context.Context, filter EventFilter) ([]*Eve
 	return events, rows.Err()
 }
 
+func (s *MySQLStore) CreateIncident(ctx context.Context, incident *Incident) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO incidents (`+incidentColumns+`)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		incident.ID, incident.ProjectKey, incident.Fingerprint, incident.Title, incident.Severity,
+		incident.Source, incident.Status, incident.FirstEventID, incident.TaskID, incident.EventCount,
+		incident.FirstSeen, incident.LastSeen,
+	)
+	if err != nil {
+		return fmt.Errorf("insert incident: %w", err)
+	}
+	return nil
+}
+
+func (s *MySQLStore) GetIncident(ctx context.Context, id string) (*Incident, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+incidentColumns+" FROM incidents WHERE id = ?", id)
+	incident, err := s.scanIncident(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return incident, err
+}
+
+func (s *MySQLStore) UpdateIncident(ctx context.Context, incident *Incident) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE incidents SET project_key=?, fingerprint=?, title=?, severity=?, source=?, status=?, first_event_id=?, task_id=?, event_count=?, first_seen=?, last_seen=?
+		 WHERE id=?`,
+		incident.ProjectKey, incident.Fingerprint, incident.Title, incident.Severity,
+		incident.Source, incident.Status, incident.FirstEventID, incident.TaskID, incident.EventCount,
+		incident.FirstSeen, incident.LastSeen, incident.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update incident: %w", err)
+	}
+	return nil
+}
+
+func (s *MySQLStore) ListIncidents(ctx context.Context, filter IncidentFilter) ([]*Incident, error) {
+	query := "SELECT " + incidentColumns + " FROM incidents"
+	var conditions []string
+	var args []any
+
+	if filter.ProjectKey != "" {
+		conditions = append(conditions, "project_key = ?")
+		args = append(args, filter.ProjectKey)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Severity != "" {
+		conditions = append(conditions, "severity = ?")
+		args = append(args, filter.Severity)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY last_seen DESC"
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	offset := filter.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list incidents: %w", err)
+	}
+	defer rows.Close()
+
+	var incidents []*Incident
+	for rows.Next() {
+		incident, err := s.scanIncident(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan incident: %w", err)
+		}
+		incidents = append(incidents, incident)
+	}
+	return incidents, rows.Err()
+}
+
+func (s *MySQLStore) FindOpenIncident(ctx context.Context, projectKey, fingerprint string, since time.Time) (*Incident, error) {
+	row := s.db.QueryRowContext(ctx,
+		"SELECT "+incidentColumns+` FROM incidents
+		 WHERE project_key = ? AND fingerprint = ? AND status = ? AND last_seen >= ?
+		 ORDER BY last_seen DESC LIMIT 1`,
+		projectKey, fingerprint, IncidentStatusOpen, since)
+	incident, err := s.scanIncident(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return incident, err
+}
+
+func (s *MySQLStore) scanIncident(row scannable) (*Incident, error) {
+	var incident Incident
+	err := row.Scan(
+		&incident.ID, &incident.ProjectKey, &incident.Fingerprint, &incident.Title, &incident.Severity,
+		&incident.Source, &incident.Status, &incident.FirstEventID, &incident.TaskID, &incident.EventCount,
+		&incident.FirstSeen, &incident.LastSeen,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
 func (s *MySQLStore) CreateTask(ctx context.Context, task *DiagnosisTask) error {
 	var startedAt, finishedAt sql.NullTime
 	if task.StartedAt != nil {
@@ -272,13 +495,21 @@ func (s *MySQLStore) CreateTask(ctx context.Context, task *DiagnosisTask) error
 	if task.FinishedAt != nil {
 		finishedAt = sql.NullTime{Time: *task.FinishedAt, Valid: true}
 	}
+	actions, err := marshalList("actions", task.Actions)
+	if err != nil {
+		return err
+	}
+	attempts, err := marshalList("attempts", task.Attempts)
+	if err != nil {
+		return err
+	}
 
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO diagnosis_tasks (id, incident_id, project_key, status, priority, session_id, num_turns, duration_ms, input_tokens, output_tokens, error, retry_count, created_at, started_at, finished_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO diagnosis_tasks (id, incident_id, project_key, status, priority, session_id, num_turns, duration_ms, input_tokens, output_tokens, error, retry_count, created_at, started_at, finished_at, actions, failure_class, attempts)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		task.ID, task.EventID, task.ProjectKey, string(task.Status), task.Priority,
 		task.SessionID, task.NumTurns, task.DurationMs, task.InputTokens, task.OutputTokens,
-		task.Error, task.RetryCount, task.CreatedAt, startedAt, finishedAt,
+		task.Error, task.RetryCount, task.CreatedAt, startedAt, finishedAt, actions, task.FailureClass, attempts,
 	)
 	if err != nil {
 		return fmt.Errorf("insert task: %w", err)
@@ -288,7 +519,7 @@ func (s *MySQLStore) CreateTask(ctx context.Context, task *DiagnosisTask) error
 
 func (s *MySQLStore) GetTask(ctx context.Context, id string) (*DiagnosisTask, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, incident_id, project_key, status, priority, session_id, num_turns, duration_ms, input_tokens, output_tokens, error, retry_count, created_at, started_at, finished_at
+		`SELECT id, incident_id, project_key, status, priority, session_id, num_turns, duration_ms, input_tokens, output_tokens, error, retry_count, created_at, started_at, finished_at, actions, failure_class, attempts, lease_owner, lease_until
 		 FROM diagnosis_tasks WHERE id = ?`, id)
 
 	task, err := s.scanTask(row)
@@ -306,13 +537,21 @@ func (s *MySQLStore) UpdateTask(ctx context.Context, task *DiagnosisTask) error
 	if task.FinishedAt != nil {
 		finishedAt = sql.NullTime{Time: *task.FinishedAt, Valid: true}
 	}
+	actions, err := marshalList("actions", task.Actions)
+	if err != nil {
+		return err
+	}
+	attempts, err := marshalList("attempts", task.Attempts)
+	if err != nil {
+		return err
+	}
 
-	_, err := s.db.ExecContext(ctx,
-		`UPDATE diagnosis_tasks SET incident_id=?, project_key=?, status=?, priority=?, session_id=?, num_turns=?, duration_ms=?, input_tokens=?, output_tokens=?, error=?, retry_count=?, created_at=?, started_at=?, finished_at=?
+	_, err = s.db.ExecContext(ctx,
+		`UPDATE diagnosis_tasks SET incident_id=?, project_key=?, status=?, priority=?, session_id=?, num_turns=?, duration_ms=?, input_tokens=?, output_tokens=?, error=?, retry_count=?, created_at=?, started_at=?, finished_at=?, actions=?, failure_class=?, attempts=?
 		 WHERE id=?`,
 		task.EventID, task.ProjectKey, string(task.Status), task.Priority,
 		task.SessionID, task.NumTurns, task.DurationMs, task.InputTokens, task.OutputTokens,
-		task.Error, task.RetryCount, task.CreatedAt, startedAt, finishedAt, task.ID,
+		task.Error, task.RetryCount, task.CreatedAt, startedAt, finishedAt, actions, task.FailureClass, attempts, task.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("update task: %w", err)
@@ -321,7 +560,7 @@ func (s *MySQLStore) UpdateTask(ctx context.Context, task *DiagnosisTask) error
 }
 
 func (s *MySQLStore) ListTasks(ctx context.Context, filter TaskFilter) ([]*DiagnosisTask, error) {
-	query := "SELECT id, incident_id, project_key, status, priority, session_id, num_turns, duration_ms, input_tokens, output_tokens, error, retry_count, created_at, started_at, finished_at FROM diagnosis_tasks"
+	query := "SELECT id, incident_id, project_key, status, priority, session_id, num_turns, duration_ms, input_tokens, output_tokens, error, retry_count, created_at, started_at, finished_at, actions, failure_class, attempts, lease_owner, lease_until FROM diagnosis_tasks"
 	var conditions []string
 	var args []any
 
@@ -390,6 +629,90 @@ func (s *MySQLStore) CountByStatus(ctx context.Context) (map[TaskStatus]int, err
 	return result, rows.Err()
 }
 
+func (s *MySQLStore) ListClaimableTasks(ctx context.Context, now time.Time, limit int) ([]*DiagnosisTask, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, incident_id, project_key, status, priority, session_id, num_turns, duration_ms, input_tokens, output_tokens, error, retry_count, created_at, started_at, finished_at, actions, failure_class, attempts, lease_owner, lease_until
+		 FROM diagnosis_tasks WHERE status IN `+unfinishedTaskStatuses+` AND lease_until < ?
+		 ORDER BY priority DESC, created_at ASC LIMIT ?`, now.UnixMilli(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("list claimable tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []*DiagnosisTask
+	for rows.Next() {
+		task, err := s.scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+func (s *MySQLStore) CountLeasedTasks(ctx context.Context, now time.Time) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT project_key, COUNT(*) FROM diagnosis_tasks WHERE status IN "+unfinishedTaskStatuses+" AND lease_until >= ? GROUP BY project_key",
+		now.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("count leased tasks: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]int)
+	for rows.Next() {
+		var key string
+		var count int
+		if err := rows.Scan(&key, &count); err != nil {
+			return nil, fmt.Errorf("scan leased count: %w", err)
+		}
+		result[key] = count
+	}
+	return result, rows.Err()
+}
+
+func (s *MySQLStore) AcquireTaskLease(ctx context.Context, id, owner string, now, until time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE diagnosis_tasks SET lease_owner = ?, lease_until = ?
+		 WHERE id = ? AND status IN `+unfinishedTaskStatuses+` AND (lease_until < ? OR lease_owner = ?)`,
+		owner, until.UnixMilli(), id, now.UnixMilli(), owner)
+	if err != nil {
+		return false, fmt.Errorf("acquire task lease: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("acquire task lease: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (s *MySQLStore) RenewTaskLease(ctx context.Context, id, owner string, until time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE diagnosis_tasks SET lease_until = ? WHERE id = ? AND lease_owner = ?",
+		until.UnixMilli(), id, owner)
+	if err != nil {
+		return false, fmt.Errorf("renew task lease: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("renew task lease: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (s *MySQLStore) ReleaseTaskLease(ctx context.Context, id, owner string) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE diagnosis_tasks SET lease_owner = '', lease_until = 0 WHERE id = ? AND lease_owner = ?",
+		id, owner)
+	if err != nil {
+		return fmt.Errorf("release task lease: %w", err)
+	}
+	return nil
+}
+
 func (s *MySQLStore) SaveReport(ctx context.Context, report *DiagnosisReport) error {
 	tools := report.ToolsUsed
 	if tools == nil {
@@ -416,16 +739,20 @@ func (s *MySQLStore) SaveReport(ctx context.Context, report *DiagnosisReport) er
 	if report.QualityScore != nil {
 		qualityScore = report.QualityScore
 	}
+	review := json.RawMessage("null")
+	if report.Review != nil {
+		review = report.Review
+	}
 
 	_, err = s.db.ExecContext(ctx,
-		`INSERT INTO diagnosis_reports (id, task_id, incident_id, project_key, project_name, summary, raw_result, has_issue, confidence, tools_used, skills_used, tainted, notified, diagnosed_at, structured_result, quality_score, commit_hash, prompt_version, original_confidence, final_confidence, final_confidence_label, fingerprint, reused_from_id)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		`INSERT INTO diagnosis_reports (id, task_id, incident_id, project_key, project_name, summary, raw_result, has_issue, confidence, tools_used, skills_used, tainted, notified, diagnosed_at, structured_result, quality_score, commit_hash, prompt_version, original_confidence, final_confidence, final_confidence_label, fingerprint, reused_from_id, review)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		report.ID, report.TaskID, report.EventID, report.ProjectKey, report.ProjectName,
 		report.Summary, report.RawResult, report.HasIssue, report.Confidence,
 		string(toolsUsed), string(skillsUsed), report.Tainted, report.Notified, report.DiagnosedAt,
 		string(structuredResult), string(qualityScore), report.CommitHash, report.PromptVersion,
 		report.OriginalConfidence, report.FinalConfidence, report.FinalConfLabel,
-		report.Fingerprint, report.ReusedFromID,
+		report.Fingerprint, report.ReusedFromID, string(review),
 	)
 	if err != nil {
 		return fmt.Errorf("insert report: %w", err)
@@ -435,7 +762,7 @@ func (s *MySQLStore) SaveReport(ctx context.Context, report *DiagnosisReport) er
 
 func (s *MySQLStore) GetReport(ctx context.Context, taskID string) (*DiagnosisReport, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, task_id, incident_id, project_key, project_name, summary, raw_result, has_issue, confidence, tools_used, skills_used, tainted, notified, diagnosed_at, structured_result, quality_score, commit_hash, prompt_version, original_confidence, final_confidence, final_confidence_label, fingerprint, reused_from_id
+		`SELECT id, task_id, incident_id, project_key, project_name, summary, raw_result, has_issue, confidence, tools_used, skills_used, tainted, notified, diagnosed_at, structured_result, quality_score, commit_hash, prompt_version, original_confidence, final_confidence, final_confidence_label, fingerprint, reused_from_id, review
 		 FROM diagnosis_reports WHERE task_id = ?`, taskID)
 
 	report, err := s.scanReport(row)
@@ -447,7 +774,7 @@ func (s *MySQLStore) GetReport(ctx context.Context, taskID string) (*DiagnosisRe
 
 func (s *MySQLStore) FindRecentReportByFingerprint(ctx context.Context, projectKey, fingerprint string, since time.Time) (*DiagnosisReport, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, task_id, incident_id, project_key, project_name, summary, raw_result, has_issue, confidence, tools_used, skills_used, tainted, notified, diagnosed_at, structured_result, quality_score, commit_hash, prompt_version, original_confidence, final_confidence, final_confidence_label, fingerprint, reused_from_id
+		`SELECT id, task_id, incident_id, project_key, project_name, summary, raw_result, has_issue, confidence, tools_used, skills_used, tainted, notified, diagnosed_at, structured_result, quality_score, commit_hash, prompt_version, original_confidence, final_confidence, final_confidence_label, fingerprint, reused_from_id, review
 		 FROM diagnosis_reports
 		 WHERE project_key = ? AND fingerprint = ? AND diagnosed_at > ? AND reused_from_id = ''
 		 ORDER BY diagnosed_at DESC LIMIT 1`, projectKey, fingerprint, since)
@@ -491,6 +818,238 @@ func (s *MySQLStore) GetUsageSummary(ctx context.Context) (*UsageSummary, error)
 	return summary, nil
 }
 
+func (s *MySQLStore) SumTokens(ctx context.Context, projectKey string, since time.Time) (int64, error) {
+	var total int64
+	for _, query := range []string{
+		"SELECT COALESCE(SUM(input_tokens + output_tokens), 0) FROM diagnosis_tasks WHERE COALESCE(finished_at, created_at) >= ?",
+		"SELECT COALESCE(SUM(input_tokens + output_tokens), 0) FROM report_follow_ups WHERE asked_at >= ?",
+	} {
+		args := []any{since}
+		if projectKey != "" {
+			query += " AND project_key = ?"
+			args = append(args, projectKey)
+		}
+		var sum int64
+		if err := s.db.QueryRowContext(ctx, query, args...).Scan(&sum); err != nil {
+			return 0, fmt.Errorf("sum tokens: %w", err)
+		}
+		total += sum
+	}
+	return total, nil
+}
+
+func (s *MySQLStore) AddReportFollowUp(ctx context.Context, fu *ReportFollowUp) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO report_follow_ups (id, report_id, task_id, project_key, question, answer, asked_by, session_id, commit_hash, tainted, duration_ms, input_tokens, output_tokens, asked_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		fu.ID, fu.ReportID, fu.TaskID, fu.ProjectKey, fu.Question, fu.Answer, fu.AskedBy, fu.SessionID,
+		fu.CommitHash, fu.Tainted, fu.DurationMs, fu.InputTokens, fu.OutputTokens, fu.AskedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert report follow-up: %w", err)
+	}
+	return nil
+}
+
+func (s *MySQLStore) ListReportFollowUps(ctx context.Context, reportID string) ([]*ReportFollowUp, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, report_id, task_id, project_key, question, answer, asked_by, session_id, commit_hash, tainted, duration_ms, input_tokens, output_tokens, asked_at
+		 FROM report_follow_ups WHERE report_id = ? ORDER BY asked_at ASC`, reportID)
+	if err != nil {
+		return nil, fmt.Errorf("list report follow-ups: %w", err)
+	}
+	defer rows.Close()
+
+	var followUps []*ReportFollowUp
+	for rows.Next() {
+		var fu ReportFollowUp
+		if err := rows.Scan(
+			&fu.ID, &fu.ReportID, &fu.TaskID, &fu.ProjectKey, &fu.Question, &fu.Answer, &fu.AskedBy, &fu.SessionID,
+			&fu.CommitHash, &fu.Tainted, &fu.DurationMs, &fu.InputTokens, &fu.OutputTokens, &fu.AskedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan report follow-up: %w", err)
+		}
+		followUps = append(followUps, &fu)
+	}
+	return followUps, rows.Err()
+}
+
+func (s *MySQLStore) CreateSilenceRule(ctx context.Context, rule *SilenceRule) error {
+	matchers, err := json.Marshal(rule.Matchers)
+	if err != nil {
+		return fmt.Errorf("marshal matchers: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO silence_rules (id, project_key, matchers, action, severity, reason, created_by, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rule.ID, rule.ProjectKey, string(matchers), rule.Action, rule.Severity,
+		rule.Reason, rule.CreatedBy, rule.CreatedAt, rule.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert silence rule: %w", err)
+	}
+	return nil
+}
+
+func (s *MySQLStore) GetSilenceRule(ctx context.Context, id string) (*SilenceRule, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT id, project_key, matchers, action, severity, reason, created_by, created_at, expires_at
+		 FROM silence_rules WHERE id = ?`, id)
+
+	rule, err := s.scanSilenceRule(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return rule, err
+}
+
+func (s *MySQLStore) UpdateSilenceRule(ctx context.Context, rule *SilenceRule) error {
+	matchers, err := json.Marshal(rule.Matchers)
+	if err != nil {
+		return fmt.Errorf("marshal matchers: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx,
+		`UPDATE silence_rules SET project_key=?, matchers=?, action=?, severity=?, reason=?, created_by=?, created_at=?, expires_at=?
+		 WHERE id=?`,
+		rule.ProjectKey, string(matchers), rule.Action, rule.Severity,
+		rule.Reason, rule.CreatedBy, rule.CreatedAt, rule.ExpiresAt, rule.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update silence rule: %w", err)
+	}
+	return nil
+}
+
+func (s *MySQLStore) ListSilenceRules(ctx context.Context, activeAt time.Time) ([]*SilenceRule, error) {
+	query := "SELECT id, project_key, matchers, action, severity, reason, created_by, created_at, expires_at FROM silence_rules"
+	var args []any
+	if !activeAt.IsZero() {
+		query += " WHERE expires_at > ?"
+		args = append(args, activeAt)
+	}
+	query += " ORDER BY created_at DESC"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list silence rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []*SilenceRule
+	for rows.Next() {
+		rule, err := s.scanSilenceRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan silence rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (s *MySQLStore) scanSilenceRule(row scannable) (*SilenceRule, error) {
+	var rule SilenceRule
+	var matchersStr string
+	err := row.Scan(
+		&rule.ID, &rule.ProjectKey, &matchersStr, &rule.Action, &rule.Severity,
+		&rule.Reason, &rule.CreatedBy, &rule.CreatedAt, &rule.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(matchersStr), &rule.Matchers); err != nil {
+		return nil, fmt.Errorf("unmarshal matchers: %w", err)
+	}
+	return &rule, nil
+}
+
+func (s *MySQLStore) ClaimDedupKey(ctx context.Context, key string, window time.Duration, now time.Time) (bool, error) {
+	// Columns are only overwritten when the previous claim is outside the
+	// window (expires_at first, while seen_at still holds the old value).
+	// Without CLIENT_FOUND_ROWS an unchanged row reports 0 affected rows.
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO dedup_keys (dedup_key, seen_at, expires_at) VALUES (?, ?, ?)
+		 ON DUPLICATE KEY UPDATE
+		   expires_at = IF(seen_at <= ?, VALUES(expires_at), expires_at),
+		   seen_at = IF(seen_at <= ?, VALUES(seen_at), seen_at)`,
+		key, now, now.Add(window), now.Add(-window), now.Add(-window),
+	)
+	if err != nil {
+		return false, fmt.Errorf("claim dedup key: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("claim dedup key: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (s *MySQLStore) PurgeDedupKeys(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM dedup_keys WHERE expires_at <= ?", before)
+	if err != nil {
+		return 0, fmt.Errorf("purge dedup keys: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func (s *MySQLStore) ClaimIdempotencyKey(ctx context.Context, rec *IdempotencyRecord) (*IdempotencyRecord, error) {
+	// Columns are only overwritten when the previous record has expired;
+	// expires_at is assigned last so every IF still sees the old value.
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO idempotency_keys (idem_key, event_id, task_id, status, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?)
+		 ON DUPLICATE KEY UPDATE
+		   event_id = IF(expires_at <= ?, VALUES(event_id), event_id),
+		   task_id = IF(expires_at <= ?, VALUES(task_id), task_id),
+		   status = IF(expires_at <= ?, VALUES(status), status),
+		   created_at = IF(expires_at <= ?, VALUES(created_at), created_at),
+		   expires_at = IF(expires_at <= ?, VALUES(expires_at), expires_at)`,
+		rec.Key, rec.EventID, rec.TaskID, rec.Status, rec.CreatedAt, rec.ExpiresAt,
+		rec.CreatedAt, rec.CreatedAt, rec.CreatedAt, rec.CreatedAt, rec.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claim idempotency key: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("claim idempotency key: %w", err)
+	} else if n > 0 {
+		return nil, nil
+	}
+
+	var existing IdempotencyRecord
+	err = s.db.QueryRowContext(ctx,
+		`SELECT idem_key, event_id, task_id, status, created_at, expires_at FROM idempotency_keys WHERE idem_key = ?`,
+		rec.Key,
+	).Scan(&existing.Key, &existing.EventID, &existing.TaskID, &existing.Status, &existing.CreatedAt, &existing.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("get idempotency key: %w", err)
+	}
+	return &existing, nil
+}
+
+func (s *MySQLStore) CompleteIdempotencyKey(ctx context.Context, key, taskID, status string, expiresAt time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE idempotency_keys SET task_id = ?, status = ?, expires_at = ? WHERE idem_key = ?", taskID, status, expiresAt.UTC(), key)
+	if err != nil {
+		return fmt.Errorf("complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (s *MySQLStore) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE idem_key = ?", key); err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (s *MySQLStore) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= ?", before)
+	if err != nil {
+		return 0, fmt.Errorf("purge idempotency keys: %w", err)
+	}
+	return res.RowsAffected()
+}
+
 func (s *MySQLStore) Close() error {
 	s.log.Info("store.mysql.closing")
 	return s.db.Close()
@@ -508,6 +1067,8 @@ func (s *MySQLStore) scanEvent(row mysqlScannable) (*Event, error) {
 	err := row.Scan(
 		&event.ID, &event.ProjectKey, &payloadStr, &event.Source,
 		&event.Severity, &event.Title, &event.Status, &event.ReceivedAt,
+		&event.Fingerprint, &event.Credential, &event.SilenceRuleID, &event.IncidentID,
+		&event.TaskID, &event.ReportID, &event.NotificationRef,
 	)
 	if err != nil {
 		return nil, err
@@ -518,16 +1079,24 @@ func (s *MySQLStore) scanEvent(row mysqlScannable) (*Event, error) {
 
 func (s *MySQLStore) scanTask(row mysqlScannable) (*DiagnosisTask, error) {
 	var task DiagnosisTask
-	var status string
+	var status, actionsStr, attemptsStr string
 	var startedAt, finishedAt sql.NullTime
+	var leaseUntil int64
 	err := row.Scan(
 		&task.ID, &task.EventID, &task.ProjectKey, &status, &task.Priority,
 		&task.SessionID, &task.NumTurns, &task.DurationMs, &task.InputTokens, &task.OutputTokens,
-		&task.Error, &task.RetryCount, &task.CreatedAt, &startedAt, &finishedAt,
+		&task.Error, &task.RetryCount, &task.CreatedAt, &startedAt, &finishedAt, &actionsStr,
+		&task.FailureClass, &attemptsStr, &task.LeaseOwner, &leaseUntil,
 	)
 	if err != nil {
 		return nil, err
 	}
+	if err := json.Unmarshal([]byte(actionsStr), &task.Actions); err != nil {
+		return nil, fmt.Errorf("unmarshal actions: %w", err)
+	}
+	if err := json.Unmarshal([]byte(attemptsStr), &task.Attempts); err != nil {
+		return nil, fmt.Errorf("unmarshal attempts: %w", err)
+	}
 	task.Status = TaskStatus(status)
 	if startedAt.Valid {
 		task.StartedAt = &startedAt.Time
@@ -535,20 +1104,21 @@ func (s *MySQLStore) scanTask(row mysqlScannable) (*DiagnosisTask, error) {
 	if finishedAt.Valid {
 		task.FinishedAt = &finishedAt.Time
 	}
+	task.LeaseExpiresAt = leaseTime(leaseUntil)
 	return &task, nil
 }
 
 func (s *MySQLStore) scanReport(row mysqlScannable) (*DiagnosisReport, error) {
 	var report DiagnosisReport
 	var toolsUsedStr, skillsUsedStr string
-	var structuredResult, qualityScore []byte
+	var structuredResult, qualityScore, review []byte
 	err := row.Scan(
 		&report.ID, &report.TaskID, &report.EventID, &report.ProjectKey, &report.ProjectName,
 		&report.Summary, &report.RawResult, &report.HasIssue, &report.Confidence,
 		&toolsUsedStr, &skillsUsedStr, &report.Tainted, &report.Notified, &report.DiagnosedAt,
 		&structuredResult, &qualityScore, &report.CommitHash, &report.PromptVersion,
 		&report.OriginalConfidence, &report.FinalConfidence, &report.FinalConfLabel,
-		&report.Fingerprint, &report.ReusedFromID,
+		&report.Fingerprint, &report.ReusedFromID, &review,
 	)
 	if err != nil {
 		return nil, err
@@ -561,5 +1131,8 @@ func (s *MySQLStore) scanReport(row mysqlScannable) (*DiagnosisReport, error) {
 	}
 	report.StructuredResult = json.RawMessage(structuredResult)
 	report.QualityScore = json.RawMessage(qualityScore)
+	if string(review) != "null" {
+		report.Review = json.RawMessage(review)
+	}
 	return &report, nil
 }
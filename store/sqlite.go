@@ -57,7 +57,14 @@ CREATE TABLE IF NOT EXISTS events (
     severity TEXT NOT NULL DEFAULT 'warning',
     title TEXT NOT NULL DEFAULT '',
     status TEXT NOT NULL DEFAULT 'pending',
-    received_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+    received_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    fingerprint TEXT NOT NULL DEFAULT '',
+    credential TEXT NOT NULL DEFAULT '',
+    silence_rule_id TEXT NOT NULL DEFAULT '',
+    incident_id TEXT NOT NULL DEFAULT '',
+    task_id TEXT NOT NULL DEFAULT '',
+    report_id TEXT NOT NULL DEFAULT '',
+    notification_ref TEXT NOT NULL DEFAULT ''
 );
 CREATE INDEX IF NOT EXISTS idx_events_project_key ON events(project_key);
 CREATE INDEX IF NOT EXISTS idx_events_status ON events(status);
@@ -79,7 +86,12 @@ CREATE TABLE IF NOT EXISTS diagnosis_tasks (
     retry_count INTEGER NOT NULL DEFAULT 0,
     created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     started_at DATETIME,
-    finished_at DATETIME
+    finished_at DATETIME,
+    actions TEXT NOT NULL DEFAULT '[]',
+    failure_class TEXT NOT NULL DEFAULT '',
+    attempts TEXT NOT NULL DEFAULT '[]',
+    lease_owner TEXT NOT NULL DEFAULT '',
+    lease_until INTEGER NOT NULL DEFAULT 0
 );
 CREATE INDEX IF NOT EXISTS idx_tasks_status ON diagnosis_tasks(status);
 CREATE INDEX IF NOT EXISTS idx_tasks_incident ON diagnosis_tasks(incident_id);
@@ -107,19 +119,93 @@ CREATE TABLE IF NOT EXISTS diagnosis_reports (
     final_confidence REAL NOT NULL DEFAULT 0,
     final_confidence_label TEXT NOT NULL DEFAULT '',
     fingerprint TEXT NOT NULL DEFAULT '',
-    reused_from_id TEXT NOT NULL DEFAULT ''
+    reused_from_id TEXT NOT NULL DEFAULT '',
+    review TEXT NOT NULL DEFAULT ''
 );
 CREATE INDEX IF NOT EXISTS idx_reports_task ON diagnosis_reports(task_id);
 CREATE INDEX IF NOT EXISTS idx_reports_project ON diagnosis_reports(project_key);
 CREATE INDEX IF NOT EXISTS idx_reports_fingerprint ON diagnosis_reports(project_key, fingerprint, diagnosed_at);
+
+CREATE TABLE IF NOT EXISTS report_follow_ups (
+    id TEXT PRIMARY KEY,
+    report_id TEXT NOT NULL REFERENCES diagnosis_reports(id),
+    task_id TEXT NOT NULL DEFAULT '',
+    project_key TEXT NOT NULL DEFAULT '',
+    question TEXT NOT NULL DEFAULT '',
+    answer TEXT NOT NULL DEFAULT '',
+    asked_by TEXT NOT NULL DEFAULT '',
+    session_id TEXT NOT NULL DEFAULT '',
+    commit_hash TEXT NOT NULL DEFAULT '',
+    tainted BOOLEAN NOT NULL DEFAULT 0,
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    input_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    asked_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_follow_ups_report ON report_follow_ups(report_id, asked_at);
+CREATE INDEX IF NOT EXISTS idx_follow_ups_asked_at ON report_follow_ups(asked_at);
+
+CREATE TABLE IF NOT EXISTS dedup_keys (
+    dedup_key TEXT PRIMARY KEY,
+    seen_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_dedup_keys_expires ON dedup_keys(expires_at);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    idem_key TEXT PRIMARY KEY,
+    event_id TEXT NOT NULL DEFAULT '',
+    task_id TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires ON idempotency_keys(expires_at);
+
+CREATE TABLE IF NOT EXISTS silence_rules (
+    id TEXT PRIMARY KEY,
+    project_key TEXT NOT NULL DEFAULT '',
+    matchers TEXT NOT NULL DEFAULT '[]',
+    action TEXT NOT NULL DEFAULT 'drop',
+    severity TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_silence_rules_expires ON silence_rules(expires_at);
+
+CREATE TABLE IF NOT EXISTS incidents (
+    id TEXT PRIMARY KEY,
+    project_key TEXT NOT NULL,
+    fingerprint TEXT NOT NULL DEFAULT '',
+    title TEXT NOT NULL DEFAULT '',
+    severity TEXT NOT NULL DEFAULT 'warning',
+    source TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    first_event_id TEXT NOT NULL DEFAULT '',
+    task_id TEXT NOT NULL DEFAULT '',
+    event_count INTEGER NOT NULL DEFAULT 0,
+    first_seen DATETIME NOT NULL,
+    last_seen DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_incidents_group ON incidents(project_key, fingerprint, status, last_seen);
+CREATE INDEX IF NOT EXISTS idx_incidents_last_seen ON incidents(last_seen);
 `
 	_, err := s.db.Exec(schema)
 	if err != nil {
 		return err
 	}
 
-	// Migrate existing databases: add new columns to diagnosis_reports.
+	// Migrate existing databases: add new columns to events and diagnosis_reports.
 	migrations := []string{
+		"ALTER TABLE events ADD COLUMN fingerprint TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE events ADD COLUMN credential TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE events ADD COLUMN silence_rule_id TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE events ADD COLUMN incident_id TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE events ADD COLUMN task_id TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE events ADD COLUMN report_id TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE events ADD COLUMN notification_ref TEXT NOT NULL DEFAULT ''",
 		"ALTER TABLE diagnosis_reports ADD COLUMN structured_result TEXT NOT NULL DEFAULT ''",
 		"ALTER TABLE diagnosis_reports ADD COLUMN quality_score TEXT NOT NULL DEFAULT '{}'",
 		"ALTER TABLE diagnosis_reports ADD COLUMN commit_hash TEXT NOT NULL DEFAULT ''",
@@ -129,6 +215,12 @@ CREATE INDEX IF NOT EXISTS idx_reports_fingerprint ON diagnosis_reports(project_
 		"ALTER TABLE diagnosis_reports ADD COLUMN final_confidence_label TEXT NOT NULL DEFAULT ''",
 		"ALTER TABLE diagnosis_reports ADD COLUMN fingerprint TEXT NOT NULL DEFAULT ''",
 		"ALTER TABLE diagnosis_reports ADD COLUMN reused_from_id TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE diagnosis_tasks ADD COLUMN actions TEXT NOT NULL DEFAULT '[]'",
+		"ALTER TABLE diagnosis_tasks ADD COLUMN failure_class TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE diagnosis_tasks ADD COLUMN attempts TEXT NOT NULL DEFAULT '[]'",
+		"ALTER TABLE diagnosis_tasks ADD COLUMN lease_owner TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE diagnosis_tasks ADD COLUMN lease_until INTEGER NOT NULL DEFAULT 0",
+		"ALTER TABLE diagnosis_reports ADD COLUMN review TEXT NOT NULL DEFAULT ''",
 	}
 	for _, stmt := range migrations {
 		if err := s.addColumnIfNotExists(stmt); err != nil {
@@ -138,6 +230,10 @@ CREATE INDEX IF NOT EXISTS idx_reports_fingerprint ON diagnosis_reports(project_
 
 	// P1: fingerprint index (CREATE INDEX IF NOT EXISTS is idempotent)
 	_, _ = s.db.Exec("CREATE INDEX IF NOT EXISTS idx_reports_fingerprint ON diagnosis_reports(project_key, fingerprint, diagnosed_at)")
+	_, _ = s.db.Exec("CREATE INDEX IF NOT EXISTS idx_events_fingerprint ON events(project_key, fingerprint)")
+	_, _ = s.db.Exec("CREATE INDEX IF NOT EXISTS idx_events_incident ON events(incident_id)")
+	_, _ = s.db.Exec("CREATE INDEX IF NOT EXISTS idx_events_task ON events(task_id)")
+	_, _ = s.db.Exec("CREATE INDEX IF NOT EXISTS idx_tasks_lease ON diagnosis_tasks(status, lease_until)")
 
 	return nil
 }
@@ -157,10 +253,11 @@ func (s *SQLiteStore) CreateEvent(ctx context.Context, event *Event) error {
 	}
 
 	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO events (id, project_key, payload, source, severity, title, status, received_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		`INSERT INTO events (id, project_key, payload, source, severity, title, status, received_at, fingerprint, credential, silence_rule_id, incident_id, task_id, report_id, notification_ref)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		event.ID, event.ProjectKey, string(payload), event.Source, event.Severity,
-		event.Title, event.Status, event.ReceivedAt,
+		event.Title, event.Status, event.ReceivedAt, event.Fingerprint, event.Credential, event.SilenceRuleID, event.IncidentID,
+		event.TaskID, event.ReportID, event.NotificationRef,
 	)
 	if err != nil {
 		return fmt.Errorf("insert event: %w", err)
@@ -170,7 +267,7 @@ func (s *SQLiteStore) CreateEvent(ctx context.Context, event *Event) error {
 
 func (s *SQLiteStore) GetEvent(ctx context.Context, id string) (*Event, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, project_key, payload, source, severity, title, status, received_at
+		`SELECT id, project_key, payload, source, severity, title, status, received_at, fingerprint, credential, silence_rule_id, incident_id, task_id, report_id, notification_ref
 		 FROM events WHERE id = ?`, id)
 
 	event, err := s.scanEvent(row)
@@ -187,10 +284,11 @@ func (s *SQLiteStore) UpdateEvent(ctx context.Context, event *Event) error {
 	}
 
 	_, err := s.db.ExecContext(ctx,
-		`UPDATE events SET project_key=?, payload=?, source=?, severity=?, title=?, status=?, received_at=?
+		`UPDATE events SET project_key=?, payload=?, source=?, severity=?, title=?, status=?, received_at=?, fingerprint=?, credential=?, silence_rule_id=?, incident_id=?, task_id=?, report_id=?, notification_ref=?
 		 WHERE id=?`,
 		event.ProjectKey, string(payload), event.Source, event.Severity,
-		event.Title, event.Status, event.ReceivedAt, event.ID,
+		event.Title, event.Status, event.ReceivedAt, event.Fingerprint, event.Credential, event.SilenceRuleID, event.IncidentID,
+		event.TaskID, event.ReportID, event.NotificationRef, event.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("update event: %w", err)
@@ -199,7 +297,7 @@ func (s *SQLiteStore) UpdateEvent(ctx context.Context, event *Event) error {
 }
 
 func (s *SQLiteStore) ListEvents(ctx context.Context, filter EventFilter) ([]*Event, error) {
-	query := "SELECT id, project_key, payload, source, severity, title, status, received_at FROM events"
+	query := "SELECT id, project_key, payload, source, severity, title, status, received_at, fingerprint, credential, silence_rule_id, incident_id, task_id, report_id, notification_ref FROM events"
 	var conditions []string
 	var args []any
 
@@ -219,6 +317,18 @@ func (s *SQLiteStore) ListEvents(ctx context.Context, filter EventFilter) ([]*Ev
 		conditions = append(conditions, "severity = ?")
 		args = append(args, filter.Severity)
 	}
+	if filter.Fingerprint != "" {
+		conditions = append(conditions, "fingerprint = ?")
+		args = append(args, filter.Fingerprint)
+	}
+	if filter.IncidentID != "" {
+		conditions = append(conditions, "incident_id = ?")
+		args = append(args, filter.IncidentID)
+	}
+	if filter.TaskID != "" {
+		conditions = append(conditions, "task_id = ?")
+		args = append(args, filter.TaskID)
+	}
 
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
@@ -253,6 +363,120 @@ func (s *SQLiteStore) ListEvents(ctx context.Context, filter EventFilter) ([]*Ev
 	return events, rows.Err()
 }
 
+const incidentColumns = "id, project_key, fingerprint, title, severity, source, status, first_event_id, task_id, event_count, first_seen, last_seen"
+
+func (s *SQLiteStore) CreateIncident(ctx context.Context, incident *Incident) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO incidents (`+incidentColumns+`)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		incident.ID, incident.ProjectKey, incident.Fingerprint, incident.Title, incident.Severity,
+		incident.Source, incident.Status, incident.FirstEventID, incident.TaskID, incident.EventCount,
+		incident.FirstSeen.UTC(), incident.LastSeen.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("insert incident: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetIncident(ctx context.Context, id string) (*Incident, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+incidentColumns+" FROM incidents WHERE id = ?", id)
+	incident, err := s.scanIncident(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return incident, err
+}
+
+func (s *SQLiteStore) UpdateIncident(ctx context.Context, incident *Incident) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE incidents SET project_key=?, fingerprint=?, title=?, severity=?, source=?, status=?, first_event_id=?, task_id=?, event_count=?, first_seen=?, last_seen=?
+		 WHERE id=?`,
+		incident.ProjectKey, incident.Fingerprint, incident.Title, incident.Severity,
+		incident.Source, incident.Status, incident.FirstEventID, incident.TaskID, incident.EventCount,
+		incident.FirstSeen.UTC(), incident.LastSeen.UTC(), incident.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update incident: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) ListIncidents(ctx context.Context, filter IncidentFilter) ([]*Incident, error) {
+	query := "SELECT " + incidentColumns + " FROM incidents"
+	var conditions []string
+	var args []any
+
+	if filter.ProjectKey != "" {
+		conditions = append(conditions, "project_key = ?")
+		args = append(args, filter.ProjectKey)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Severity != "" {
+		conditions = append(conditions, "severity = ?")
+		args = append(args, filter.Severity)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY last_seen DESC"
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	offset := filter.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list incidents: %w", err)
+	}
+	defer rows.Close()
+
+	var incidents []*Incident
+	for rows.Next() {
+		incident, err := s.scanIncident(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan incident: %w", err)
+		}
+		incidents = append(incidents, incident)
+	}
+	return incidents, rows.Err()
+}
+
+func (s *SQLiteStore) FindOpenIncident(ctx context.Context, projectKey, fingerprint string, since time.Time) (*Incident, error) {
+	row := s.db.QueryRowContext(ctx,
+		"SELECT "+incidentColumns+` FROM incidents
+		 WHERE project_key = ? AND fingerprint = ? AND status = ? AND last_seen >= ?
+		 ORDER BY last_seen DESC LIMIT 1`,
+		projectKey, fingerprint, IncidentStatusOpen, since.UTC())
+	incident, err := s.scanIncident(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return incident, err
+}
+
+func (s *SQLiteStore) scanIncident(row scannable) (*Incident, error) {
+	var incident Incident
+	err := row.Scan(
+		&incident.ID, &incident.ProjectKey, &incident.Fingerprint, &incident.Title, &incident.Severity,
+		&incident.Source, &incident.Status, &incident.FirstEventID, &incident.TaskID, &incident.EventCount,
+		&incident.FirstSeen, &incident.LastSeen,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
 func (s *SQLiteStore) CreateTask(ctx context.Context, task *DiagnosisTask) error {
 	var startedAt, finishedAt sql.NullTime
 	if task.StartedAt != nil {
@@ -261,13 +485,21 @@ func (s *SQLiteStore) CreateTask(ctx context.Context, task *DiagnosisTask) error
 	if task.FinishedAt != nil {
 		finishedAt = sql.NullTime{Time: *task.FinishedAt, Valid: true}
 	}
+	actions, err := marshalList("actions", task.Actions)
+	if err != nil {
+		return err
+	}
+	attempts, err := marshalList("attempts", task.Attempts)
+	if err != nil {
+		return err
+	}
 
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO diagnosis_tasks (id, incident_id, project_key, status, priority, session_id, num_turns, duration_ms, input_tokens, output_tokens, error, retry_count, created_at, started_at, finished_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO diagnosis_tasks (id, incident_id, project_key, status, priority, session_id, num_turns, duration_ms, input_tokens, output_tokens, error, retry_count, created_at, started_at, finished_at, actions, failure_class, attempts)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		task.ID, task.EventID, task.ProjectKey, string(task.Status), task.Priority,
 		task.SessionID, task.NumTurns, task.DurationMs, task.InputTokens, task.OutputTokens,
-		task.Error, task.RetryCount, task.CreatedAt, startedAt, finishedAt,
+		task.Error, task.RetryCount, task.CreatedAt, startedAt, finishedAt, actions, task.FailureClass, attempts,
 	)
 	if err != nil {
 		return fmt.Errorf("insert task: %w", err)
@@ -277,7 +509,7 @@ func (s *SQLiteStore) CreateTask(ctx context.Context, task *DiagnosisTask) error
 
 func (s *SQLiteStore) GetTask(ctx context.Context, id string) (*DiagnosisTask, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, incident_id, project_key, status, priority, session_id, num_turns, duration_ms, input_tokens, output_tokens, error, retry_count, created_at, started_at, finished_at
+		`SELECT id, incident_id, project_key, status, priority, session_id, num_turns, duration_ms, input_tokens, output_tokens, error, retry_count, created_at, started_at, finished_at, actions, failure_class, attempts, lease_owner, lease_until
 		 FROM diagnosis_tasks WHERE id = ?`, id)
 
 	task, err := s.scanTask(row)
@@ -295,13 +527,21 @@ func (s *SQLiteStore) UpdateTask(ctx context.Context, task *DiagnosisTask) error
 	if task.FinishedAt != nil {
 		finishedAt = sql.NullTime{Time: *task.FinishedAt, Valid: true}
 	}
+	actions, err := marshalList("actions", task.Actions)
+	if err != nil {
+		return err
+	}
+	attempts, err := marshalList("attempts", task.Attempts)
+	if err != nil {
+		return err
+	}
 
-	_, err := s.db.ExecContext(ctx,
-		`UPDATE diagnosis_tasks SET incident_id=?, project_key=?, status=?, priority=?, session_id=?, num_turns=?, duration_ms=?, input_tokens=?, output_tokens=?, error=?, retry_count=?, created_at=?, started_at=?, finished_at=?
+	_, err = s.db.ExecContext(ctx,
+		`UPDATE diagnosis_tasks SET incident_id=?, project_key=?, status=?, priority=?, session_id=?, num_turns=?, duration_ms=?, input_tokens=?, output_tokens=?, error=?, retry_count=?, created_at=?, started_at=?, finished_at=?, actions=?, failure_class=?, attempts=?
 		 WHERE id=?`,
 		task.EventID, task.ProjectKey, string(task.Status), task.Priority,
 		task.SessionID, task.NumTurns, task.DurationMs, task.InputTokens, task.OutputTokens,
-		task.Error, task.RetryCount, task.CreatedAt, startedAt, finishedAt, task.ID,
+		task.Error, task.RetryCount, task.CreatedAt, startedAt, finishedAt, actions, task.FailureClass, attempts, task.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("update task: %w", err)
@@ -310,7 +550,7 @@ func (s *SQLiteStore) UpdateTask(ctx context.Context, task *DiagnosisTask) error
 }
 
 func (s *SQLiteStore) ListTasks(ctx context.Context, filter TaskFilter) ([]*DiagnosisTask, error) {
-	query := "SELECT id, incident_id, project_key, status, priority, session_id, num_turns, duration_ms, input_tokens, output_tokens, error, retry_count, created_at, started_at, finished_at FROM diagnosis_tasks"
+	query := "SELECT id, incident_id, project_key, status, priority, session_id, num_turns, duration_ms, input_tokens, output_tokens, error, retry_count, created_at, started_at, finished_at, actions, failure_class, attempts, lease_owner, lease_until FROM diagnosis_tasks"
 	var conditions []string
 	var args []any
 
@@ -379,6 +619,90 @@ func (s *SQLiteStore) CountByStatus(ctx context.Context) (map[TaskStatus]int, er
 	return result, rows.Err()
 }
 
+func (s *SQLiteStore) ListClaimableTasks(ctx context.Context, now time.Time, limit int) ([]*DiagnosisTask, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, incident_id, project_key, status, priority, session_id, num_turns, duration_ms, input_tokens, output_tokens, error, retry_count, created_at, started_at, finished_at, actions, failure_class, attempts, lease_owner, lease_until
+		 FROM diagnosis_tasks WHERE status IN `+unfinishedTaskStatuses+` AND lease_until < ?
+		 ORDER BY priority DESC, created_at ASC LIMIT ?`, now.UnixMilli(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("list claimable tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []*DiagnosisTask
+	for rows.Next() {
+		task, err := s.scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+func (s *SQLiteStore) CountLeasedTasks(ctx context.Context, now time.Time) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT project_key, COUNT(*) FROM diagnosis_tasks WHERE status IN "+unfinishedTaskStatuses+" AND lease_until >= ? GROUP BY project_key",
+		now.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("count leased tasks: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]int)
+	for rows.Next() {
+		var key string
+		var count int
+		if err := rows.Scan(&key, &count); err != nil {
+			return nil, fmt.Errorf("scan leased count: %w", err)
+		}
+		result[key] = count
+	}
+	return result, rows.Err()
+}
+
+func (s *SQLiteStore) AcquireTaskLease(ctx context.Context, id, owner string, now, until time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE diagnosis_tasks SET lease_owner = ?, lease_until = ?
+		 WHERE id = ? AND status IN `+unfinishedTaskStatuses+` AND (lease_until < ? OR lease_owner = ?)`,
+		owner, until.UnixMilli(), id, now.UnixMilli(), owner)
+	if err != nil {
+		return false, fmt.Errorf("acquire task lease: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("acquire task lease: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (s *SQLiteStore) RenewTaskLease(ctx context.Context, id, owner string, until time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE diagnosis_tasks SET lease_until = ? WHERE id = ? AND lease_owner = ?",
+		until.UnixMilli(), id, owner)
+	if err != nil {
+		return false, fmt.Errorf("renew task lease: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("renew task lease: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (s *SQLiteStore) ReleaseTaskLease(ctx context.Context, id, owner string) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE diagnosis_tasks SET lease_owner = '', lease_until = 0 WHERE id = ? AND lease_owner = ?",
+		id, owner)
+	if err != nil {
+		return fmt.Errorf("release task lease: %w", err)
+	}
+	return nil
+}
+
 func (s *SQLiteStore) SaveReport(ctx context.Context, report *DiagnosisReport) error {
 	tools := report.ToolsUsed
 	if tools == nil {
@@ -407,14 +731,14 @@ func (s *SQLiteStore) SaveReport(ctx context.Context, report *DiagnosisReport) e
 	}
 
 	_, err = s.db.ExecContext(ctx,
-		`INSERT INTO diagnosis_reports (id, task_id, incident_id, project_key, project_name, summary, raw_result, has_issue, confidence, tools_used, skills_used, tainted, notified, diagnosed_at, structured_result, quality_score, commit_hash, prompt_version, original_confidence, final_confidence, final_confidence_label, fingerprint, reused_from_id)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		`INSERT INTO diagnosis_reports (id, task_id, incident_id, project_key, project_name, summary, raw_result, has_issue, confidence, tools_used, skills_used, tainted, notified, diagnosed_at, structured_result, quality_score, commit_hash, prompt_version, original_confidence, final_confidence, final_confidence_label, fingerprint, reused_from_id, review)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		report.ID, report.TaskID, report.EventID, report.ProjectKey, report.ProjectName,
 		report.Summary, report.RawResult, report.HasIssue, report.Confidence,
 		string(toolsUsed), string(skillsUsed), report.Tainted, report.Notified, report.DiagnosedAt,
 		structuredResult, qualityScore, report.CommitHash, report.PromptVersion,
 		report.OriginalConfidence, report.FinalConfidence, report.FinalConfLabel,
-		report.Fingerprint, report.ReusedFromID,
+		report.Fingerprint, report.ReusedFromID, string(report.Review),
 	)
 	if err != nil {
 		return fmt.Errorf("insert report: %w", err)
@@ -424,7 +748,7 @@ func (s *SQLiteStore) SaveReport(ctx context.Context, report *DiagnosisReport) e
 
 func (s *SQLiteStore) GetReport(ctx context.Context, taskID string) (*DiagnosisReport, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, task_id, incident_id, project_key, project_name, summary, raw_result, has_issue, confidence, tools_used, skills_used, tainted, notified, diagnosed_at, structured_result, quality_score, commit_hash, prompt_version, original_confidence, final_confidence, final_confidence_label, fingerprint, reused_from_id
+		`SELECT id, task_id, incident_id, project_key, project_name, summary, raw_result, has_issue, confidence, tools_used, skills_used, tainted, notified, diagnosed_at, structured_result, quality_score, commit_hash, prompt_version, original_confidence, final_confidence, final_confidence_label, fingerprint, reused_from_id, review
 		 FROM diagnosis_reports WHERE task_id = ?`, taskID)
 
 	report, err := s.scanReport(row)
@@ -436,7 +760,7 @@ func (s *SQLiteStore) GetReport(ctx context.Context, taskID string) (*DiagnosisR
 
 func (s *SQLiteStore) FindRecentReportByFingerprint(ctx context.Context, projectKey, fingerprint string, since time.Time) (*DiagnosisReport, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, task_id, incident_id, project_key, project_name, summary, raw_result, has_issue, confidence, tools_used, skills_used, tainted, notified, diagnosed_at, structured_result, quality_score, commit_hash, prompt_version, original_confidence, final_confidence, final_confidence_label, fingerprint, reused_from_id
+		`SELECT id, task_id, incident_id, project_key, project_name, summary, raw_result, has_issue, confidence, tools_used, skills_used, tainted, notified, diagnosed_at, structured_result, quality_score, commit_hash, prompt_version, original_confidence, final_confidence, final_confidence_label, fingerprint, reused_from_id, review
 		 FROM diagnosis_reports
 		 WHERE project_key = ? AND fingerprint = ? AND diagnosed_at > ? AND reused_from_id = ''
 		 ORDER BY diagnosed_at DESC LIMIT 1`, projectKey, fingerprint, since)
@@ -448,6 +772,42 @@ func (s *SQLiteStore) FindRecentReportByFingerprint(ctx context.Context, project
 	return report, err
 }
 
+func (s *SQLiteStore) AddReportFollowUp(ctx context.Context, fu *ReportFollowUp) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO report_follow_ups (id, report_id, task_id, project_key, question, answer, asked_by, session_id, commit_hash, tainted, duration_ms, input_tokens, output_tokens, asked_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		fu.ID, fu.ReportID, fu.TaskID, fu.ProjectKey, fu.Question, fu.Answer, fu.AskedBy, fu.SessionID,
+		fu.CommitHash, fu.Tainted, fu.DurationMs, fu.InputTokens, fu.OutputTokens, fu.AskedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert report follow-up: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) ListReportFollowUps(ctx context.Context, reportID string) ([]*ReportFollowUp, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, report_id, task_id, project_key, question, answer, asked_by, session_id, commit_hash, tainted, duration_ms, input_tokens, output_tokens, asked_at
+		 FROM report_follow_ups WHERE report_id = ? ORDER BY asked_at ASC`, reportID)
+	if err != nil {
+		return nil, fmt.Errorf("list report follow-ups: %w", err)
+	}
+	defer rows.Close()
+
+	var followUps []*ReportFollowUp
+	for rows.Next() {
+		var fu ReportFollowUp
+		if err := rows.Scan(
+			&fu.ID, &fu.ReportID, &fu.TaskID, &fu.ProjectKey, &fu.Question, &fu.Answer, &fu.AskedBy, &fu.SessionID,
+			&fu.CommitHash, &fu.Tainted, &fu.DurationMs, &fu.InputTokens, &fu.OutputTokens, &fu.AskedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan report follow-up: %w", err)
+		}
+		followUps = append(followUps, &fu)
+	}
+	return followUps, rows.Err()
+}
+
 func (s *SQLiteStore) GetUsageSummary(ctx context.Context) (*UsageSummary, error) {
 	summary := &UsageSummary{
 		TasksByStatus: make(map[TaskStatus]int),
@@ -480,6 +840,196 @@ func (s *SQLiteStore) GetUsageSummary(ctx context.Context) (*UsageSummary, error
 	return summary, nil
 }
 
+func (s *SQLiteStore) SumTokens(ctx context.Context, projectKey string, since time.Time) (int64, error) {
+	var total int64
+	for _, query := range []string{
+		"SELECT COALESCE(SUM(input_tokens + output_tokens), 0) FROM diagnosis_tasks WHERE COALESCE(finished_at, created_at) >= ?",
+		"SELECT COALESCE(SUM(input_tokens + output_tokens), 0) FROM report_follow_ups WHERE asked_at >= ?",
+	} {
+		args := []any{since}
+		if projectKey != "" {
+			query += " AND project_key = ?"
+			args = append(args, projectKey)
+		}
+		var sum int64
+		if err := s.db.QueryRowContext(ctx, query, args...).Scan(&sum); err != nil {
+			return 0, fmt.Errorf("sum tokens: %w", err)
+		}
+		total += sum
+	}
+	return total, nil
+}
+
+func (s *SQLiteStore) CreateSilenceRule(ctx context.Context, rule *SilenceRule) error {
+	matchers, err := json.Marshal(rule.Matchers)
+	if err != nil {
+		return fmt.Errorf("marshal matchers: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO silence_rules (id, project_key, matchers, action, severity, reason, created_by, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rule.ID, rule.ProjectKey, string(matchers), rule.Action, rule.Severity,
+		rule.Reason, rule.CreatedBy, rule.CreatedAt.UTC(), rule.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("insert silence rule: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetSilenceRule(ctx context.Context, id string) (*SilenceRule, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT id, project_key, matchers, action, severity, reason, created_by, created_at, expires_at
+		 FROM silence_rules WHERE id = ?`, id)
+
+	rule, err := s.scanSilenceRule(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return rule, err
+}
+
+func (s *SQLiteStore) UpdateSilenceRule(ctx context.Context, rule *SilenceRule) error {
+	matchers, err := json.Marshal(rule.Matchers)
+	if err != nil {
+		return fmt.Errorf("marshal matchers: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx,
+		`UPDATE silence_rules SET project_key=?, matchers=?, action=?, severity=?, reason=?, created_by=?, created_at=?, expires_at=?
+		 WHERE id=?`,
+		rule.ProjectKey, string(matchers), rule.Action, rule.Severity,
+		rule.Reason, rule.CreatedBy, rule.CreatedAt.UTC(), rule.ExpiresAt.UTC(), rule.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update silence rule: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) ListSilenceRules(ctx context.Context, activeAt time.Time) ([]*SilenceRule, error) {
+	query := "SELECT id, project_key, matchers, action, severity, reason, created_by, created_at, expires_at FROM silence_rules"
+	var args []any
+	if !activeAt.IsZero() {
+		query += " WHERE expires_at > ?"
+		args = append(args, activeAt.UTC())
+	}
+	query += " ORDER BY created_at DESC"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list silence rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []*SilenceRule
+	for rows.Next() {
+		rule, err := s.scanSilenceRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan silence rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (s *SQLiteStore) scanSilenceRule(row scannable) (*SilenceRule, error) {
+	var rule SilenceRule
+	var matchersStr string
+	err := row.Scan(
+		&rule.ID, &rule.ProjectKey, &matchersStr, &rule.Action, &rule.Severity,
+		&rule.Reason, &rule.CreatedBy, &rule.CreatedAt, &rule.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(matchersStr), &rule.Matchers); err != nil {
+		return nil, fmt.Errorf("unmarshal matchers: %w", err)
+	}
+	return &rule, nil
+}
+
+func (s *SQLiteStore) ClaimDedupKey(ctx context.Context, key string, window time.Duration, now time.Time) (bool, error) {
+	// The upsert only fires when the previous claim is outside the window,
+	// so RowsAffected is 0 for a duplicate and 1 for a new or renewed claim.
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO dedup_keys (dedup_key, seen_at, expires_at) VALUES (?, ?, ?)
+		 ON CONFLICT(dedup_key) DO UPDATE SET seen_at=excluded.seen_at, expires_at=excluded.expires_at
+		 WHERE dedup_keys.seen_at <= ?`,
+		key, now.UTC(), now.Add(window).UTC(), now.Add(-window).UTC(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("claim dedup key: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("claim dedup key: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (s *SQLiteStore) PurgeDedupKeys(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM dedup_keys WHERE expires_at <= ?", before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("purge dedup keys: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func (s *SQLiteStore) ClaimIdempotencyKey(ctx context.Context, rec *IdempotencyRecord) (*IdempotencyRecord, error) {
+	// An expired record is overwritten in place; a live one is left alone
+	// and returned to the caller.
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO idempotency_keys (idem_key, event_id, task_id, status, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(idem_key) DO UPDATE SET event_id=excluded.event_id, task_id=excluded.task_id,
+		   status=excluded.status, created_at=excluded.created_at, expires_at=excluded.expires_at
+		 WHERE idempotency_keys.expires_at <= ?`,
+		rec.Key, rec.EventID, rec.TaskID, rec.Status, rec.CreatedAt.UTC(), rec.ExpiresAt.UTC(), rec.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claim idempotency key: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("claim idempotency key: %w", err)
+	} else if n > 0 {
+		return nil, nil
+	}
+
+	var existing IdempotencyRecord
+	err = s.db.QueryRowContext(ctx,
+		`SELECT idem_key, event_id, task_id, status, created_at, expires_at FROM idempotency_keys WHERE idem_key = ?`,
+		rec.Key,
+	).Scan(&existing.Key, &existing.EventID, &existing.TaskID, &existing.Status, &existing.CreatedAt, &existing.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("get idempotency key: %w", err)
+	}
+	return &existing, nil
+}
+
+func (s *SQLiteStore) CompleteIdempotencyKey(ctx context.Context, key, taskID, status string, expiresAt time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE idempotency_keys SET task_id = ?, status = ?, expires_at = ? WHERE idem_key = ?", taskID, status, expiresAt.UTC(), key)
+	if err != nil {
+		return fmt.Errorf("complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE idem_key = ?", key); err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= ?", before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("purge idempotency keys: %w", err)
+	}
+	return res.RowsAffected()
+}
+
 func (s *SQLiteStore) Close() error {
 	s.log.Info("store.sqlite.closing")
 	return s.db.Close()
@@ -497,6 +1047,8 @@ func (s *SQLiteStore) scanEvent(row scannable) (*Event, error) {
 	err := row.Scan(
 		&event.ID, &event.ProjectKey, &payloadStr, &event.Source,
 		&event.Severity, &event.Title, &event.Status, &event.ReceivedAt,
+		&event.Fingerprint, &event.Credential, &event.SilenceRuleID, &event.IncidentID,
+		&event.TaskID, &event.ReportID, &event.NotificationRef,
 	)
 	if err != nil {
 		return nil, err
@@ -507,16 +1059,24 @@ func (s *SQLiteStore) scanEvent(row scannable) (*Event, error) {
 
 func (s *SQLiteStore) scanTask(row scannable) (*DiagnosisTask, error) {
 	var task DiagnosisTask
-	var status string
+	var status, actionsStr, attemptsStr string
 	var startedAt, finishedAt sql.NullTime
+	var leaseUntil int64
 	err := row.Scan(
 		&task.ID, &task.EventID, &task.ProjectKey, &status, &task.Priority,
 		&task.SessionID, &task.NumTurns, &task.DurationMs, &task.InputTokens, &task.OutputTokens,
-		&task.Error, &task.RetryCount, &task.CreatedAt, &startedAt, &finishedAt,
+		&task.Error, &task.RetryCount, &task.CreatedAt, &startedAt, &finishedAt, &actionsStr,
+		&task.FailureClass, &attemptsStr, &task.LeaseOwner, &leaseUntil,
 	)
 	if err != nil {
 		return nil, err
 	}
+	if err := json.Unmarshal([]byte(actionsStr), &task.Actions); err != nil {
+		return nil, fmt.Errorf("unmarshal actions: %w", err)
+	}
+	if err := json.Unmarshal([]byte(attemptsStr), &task.Attempts); err != nil {
+		return nil, fmt.Errorf("unmarshal attempts: %w", err)
+	}
 	task.Status = TaskStatus(status)
 	if startedAt.Valid {
 		task.StartedAt = &startedAt.Time
@@ -524,20 +1084,21 @@ func (s *SQLiteStore) scanTask(row scannable) (*DiagnosisTask, error) {
 	if finishedAt.Valid {
 		task.FinishedAt = &finishedAt.Time
 	}
+	task.LeaseExpiresAt = leaseTime(leaseUntil)
 	return &task, nil
 }
 
 func (s *SQLiteStore) scanReport(row scannable) (*DiagnosisReport, error) {
 	var report DiagnosisReport
 	var toolsUsedStr, skillsUsedStr string
-	var structuredResultStr, qualityScoreStr string
+	var structuredResultStr, qualityScoreStr, reviewStr string
 	err := row.Scan(
 		&report.ID, &report.TaskID, &report.EventID, &report.ProjectKey, &report.ProjectName,
 		&report.Summary, &report.RawResult, &report.HasIssue, &report.Confidence,
 		&toolsUsedStr, &skillsUsedStr, &report.Tainted, &report.Notified, &report.DiagnosedAt,
 		&structuredResultStr, &qualityScoreStr, &report.CommitHash, &report.PromptVersion,
 		&report.OriginalConfidence, &report.FinalConfidence, &report.FinalConfLabel,
-		&report.Fingerprint, &report.ReusedFromID,
+		&report.Fingerprint, &report.ReusedFromID, &reviewStr,
 	)
 	if err != nil {
 		return nil, err
@@ -554,5 +1115,8 @@ func (s *SQLiteStore) scanReport(row scannable) (*DiagnosisReport, error) {
 	if qualityScoreStr != "" {
 		report.QualityScore = json.RawMessage(qualityScoreStr)
 	}
+	if reviewStr != "" {
+		report.Review = json.RawMessage(reviewStr)
+	}
 	return &report, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
@@ -82,6 +83,7 @@ func makeReport(id, taskID, eventID, projectKey string) *DiagnosisReport {
 		FinalConfLabel:     "high",
 		Fingerprint:        "fp-123",
 		ReusedFromID:       "",
+		Review:             json.RawMessage(`{"adjusted_confidence":0.92}`),
 	}
 }
 
@@ -91,6 +93,7 @@ func TestSQLiteStore_CreateEvent_GetEvent(t *testing.T) {
 
 	now := time.Now().UTC().Truncate(time.Second)
 	ev := makeEvent("evt-1", "proj-a", "error", now)
+	ev.Credential = "orders-reporter"
 
 	if err := s.CreateEvent(ctx, ev); err != nil {
 		t.Fatalf("CreateEvent: %v", err)
@@ -127,6 +130,9 @@ func TestSQLiteStore_CreateEvent_GetEvent(t *testing.T) {
 	if !got.ReceivedAt.Equal(ev.ReceivedAt) {
 		t.Errorf("ReceivedAt = %v, want %v", got.ReceivedAt, ev.ReceivedAt)
 	}
+	if got.Credential != ev.Credential {
+		t.Errorf("Credential = %q, want %q", got.Credential, ev.Credential)
+	}
 }
 
 func TestSQLiteStore_GetEvent_NotFound(t *testing.T) {
@@ -234,6 +240,35 @@ func TestSQLiteStore_ListEvents(t *testing.T) {
 	}
 }
 
+func TestSQLiteStore_ListEvents_Fingerprint(t *testing.T) {
+	s := newTestSQLiteStore(t)
+	ctx := context.Background()
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	e1 := makeEvent("e1", "proj-a", "critical", base)
+	e1.Fingerprint = "am-1"
+	e2 := makeEvent("e2", "proj-a", "critical", base.Add(time.Hour))
+	e2.Fingerprint = "am-1"
+	e3 := makeEvent("e3", "proj-a", "critical", base.Add(2*time.Hour))
+	e3.Fingerprint = "am-2"
+	for _, ev := range []*Event{e1, e2, e3} {
+		if err := s.CreateEvent(ctx, ev); err != nil {
+			t.Fatalf("CreateEvent(%s): %v", ev.ID, err)
+		}
+	}
+
+	got, err := s.ListEvents(ctx, EventFilter{ProjectKey: "proj-a", Fingerprint: "am-1"})
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("len = %d, want 2", len(got))
+	}
+	if got[0].Fingerprint != "am-1" {
+		t.Errorf("Fingerprint = %q, want %q", got[0].Fingerprint, "am-1")
+	}
+}
+
 func TestSQLiteStore_CreateTask_GetTask(t *testing.T) {
 	s := newTestSQLiteStore(t)
 	ctx := context.Background()
@@ -287,6 +322,29 @@ func TestSQLiteStore_CreateTask_GetTask(t *testing.T) {
 	if !got.FinishedAt.Truncate(time.Second).Equal(task.FinishedAt.Truncate(time.Second)) {
 		t.Errorf("FinishedAt = %v, want %v", *got.FinishedAt, *task.FinishedAt)
 	}
+
+	if len(got.Actions) != 0 {
+		t.Errorf("Actions = %v, want none", got.Actions)
+	}
+
+	// Admin actions roundtrip through UpdateTask
+	task.Status = StatusCancelled
+	task.Actions = []TaskAction{{Action: "cancel", By: "alice", Reason: "noise", Detail: "removed from queue", At: now}}
+	task.FailureClass = "rate_limited"
+	task.Attempts = []TaskAttempt{{Number: 0, Class: "rate_limited", Error: "429", StartedAt: now, FinishedAt: now, BackoffMs: 30000}}
+	if err := s.UpdateTask(ctx, task); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	got, err = s.GetTask(ctx, "task-1")
+	if err != nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+	if len(got.Actions) != 1 || got.Actions[0].By != "alice" || got.Actions[0].Detail != "removed from queue" || !got.Actions[0].At.Equal(now) {
+		t.Errorf("Actions = %+v", got.Actions)
+	}
+	if got.FailureClass != "rate_limited" || len(got.Attempts) != 1 || got.Attempts[0].BackoffMs != 30000 {
+		t.Errorf("FailureClass = %q, Attempts = %+v", got.FailureClass, got.Attempts)
+	}
 }
 
 func TestSQLiteStore_GetTask_NotFound(t *testing.T) {
@@ -472,6 +530,9 @@ func TestSQLiteStore_SaveReport_GetReport(t *testing.T) {
 	if string(got.QualityScore) != `{"score":0.95}` {
 		t.Errorf("QualityScore = %s, want %s", got.QualityScore, `{"score":0.95}`)
 	}
+	if string(got.Review) != `{"adjusted_confidence":0.92}` {
+		t.Errorf("Review = %s, want %s", got.Review, `{"adjusted_confidence":0.92}`)
+	}
 
 	// Verify fingerprint fields
 	if got.Fingerprint != report.Fingerprint {
@@ -491,6 +552,57 @@ func TestSQLiteStore_SaveReport_GetReport(t *testing.T) {
 	}
 }
 
+func TestSQLiteStore_ReportFollowUps(t *testing.T) {
+	s := newTestSQLiteStore(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	if err := s.CreateEvent(ctx, makeEvent("evt-f1", "proj-a", "error", now)); err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	if err := s.CreateTask(ctx, makeTask("task-f1", "evt-f1", "proj-a", StatusCompleted)); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if err := s.SaveReport(ctx, makeReport("rpt-f1", "task-f1", "evt-f1", "proj-a")); err != nil {
+		t.Fatalf("SaveReport: %v", err)
+	}
+
+	before, err := s.SumTokens(ctx, "proj-a", now)
+	if err != nil {
+		t.Fatalf("SumTokens: %v", err)
+	}
+	for i, q := range []string{"what about the retry path?", "and the timeout?"} {
+		fu := &ReportFollowUp{
+			ID: "fu-" + strconv.Itoa(i), ReportID: "rpt-f1", TaskID: "task-f1", ProjectKey: "proj-a",
+			Question: q, Answer: "answer " + q, AskedBy: "alice", SessionID: "T-1",
+			CommitHash: "abc123", DurationMs: 1200, InputTokens: 100, OutputTokens: 20,
+			AskedAt: now.Add(time.Duration(i) * time.Minute),
+		}
+		if err := s.AddReportFollowUp(ctx, fu); err != nil {
+			t.Fatalf("AddReportFollowUp: %v", err)
+		}
+	}
+
+	got, err := s.ListReportFollowUps(ctx, "rpt-f1")
+	if err != nil {
+		t.Fatalf("ListReportFollowUps: %v", err)
+	}
+	if len(got) != 2 || got[0].Question != "what about the retry path?" || got[1].ID != "fu-1" {
+		t.Fatalf("follow-ups = %+v", got)
+	}
+	if got[0].Answer != "answer what about the retry path?" || got[0].SessionID != "T-1" || got[0].AskedBy != "alice" ||
+		got[0].InputTokens != 100 || got[0].DurationMs != 1200 || !got[0].AskedAt.Equal(now) {
+		t.Errorf("follow-up fields mismatch: %+v", got[0])
+	}
+	if none, err := s.ListReportFollowUps(ctx, "rpt-other"); err != nil || len(none) != 0 {
+		t.Errorf("other report: %v, %v", none, err)
+	}
+	// Follow-up tokens count against budgets.
+	if after, err := s.SumTokens(ctx, "proj-a", now); err != nil || after != before+240 {
+		t.Errorf("SumTokens = %d, %v; want %d", after, err, before+240)
+	}
+}
+
 func TestSQLiteStore_GetReport_NotFound(t *testing.T) {
 	s := newTestSQLiteStore(t)
 	ctx := context.Background()
@@ -646,3 +758,361 @@ func TestSQLiteStore_GetUsageSummary(t *testing.T) {
 		t.Errorf("TotalOutputTokens = %d, want 1600", summary.TotalOutputTokens)
 	}
 }
+
+func TestSQLiteStore_SumTokens(t *testing.T) {
+	s := newTestSQLiteStore(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	old := now.Add(-48 * time.Hour)
+
+	for _, id := range []string{"evt-1", "evt-2", "evt-3"} {
+		if err := s.CreateEvent(ctx, makeEvent(id, "proj-a", "error", now)); err != nil {
+			t.Fatalf("CreateEvent: %v", err)
+		}
+	}
+	t1 := makeTask("ts-1", "evt-1", "proj-a", StatusCompleted)
+	t2 := makeTask("ts-2", "evt-2", "proj-b", StatusCompleted)
+	t3 := makeTask("ts-3", "evt-3", "proj-a", StatusCompleted)
+	t3.CreatedAt = old
+	t3.FinishedAt = &old
+	for _, task := range []*DiagnosisTask{t1, t2, t3} {
+		if err := s.CreateTask(ctx, task); err != nil {
+			t.Fatalf("CreateTask: %v", err)
+		}
+	}
+
+	since := now.Add(-time.Hour)
+	tests := []struct {
+		project string
+		since   time.Time
+		want    int64
+	}{
+		{"", since, 600},
+		{"proj-a", since, 300},
+		{"proj-a", old.Add(-time.Hour), 600},
+		{"proj-c", since, 0},
+	}
+	for _, tt := range tests {
+		got, err := s.SumTokens(ctx, tt.project, tt.since)
+		if err != nil {
+			t.Fatalf("SumTokens(%q): %v", tt.project, err)
+		}
+		if got != tt.want {
+			t.Errorf("SumTokens(%q, %v) = %d, want %d", tt.project, tt.since, got, tt.want)
+		}
+	}
+}
+
+func TestSQLiteStore_TaskLeases(t *testing.T) {
+	s := newTestSQLiteStore(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	if err := s.CreateEvent(ctx, makeEvent("evt-l", "proj-a", "error", now)); err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	low := makeTask("tl-low", "evt-l", "proj-a", StatusQueued)
+	high := makeTask("tl-high", "evt-l", "proj-a", StatusQueued)
+	high.Priority = 100
+	done := makeTask("tl-done", "evt-l", "proj-a", StatusCompleted)
+	for _, task := range []*DiagnosisTask{low, high, done} {
+		if err := s.CreateTask(ctx, task); err != nil {
+			t.Fatalf("CreateTask: %v", err)
+		}
+	}
+
+	claimable, err := s.ListClaimableTasks(ctx, now, 10)
+	if err != nil {
+		t.Fatalf("ListClaimableTasks: %v", err)
+	}
+	if len(claimable) != 2 || claimable[0].ID != "tl-high" {
+		t.Fatalf("claimable = %v, want tl-high then tl-low", claimable)
+	}
+
+	until := now.Add(30 * time.Second)
+	if ok, err := s.AcquireTaskLease(ctx, "tl-high", "replica-a", now, until); err != nil || !ok {
+		t.Fatalf("AcquireTaskLease(a) = %v, %v", ok, err)
+	}
+	if ok, _ := s.AcquireTaskLease(ctx, "tl-high", "replica-b", now, until); ok {
+		t.Error("replica-b acquired a live lease")
+	}
+	if ok, _ := s.AcquireTaskLease(ctx, "tl-done", "replica-b", now, until); ok {
+		t.Error("acquired a finished task")
+	}
+	got, _ := s.GetTask(ctx, "tl-high")
+	if got.LeaseOwner != "replica-a" || got.LeaseExpiresAt == nil || !got.LeaseExpiresAt.Equal(until) {
+		t.Errorf("lease = %q until %v", got.LeaseOwner, got.LeaseExpiresAt)
+	}
+	// UpdateTask leaves the lease alone.
+	got.Status = StatusRunning
+	if err := s.UpdateTask(ctx, got); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if got, _ = s.GetTask(ctx, "tl-high"); got.LeaseOwner != "replica-a" {
+		t.Errorf("UpdateTask dropped the lease: owner = %q", got.LeaseOwner)
+	}
+
+	leased, err := s.CountLeasedTasks(ctx, now)
+	if err != nil || leased["proj-a"] != 1 {
+		t.Errorf("CountLeasedTasks = %v, %v", leased, err)
+	}
+	if claimable, _ = s.ListClaimableTasks(ctx, now, 10); len(claimable) != 1 {
+		t.Errorf("claimable while leased = %d, want 1", len(claimable))
+	}
+
+	if ok, _ := s.RenewTaskLease(ctx, "tl-high", "replica-b", until); ok {
+		t.Error("replica-b renewed replica-a's lease")
+	}
+	if ok, _ := s.RenewTaskLease(ctx, "tl-high", "replica-a", until.Add(time.Minute)); !ok {
+		t.Error("replica-a could not renew its lease")
+	}
+
+	// Once the lease expires another replica takes over.
+	later := until.Add(2 * time.Minute)
+	if claimable, _ = s.ListClaimableTasks(ctx, later, 10); len(claimable) != 2 {
+		t.Errorf("claimable after expiry = %d, want 2", len(claimable))
+	}
+	if ok, _ := s.AcquireTaskLease(ctx, "tl-high", "replica-b", later, later.Add(time.Minute)); !ok {
+		t.Error("replica-b could not take over an expired lease")
+	}
+	if ok, _ := s.RenewTaskLease(ctx, "tl-high", "replica-a", later.Add(time.Minute)); ok {
+		t.Error("replica-a renewed a lease it lost")
+	}
+
+	if err := s.ReleaseTaskLease(ctx, "tl-high", "replica-b"); err != nil {
+		t.Fatalf("ReleaseTaskLease: %v", err)
+	}
+	if got, _ = s.GetTask(ctx, "tl-high"); got.LeaseOwner != "" || got.LeaseExpiresAt != nil {
+		t.Errorf("after release: owner %q until %v", got.LeaseOwner, got.LeaseExpiresAt)
+	}
+}
+
+func TestSQLiteStore_ClaimDedupKey(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "dedup.db")
+	ctx := context.Background()
+	window := 10 * time.Minute
+	base := time.Now().UTC().Truncate(time.Second)
+
+	s1, err := NewSQLiteStore(dbPath, logger.Nop())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	ok, err := s1.ClaimDedupKey(ctx, "proj:abc", window, base)
+	if err != nil || !ok {
+		t.Fatalf("first claim: ok=%v err=%v", ok, err)
+	}
+	ok, err = s1.ClaimDedupKey(ctx, "proj:abc", window, base.Add(time.Minute))
+	if err != nil || ok {
+		t.Fatalf("duplicate within window: ok=%v err=%v", ok, err)
+	}
+	s1.Close()
+
+	// State survives a restart.
+	s2, err := NewSQLiteStore(dbPath, logger.Nop())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore reopen: %v", err)
+	}
+	defer s2.Close()
+	ok, err = s2.ClaimDedupKey(ctx, "proj:abc", window, base.Add(5*time.Minute))
+	if err != nil || ok {
+		t.Fatalf("duplicate after reopen: ok=%v err=%v", ok, err)
+	}
+	ok, err = s2.ClaimDedupKey(ctx, "proj:abc", window, base.Add(window))
+	if err != nil || !ok {
+		t.Fatalf("claim after window: ok=%v err=%v", ok, err)
+	}
+
+	n, err := s2.PurgeDedupKeys(ctx, base.Add(window+time.Minute))
+	if err != nil || n != 0 {
+		t.Fatalf("purge before expiry: n=%d err=%v", n, err)
+	}
+	n, err = s2.PurgeDedupKeys(ctx, base.Add(3*window))
+	if err != nil || n != 1 {
+		t.Fatalf("purge after expiry: n=%d err=%v", n, err)
+	}
+}
+
+func TestSQLiteStore_IdempotencyKeys(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "idem.db")
+	ctx := context.Background()
+	window := time.Hour
+	pendingTTL := time.Minute
+	base := time.Now().UTC().Truncate(time.Second)
+	claim := func(s *SQLiteStore, eventID string, at time.Time) *IdempotencyRecord {
+		t.Helper()
+		existing, err := s.ClaimIdempotencyKey(ctx, &IdempotencyRecord{
+			Key: "cred:k1", EventID: eventID, Status: IdempotencyStatusPending,
+			CreatedAt: at, ExpiresAt: at.Add(pendingTTL),
+		})
+		if err != nil {
+			t.Fatalf("ClaimIdempotencyKey: %v", err)
+		}
+		return existing
+	}
+
+	s1, err := NewSQLiteStore(dbPath, logger.Nop())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	if existing := claim(s1, "evt-1", base); existing != nil {
+		t.Fatalf("first claim returned %+v", existing)
+	}
+	if existing := claim(s1, "evt-2", base.Add(30*time.Second)); existing == nil || existing.Status != IdempotencyStatusPending {
+		t.Fatalf("in-flight claim: %+v", existing)
+	}
+	if err := s1.CompleteIdempotencyKey(ctx, "cred:k1", "task-1", "queued", base.Add(window)); err != nil {
+		t.Fatalf("CompleteIdempotencyKey: %v", err)
+	}
+	s1.Close()
+
+	// Completing extends the key from the pending TTL to the full window,
+	// and the outcome survives a restart.
+	s2, err := NewSQLiteStore(dbPath, logger.Nop())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore reopen: %v", err)
+	}
+	defer s2.Close()
+	existing := claim(s2, "evt-3", base.Add(30*time.Minute))
+	if existing == nil || existing.EventID != "evt-1" || existing.TaskID != "task-1" || existing.Status != "queued" {
+		t.Fatalf("replay after reopen: %+v", existing)
+	}
+
+	// An expired key is claimed afresh.
+	if existing := claim(s2, "evt-4", base.Add(window)); existing != nil {
+		t.Fatalf("claim after expiry returned %+v", existing)
+	}
+	if err := s2.ReleaseIdempotencyKey(ctx, "cred:k1"); err != nil {
+		t.Fatalf("ReleaseIdempotencyKey: %v", err)
+	}
+	if existing := claim(s2, "evt-5", base.Add(window+time.Minute)); existing != nil {
+		t.Fatalf("claim after release returned %+v", existing)
+	}
+
+	// A pending claim whose request never completed lapses after its TTL.
+	if existing := claim(s2, "evt-6", base.Add(window+time.Minute+pendingTTL)); existing != nil {
+		t.Fatalf("claim after stale pending returned %+v", existing)
+	}
+
+	n, err := s2.PurgeIdempotencyKeys(ctx, base.Add(3*window))
+	if err != nil || n != 1 {
+		t.Fatalf("purge after expiry: n=%d err=%v", n, err)
+	}
+}
+
+func TestSQLiteStore_SilenceRules(t *testing.T) {
+	s := newTestSQLiteStore(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	active := &SilenceRule{
+		ID:         "sil-1",
+		ProjectKey: "proj-a",
+		Matchers:   []SilenceMatcher{{Field: "error", Pattern: "timeout"}},
+		Action:     "drop",
+		Reason:     "known flaky upstream",
+		CreatedBy:  "alice",
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(time.Hour),
+	}
+	expired := &SilenceRule{
+		ID:        "sil-2",
+		Matchers:  []SilenceMatcher{{Field: "msg", Pattern: "x"}},
+		Action:    "downgrade",
+		Severity:  "info",
+		CreatedAt: now.Add(-2 * time.Hour),
+		ExpiresAt: now.Add(-time.Hour),
+	}
+	for _, r := range []*SilenceRule{active, expired} {
+		if err := s.CreateSilenceRule(ctx, r); err != nil {
+			t.Fatalf("CreateSilenceRule: %v", err)
+		}
+	}
+
+	got, err := s.GetSilenceRule(ctx, "sil-1")
+	if err != nil || got == nil {
+		t.Fatalf("GetSilenceRule: %v %v", got, err)
+	}
+	if got.Reason != active.Reason || len(got.Matchers) != 1 || got.Matchers[0].Pattern != "timeout" {
+		t.Errorf("unexpected rule: %+v", got)
+	}
+
+	rules, err := s.ListSilenceRules(ctx, now)
+	if err != nil || len(rules) != 1 || rules[0].ID != "sil-1" {
+		t.Fatalf("ListSilenceRules(active): %v %v", rules, err)
+	}
+	rules, err = s.ListSilenceRules(ctx, time.Time{})
+	if err != nil || len(rules) != 2 {
+		t.Fatalf("ListSilenceRules(all): %v %v", rules, err)
+	}
+
+	got.ExpiresAt = now
+	if err := s.UpdateSilenceRule(ctx, got); err != nil {
+		t.Fatalf("UpdateSilenceRule: %v", err)
+	}
+	if rules, _ := s.ListSilenceRules(ctx, now); len(rules) != 0 {
+		t.Errorf("expected no active rules after expiry, got %d", len(rules))
+	}
+
+	if missing, err := s.GetSilenceRule(ctx, "nope"); err != nil || missing != nil {
+		t.Errorf("expected nil for missing rule, got %v %v", missing, err)
+	}
+}
+
+func TestSQLiteStore_Incidents(t *testing.T) {
+	s := newTestSQLiteStore(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	open := &Incident{
+		ID: "inc-1", ProjectKey: "proj-a", Fingerprint: "fp-1", Title: "boom", Severity: "warning",
+		Status: IncidentStatusOpen, FirstEventID: "evt-1", EventCount: 1,
+		FirstSeen: now.Add(-20 * time.Minute), LastSeen: now.Add(-5 * time.Minute),
+	}
+	stale := &Incident{
+		ID: "inc-2", ProjectKey: "proj-a", Fingerprint: "fp-1", Severity: "warning",
+		Status: IncidentStatusOpen, EventCount: 1,
+		FirstSeen: now.Add(-3 * time.Hour), LastSeen: now.Add(-2 * time.Hour),
+	}
+	for _, inc := range []*Incident{open, stale} {
+		if err := s.CreateIncident(ctx, inc); err != nil {
+			t.Fatalf("CreateIncident: %v", err)
+		}
+	}
+
+	got, err := s.FindOpenIncident(ctx, "proj-a", "fp-1", now.Add(-30*time.Minute))
+	if err != nil || got == nil || got.ID != "inc-1" {
+		t.Fatalf("FindOpenIncident: %v %v", got, err)
+	}
+	if got, _ := s.FindOpenIncident(ctx, "proj-b", "fp-1", now.Add(-30*time.Minute)); got != nil {
+		t.Errorf("expected no incident for another project, got %s", got.ID)
+	}
+
+	got.Status = IncidentStatusResolved
+	got.EventCount = 3
+	if err := s.UpdateIncident(ctx, got); err != nil {
+		t.Fatalf("UpdateIncident: %v", err)
+	}
+	if got, _ := s.FindOpenIncident(ctx, "proj-a", "fp-1", now.Add(-30*time.Minute)); got != nil {
+		t.Errorf("resolved incident should not be found, got %s", got.ID)
+	}
+
+	list, err := s.ListIncidents(ctx, IncidentFilter{ProjectKey: "proj-a"})
+	if err != nil || len(list) != 2 || list[0].ID != "inc-1" || list[0].EventCount != 3 {
+		t.Fatalf("ListIncidents: %+v %v", list, err)
+	}
+	list, _ = s.ListIncidents(ctx, IncidentFilter{Status: IncidentStatusOpen})
+	if len(list) != 1 || list[0].ID != "inc-2" {
+		t.Errorf("ListIncidents(open): %+v", list)
+	}
+
+	ev := makeEvent("evt-1", "proj-a", "warning", now)
+	ev.IncidentID = "inc-1"
+	if err := s.CreateEvent(ctx, ev); err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	events, _ := s.ListEvents(ctx, EventFilter{IncidentID: "inc-1"})
+	if len(events) != 1 || events[0].IncidentID != "inc-1" {
+		t.Errorf("ListEvents by incident: %+v", events)
+	}
+}
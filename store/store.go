@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -16,6 +17,10 @@ const (
 	StatusCompleted TaskStatus = "completed"
 	StatusFailed    TaskStatus = "failed"
 	StatusTimeout   TaskStatus = "timeout"
+	// StatusExpired marks a task dropped after exceeding its max queue wait.
+	StatusExpired TaskStatus = "expired"
+	// StatusCancelled marks a task cancelled through the admin API.
+	StatusCancelled TaskStatus = "cancelled"
 )
 
 // Event represents an event record in the store.
@@ -28,6 +33,95 @@ type Event struct {
 	Title      string          `json:"title"`
 	Status     string          `json:"status"`
 	ReceivedAt time.Time       `json:"received_at"`
+
+	// Fingerprint is the reporter-supplied identity of the underlying alert
+	// (e.g. the Alertmanager fingerprint). Empty for payload-only events.
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	// Credential is the intake credential that submitted the event.
+	Credential string `json:"credential,omitempty"`
+
+	// SilenceRuleID is the silence rule that suppressed or downgraded the event.
+	SilenceRuleID string `json:"silence_rule_id,omitempty"`
+
+	// IncidentID is the incident this event was grouped into.
+	IncidentID string `json:"incident_id,omitempty"`
+
+	// TaskID is the in-flight diagnosis task a coalesced event was attached
+	// to, and ReportID the report that task produced for it.
+	TaskID   string `json:"task_id,omitempty"`
+	ReportID string `json:"report_id,omitempty"`
+	// NotificationRef identifies the notification that covered a coalesced
+	// event, as channel:report ID (e.g. "feishu:rpt-task-1a2b3c4d").
+	NotificationRef string `json:"notification_ref,omitempty"`
+}
+
+// EventStatusGrouped marks an event attached to an already-open incident;
+// it is not diagnosed on its own.
+const EventStatusGrouped = "grouped"
+
+// EventStatusCoalesced marks an event attached to a queued or running task
+// with the same diagnosis fingerprint; it takes that task's outcome.
+const EventStatusCoalesced = "coalesced"
+
+// Incident status values.
+const (
+	IncidentStatusOpen     = "open"
+	IncidentStatusResolved = "resolved"
+)
+
+// Incident groups events of one project that share a fingerprint and arrive
+// close together. Only the first event is diagnosed.
+type Incident struct {
+	ID           string    `json:"id"`
+	ProjectKey   string    `json:"project_key"`
+	Fingerprint  string    `json:"fingerprint"`
+	Title        string    `json:"title"`
+	Severity     string    `json:"severity"`
+	Source       string    `json:"source"`
+	Status       string    `json:"status"`
+	FirstEventID string    `json:"first_event_id"`
+	TaskID       string    `json:"task_id"`
+	EventCount   int       `json:"event_count"`
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+}
+
+// EventStatusSuppressed marks an event muted by a silence rule. It is
+// recorded for auditing but never diagnosed.
+const EventStatusSuppressed = "suppressed"
+
+// SilenceMatcher matches a payload field (dotted path) against a regular expression.
+type SilenceMatcher struct {
+	Field   string `json:"field"`
+	Pattern string `json:"pattern"`
+}
+
+// SilenceRule mutes or downgrades matching events until it expires.
+type SilenceRule struct {
+	ID         string           `json:"id"`
+	ProjectKey string           `json:"project_key"` // empty matches every project
+	Matchers   []SilenceMatcher `json:"matchers"`
+	Action     string           `json:"action"`             // "drop" or "downgrade"
+	Severity   string           `json:"severity,omitempty"` // target severity for "downgrade"
+	Reason     string           `json:"reason"`
+	CreatedBy  string           `json:"created_by"`
+	CreatedAt  time.Time        `json:"created_at"`
+	ExpiresAt  time.Time        `json:"expires_at"`
+}
+
+// IdempotencyStatusPending marks a key whose first request is still in flight.
+const IdempotencyStatusPending = "pending"
+
+// IdempotencyRecord maps an intake Idempotency-Key to the event and task the
+// first request with that key produced.
+type IdempotencyRecord struct {
+	Key       string    `json:"key"`
+	EventID   string    `json:"event_id"`
+	TaskID    string    `json:"task_id"`
+	Status    string    `json:"status"` // intake response status, or "pending"
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 // DiagnosisTask represents a diagnosis task record in the store.
@@ -47,6 +141,49 @@ type DiagnosisTask struct {
 	CreatedAt    time.Time  `json:"created_at"`
 	StartedAt    *time.Time `json:"started_at"`
 	FinishedAt   *time.Time `json:"finished_at"`
+	// Actions records admin actions (cancel, priority, pause, resume), oldest first.
+	Actions []TaskAction `json:"actions,omitempty"`
+	// FailureClass classifies the last failed attempt (retryable,
+	// non_retryable, rate_limited, timeout).
+	FailureClass string `json:"failure_class,omitempty"`
+	// Attempts records every failed attempt, oldest first.
+	Attempts []TaskAttempt `json:"attempts,omitempty"`
+	// LeaseOwner is the replica holding the task and LeaseExpiresAt when
+	// its lease runs out. Both are read-only here: CreateTask and
+	// UpdateTask leave them alone, the lease methods manage them.
+	LeaseOwner     string     `json:"lease_owner,omitempty"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+}
+
+// TaskAttempt is the outcome of one failed diagnosis attempt.
+type TaskAttempt struct {
+	Number     int       `json:"number"`
+	Class      string    `json:"class"`
+	Error      string    `json:"error"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	BackoffMs  int64     `json:"backoff_ms,omitempty"`
+}
+
+// TaskAction is an admin action taken on a task.
+type TaskAction struct {
+	Action string    `json:"action"`
+	By     string    `json:"by,omitempty"`
+	Reason string    `json:"reason,omitempty"`
+	Detail string    `json:"detail,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// marshalList encodes a task's actions or attempts for the SQL backends.
+func marshalList[T any](name string, list []T) (string, error) {
+	if list == nil {
+		list = []T{}
+	}
+	b, err := json.Marshal(list)
+	if err != nil {
+		return "", fmt.Errorf("marshal %s: %w", name, err)
+	}
+	return string(b), nil
 }
 
 // DiagnosisReport represents a diagnosis report record in the store.
@@ -74,16 +211,49 @@ type DiagnosisReport struct {
 	OriginalConfidence float64         `json:"original_confidence,omitempty"`
 	FinalConfidence    float64         `json:"final_confidence,omitempty"`
 	FinalConfLabel     string          `json:"final_confidence_label,omitempty"`
+	// Review is the reviewer stage's verdicts (nil if not reviewed).
+	Review json.RawMessage `json:"review,omitempty"`
 
 	// P1: Fingerprint reuse
 	Fingerprint  string `json:"fingerprint,omitempty"`
 	ReusedFromID string `json:"reused_from_id,omitempty"`
 }
 
+// ReportFollowUp is one follow-up question on a finished diagnosis and the
+// agent's answer, asked by continuing the diagnosis' Amp thread.
+type ReportFollowUp struct {
+	ID           string    `json:"id"`
+	ReportID     string    `json:"report_id"`
+	TaskID       string    `json:"task_id"`
+	ProjectKey   string    `json:"project_key"`
+	Question     string    `json:"question"`
+	Answer       string    `json:"answer"`
+	AskedBy      string    `json:"asked_by,omitempty"`
+	SessionID    string    `json:"session_id"`
+	CommitHash   string    `json:"commit_hash,omitempty"`
+	Tainted      bool      `json:"tainted"`
+	DurationMs   int64     `json:"duration_ms"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	AskedAt      time.Time `json:"asked_at"`
+}
+
 // EventFilter specifies criteria for listing events.
 type EventFilter struct {
+	ProjectKey  string `json:"project_key"`
+	Source      string `json:"source"`
+	Status      string `json:"status"`
+	Severity    string `json:"severity"`
+	Fingerprint string `json:"fingerprint"`
+	IncidentID  string `json:"incident_id"`
+	TaskID      string `json:"task_id"`
+	Limit       int    `json:"limit"`
+	Offset      int    `json:"offset"`
+}
+
+// IncidentFilter specifies criteria for listing incidents.
+type IncidentFilter struct {
 	ProjectKey string `json:"project_key"`
-	Source     string `json:"source"`
 	Status     string `json:"status"`
 	Severity   string `json:"severity"`
 	Limit      int    `json:"limit"`
@@ -108,6 +278,19 @@ type UsageSummary struct {
 	TotalOutputTokens int64              `json:"total_output_tokens"`
 }
 
+// unfinishedTaskStatuses are the task statuses a lease can be taken on,
+// as an SQL list.
+const unfinishedTaskStatuses = "('pending', 'queued', 'running')"
+
+// leaseTime converts a lease expiry stored as Unix milliseconds; 0 is no lease.
+func leaseTime(ms int64) *time.Time {
+	if ms == 0 {
+		return nil
+	}
+	t := time.UnixMilli(ms)
+	return &t
+}
+
 // Store defines the persistence interface for amp-sentinel.
 type Store interface {
 	CreateEvent(ctx context.Context, event *Event) error
@@ -115,17 +298,73 @@ type Store interface {
 	UpdateEvent(ctx context.Context, event *Event) error
 	ListEvents(ctx context.Context, filter EventFilter) ([]*Event, error)
 
+	CreateIncident(ctx context.Context, incident *Incident) error
+	GetIncident(ctx context.Context, id string) (*Incident, error)
+	UpdateIncident(ctx context.Context, incident *Incident) error
+	ListIncidents(ctx context.Context, filter IncidentFilter) ([]*Incident, error)
+	// FindOpenIncident returns the most recently active open incident for the
+	// project and fingerprint whose last event is at or after since, or nil.
+	FindOpenIncident(ctx context.Context, projectKey, fingerprint string, since time.Time) (*Incident, error)
+
 	CreateTask(ctx context.Context, task *DiagnosisTask) error
 	GetTask(ctx context.Context, id string) (*DiagnosisTask, error)
 	UpdateTask(ctx context.Context, task *DiagnosisTask) error
 	ListTasks(ctx context.Context, filter TaskFilter) ([]*DiagnosisTask, error)
 	CountByStatus(ctx context.Context) (map[TaskStatus]int, error)
 
+	// ListClaimableTasks returns unfinished tasks with no live lease at now,
+	// highest priority first, then oldest first.
+	ListClaimableTasks(ctx context.Context, now time.Time, limit int) ([]*DiagnosisTask, error)
+	// CountLeasedTasks counts tasks under a live lease per project.
+	CountLeasedTasks(ctx context.Context, now time.Time) (map[string]int, error)
+	// AcquireTaskLease leases an unfinished task to owner until the given
+	// time. Returns false if another owner holds a live lease or the task
+	// is finished.
+	AcquireTaskLease(ctx context.Context, id, owner string, now, until time.Time) (bool, error)
+	// RenewTaskLease extends owner's lease. Returns false if owner no
+	// longer holds it.
+	RenewTaskLease(ctx context.Context, id, owner string, until time.Time) (bool, error)
+	// ReleaseTaskLease drops owner's lease so the task can be claimed at once.
+	ReleaseTaskLease(ctx context.Context, id, owner string) error
+
 	SaveReport(ctx context.Context, report *DiagnosisReport) error
 	GetReport(ctx context.Context, taskID string) (*DiagnosisReport, error)
 	FindRecentReportByFingerprint(ctx context.Context, projectKey, fingerprint string, since time.Time) (*DiagnosisReport, error)
+	// AddReportFollowUp appends a follow-up Q&A turn to a report's history.
+	AddReportFollowUp(ctx context.Context, followUp *ReportFollowUp) error
+	// ListReportFollowUps returns a report's follow-up history, oldest first.
+	ListReportFollowUps(ctx context.Context, reportID string) ([]*ReportFollowUp, error)
 
 	GetUsageSummary(ctx context.Context) (*UsageSummary, error)
+	// SumTokens returns input plus output tokens of tasks finished and
+	// report follow-ups asked since the given time. An empty projectKey sums
+	// all projects.
+	SumTokens(ctx context.Context, projectKey string, since time.Time) (int64, error)
+
+	CreateSilenceRule(ctx context.Context, rule *SilenceRule) error
+	GetSilenceRule(ctx context.Context, id string) (*SilenceRule, error)
+	UpdateSilenceRule(ctx context.Context, rule *SilenceRule) error
+	// ListSilenceRules returns rules that have not expired at activeAt, newest
+	// first. A zero activeAt returns every rule.
+	ListSilenceRules(ctx context.Context, activeAt time.Time) ([]*SilenceRule, error)
+
+	// ClaimDedupKey atomically records an intake dedup key. It returns true if
+	// the key is new or was last claimed at or before now-window.
+	ClaimDedupKey(ctx context.Context, key string, window time.Duration, now time.Time) (bool, error)
+	// PurgeDedupKeys removes dedup keys whose window ended before the given time.
+	PurgeDedupKeys(ctx context.Context, before time.Time) (int64, error)
+
+	// ClaimIdempotencyKey inserts rec unless an unexpired record with the same
+	// key exists. It returns nil when rec was claimed, otherwise the existing
+	// record. Expiry is judged against rec.CreatedAt.
+	ClaimIdempotencyKey(ctx context.Context, rec *IdempotencyRecord) (*IdempotencyRecord, error)
+	// CompleteIdempotencyKey records the outcome of the request that claimed
+	// key and moves its expiry from the short pending claim to expiresAt.
+	CompleteIdempotencyKey(ctx context.Context, key, taskID, status string, expiresAt time.Time) error
+	// ReleaseIdempotencyKey deletes a claim so a failed request can be retried.
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+	// PurgeIdempotencyKeys removes records that expired before the given time.
+	PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int64, error)
 
 	Close() error
 }